@@ -0,0 +1,222 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/user"
+	"strings"
+
+	"github.com/ahmadmirdas/julo-test/config"
+	"github.com/ahmadmirdas/julo-test/config/database"
+	"github.com/ahmadmirdas/julo-test/repository/database/models"
+	"github.com/ahmadmirdas/julo-test/server/middleware"
+	"github.com/ahmadmirdas/julo-test/service"
+	"github.com/ahmadmirdas/julo-test/utils/activity"
+)
+
+const adminUsage = `Usage: julo-test admin <command> [flags]
+
+Commands:
+  wallet   -customer xid                              wallets, pockets and balances of a customer
+  history  -customer xid [-currency c] [-limit n] [-offset n]
+                                                      recent transactions of the main wallet
+  freeze   -customer xid [-currency c] -reason text   disable the wallet until it is unfrozen
+  unfreeze -customer xid [-currency c] -reason text   restore the state the wallet was frozen in
+  adjust   -customer xid [-currency c] -amount n -reason text [-reference id]
+                                                      request a credit of a positive or a debit of a
+                                                      negative amount, posted once another staff
+                                                      member approves it
+  token    -customer xid -reason text                 mint a customer api token for testing
+  staff-token [-role viewer|operator|approver] -reason text
+                                                      mint your own back-office api token, with your
+                                                      registered role by default
+  export   -customer xid [-currency c] [-out file]    every transaction as json lines
+  audit    [-customer xid] [-actor-filter name] [-action a] [-limit n] [-offset n]
+                                                      read the audit log
+
+The actor is the os user, who must be registered in admin.staff with the role the command needs:
+viewer to read, operator for every other command. Every command but audit is written to the audit log.
+`
+
+// adminCommandRole is the role each command needs, the same as the back-office api route doing the same
+var adminCommandRole = map[string]string{
+	"wallet":      middleware.RoleViewer,
+	"history":     middleware.RoleViewer,
+	"export":      middleware.RoleViewer,
+	"audit":       middleware.RoleViewer,
+	"freeze":      middleware.RoleOperator,
+	"unfreeze":    middleware.RoleOperator,
+	"adjust":      middleware.RoleOperator,
+	"token":       middleware.RoleOperator,
+	"staff-token": middleware.RoleViewer,
+}
+
+// runAdmin runs the support commands against the configured database and prints json
+func runAdmin(args []string) error {
+	fs := flag.NewFlagSet("admin", flag.ExitOnError)
+	fs.Usage = func() { fmt.Fprint(fs.Output(), adminUsage) }
+	fs.Parse(args)
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return fmt.Errorf("admin needs a command")
+	}
+	command, args := fs.Arg(0), fs.Args()[1:]
+	required, ok := adminCommandRole[command]
+	if !ok {
+		fs.Usage()
+		return fmt.Errorf("unknown admin command %q", command)
+	}
+	actor, err := staffActor()
+	if err != nil {
+		return err
+	}
+	if role := middleware.StaffRole(actor); !middleware.HasAdminRole(role, required) {
+		return fmt.Errorf("%s needs the %s role, %s is registered as %q", command, required, actor, role)
+	}
+
+	db := database.DbConn(database.ConfigParam())
+	defer db.Close()
+	admin := service.NewAdminService(
+		models.NewDBWalletRepo(db),
+		models.NewDBPocketRepo(db),
+		models.NewDBAuditRepo(db),
+		models.NewDBAdjustmentRepo(db),
+		service.AdminTokens{Customer: middleware.GenerateToken, Staff: middleware.GenerateAdminToken},
+	)
+	ctx := activity.WithActorID(activity.NewContext("Admin."+command), actor)
+
+	cmd := flag.NewFlagSet("admin "+command, flag.ExitOnError)
+	customerXId := cmd.String("customer", "", "customer xid")
+	currency := cmd.String("currency", config.Config.CurrencyCfg.Default, "wallet currency")
+	reason := cmd.String("reason", "", "why the action is taken, written to the audit log")
+
+	switch command {
+	case "wallet":
+		cmd.Parse(args)
+		wallets, err := admin.FindWallets(ctx, *customerXId)
+		if err != nil {
+			return err
+		}
+		return printJSON(os.Stdout, wallets)
+	case "history":
+		limit := cmd.Int("limit", 20, "transactions per page")
+		offset := cmd.Int("offset", 0, "transactions to skip")
+		cmd.Parse(args)
+		histories, err := admin.GetHistory(ctx, service.ParamAdminHistory{
+			CustomerXId: *customerXId,
+			Currency:    *currency,
+			Limit:       *limit,
+			Offset:      *offset,
+		})
+		if err != nil {
+			return err
+		}
+		return printJSON(os.Stdout, histories)
+	case "freeze", "unfreeze":
+		cmd.Parse(args)
+		param := service.ParamAdminFreeze{CustomerXId: *customerXId, Currency: *currency, Reason: *reason}
+		freeze := admin.FreezeWallet
+		if command == "unfreeze" {
+			freeze = admin.UnfreezeWallet
+		}
+		wallet, err := freeze(ctx, param)
+		if err != nil {
+			return err
+		}
+		return printJSON(os.Stdout, wallet)
+	case "adjust":
+		amount := cmd.Float64("amount", 0, "signed amount, negative to debit")
+		reference := cmd.String("reference", "", "reference id, generated when empty")
+		cmd.Parse(args)
+		adjustment, err := admin.RequestAdjustment(ctx, service.ParamAdminAdjust{
+			CustomerXId: *customerXId,
+			Currency:    *currency,
+			Amount:      *amount,
+			Reason:      *reason,
+			ReferenceID: *reference,
+		})
+		if err != nil {
+			return err
+		}
+		return printJSON(os.Stdout, adjustment)
+	case "token":
+		cmd.Parse(args)
+		token, err := admin.MintToken(ctx, *customerXId, *reason)
+		if err != nil {
+			return err
+		}
+		return printJSON(os.Stdout, map[string]string{"customer_xid": *customerXId, "token": token})
+	case "staff-token":
+		role := cmd.String("role", middleware.StaffRole(actor), "viewer, operator or approver, up to your registered role")
+		cmd.Parse(args)
+		token, err := admin.MintStaffToken(ctx, *role, *reason)
+		if err != nil {
+			return err
+		}
+		return printJSON(os.Stdout, map[string]string{"name": actor, "role": *role, "token": token})
+	case "export":
+		out := cmd.String("out", "", "json lines file, stdout when empty")
+		cmd.Parse(args)
+		var w io.Writer = os.Stdout
+		if *out != "" {
+			f, err := os.Create(*out)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			w = f
+		}
+		enc := json.NewEncoder(w)
+		n, err := admin.ExportHistory(ctx, service.ParamAdminHistory{CustomerXId: *customerXId, Currency: *currency},
+			func(tx service.AdminTransaction) error { return enc.Encode(tx) })
+		if err != nil {
+			return err
+		}
+		if *out != "" {
+			fmt.Fprintf(os.Stderr, "exported %d transactions to %s\n", n, *out)
+		}
+		return nil
+	case "audit":
+		actorFilter := cmd.String("actor-filter", "", "only actions of this actor")
+		action := cmd.String("action", "", "only this action, wallet.freeze for instance")
+		limit := cmd.Int("limit", 50, "entries per page")
+		offset := cmd.Int("offset", 0, "entries to skip")
+		cmd.Parse(args)
+		logs, err := admin.GetAuditLogs(ctx, models.ParamGetAuditLogs{
+			CustomerXId: *customerXId,
+			Actor:       *actorFilter,
+			Action:      *action,
+			Limit:       *limit,
+			Offset:      *offset,
+		})
+		if err != nil {
+			return err
+		}
+		return printJSON(os.Stdout, logs)
+	default:
+		fs.Usage()
+		return fmt.Errorf("unknown admin command %q", command)
+	}
+}
+
+// staffActor returns the os user running the command, the same name staff use on the back-office api
+// so one person cannot both request and approve an adjustment
+func staffActor() (string, error) {
+	u, err := user.Current()
+	if err != nil {
+		return "", err
+	}
+	if u.Username == "" {
+		return "", fmt.Errorf("the os user has no name")
+	}
+	return strings.ToLower(u.Username), nil
+}
+
+func printJSON(w io.Writer, v interface{}) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
@@ -0,0 +1,46 @@
+// Package api holds the OpenAPI document of the /api/v1 routes
+package api
+
+import (
+	_ "embed"
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// BasePath is the server url of the document, its paths are relative to it
+var BasePath string = "/api/v1"
+
+//go:embed openapi.json
+var Spec []byte
+
+var methods = []string{http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete}
+
+// ServeSpec writes the OpenAPI document
+func ServeSpec(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(Spec)
+}
+
+// Operations returns the "METHOD /path" of every operation in the document
+func Operations() ([]string, error) {
+	var doc struct {
+		Paths map[string]map[string]json.RawMessage `json:"paths"`
+	}
+	if err := json.Unmarshal(Spec, &doc); err != nil {
+		return nil, err
+	}
+
+	var ops []string
+	for path, item := range doc.Paths {
+		for _, method := range methods {
+			if _, ok := item[strings.ToLower(method)]; ok {
+				ops = append(ops, method+" "+BasePath+path)
+			}
+		}
+	}
+	sort.Strings(ops)
+	return ops, nil
+}
@@ -0,0 +1,171 @@
+// Package client is a typed Go client of the /api/v1 wallet API described in api/openapi.json
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Error is the error envelope returned by the API
+type Error struct {
+	StatusCode int
+	Code       int    `json:"code"`
+	Message    string `json:"message"`
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("wallet api error %d: %s", e.StatusCode, e.Message)
+}
+
+type responseAPI struct {
+	Status string          `json:"status"`
+	Data   json.RawMessage `json:"data"`
+	Error  *Error          `json:"error"`
+}
+
+type Client struct {
+	baseURL    string
+	httpClient *http.Client
+	maxRetries int
+	backoff    time.Duration
+
+	mutex sync.RWMutex
+	token string
+}
+
+type Option func(*Client)
+
+// WithHTTPClient replaces the default client with a 15 seconds timeout
+func WithHTTPClient(c *http.Client) Option {
+	return func(cl *Client) { cl.httpClient = c }
+}
+
+// WithToken sets a customer token created earlier by Init
+func WithToken(token string) Option {
+	return func(cl *Client) { cl.token = token }
+}
+
+// WithRetries sets how many times an idempotent request is retried on a network error or
+// a 502, 503 or 504 response, backoff is doubled on every retry
+func WithRetries(maxRetries int, backoff time.Duration) Option {
+	return func(cl *Client) {
+		cl.maxRetries = maxRetries
+		cl.backoff = backoff
+	}
+}
+
+// New returns a client of the API served at baseURL, e.g. http://localhost:5000/api/v1
+func New(baseURL string, opts ...Option) *Client {
+	c := &Client{
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		httpClient: &http.Client{Timeout: 15 * time.Second},
+		maxRetries: 3,
+		backoff:    200 * time.Millisecond,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// Token returns the customer token sent with every request
+func (c *Client) Token() string {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	return c.token
+}
+
+func (c *Client) SetToken(token string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.token = token
+}
+
+// do sends form as query for GET and as body otherwise, and decodes the data of the envelope into out.
+// Only GET, PATCH and DELETE are retried, a POST may have been applied before the connection failed.
+func (c *Client) do(ctx context.Context, method, path string, form url.Values, out interface{}) error {
+	retries := 0
+	if method != http.MethodPost {
+		retries = c.maxRetries
+	}
+
+	backoff := c.backoff
+	for attempt := 0; ; attempt++ {
+		err := c.send(ctx, method, path, form, out)
+		if err == nil || attempt >= retries || !retryable(err) {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+func (c *Client) send(ctx context.Context, method, path string, form url.Values, out interface{}) error {
+	target := c.baseURL + path
+	var body io.Reader
+	if method == http.MethodGet {
+		if len(form) > 0 {
+			target += "?" + form.Encode()
+		}
+	} else {
+		body = strings.NewReader(form.Encode())
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, target, body)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	}
+	if token := c.Token(); token != "" {
+		req.Header.Set("Authorization", "Token "+token)
+	}
+
+	res, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	var envelope responseAPI
+	if err := json.NewDecoder(res.Body).Decode(&envelope); err != nil {
+		return &Error{StatusCode: res.StatusCode, Code: res.StatusCode, Message: "invalid response: " + err.Error()}
+	}
+	if envelope.Error != nil {
+		envelope.Error.StatusCode = res.StatusCode
+		return envelope.Error
+	}
+	if res.StatusCode >= http.StatusBadRequest {
+		return &Error{StatusCode: res.StatusCode, Code: res.StatusCode, Message: res.Status}
+	}
+	if out == nil {
+		return nil
+	}
+	return json.Unmarshal(envelope.Data, out)
+}
+
+func retryable(err error) bool {
+	var apiErr *Error
+	if errors.As(err, &apiErr) {
+		switch apiErr.StatusCode {
+		case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+			return true
+		}
+		return false
+	}
+	return !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded)
+}
@@ -0,0 +1,341 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+type Wallet struct {
+	ID        string  `json:"id"`
+	OwnedBy   string  `json:"owned_by"`
+	Status    string  `json:"status"`
+	EnabledAt string  `json:"enabled_at"`
+	Balance   float64 `json:"balance"`
+	Currency  string  `json:"currency"`
+}
+
+type Deposit struct {
+	ID          string  `json:"id"`
+	DepositedBy string  `json:"deposited_by"`
+	Status      string  `json:"status"`
+	DepositedAt string  `json:"deposited_at"`
+	Amount      float64 `json:"amount"`
+	Currency    string  `json:"currency"`
+	ReferenceID string  `json:"reference_id"`
+}
+
+type Withdrawal struct {
+	ID           string  `json:"id"`
+	WithdrawnBy  string  `json:"withdrawn_by"`
+	Status       string  `json:"status"`
+	WithdrawnAt  string  `json:"withdrawn_at"`
+	Amount       float64 `json:"amount"`
+	Currency     string  `json:"currency"`
+	ReferenceID  string  `json:"reference_id"`
+	Fee          float64 `json:"fee"`
+	TotalDebited float64 `json:"total_debited"`
+}
+
+type Pocket struct {
+	ID       string  `json:"id"`
+	WalletID string  `json:"wallet_id"`
+	Name     string  `json:"name"`
+	Status   string  `json:"status"`
+	Balance  float64 `json:"balance"`
+	Currency string  `json:"currency"`
+}
+
+type PocketTransfer struct {
+	ID            string  `json:"id"`
+	TransferredBy string  `json:"transferred_by"`
+	Status        string  `json:"status"`
+	TransferredAt string  `json:"transferred_at"`
+	Amount        float64 `json:"amount"`
+	Currency      string  `json:"currency"`
+	ReferenceID   string  `json:"reference_id"`
+}
+
+type FxQuote struct {
+	ID           string  `json:"id"`
+	FromCurrency string  `json:"from_currency"`
+	ToCurrency   string  `json:"to_currency"`
+	Rate         float64 `json:"rate"`
+	FromAmount   float64 `json:"from_amount"`
+	ToAmount     float64 `json:"to_amount"`
+	ExpiresAt    string  `json:"expires_at"`
+}
+
+type Conversion struct {
+	ID           string  `json:"id"`
+	QuoteID      string  `json:"quote_id"`
+	FromWalletID string  `json:"from_wallet_id"`
+	ToWalletID   string  `json:"to_wallet_id"`
+	FromAmount   float64 `json:"from_amount"`
+	ToAmount     float64 `json:"to_amount"`
+	Rate         float64 `json:"rate"`
+	ConvertedAt  string  `json:"converted_at"`
+}
+
+type Loan struct {
+	ID           string            `json:"id"`
+	CustomerXId  string            `json:"customer_xid"`
+	WalletID     string            `json:"wallet_id"`
+	Principal    float64           `json:"principal"`
+	Currency     string            `json:"currency"`
+	Status       string            `json:"status"`
+	ReferenceID  string            `json:"reference_id"`
+	DisbursedAt  time.Time         `json:"disbursed_at"`
+	Installments []LoanInstallment `json:"installments"`
+}
+
+type LoanInstallment struct {
+	ID         string    `json:"id"`
+	Sequence   int       `json:"sequence"`
+	DueDate    time.Time `json:"due_date"`
+	Amount     float64   `json:"amount"`
+	Penalty    float64   `json:"penalty"`
+	PaidAmount float64   `json:"paid_amount"`
+	Status     string    `json:"status"`
+	PaidAt     time.Time `json:"paid_at"`
+}
+
+type Schedule struct {
+	ID           string  `json:"id"`
+	Status       string  `json:"status"`
+	Currency     string  `json:"currency"`
+	FromPocketID string  `json:"from_pocket_id"`
+	ToPocketID   string  `json:"to_pocket_id"`
+	Amount       float64 `json:"amount"`
+	Cron         string  `json:"cron,omitempty"`
+	NextRunAt    string  `json:"next_run_at"`
+	LastRunAt    string  `json:"last_run_at,omitempty"`
+	LastStatus   string  `json:"last_status,omitempty"`
+	LastError    string  `json:"last_error,omitempty"`
+}
+
+// Transaction is a deposit or withdrawal request, PocketID moves a pocket instead of the main wallet
+type Transaction struct {
+	Currency    string
+	Amount      float64
+	ReferenceID string
+	PocketID    string
+}
+
+// Transfer moves Amount between pockets, an empty pocket id is the main wallet
+type Transfer struct {
+	Currency     string
+	Amount       float64
+	ReferenceID  string
+	FromPocketID string
+	ToPocketID   string
+}
+
+// NewSchedule is a one-off transfer when RunAt is set, a recurring one when Cron is set
+type NewSchedule struct {
+	Currency     string
+	Amount       float64
+	FromPocketID string
+	ToPocketID   string
+	RunAt        time.Time
+	Cron         string
+}
+
+// Init creates a token for the customer and uses it for the next requests
+func (c *Client) Init(ctx context.Context, customerXId string) (string, error) {
+	var res struct {
+		Token string `json:"token"`
+	}
+	err := c.do(ctx, http.MethodPost, "/init", url.Values{"customer_xid": {customerXId}}, &res)
+	if err != nil {
+		return "", err
+	}
+
+	c.SetToken(res.Token)
+	return res.Token, nil
+}
+
+func (c *Client) EnableWallet(ctx context.Context, currency string) (*Wallet, error) {
+	var res Wallet
+	if err := c.do(ctx, http.MethodPost, "/wallet", currencyForm(currency), &res); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+func (c *Client) GetWallet(ctx context.Context, currency string) (*Wallet, error) {
+	var res Wallet
+	if err := c.do(ctx, http.MethodGet, "/wallet", currencyForm(currency), &res); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+func (c *Client) DisableWallet(ctx context.Context, currency string) (*Wallet, error) {
+	form := currencyForm(currency)
+	form.Set("is_disabled", "true")
+	var res Wallet
+	if err := c.do(ctx, http.MethodPatch, "/wallet", form, &res); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+func (c *Client) Deposit(ctx context.Context, param Transaction) (*Deposit, error) {
+	var res Deposit
+	if err := c.do(ctx, http.MethodPost, "/wallet/deposits", param.form(), &res); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+func (c *Client) Withdraw(ctx context.Context, param Transaction) (*Withdrawal, error) {
+	var res Withdrawal
+	if err := c.do(ctx, http.MethodPost, "/wallet/withdrawals", param.form(), &res); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+func (c *Client) CreatePocket(ctx context.Context, name, currency string) (*Pocket, error) {
+	form := currencyForm(currency)
+	form.Set("name", name)
+	var res Pocket
+	if err := c.do(ctx, http.MethodPost, "/wallet/pockets", form, &res); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+func (c *Client) ListPockets(ctx context.Context) ([]Pocket, error) {
+	var res []Pocket
+	if err := c.do(ctx, http.MethodGet, "/wallet/pockets", nil, &res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (c *Client) RenamePocket(ctx context.Context, pocketID, name string) (*Pocket, error) {
+	var res Pocket
+	if err := c.do(ctx, http.MethodPatch, "/wallet/pockets/"+url.PathEscape(pocketID), url.Values{"name": {name}}, &res); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+func (c *Client) ClosePocket(ctx context.Context, pocketID string) (*Pocket, error) {
+	var res Pocket
+	if err := c.do(ctx, http.MethodDelete, "/wallet/pockets/"+url.PathEscape(pocketID), nil, &res); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+func (c *Client) TransferPocket(ctx context.Context, param Transfer) (*PocketTransfer, error) {
+	form := currencyForm(param.Currency)
+	form.Set("amount", formatAmount(param.Amount))
+	setIf(form, "reference_id", param.ReferenceID)
+	setIf(form, "from_pocket_id", param.FromPocketID)
+	setIf(form, "to_pocket_id", param.ToPocketID)
+	var res PocketTransfer
+	if err := c.do(ctx, http.MethodPost, "/wallet/pockets/transfers", form, &res); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+func (c *Client) CreateConversionQuote(ctx context.Context, fromCurrency, toCurrency string, amount float64) (*FxQuote, error) {
+	form := url.Values{
+		"from_currency": {fromCurrency},
+		"to_currency":   {toCurrency},
+		"amount":        {formatAmount(amount)},
+	}
+	var res FxQuote
+	if err := c.do(ctx, http.MethodPost, "/wallet/conversions/quotes", form, &res); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+func (c *Client) CreateConversion(ctx context.Context, quoteID string) (*Conversion, error) {
+	var res Conversion
+	if err := c.do(ctx, http.MethodPost, "/wallet/conversions", url.Values{"quote_id": {quoteID}}, &res); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+func (c *Client) ListLoans(ctx context.Context) ([]Loan, error) {
+	var res []Loan
+	if err := c.do(ctx, http.MethodGet, "/wallet/loans", nil, &res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (c *Client) CreateSchedule(ctx context.Context, param NewSchedule) (*Schedule, error) {
+	form := currencyForm(param.Currency)
+	form.Set("amount", formatAmount(param.Amount))
+	setIf(form, "from_pocket_id", param.FromPocketID)
+	setIf(form, "to_pocket_id", param.ToPocketID)
+	setIf(form, "cron", param.Cron)
+	if !param.RunAt.IsZero() {
+		form.Set("run_at", param.RunAt.Format(time.RFC3339))
+	}
+	var res Schedule
+	if err := c.do(ctx, http.MethodPost, "/wallet/schedules", form, &res); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+func (c *Client) ListSchedules(ctx context.Context) ([]Schedule, error) {
+	var res []Schedule
+	if err := c.do(ctx, http.MethodGet, "/wallet/schedules", nil, &res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// UpdateScheduleStatus pauses or resumes a schedule, status is active or paused
+func (c *Client) UpdateScheduleStatus(ctx context.Context, scheduleID, status string) (*Schedule, error) {
+	var res Schedule
+	if err := c.do(ctx, http.MethodPatch, "/wallet/schedules/"+url.PathEscape(scheduleID), url.Values{"status": {status}}, &res); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+func (c *Client) CancelSchedule(ctx context.Context, scheduleID string) (*Schedule, error) {
+	var res Schedule
+	if err := c.do(ctx, http.MethodDelete, "/wallet/schedules/"+url.PathEscape(scheduleID), nil, &res); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+func (t Transaction) form() url.Values {
+	form := currencyForm(t.Currency)
+	form.Set("amount", formatAmount(t.Amount))
+	form.Set("reference_id", t.ReferenceID)
+	setIf(form, "pocket_id", t.PocketID)
+	return form
+}
+
+func currencyForm(currency string) url.Values {
+	form := url.Values{}
+	setIf(form, "currency", currency)
+	return form
+}
+
+func setIf(form url.Values, key, value string) {
+	if value != "" {
+		form.Set(key, value)
+	}
+}
+
+func formatAmount(amount float64) string {
+	return strconv.FormatFloat(amount, 'f', -1, 64)
+}
@@ -2,9 +2,17 @@ package config
 
 import (
 	"fmt"
-	"path/filepath"
+	"reflect"
+	"strings"
 
+	"github.com/ahmadmirdas/julo-test/utils/fee"
+	"github.com/ahmadmirdas/julo-test/utils/fx"
+	"github.com/ahmadmirdas/julo-test/utils/limit"
 	log "github.com/ahmadmirdas/julo-test/utils/log"
+	"github.com/ahmadmirdas/julo-test/utils/outbox"
+	"github.com/ahmadmirdas/julo-test/utils/penalty"
+	"github.com/ahmadmirdas/julo-test/utils/tlsconfig"
+	"github.com/ahmadmirdas/julo-test/utils/tracing"
 	joonix "github.com/joonix/log"
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/viper"
@@ -16,12 +24,15 @@ var Config config
 
 type config struct {
 	Environment string `mapstructure:"environment"`
+	LogCfg      struct {
+		Level string `mapstructure:"level"`
+	} `mapstructure:"log"`
 	PostgresCfg struct {
 		Database    string `mapstructure:"database"`
 		Host        string `mapstructure:"host"`
 		Port        string `mapstructure:"port"`
 		Username    string `mapstructure:"username"`
-		Password    string `mapstructure:"password"`
+		Password    string `mapstructure:"password" secret:"true"`
 		MaxConn     int    `mapstructure:"max_conn"`
 		MinIdleConn int    `mapstructure:"min_idle_conn"`
 		MaxRetries  int    `mapstructure:"max_retries"`
@@ -29,56 +40,201 @@ type config struct {
 	JWTCfg struct {
 		Issuer  string `mapstructure:"issuer"`
 		Exp     int    `mapstructure:"exp"`
-		SignKey string `mapstructure:"sign_key"`
+		SignKey string `mapstructure:"sign_key" secret:"true"`
 	} `mapstructure:"jwt"`
+	ServerCfg struct {
+		Address           string `mapstructure:"address"`
+		ReadTimeout       int    `mapstructure:"read_timeout"`
+		ReadHeaderTimeout int    `mapstructure:"read_header_timeout"`
+		WriteTimeout      int    `mapstructure:"write_timeout"`
+		IdleTimeout       int    `mapstructure:"idle_timeout"`
+		MaxHeaderBytes    int    `mapstructure:"max_header_bytes"`
+		ShutdownGrace     int    `mapstructure:"shutdown_grace"`
+		DrainDelay        int    `mapstructure:"drain_delay"`
+		// MetricsAddress serves /metrics without auth, it must not be reachable from outside
+		MetricsAddress string `mapstructure:"metrics_address"`
+	} `mapstructure:"server"`
+	TLSCfg  tlsconfig.Config `mapstructure:"tls"`
+	GrpcCfg struct {
+		Port string `mapstructure:"port"`
+	} `mapstructure:"grpc"`
+	InternalCfg struct {
+		Token string `mapstructure:"token" secret:"true"`
+	} `mapstructure:"internal"`
+	AdminCfg struct {
+		Issuer  string `mapstructure:"issuer"`
+		Exp     int    `mapstructure:"exp"`
+		SignKey string `mapstructure:"sign_key" secret:"true"`
+		// Staff maps every back-office staff member to the highest role they may hold
+		Staff map[string]string `mapstructure:"staff"`
+	} `mapstructure:"admin"`
+	CurrencyCfg struct {
+		Default   string   `mapstructure:"default"`
+		Supported []string `mapstructure:"supported"`
+	} `mapstructure:"currency"`
+	FxCfg struct {
+		QuoteTTL   int `mapstructure:"quote_ttl"`
+		fx.Pricing `mapstructure:",squash"`
+	} `mapstructure:"fx"`
+	FeeCfg struct {
+		RevenueAccount string     `mapstructure:"revenue_account"`
+		Rules          []fee.Rule `mapstructure:"rules"`
+	} `mapstructure:"fee"`
+	LimitCfg struct {
+		Tiers      map[string]limit.TierLimit            `mapstructure:"tiers"`
+		Currencies map[string]map[string]limit.TierLimit `mapstructure:"currencies"`
+	} `mapstructure:"limit"`
+	LoanCfg struct {
+		CollectInterval int `mapstructure:"collect_interval"`
+		RetryInterval   int `mapstructure:"retry_interval"`
+		BatchSize       int `mapstructure:"batch_size"`
+		GraceDays       int `mapstructure:"grace_days"`
+		MaxTenor        int `mapstructure:"max_tenor"`
+	} `mapstructure:"loan"`
+	PenaltyCfg struct {
+		AccrualInterval int            `mapstructure:"accrual_interval"`
+		Rules           []penalty.Rule `mapstructure:"rules"`
+	} `mapstructure:"penalty"`
+	JobCfg struct {
+		Concurrency       int `mapstructure:"concurrency"`
+		PollInterval      int `mapstructure:"poll_interval"`
+		VisibilityTimeout int `mapstructure:"visibility_timeout"`
+		RetryBackoff      int `mapstructure:"retry_backoff"`
+		MaxBackoff        int `mapstructure:"max_backoff"`
+		Retention         int `mapstructure:"retention"`
+	} `mapstructure:"job"`
+	OutboxCfg struct {
+		PollInterval int                 `mapstructure:"poll_interval"`
+		BatchSize    int                 `mapstructure:"batch_size"`
+		MaxAttempts  int                 `mapstructure:"max_attempts"`
+		Sinks        []outbox.SinkConfig `mapstructure:"sinks"`
+	} `mapstructure:"outbox"`
+	WebhookCfg struct {
+		Timeout     int `mapstructure:"timeout"`
+		MaxAttempts int `mapstructure:"max_attempts"`
+	} `mapstructure:"webhook"`
+	ScheduleCfg struct {
+		PollInterval int `mapstructure:"poll_interval"`
+		BatchSize    int `mapstructure:"batch_size"`
+		MaxAttempts  int `mapstructure:"max_attempts"`
+		RetryBackoff int `mapstructure:"retry_backoff"`
+		MaxBackoff   int `mapstructure:"max_backoff"`
+	} `mapstructure:"schedule"`
+	TracingCfg tracing.Config `mapstructure:"tracing"`
+	StreamCfg  struct {
+		Heartbeat int `mapstructure:"heartbeat"`
+		BatchSize int `mapstructure:"batch_size"`
+	} `mapstructure:"stream"`
+	MigrationCfg struct {
+		RequireLatest bool `mapstructure:"require_latest"`
+	} `mapstructure:"migration"`
+	HealthCfg struct {
+		Timeout      int     `mapstructure:"timeout"`
+		MaxPoolUsage float64 `mapstructure:"max_pool_usage"`
+	} `mapstructure:"health"`
+	KycCfg struct {
+		Capabilities map[string][]string `mapstructure:"capabilities"`
+	} `mapstructure:"kyc"`
 }
 
-func init() {
-	var err error
-
+// Load reads the configuration file at path, or application.<env>.yml from config/app or the working
+// directory when path is empty. Every key is overridden by its JT_ environment variable, postgres.password
+// by JT_POSTGRES_PASSWORD for instance, and string keys read their value from the file named by the
+// <key>_file key or JT_<KEY>_FILE variable.
+func Load(path string) error {
 	configureLogging()
 
-	viper.SetEnvPrefix("JT")
-	viper.AutomaticEnv()
+	v := viper.GetViper()
+	setupEnv(v)
 
-	configName := "application"
+	if v.IsSet("ENV") {
+		Environment = v.GetString("ENV")
+	}
+	logrus.Info("JT_ENV: ", Environment)
 
-	if viper.IsSet("ENV") {
-		Environment = viper.GetString("ENV")
+	if path == "" {
+		path = v.GetString("CONFIG")
+	}
+	if path != "" {
+		v.SetConfigFile(path)
+	} else {
+		configName := "application"
+		if Environment != "production" {
+			configName = configName + "." + Environment
+		}
+		// name of config file (without extension)
+		v.SetConfigName(configName)
+		v.AddConfigPath("config/app")
+		// optionally look for config in the working directory
+		v.AddConfigPath(".")
 	}
 
-	if Environment != "production" {
-		configName = configName + "." + Environment
+	cfg, err := read(v)
+	if err != nil {
+		return err
 	}
+	logrus.Info("Config file: ", v.ConfigFileUsed())
 
-	logrus.Info("JT_ENV: ", Environment)
+	Config = cfg
+	return SetLogLevel(Config.LogCfg.Level)
+}
 
-	// name of config file (without extension)
-	viper.SetConfigName(configName)
-	viper.AddConfigPath(filepath.Join(GetAppBasePath(), "config/app"))
-	// optionally look for config in the working directory
-	viper.AddConfigPath(".")
+// Reload reads the config file again and returns its reloadable settings, an invalid file returns an
+// error so the running values are kept
+func Reload() (Reloadable, error) {
+	v := viper.New()
+	setupEnv(v)
+	v.SetConfigFile(viper.ConfigFileUsed())
 
-	// Find and read the config file
-	err = viper.ReadInConfig()
+	cfg, err := read(v)
 	if err != nil {
-		panic(fmt.Errorf("fatal error config file: %s", err))
+		return Reloadable{}, err
+	}
+	if err := cfg.Validate(); err != nil {
+		return Reloadable{}, err
 	}
+	return cfg.Reloadable(), nil
+}
 
-	//Unmarshal application yml to config
-	err = viper.Unmarshal(&Config)
+// SetLogLevel applies a logrus level name, empty keeps debug
+func SetLogLevel(level string) error {
+	if level == "" {
+		logrus.SetLevel(logrus.DebugLevel)
+		return nil
+	}
+	lvl, err := logrus.ParseLevel(level)
 	if err != nil {
-		logrus.Errorf("unable to decode into struct, %v", err)
+		return err
 	}
+	logrus.SetLevel(lvl)
+	return nil
+}
+
+func setupEnv(v *viper.Viper) {
+	v.SetEnvPrefix("JT")
+	v.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
+	v.AutomaticEnv()
 }
 
-func GetAppBasePath() string {
-	basePath, _ := filepath.Abs(".")
-	for filepath.Base(basePath) != "julo-test" {
-		basePath = filepath.Dir(basePath)
+// read reads the config file of v with the environment and secret files applied
+func read(v *viper.Viper) (config, error) {
+	var cfg config
+	// Find and read the config file
+	if err := v.ReadInConfig(); err != nil {
+		return cfg, fmt.Errorf("read config file: %w", err)
+	}
+	if err := bindEnv(v, reflect.TypeOf(cfg), ""); err != nil {
+		return cfg, err
+	}
+	if err := readSecretFiles(v, reflect.TypeOf(cfg), ""); err != nil {
+		return cfg, err
 	}
 
-	return basePath
+	//Unmarshal application yml to config
+	if err := v.Unmarshal(&cfg); err != nil {
+		return cfg, fmt.Errorf("decode config: %w", err)
+	}
+	return cfg, nil
 }
 
 func configureLogging() {
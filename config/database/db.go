@@ -1,6 +1,10 @@
 package database
 
 import (
+	"net"
+	"net/url"
+
+	"github.com/ahmadmirdas/julo-test/config"
 	pg "github.com/go-pg/pg/v10"
 	"github.com/sirupsen/logrus"
 )
@@ -16,6 +20,21 @@ type ParamConn struct {
 	MaxRetries  int
 }
 
+// ConfigParam returns the connection settings of the postgres configuration
+func ConfigParam() ParamConn {
+	cfgDb := config.Config.PostgresCfg
+	return ParamConn{
+		Username:    cfgDb.Username,
+		Password:    cfgDb.Password,
+		Host:        cfgDb.Host,
+		Port:        cfgDb.Port,
+		Database:    cfgDb.Database,
+		MaxConn:     cfgDb.MaxConn,
+		MinIdleConn: cfgDb.MinIdleConn,
+		MaxRetries:  cfgDb.MaxRetries,
+	}
+}
+
 func DbConn(param ParamConn) *pg.DB {
 	logrus.Info("Initialize Database")
 
@@ -31,3 +50,15 @@ func DbConn(param ParamConn) *pg.DB {
 
 	return db
 }
+
+// DSN returns the connection url of param for database/sql drivers, without TLS like DbConn
+func DSN(param ParamConn) string {
+	dsn := url.URL{
+		Scheme:   "postgres",
+		User:     url.UserPassword(param.Username, param.Password),
+		Host:     net.JoinHostPort(param.Host, param.Port),
+		Path:     "/" + param.Database,
+		RawQuery: "sslmode=disable",
+	}
+	return dsn.String()
+}
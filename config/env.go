@@ -0,0 +1,97 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+// SecretFileSuffix names the key holding the path of the file a secret is read from
+var SecretFileSuffix string = "_file"
+
+// bindEnv binds the JT_ variable of every leaf key of t, so keys missing from the config file can be
+// set from the environment as well
+func bindEnv(v *viper.Viper, t reflect.Type, prefix string) error {
+	return walkKeys(t, prefix, func(key string, field reflect.StructField) error {
+		if err := v.BindEnv(key); err != nil {
+			return err
+		}
+		if field.Type.Kind() == reflect.String {
+			return v.BindEnv(key + SecretFileSuffix)
+		}
+		return nil
+	})
+}
+
+// readSecretFiles sets every string key with a <key>_file path to the content of that file
+func readSecretFiles(v *viper.Viper, t reflect.Type, prefix string) error {
+	return walkKeys(t, prefix, func(key string, field reflect.StructField) error {
+		if field.Type.Kind() != reflect.String {
+			return nil
+		}
+		path := v.GetString(key + SecretFileSuffix)
+		if path == "" {
+			return nil
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("read %s%s: %w", key, SecretFileSuffix, err)
+		}
+		v.Set(key, strings.TrimRight(string(content), "\r\n"))
+		return nil
+	})
+}
+
+// secretKeys returns the keys of t tagged secret
+func secretKeys(t reflect.Type) map[string]bool {
+	keys := make(map[string]bool)
+	walkKeys(t, "", func(key string, field reflect.StructField) error {
+		if field.Tag.Get("secret") == "true" {
+			keys[key] = true
+		}
+		return nil
+	})
+	return keys
+}
+
+// walkKeys calls fn with the dotted viper key of every scalar or string list field of t,
+// maps and lists of structs are configured from the file only
+func walkKeys(t reflect.Type, prefix string, fn func(key string, field reflect.StructField) error) error {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name, opts, _ := strings.Cut(field.Tag.Get("mapstructure"), ",")
+		if name == "-" {
+			continue
+		}
+
+		key := prefix
+		if opts != "squash" {
+			if name == "" {
+				name = strings.ToLower(field.Name)
+			}
+			key = strings.TrimPrefix(prefix+"."+name, ".")
+		}
+
+		switch field.Type.Kind() {
+		case reflect.Struct:
+			if err := walkKeys(field.Type, key, fn); err != nil {
+				return err
+			}
+		case reflect.Map:
+		case reflect.Slice:
+			if field.Type.Elem().Kind() == reflect.String {
+				if err := fn(key, field); err != nil {
+					return err
+				}
+			}
+		default:
+			if err := fn(key, field); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
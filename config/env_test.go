@@ -0,0 +1,100 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+type secretsCfg struct {
+	Postgres struct {
+		Host     string `mapstructure:"host"`
+		Password string `mapstructure:"password" secret:"true"`
+		Port     int    `mapstructure:"port"`
+	} `mapstructure:"postgres"`
+	Shared struct {
+		Token string `mapstructure:"token" secret:"true"`
+	} `mapstructure:",squash"`
+	Roles   map[string]string `mapstructure:"roles"`
+	Ignored string            `mapstructure:"-"`
+}
+
+func TestReadSecretFiles(t *testing.T) {
+	dir := t.TempDir()
+	secret := filepath.Join(dir, "password")
+	if err := os.WriteFile(secret, []byte("s3cret\r\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		values  map[string]interface{}
+		key     string
+		want    string
+		wantErr bool
+	}{
+		{name: "value kept without file", values: map[string]interface{}{"postgres.password": "inline"}, key: "postgres.password", want: "inline"},
+		{name: "file overrides value", values: map[string]interface{}{"postgres.password": "inline", "postgres.password_file": secret}, key: "postgres.password", want: "s3cret"},
+		{name: "squashed key", values: map[string]interface{}{"token_file": secret}, key: "token", want: "s3cret"},
+		{name: "empty file path ignored", values: map[string]interface{}{"postgres.host": "db", "postgres.host_file": ""}, key: "postgres.host", want: "db"},
+		{name: "missing file", values: map[string]interface{}{"postgres.password_file": filepath.Join(dir, "missing")}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := viper.New()
+			for key, value := range tt.values {
+				v.Set(key, value)
+			}
+			err := readSecretFiles(v, reflect.TypeOf(secretsCfg{}), "")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("readSecretFiles() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if got := v.GetString(tt.key); got != tt.want {
+				t.Errorf("%s = %q, want %q", tt.key, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBindEnv(t *testing.T) {
+	t.Setenv("JT_POSTGRES_PASSWORD", "from-env")
+	t.Setenv("JT_TOKEN_FILE", "/run/secrets/token")
+	t.Setenv("JT_ROLES", "ignored")
+
+	v := viper.New()
+	setupEnv(v)
+	if err := bindEnv(v, reflect.TypeOf(secretsCfg{}), ""); err != nil {
+		t.Fatalf("bindEnv() error = %v", err)
+	}
+	tests := []struct {
+		key  string
+		want string
+	}{
+		{key: "postgres.password", want: "from-env"},
+		{key: "token_file", want: "/run/secrets/token"},
+		{key: "postgres.host"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.key, func(t *testing.T) {
+			if got := v.GetString(tt.key); got != tt.want {
+				t.Errorf("%s = %q, want %q", tt.key, got, tt.want)
+			}
+		})
+	}
+	if keys := v.AllKeys(); contains(keys, "roles") || contains(keys, "postgres.port_file") {
+		t.Errorf("bound keys %v, want maps and non string file keys left out", keys)
+	}
+}
+
+func TestSecretKeys(t *testing.T) {
+	want := map[string]bool{"postgres.password": true, "token": true}
+	if got := secretKeys(reflect.TypeOf(secretsCfg{})); !reflect.DeepEqual(got, want) {
+		t.Errorf("secretKeys() = %v, want %v", got, want)
+	}
+}
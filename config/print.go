@@ -0,0 +1,47 @@
+package config
+
+import (
+	"io"
+	"reflect"
+	"strings"
+
+	"github.com/spf13/viper"
+	"gopkg.in/yaml.v3"
+)
+
+// Redacted replaces the secret values in a printed configuration
+var Redacted string = "REDACTED"
+
+// Print writes the effective configuration as yaml, with the file, environment and secret files merged
+func Print(w io.Writer, redacted bool) error {
+	settings := viper.AllSettings()
+	var secrets map[string]bool
+	if redacted {
+		secrets = secretKeys(reflect.TypeOf(Config))
+	}
+	clean(settings, "", secrets)
+
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	defer enc.Close()
+	return enc.Encode(settings)
+}
+
+// clean drops the unset secret file keys bound from the environment and redacts the secrets
+func clean(settings map[string]interface{}, prefix string, secrets map[string]bool) {
+	for name, value := range settings {
+		key := strings.TrimPrefix(prefix+"."+name, ".")
+		switch value := value.(type) {
+		case map[string]interface{}:
+			clean(value, key, secrets)
+		case string:
+			if strings.HasSuffix(name, SecretFileSuffix) && value == "" {
+				delete(settings, name)
+				continue
+			}
+			if secrets[key] && value != "" {
+				settings[name] = Redacted
+			}
+		}
+	}
+}
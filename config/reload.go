@@ -0,0 +1,25 @@
+package config
+
+import (
+	"github.com/ahmadmirdas/julo-test/utils/fee"
+	"github.com/ahmadmirdas/julo-test/utils/limit"
+)
+
+// Reloadable holds the settings applied without a restart, every other change needs one
+type Reloadable struct {
+	LogLevel        string
+	FeeRules        []fee.Rule
+	LimitTiers      map[string]limit.TierLimit
+	LimitCurrencies map[string]map[string]limit.TierLimit
+	KycCapabilities map[string][]string
+}
+
+func (c *config) Reloadable() Reloadable {
+	return Reloadable{
+		LogLevel:        c.LogCfg.Level,
+		FeeRules:        c.FeeCfg.Rules,
+		LimitTiers:      c.LimitCfg.Tiers,
+		LimitCurrencies: c.LimitCfg.Currencies,
+		KycCapabilities: c.KycCfg.Capabilities,
+	}
+}
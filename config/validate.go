@@ -0,0 +1,210 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+
+	"github.com/ahmadmirdas/julo-test/utils/fee"
+	"github.com/ahmadmirdas/julo-test/utils/fx"
+	"github.com/ahmadmirdas/julo-test/utils/kyc"
+	"github.com/ahmadmirdas/julo-test/utils/outbox"
+	"github.com/ahmadmirdas/julo-test/utils/tracing"
+	"github.com/sirupsen/logrus"
+)
+
+// Validate reports every missing or invalid value at once
+func (c *config) Validate() error {
+	v := &validator{}
+
+	if c.LogCfg.Level != "" {
+		if _, err := logrus.ParseLevel(c.LogCfg.Level); err != nil {
+			v.add("log.level: %v", err)
+		}
+	}
+
+	v.postgres(c)
+
+	v.required("jwt.issuer", c.JWTCfg.Issuer)
+	v.required("jwt.sign_key", c.JWTCfg.SignKey)
+	v.positive("jwt.exp", c.JWTCfg.Exp)
+
+	v.required("server.address", c.ServerCfg.Address)
+	v.nonNegative("server.read_timeout", c.ServerCfg.ReadTimeout)
+	v.nonNegative("server.read_header_timeout", c.ServerCfg.ReadHeaderTimeout)
+	v.nonNegative("server.write_timeout", c.ServerCfg.WriteTimeout)
+	v.nonNegative("server.idle_timeout", c.ServerCfg.IdleTimeout)
+	v.nonNegative("server.max_header_bytes", c.ServerCfg.MaxHeaderBytes)
+	v.nonNegative("server.shutdown_grace", c.ServerCfg.ShutdownGrace)
+	v.nonNegative("server.drain_delay", c.ServerCfg.DrainDelay)
+	v.required("server.metrics_address", c.ServerCfg.MetricsAddress)
+	if c.ServerCfg.MetricsAddress != "" && c.ServerCfg.MetricsAddress == c.ServerCfg.Address {
+		v.add("server.metrics_address must differ from server.address")
+	}
+
+	if c.TLSCfg.Enabled {
+		v.file("tls.cert_file", c.TLSCfg.CertFile)
+		v.file("tls.key_file", c.TLSCfg.KeyFile)
+		if c.TLSCfg.ClientCAFile != "" {
+			v.file("tls.client_ca_file", c.TLSCfg.ClientCAFile)
+		}
+		if err := c.TLSCfg.Check(); err != nil {
+			v.add("tls: %v", err)
+		}
+	}
+
+	v.port("grpc.port", c.GrpcCfg.Port)
+	v.required("internal.token", c.InternalCfg.Token)
+	v.required("admin.issuer", c.AdminCfg.Issuer)
+	v.required("admin.sign_key", c.AdminCfg.SignKey)
+	v.positive("admin.exp", c.AdminCfg.Exp)
+	if c.AdminCfg.SignKey != "" && c.AdminCfg.SignKey == c.JWTCfg.SignKey {
+		v.add("admin.sign_key must differ from jwt.sign_key")
+	}
+	staff := make([]string, 0, len(c.AdminCfg.Staff))
+	for name := range c.AdminCfg.Staff {
+		staff = append(staff, name)
+	}
+	sort.Strings(staff)
+	for _, name := range staff {
+		v.oneOf("admin.staff."+name, c.AdminCfg.Staff[name], "viewer", "operator", "approver")
+	}
+
+	v.required("currency.default", c.CurrencyCfg.Default)
+	if len(c.CurrencyCfg.Supported) == 0 {
+		v.add("currency.supported is required")
+	} else if c.CurrencyCfg.Default != "" && !contains(c.CurrencyCfg.Supported, c.CurrencyCfg.Default) {
+		v.add("currency.default %s is not in currency.supported", c.CurrencyCfg.Default)
+	}
+
+	v.positive("fx.quote_ttl", c.FxCfg.QuoteTTL)
+	if c.FxCfg.Rounding != "" {
+		v.oneOf("fx.rounding", c.FxCfg.Rounding, fx.RoundingDown, fx.RoundingHalfUp)
+	}
+	v.required("fee.revenue_account", c.FeeCfg.RevenueAccount)
+	if err := fee.Validate(c.FeeCfg.Rules); err != nil {
+		v.add("fee.rules: %v", err)
+	}
+	for level := range c.KycCfg.Capabilities {
+		if !kyc.IsValidLevel(level) {
+			v.add("kyc.capabilities: unknown level %q", level)
+		}
+	}
+
+	v.nonNegative("loan.collect_interval", c.LoanCfg.CollectInterval)
+	v.nonNegative("loan.retry_interval", c.LoanCfg.RetryInterval)
+	v.nonNegative("loan.batch_size", c.LoanCfg.BatchSize)
+	v.nonNegative("loan.grace_days", c.LoanCfg.GraceDays)
+	v.positive("loan.max_tenor", c.LoanCfg.MaxTenor)
+	v.nonNegative("penalty.accrual_interval", c.PenaltyCfg.AccrualInterval)
+	v.nonNegative("schedule.poll_interval", c.ScheduleCfg.PollInterval)
+	v.nonNegative("schedule.batch_size", c.ScheduleCfg.BatchSize)
+	v.nonNegative("schedule.max_attempts", c.ScheduleCfg.MaxAttempts)
+	v.nonNegative("job.concurrency", c.JobCfg.Concurrency)
+	v.nonNegative("job.poll_interval", c.JobCfg.PollInterval)
+	v.nonNegative("job.visibility_timeout", c.JobCfg.VisibilityTimeout)
+	v.nonNegative("job.retention", c.JobCfg.Retention)
+	v.nonNegative("outbox.poll_interval", c.OutboxCfg.PollInterval)
+	v.nonNegative("outbox.batch_size", c.OutboxCfg.BatchSize)
+	v.nonNegative("outbox.max_attempts", c.OutboxCfg.MaxAttempts)
+	for i, sink := range c.OutboxCfg.Sinks {
+		v.oneOf(fmt.Sprintf("outbox.sinks[%d].type", i), sink.Type, outbox.SinkHTTP, outbox.SinkFile, outbox.SinkNATS, outbox.SinkKafkaREST)
+	}
+	v.nonNegative("webhook.timeout", c.WebhookCfg.Timeout)
+	v.nonNegative("webhook.max_attempts", c.WebhookCfg.MaxAttempts)
+	v.nonNegative("stream.heartbeat", c.StreamCfg.Heartbeat)
+	v.nonNegative("stream.batch_size", c.StreamCfg.BatchSize)
+
+	if c.TracingCfg.Exporter != "" {
+		v.oneOf("tracing.exporter", c.TracingCfg.Exporter, tracing.ExporterOTLP, tracing.ExporterStdout, tracing.ExporterNone)
+	}
+	v.ratio("tracing.sample_ratio", c.TracingCfg.SampleRatio)
+	v.nonNegative("health.timeout", c.HealthCfg.Timeout)
+	v.ratio("health.max_pool_usage", c.HealthCfg.MaxPoolUsage)
+
+	return errors.Join(v.errs...)
+}
+
+// ValidatePostgres reports every missing or invalid postgres value, the only settings migrations need
+func (c *config) ValidatePostgres() error {
+	v := &validator{}
+	v.postgres(c)
+	return errors.Join(v.errs...)
+}
+
+type validator struct {
+	errs []error
+}
+
+func (v *validator) postgres(c *config) {
+	v.required("postgres.host", c.PostgresCfg.Host)
+	v.port("postgres.port", c.PostgresCfg.Port)
+	v.required("postgres.database", c.PostgresCfg.Database)
+	v.required("postgres.username", c.PostgresCfg.Username)
+	v.required("postgres.password", c.PostgresCfg.Password)
+	v.nonNegative("postgres.max_conn", c.PostgresCfg.MaxConn)
+	v.nonNegative("postgres.min_idle_conn", c.PostgresCfg.MinIdleConn)
+	v.nonNegative("postgres.max_retries", c.PostgresCfg.MaxRetries)
+}
+
+func (v *validator) add(format string, args ...interface{}) {
+	v.errs = append(v.errs, fmt.Errorf(format, args...))
+}
+
+func (v *validator) required(key, value string) {
+	if value == "" {
+		v.add("%s is required", key)
+	}
+}
+
+func (v *validator) positive(key string, value int) {
+	if value <= 0 {
+		v.add("%s must be greater than 0, got %d", key, value)
+	}
+}
+
+func (v *validator) nonNegative(key string, value int) {
+	if value < 0 {
+		v.add("%s must not be negative, got %d", key, value)
+	}
+}
+
+func (v *validator) ratio(key string, value float64) {
+	if value < 0 || value > 1 {
+		v.add("%s must be between 0 and 1, got %v", key, value)
+	}
+}
+
+func (v *validator) port(key, value string) {
+	port, err := strconv.Atoi(value)
+	if err != nil || port <= 0 || port > 65535 {
+		v.add("%s must be a port number, got %q", key, value)
+	}
+}
+
+func (v *validator) oneOf(key, value string, allowed ...string) {
+	if !contains(allowed, value) {
+		v.add("%s must be one of %q, got %q", key, allowed, value)
+	}
+}
+
+func (v *validator) file(key, path string) {
+	if path == "" {
+		v.add("%s is required", key)
+		return
+	}
+	if _, err := os.Stat(path); err != nil {
+		v.add("%s: %v", key, err)
+	}
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
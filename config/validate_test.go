@@ -0,0 +1,119 @@
+package config
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+// devConfig reads the development config shipped with the repository with the secrets it leaves to the
+// environment filled in
+func devConfig(t *testing.T) config {
+	t.Helper()
+	v := viper.New()
+	v.SetConfigFile("app/application.dev.yml")
+	cfg, err := read(v)
+	if err != nil {
+		t.Fatalf("read() error = %v", err)
+	}
+	cfg.PostgresCfg.Password = "postgres"
+	cfg.JWTCfg.SignKey = "customer-key"
+	cfg.InternalCfg.Token = "internal-token"
+	cfg.AdminCfg.SignKey = "admin-key"
+	return cfg
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		mutate  func(c *config)
+		wantErr []string
+	}{
+		{name: "dev config", mutate: func(c *config) {}},
+		{
+			name:    "missing metrics address",
+			mutate:  func(c *config) { c.ServerCfg.MetricsAddress = "" },
+			wantErr: []string{"server.metrics_address is required"},
+		},
+		{
+			name:    "metrics on the api address",
+			mutate:  func(c *config) { c.ServerCfg.MetricsAddress = c.ServerCfg.Address },
+			wantErr: []string{"server.metrics_address must differ from server.address"},
+		},
+		{
+			name:    "admin tokens signed with the customer key",
+			mutate:  func(c *config) { c.AdminCfg.SignKey = c.JWTCfg.SignKey },
+			wantErr: []string{"admin.sign_key must differ from jwt.sign_key"},
+		},
+		{
+			name:    "unknown staff role",
+			mutate:  func(c *config) { c.AdminCfg.Staff = map[string]string{"alice": "owner"} },
+			wantErr: []string{`admin.staff.alice must be one of ["viewer" "operator" "approver"], got "owner"`},
+		},
+		{
+			name:    "default currency not supported",
+			mutate:  func(c *config) { c.CurrencyCfg.Default = "EUR"; c.CurrencyCfg.Supported = []string{"IDR"} },
+			wantErr: []string{"currency.default EUR is not in currency.supported"},
+		},
+		{
+			name:    "unknown log level and fee kind",
+			mutate:  func(c *config) { c.LogCfg.Level = "verbose"; c.FeeCfg.Rules[0].Kind = "fixed" },
+			wantErr: []string{"log.level: ", `fee.rules: rule 0: unknown kind "fixed"`},
+		},
+		{
+			name:    "invalid port and ratio",
+			mutate:  func(c *config) { c.GrpcCfg.Port = "http"; c.TracingCfg.SampleRatio = 1.5 },
+			wantErr: []string{`grpc.port must be a port number, got "http"`, "tracing.sample_ratio must be between 0 and 1, got 1.5"},
+		},
+		{
+			name: "every missing secret is reported",
+			mutate: func(c *config) {
+				c.PostgresCfg.Password = ""
+				c.JWTCfg.SignKey = ""
+				c.InternalCfg.Token = ""
+			},
+			wantErr: []string{"postgres.password is required", "jwt.sign_key is required", "internal.token is required"},
+		},
+		{
+			name:    "tls without certificate files",
+			mutate:  func(c *config) { c.TLSCfg.Enabled = true; c.TLSCfg.CertFile = ""; c.TLSCfg.KeyFile = "missing.key" },
+			wantErr: []string{"tls.cert_file is required", "tls.key_file: "},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := devConfig(t)
+			tt.mutate(&cfg)
+
+			err := cfg.Validate()
+			if len(tt.wantErr) == 0 {
+				if err != nil {
+					t.Fatalf("Validate() error = %v", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatalf("Validate() succeeded, want %q", tt.wantErr)
+			}
+			for _, want := range tt.wantErr {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("Validate() error = %v, want it to report %q", err, want)
+				}
+			}
+		})
+	}
+}
+
+func TestValidatePostgres(t *testing.T) {
+	cfg := devConfig(t)
+	cfg.JWTCfg.SignKey = ""
+	cfg.AdminCfg.Issuer = ""
+	if err := cfg.ValidatePostgres(); err != nil {
+		t.Fatalf("ValidatePostgres() error = %v, want other sections ignored", err)
+	}
+	cfg.PostgresCfg.Port = "0"
+	if err := cfg.ValidatePostgres(); err == nil || !strings.Contains(err.Error(), "postgres.port") {
+		t.Errorf("ValidatePostgres() error = %v, want postgres.port reported", err)
+	}
+}
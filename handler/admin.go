@@ -0,0 +1,392 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/ahmadmirdas/julo-test/repository/database/models"
+	"github.com/ahmadmirdas/julo-test/service"
+	"github.com/ahmadmirdas/julo-test/utils/activity"
+	"github.com/ahmadmirdas/julo-test/utils/log"
+	"github.com/ahmadmirdas/julo-test/utils/response"
+	"github.com/google/uuid"
+)
+
+type handlerAdmin struct {
+	admin service.AdminService
+}
+
+// HandlerAdmin serves the back-office api, the role of every route is enforced by the router
+type HandlerAdmin interface {
+	SearchWallets(w http.ResponseWriter, r *http.Request)
+	ViewWallet(w http.ResponseWriter, r *http.Request)
+	ListWalletHistories(w http.ResponseWriter, r *http.Request)
+	FreezeWallet(w http.ResponseWriter, r *http.Request)
+	UnfreezeWallet(w http.ResponseWriter, r *http.Request)
+	RequestAdjustment(w http.ResponseWriter, r *http.Request)
+	ListAdjustments(w http.ResponseWriter, r *http.Request)
+	ViewAdjustment(w http.ResponseWriter, r *http.Request)
+	ApproveAdjustment(w http.ResponseWriter, r *http.Request)
+	RejectAdjustment(w http.ResponseWriter, r *http.Request)
+	RequestReversal(w http.ResponseWriter, r *http.Request)
+	ListAuditLogs(w http.ResponseWriter, r *http.Request)
+}
+
+func NewHandlerAdmin(admin service.AdminService) HandlerAdmin {
+	return &handlerAdmin{
+		admin: admin,
+	}
+}
+
+// SearchWallets pages through wallets and pockets by owner, currency, status, balance range and creation date
+func (h *handlerAdmin) SearchWallets(w http.ResponseWriter, r *http.Request) {
+	ctx := activity.NewRequestContext(r.Context(), "Handler.AdminSearchWallets")
+
+	param, err := searchWalletsParam(r)
+	if err != nil {
+		httpErrorWrite(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	wallets, err := h.admin.SearchWallets(ctx, param)
+	if err != nil {
+		log.WithContext(ctx).Errorf("[Handler AdminSearchWallets] error when search wallets, error: %v", err)
+		httpErrorWrite(w, err.Error(), adminErrorCode(err))
+		return
+	}
+
+	httpResponseWrite(w, response.ResponseAPI{
+		Status: "success",
+		Data:   wallets,
+	}, http.StatusOK)
+}
+
+func (h *handlerAdmin) ViewWallet(w http.ResponseWriter, r *http.Request) {
+	ctx := activity.NewRequestContext(r.Context(), "Handler.AdminViewWallet")
+	walletID, ok := uuidVar(w, r, "wallet_id")
+	if !ok {
+		return
+	}
+
+	wallet, err := h.admin.GetWallet(ctx, walletID)
+	if err != nil {
+		log.WithContext(ctx).Warnf("[Handler AdminViewWallet] error when get wallet %s, error: %v", walletID, err)
+		httpErrorWrite(w, err.Error(), adminErrorCode(err))
+		return
+	}
+
+	httpResponseWrite(w, response.ResponseAPI{
+		Status: "success",
+		Data:   wallet,
+	}, http.StatusOK)
+}
+
+// ListWalletHistories pages through the transactions of any wallet or pocket, newest first
+func (h *handlerAdmin) ListWalletHistories(w http.ResponseWriter, r *http.Request) {
+	ctx := activity.NewRequestContext(r.Context(), "Handler.AdminListWalletHistories")
+	walletID, ok := uuidVar(w, r, "wallet_id")
+	if !ok {
+		return
+	}
+	limit, offset, err := pagination(r)
+	if err != nil {
+		httpErrorWrite(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	histories, err := h.admin.GetHistory(ctx, service.ParamAdminHistory{
+		WalletID: walletID,
+		Limit:    limit,
+		Offset:   offset,
+	})
+	if err != nil {
+		log.WithContext(ctx).Warnf("[Handler AdminListWalletHistories] error when get histories of %s, error: %v", walletID, err)
+		httpErrorWrite(w, err.Error(), adminErrorCode(err))
+		return
+	}
+
+	httpResponseWrite(w, response.ResponseAPI{
+		Status: "success",
+		Data:   histories,
+	}, http.StatusOK)
+}
+
+func (h *handlerAdmin) FreezeWallet(w http.ResponseWriter, r *http.Request) {
+	h.freezeWallet(w, r, true)
+}
+
+func (h *handlerAdmin) UnfreezeWallet(w http.ResponseWriter, r *http.Request) {
+	h.freezeWallet(w, r, false)
+}
+
+func (h *handlerAdmin) freezeWallet(w http.ResponseWriter, r *http.Request, frozen bool) {
+	ctx := activity.NewRequestContext(r.Context(), "Handler.AdminFreezeWallet")
+	walletID, ok := uuidVar(w, r, "wallet_id")
+	if !ok {
+		return
+	}
+
+	param := service.ParamAdminFreeze{WalletID: walletID, Reason: r.FormValue("reason")}
+	freeze := h.admin.FreezeWallet
+	if !frozen {
+		freeze = h.admin.UnfreezeWallet
+	}
+	wallet, err := freeze(ctx, param)
+	if err != nil {
+		log.WithContext(ctx).Warnf("[Handler AdminFreezeWallet] error when freeze %t wallet %s, error: %v", frozen, walletID, err)
+		httpErrorWrite(w, err.Error(), adminErrorCode(err))
+		return
+	}
+
+	log.WithContext(ctx).Infof("[Handler AdminFreezeWallet] wallet %s is %s", walletID, wallet.Status)
+	httpResponseWrite(w, response.ResponseAPI{
+		Status: "success",
+		Data:   wallet,
+	}, http.StatusOK)
+}
+
+// RequestAdjustment stores a signed manual correction, it is posted once another staff member approves it
+func (h *handlerAdmin) RequestAdjustment(w http.ResponseWriter, r *http.Request) {
+	ctx := activity.NewRequestContext(r.Context(), "Handler.AdminRequestAdjustment")
+
+	walletID := r.FormValue("wallet_id")
+	if _, err := uuid.Parse(walletID); err != nil {
+		httpErrorWrite(w, "wallet_id must be a uuid", http.StatusBadRequest)
+		return
+	}
+	amount, err := strconv.ParseFloat(r.FormValue("amount"), 64)
+	if err != nil {
+		httpErrorWrite(w, "amount must be a number, negative to debit", http.StatusBadRequest)
+		return
+	}
+
+	adjustment, err := h.admin.RequestAdjustment(ctx, service.ParamAdminAdjust{
+		WalletID:    walletID,
+		Amount:      amount,
+		Reason:      r.FormValue("reason"),
+		ReferenceID: r.FormValue("reference_id"),
+	})
+	if err != nil {
+		log.WithContext(ctx).Warnf("[Handler AdminRequestAdjustment] error when request adjustment on %s, error: %v", walletID, err)
+		httpErrorWrite(w, err.Error(), adminErrorCode(err))
+		return
+	}
+
+	httpResponseWrite(w, response.ResponseAPI{
+		Status: "success",
+		Data:   adjustment,
+	}, http.StatusCreated)
+}
+
+// ListAdjustments pages through adjustments, oldest first, optionally by status and customer
+func (h *handlerAdmin) ListAdjustments(w http.ResponseWriter, r *http.Request) {
+	ctx := activity.NewRequestContext(r.Context(), "Handler.AdminListAdjustments")
+	limit, offset, err := pagination(r)
+	if err != nil {
+		httpErrorWrite(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	customerXId := r.FormValue("customer_xid")
+	if customerXId != "" {
+		if _, err := uuid.Parse(customerXId); err != nil {
+			httpErrorWrite(w, "customer_xid must be a uuid", http.StatusBadRequest)
+			return
+		}
+	}
+
+	adjustments, err := h.admin.GetAdjustments(ctx, models.ParamGetAdjustments{
+		Status:      r.FormValue("status"),
+		CustomerXId: customerXId,
+		Limit:       limit,
+		Offset:      offset,
+	})
+	if err != nil {
+		log.WithContext(ctx).Errorf("[Handler AdminListAdjustments] error when query adjustments, error: %v", err)
+		httpErrorWrite(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	httpResponseWrite(w, response.ResponseAPI{
+		Status: "success",
+		Data:   adjustments,
+	}, http.StatusOK)
+}
+
+func (h *handlerAdmin) ViewAdjustment(w http.ResponseWriter, r *http.Request) {
+	ctx := activity.NewRequestContext(r.Context(), "Handler.AdminViewAdjustment")
+	id, ok := uuidVar(w, r, "adjustment_id")
+	if !ok {
+		return
+	}
+
+	adjustment, err := h.admin.GetAdjustment(ctx, id)
+	if err != nil {
+		httpErrorWrite(w, err.Error(), adminErrorCode(err))
+		return
+	}
+
+	httpResponseWrite(w, response.ResponseAPI{
+		Status: "success",
+		Data:   adjustment,
+	}, http.StatusOK)
+}
+
+func (h *handlerAdmin) ApproveAdjustment(w http.ResponseWriter, r *http.Request) {
+	h.reviewAdjustment(w, r, true)
+}
+
+func (h *handlerAdmin) RejectAdjustment(w http.ResponseWriter, r *http.Request) {
+	h.reviewAdjustment(w, r, false)
+}
+
+func (h *handlerAdmin) reviewAdjustment(w http.ResponseWriter, r *http.Request, approve bool) {
+	ctx := activity.NewRequestContext(r.Context(), "Handler.AdminReviewAdjustment")
+	id, ok := uuidVar(w, r, "adjustment_id")
+	if !ok {
+		return
+	}
+
+	adjustment, err := h.admin.ReviewAdjustment(ctx, id, approve, r.FormValue("note"))
+	if err != nil {
+		log.WithContext(ctx).Warnf("[Handler AdminReviewAdjustment] error when review adjustment %s, error: %v", id, err)
+		httpErrorWrite(w, err.Error(), adminErrorCode(err))
+		return
+	}
+
+	log.WithContext(ctx).Infof("[Handler AdminReviewAdjustment] adjustment %s is %s", id, adjustment.Status)
+	httpResponseWrite(w, response.ResponseAPI{
+		Status: "success",
+		Data:   adjustment,
+	}, http.StatusOK)
+}
+
+// RequestReversal stores the reversal of a deposit, withdrawal or adjustment as a pending adjustment,
+// it is posted once another staff member approves it
+func (h *handlerAdmin) RequestReversal(w http.ResponseWriter, r *http.Request) {
+	ctx := activity.NewRequestContext(r.Context(), "Handler.AdminRequestReversal")
+	id, ok := uuidVar(w, r, "transaction_id")
+	if !ok {
+		return
+	}
+
+	adjustment, err := h.admin.RequestReversal(ctx, id, r.FormValue("reason"))
+	if err != nil {
+		log.WithContext(ctx).Warnf("[Handler AdminRequestReversal] error when request reversal of %s, error: %v", id, err)
+		httpErrorWrite(w, err.Error(), adminErrorCode(err))
+		return
+	}
+
+	httpResponseWrite(w, response.ResponseAPI{
+		Status: "success",
+		Data:   adjustment,
+	}, http.StatusCreated)
+}
+
+// ListAuditLogs pages through the audit log, newest first, optionally by customer, actor and action
+func (h *handlerAdmin) ListAuditLogs(w http.ResponseWriter, r *http.Request) {
+	ctx := activity.NewRequestContext(r.Context(), "Handler.AdminListAuditLogs")
+	limit, offset, err := pagination(r)
+	if err != nil {
+		httpErrorWrite(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	customerXId := r.FormValue("customer_xid")
+	if customerXId != "" {
+		if _, err := uuid.Parse(customerXId); err != nil {
+			httpErrorWrite(w, "customer_xid must be a uuid", http.StatusBadRequest)
+			return
+		}
+	}
+
+	logs, err := h.admin.GetAuditLogs(ctx, models.ParamGetAuditLogs{
+		CustomerXId: customerXId,
+		Actor:       r.FormValue("actor"),
+		Action:      r.FormValue("action"),
+		Limit:       limit,
+		Offset:      offset,
+	})
+	if err != nil {
+		log.WithContext(ctx).Errorf("[Handler AdminListAuditLogs] error when query audit logs, error: %v", err)
+		httpErrorWrite(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	httpResponseWrite(w, response.ResponseAPI{
+		Status: "success",
+		Data:   logs,
+	}, http.StatusOK)
+}
+
+// searchWalletsParam reads the wallet filters, dates are RFC 3339 or YYYY-MM-DD and created_before is exclusive
+func searchWalletsParam(r *http.Request) (models.ParamSearchWallets, error) {
+	var param models.ParamSearchWallets
+	var err error
+	param.Limit, param.Offset, err = pagination(r)
+	if err != nil {
+		return param, err
+	}
+
+	param.CustomerXId = r.FormValue("customer_xid")
+	if param.CustomerXId != "" {
+		if _, err := uuid.Parse(param.CustomerXId); err != nil {
+			return param, errors.New("customer_xid must be a uuid")
+		}
+	}
+	param.Currency = r.FormValue("currency")
+	param.Status = r.FormValue("status")
+	switch param.Status {
+	case "", models.WalletStatusEnabled, models.WalletStatusDisabled, models.WalletStatusFrozen:
+	default:
+		return param, errors.New("status must be one of enabled, disabled or frozen")
+	}
+
+	for key, dst := range map[string]**float64{"min_balance": &param.MinBalance, "max_balance": &param.MaxBalance} {
+		v := r.FormValue(key)
+		if v == "" {
+			continue
+		}
+		balance, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return param, errors.New(key + " must be a number")
+		}
+		*dst = &balance
+	}
+	for key, dst := range map[string]*time.Time{"created_after": &param.CreatedAfter, "created_before": &param.CreatedBefore} {
+		v := r.FormValue(key)
+		if v == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			t, err = time.Parse("2006-01-02", v)
+		}
+		if err != nil {
+			return param, errors.New(key + " must be an RFC 3339 time or a YYYY-MM-DD date")
+		}
+		*dst = t
+	}
+	return param, nil
+}
+
+func adminErrorCode(err error) int {
+	switch {
+	case errors.Is(err, models.ErrWalletNotFound), errors.Is(err, models.ErrTransactionNotFound),
+		errors.Is(err, models.ErrAdjustmentNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, models.ErrSelfReview):
+		return http.StatusForbidden
+	case errors.Is(err, models.ErrAdjustmentReviewed), errors.Is(err, models.ErrTransactionReversed),
+		errors.Is(err, models.ErrWalletFrozen), errors.Is(err, models.ErrWalletNotFrozen):
+		return http.StatusConflict
+	case errors.Is(err, models.ErrInsufficientFund), errors.Is(err, models.ErrNotReversible):
+		return http.StatusUnprocessableEntity
+	case errors.Is(err, service.ErrActorRequired):
+		return http.StatusUnauthorized
+	case errors.Is(err, service.ErrReasonRequired), errors.Is(err, service.ErrAmountRequired),
+		errors.Is(err, service.ErrPocketFreeze), errors.Is(err, service.ErrInvalidReference):
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
@@ -0,0 +1,34 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/ahmadmirdas/julo-test/repository/database/models"
+	"github.com/ahmadmirdas/julo-test/service"
+)
+
+func TestAdminErrorCode(t *testing.T) {
+	tests := []struct {
+		err  error
+		want int
+	}{
+		{err: models.ErrAdjustmentNotFound, want: http.StatusNotFound},
+		{err: models.ErrSelfReview, want: http.StatusForbidden},
+		{err: fmt.Errorf("review adjustment: %w", models.ErrSelfReview), want: http.StatusForbidden},
+		{err: models.ErrAdjustmentReviewed, want: http.StatusConflict},
+		{err: models.ErrWalletFrozen, want: http.StatusConflict},
+		{err: models.ErrInsufficientFund, want: http.StatusUnprocessableEntity},
+		{err: service.ErrActorRequired, want: http.StatusUnauthorized},
+		{err: service.ErrReasonRequired, want: http.StatusBadRequest},
+		{err: fmt.Errorf("connection reset"), want: http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.err.Error(), func(t *testing.T) {
+			if got := adminErrorCode(tt.err); got != tt.want {
+				t.Errorf("adminErrorCode(%v) = %d, want %d", tt.err, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,277 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ahmadmirdas/julo-test/api"
+	"github.com/ahmadmirdas/julo-test/config"
+	"github.com/ahmadmirdas/julo-test/repository/database/models"
+	"github.com/ahmadmirdas/julo-test/repository/database/models/entity"
+	"github.com/ahmadmirdas/julo-test/server/middleware"
+	"github.com/ahmadmirdas/julo-test/service"
+	"github.com/ahmadmirdas/julo-test/utils/fee"
+	"github.com/ahmadmirdas/julo-test/utils/kyc"
+	"github.com/ahmadmirdas/julo-test/utils/limit"
+	"github.com/ahmadmirdas/julo-test/utils/settings"
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/getkin/kin-openapi/routers"
+	"github.com/getkin/kin-openapi/routers/gorillamux"
+	"github.com/golang-jwt/jwt/v4"
+)
+
+const (
+	contractCustomer = "ea0212d3-abd6-406f-8c67-868e814a2436"
+	contractWallet   = "c4d7d61f-b702-44a8-af97-5dbdafa96551"
+	contractTx       = "6ef31975-67b0-421a-9493-667569d89556"
+	contractRef      = "50535246-dcb2-4929-8cc9-004ea06f5241"
+)
+
+// fakeWalletRepo serves one enabled wallet, methods the handlers under test do not call panic
+type fakeWalletRepo struct {
+	models.WalletDBRepo
+	wallet entity.Wallet
+}
+
+func (f *fakeWalletRepo) GetWallet(ctx context.Context, customerXId, currency string) (*entity.Wallet, error) {
+	if currency != f.wallet.Currency {
+		return &entity.Wallet{}, nil
+	}
+	wallet := f.wallet
+	return &wallet, nil
+}
+
+func (f *fakeWalletRepo) GetWallets(ctx context.Context, customerXId string) ([]entity.Wallet, error) {
+	return []entity.Wallet{f.wallet}, nil
+}
+
+func (f *fakeWalletRepo) GetWalletLimit(ctx context.Context, walletID, txType string) (*entity.WalletLimit, error) {
+	return &entity.WalletLimit{WalletID: walletID, Type: txType}, nil
+}
+
+func (f *fakeWalletRepo) GetTransactionUsage(ctx context.Context, customerXId, currency, txType string, since time.Time) (*models.TransactionUsage, error) {
+	return &models.TransactionUsage{}, nil
+}
+
+func (f *fakeWalletRepo) GetTotalBalance(ctx context.Context, customerXId, currency string) (float64, error) {
+	return f.wallet.Balance, nil
+}
+
+func (f *fakeWalletRepo) WalletDeposit(ctx context.Context, param models.ParamWalletDeposit) (*entity.History, error) {
+	return f.history(models.HistoryTypeDeposit, param.Amount, param.ReferenceID), nil
+}
+
+func (f *fakeWalletRepo) WalletWithdraw(ctx context.Context, param models.ParamWalletWithdraw) (*entity.History, error) {
+	if param.Amount+param.Fee > f.wallet.Balance {
+		return nil, models.ErrInsufficientFund
+	}
+	return f.history(models.HistoryTypeWithdraw, param.Amount, param.ReferenceID), nil
+}
+
+func (f *fakeWalletRepo) history(txType string, amount float64, referenceID string) *entity.History {
+	wallet := f.wallet
+	return &entity.History{
+		ID:          contractTx,
+		WalletID:    wallet.ID,
+		Wallet:      &wallet,
+		Status:      models.HistoryStatusSuccess,
+		Type:        txType,
+		Amount:      amount,
+		Currency:    wallet.Currency,
+		ReferenceID: referenceID,
+		CreatedAt:   time.Now(),
+	}
+}
+
+// TestWalletResponsesMatchOpenAPI runs requests through the wallet handlers and validates both the
+// request and the response written by the handler against the OpenAPI document
+func TestWalletResponsesMatchOpenAPI(t *testing.T) {
+	config.Config.CurrencyCfg.Default = "IDR"
+	config.Config.CurrencyCfg.Supported = []string{"IDR", "USD"}
+	config.Config.JWTCfg.SignKey = "contract test"
+	config.Config.FeeCfg.RevenueAccount = "fee-revenue"
+
+	router := openAPIRouter(t)
+	walletRepo := &fakeWalletRepo{wallet: entity.Wallet{
+		ID:        contractWallet,
+		OwnedBy:   contractCustomer,
+		IsEnabled: true,
+		EnabledAt: time.Now(),
+		Balance:   100000,
+		Currency:  "IDR",
+		KycLevel:  kyc.LevelBasic,
+	}}
+	h := NewHandlerWallet(walletRepo, service.NewWalletService(walletRepo, nil, settings.NewStore(&settings.Settings{
+		Fee:   fee.NewEngine([]fee.Rule{{Type: models.HistoryTypeWithdraw, Segment: fee.AnySegment, Kind: fee.KindFlat, Flat: 2500}}),
+		Limit: limit.NewEngine(nil, nil),
+		Kyc:   kyc.NewPolicy(map[string][]string{kyc.LevelBasic: {models.HistoryTypeDeposit, models.HistoryTypeWithdraw}}),
+	})))
+
+	tests := []struct {
+		name    string
+		method  string
+		path    string
+		form    url.Values
+		handler http.HandlerFunc
+		status  int
+	}{
+		{
+			name:    "init",
+			method:  http.MethodPost,
+			path:    "/init",
+			form:    url.Values{"customer_xid": {contractCustomer}},
+			handler: h.InitAccountWallet,
+			status:  http.StatusOK,
+		},
+		{
+			name:    "view balance",
+			method:  http.MethodGet,
+			path:    "/wallet",
+			handler: h.ViewWalletBalance,
+			status:  http.StatusOK,
+		},
+		{
+			name:    "view balance of an unsupported currency",
+			method:  http.MethodGet,
+			path:    "/wallet?currency=XYZ",
+			handler: h.ViewWalletBalance,
+			status:  http.StatusBadRequest,
+		},
+		{
+			name:    "deposit",
+			method:  http.MethodPost,
+			path:    "/wallet/deposits",
+			form:    url.Values{"amount": {"50000"}, "reference_id": {contractRef}},
+			handler: h.DepositWallet,
+			status:  http.StatusOK,
+		},
+		{
+			name:    "deposit below the minor unit",
+			method:  http.MethodPost,
+			path:    "/wallet/deposits",
+			form:    url.Values{"amount": {"10.555"}, "reference_id": {contractRef}},
+			handler: h.DepositWallet,
+			status:  http.StatusBadRequest,
+		},
+		{
+			name:    "deposit in a currency without wallet",
+			method:  http.MethodPost,
+			path:    "/wallet/deposits",
+			form:    url.Values{"amount": {"10"}, "currency": {"USD"}, "reference_id": {contractRef}},
+			handler: h.DepositWallet,
+			status:  http.StatusBadRequest,
+		},
+		{
+			name:    "withdraw",
+			method:  http.MethodPost,
+			path:    "/wallet/withdrawals",
+			form:    url.Values{"amount": {"20000"}, "reference_id": {contractRef}},
+			handler: h.WithdrawWallet,
+			status:  http.StatusOK,
+		},
+		{
+			name:    "withdraw more than the balance",
+			method:  http.MethodPost,
+			path:    "/wallet/withdrawals",
+			form:    url.Values{"amount": {"99000"}, "reference_id": {contractRef}},
+			handler: h.WithdrawWallet,
+			status:  http.StatusUnprocessableEntity,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var body io.Reader
+			if tt.form != nil {
+				body = strings.NewReader(tt.form.Encode())
+			}
+			req := httptest.NewRequest(tt.method, "http://localhost:5000"+api.BasePath+tt.path, body)
+			if tt.form != nil {
+				req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			}
+			req = req.WithContext(context.WithValue(req.Context(), middleware.Customer, jwt.MapClaims{"customer_xid": contractCustomer}))
+
+			rec := httptest.NewRecorder()
+			tt.handler(rec, req)
+			if rec.Code != tt.status {
+				t.Fatalf("status = %d, want %d, body: %s", rec.Code, tt.status, rec.Body)
+			}
+			validateContract(t, router, req, tt.form, rec)
+		})
+	}
+}
+
+func openAPIRouter(t *testing.T) routers.Router {
+	t.Helper()
+	// the form decoder reports absent optional fields as null, drop them so they validate as absent
+	decodeForm := openapi3filter.RegisteredBodyDecoder("application/x-www-form-urlencoded")
+	openapi3filter.RegisterBodyDecoder("application/x-www-form-urlencoded",
+		func(body io.Reader, header http.Header, schema *openapi3.SchemaRef, encFn openapi3filter.EncodingFn) (any, error) {
+			value, err := decodeForm(body, header, schema, encFn)
+			if obj, ok := value.(map[string]any); ok {
+				for name, field := range obj {
+					if field == nil {
+						delete(obj, name)
+					}
+				}
+			}
+			return value, err
+		})
+	t.Cleanup(func() {
+		openapi3filter.RegisterBodyDecoder("application/x-www-form-urlencoded", decodeForm)
+	})
+	loader := openapi3.NewLoader()
+	doc, err := loader.LoadFromData(api.Spec)
+	if err != nil {
+		t.Fatalf("load openapi document: %v", err)
+	}
+	if err := doc.Validate(loader.Context); err != nil {
+		t.Fatalf("invalid openapi document: %v", err)
+	}
+	router, err := gorillamux.NewRouter(doc)
+	if err != nil {
+		t.Fatalf("route openapi document: %v", err)
+	}
+	return router
+}
+
+// validateContract checks req, whose form body was already read by the handler, and the recorded response
+func validateContract(t *testing.T, router routers.Router, req *http.Request, form url.Values, rec *httptest.ResponseRecorder) {
+	t.Helper()
+	if form != nil {
+		req.Body = io.NopCloser(strings.NewReader(form.Encode()))
+	}
+	route, pathParams, err := router.FindRoute(req)
+	if err != nil {
+		t.Fatalf("find documented operation: %v", err)
+	}
+
+	ctx := context.Background()
+	requestInput := &openapi3filter.RequestValidationInput{
+		Request:    req,
+		PathParams: pathParams,
+		Route:      route,
+		Options:    &openapi3filter.Options{AuthenticationFunc: openapi3filter.NoopAuthenticationFunc},
+	}
+	if err := openapi3filter.ValidateRequest(ctx, requestInput); err != nil {
+		t.Errorf("request does not match the document: %v", err)
+	}
+
+	responseInput := &openapi3filter.ResponseValidationInput{
+		RequestValidationInput: requestInput,
+		Status:                 rec.Code,
+		Header:                 rec.Header(),
+		Body:                   io.NopCloser(bytes.NewReader(rec.Body.Bytes())),
+		Options:                &openapi3filter.Options{IncludeResponseStatus: true},
+	}
+	if err := openapi3filter.ValidateResponse(ctx, responseInput); err != nil {
+		t.Errorf("response does not match the document: %v, body: %s", err, rec.Body)
+	}
+}
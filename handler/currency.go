@@ -0,0 +1,32 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/ahmadmirdas/julo-test/config"
+	"github.com/ahmadmirdas/julo-test/utils"
+	"github.com/ahmadmirdas/julo-test/utils/currency"
+)
+
+// requestCurrency returns the currency requested by the client, the default currency when none is sent
+func requestCurrency(r *http.Request) (currency.Currency, error) {
+	cfg := config.Config.CurrencyCfg
+	code := strings.ToUpper(r.FormValue("currency"))
+	if code == "" {
+		code = cfg.Default
+	}
+
+	return supportedCurrency(code)
+}
+
+// supportedCurrency returns the currency of code when it is one of the configured supported currencies
+func supportedCurrency(code string) (currency.Currency, error) {
+	cur, ok := currency.Get(code)
+	if !ok || !utils.Contains(cur.Code, config.Config.CurrencyCfg.Supported) {
+		return currency.Currency{}, fmt.Errorf("currency %s is not supported", strings.ToUpper(code))
+	}
+
+	return cur, nil
+}
@@ -0,0 +1,318 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/ahmadmirdas/julo-test/config"
+	"github.com/ahmadmirdas/julo-test/repository/database/models"
+	"github.com/ahmadmirdas/julo-test/repository/database/models/entity"
+	"github.com/ahmadmirdas/julo-test/server/middleware"
+	"github.com/ahmadmirdas/julo-test/utils/activity"
+	"github.com/ahmadmirdas/julo-test/utils/currency"
+	"github.com/ahmadmirdas/julo-test/utils/fx"
+	"github.com/ahmadmirdas/julo-test/utils/log"
+	"github.com/ahmadmirdas/julo-test/utils/response"
+	"github.com/ahmadmirdas/julo-test/utils/settings"
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/google/uuid"
+)
+
+const capabilityConversion = "conversion"
+
+type ResponseFxQuote struct {
+	ID           string  `json:"id"`
+	FromCurrency string  `json:"from_currency"`
+	ToCurrency   string  `json:"to_currency"`
+	Rate         float64 `json:"rate"`
+	FromAmount   float64 `json:"from_amount"`
+	ToAmount     float64 `json:"to_amount"`
+	ExpiresAt    string  `json:"expires_at"`
+}
+
+type ResponseConversion struct {
+	ID           string  `json:"id"`
+	QuoteID      string  `json:"quote_id"`
+	FromWalletID string  `json:"from_wallet_id"`
+	ToWalletID   string  `json:"to_wallet_id"`
+	FromAmount   float64 `json:"from_amount"`
+	ToAmount     float64 `json:"to_amount"`
+	Rate         float64 `json:"rate"`
+	ConvertedAt  string  `json:"converted_at"`
+}
+
+type handlerFx struct {
+	fxRepo     models.FxDBRepo
+	walletRepo models.WalletDBRepo
+	settings   *settings.Store
+}
+
+type HandlerFx interface {
+	ListFxRates(w http.ResponseWriter, r *http.Request)
+	UpdateFxRate(w http.ResponseWriter, r *http.Request)
+	ImportFxRates(w http.ResponseWriter, r *http.Request)
+	CreateConversionQuote(w http.ResponseWriter, r *http.Request)
+	CreateConversion(w http.ResponseWriter, r *http.Request)
+}
+
+func NewHandlerFx(fxRepo models.FxDBRepo, walletRepo models.WalletDBRepo, settings *settings.Store) HandlerFx {
+	return &handlerFx{
+		fxRepo:     fxRepo,
+		walletRepo: walletRepo,
+		settings:   settings,
+	}
+}
+
+func (h *handlerFx) ListFxRates(w http.ResponseWriter, r *http.Request) {
+	ctx := activity.NewRequestContext(r.Context(), "Handler.ListFxRates")
+
+	rates, err := h.fxRepo.GetRates()
+	if err != nil {
+		log.WithContext(ctx).Errorf("[Handler ListFxRates] error when query fx rates, error: %v", err)
+		httpErrorWrite(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	httpResponseWrite(w, response.ResponseAPI{
+		Status: "success",
+		Data:   rates,
+	}, http.StatusOK)
+}
+
+func (h *handlerFx) UpdateFxRate(w http.ResponseWriter, r *http.Request) {
+	actorID, _ := activity.GetActorID(r.Context())
+	ctx := activity.WithActorID(activity.NewRequestContext(r.Context(), "Handler.UpdateFxRate"), actorID)
+
+	base, okBase := currency.Get(r.FormValue("base_currency"))
+	quote, okQuote := currency.Get(r.FormValue("quote_currency"))
+	if !okBase || !okQuote || base.Code == quote.Code {
+		httpErrorWrite(w, "base_currency and quote_currency must be two different ISO 4217 codes", http.StatusBadRequest)
+		return
+	}
+	rate, err := strconv.ParseFloat(r.FormValue("rate"), 64)
+	if err != nil || rate <= 0 {
+		httpErrorWrite(w, "rate must be a positive number", http.StatusBadRequest)
+		return
+	}
+
+	h.saveRates(ctx, w, actorID, []fx.Rate{{Base: base.Code, Quote: quote.Code, Rate: rate}})
+}
+
+// ImportFxRates loads rates from a csv sent either as multipart "file" field or as the request body
+func (h *handlerFx) ImportFxRates(w http.ResponseWriter, r *http.Request) {
+	actorID, _ := activity.GetActorID(r.Context())
+	ctx := activity.WithActorID(activity.NewRequestContext(r.Context(), "Handler.ImportFxRates"), actorID)
+
+	var body io.Reader = r.Body
+	if file, _, err := r.FormFile("file"); err == nil {
+		defer file.Close()
+		body = file
+	}
+
+	rates, err := fx.ParseRates(body)
+	if err != nil {
+		log.WithContext(ctx).Warnf("[Handler ImportFxRates] invalid csv, error: %v", err)
+		httpErrorWrite(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	h.saveRates(ctx, w, actorID, rates)
+}
+
+func (h *handlerFx) saveRates(ctx context.Context, w http.ResponseWriter, actorID string, rates []fx.Rate) {
+	now := time.Now()
+	fxRates := make([]entity.FxRate, 0, len(rates))
+	for _, rate := range rates {
+		fxRates = append(fxRates, entity.FxRate{
+			BaseCurrency:  rate.Base,
+			QuoteCurrency: rate.Quote,
+			Rate:          rate.Rate,
+			UpdatedBy:     actorID,
+			UpdatedAt:     now,
+		})
+	}
+
+	err := h.fxRepo.UpsertRates(fxRates)
+	if err != nil {
+		log.WithContext(ctx).Errorf("[Handler saveRates] error when upsert fx rates, error: %v", err)
+		httpErrorWrite(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	log.WithContext(ctx).Infof("[Handler saveRates] %d fx rates updated", len(fxRates))
+	httpResponseWrite(w, response.ResponseAPI{
+		Status: "success",
+		Data:   fxRates,
+	}, http.StatusOK)
+}
+
+func (h *handlerFx) CreateConversionQuote(w http.ResponseWriter, r *http.Request) {
+	ctx := activity.NewRequestContext(r.Context(), "Handler.CreateConversionQuote")
+	cus := r.Context().Value(middleware.Customer).(jwt.MapClaims)
+	custXId := cus["customer_xid"].(string)
+	cfg := config.Config.FxCfg
+
+	from, err := supportedCurrency(r.FormValue("from_currency"))
+	if err != nil {
+		httpErrorWrite(w, "from_currency: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	to, err := supportedCurrency(r.FormValue("to_currency"))
+	if err != nil {
+		httpErrorWrite(w, "to_currency: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if from.Code == to.Code {
+		httpErrorWrite(w, "from_currency and to_currency must be two different currencies", http.StatusBadRequest)
+		return
+	}
+	amount, err := strconv.ParseFloat(r.FormValue("amount"), 64)
+	if err != nil || amount <= 0 {
+		httpErrorWrite(w, "amount must be a positive number", http.StatusBadRequest)
+		return
+	}
+	if err := from.Validate(amount); err != nil {
+		httpErrorWrite(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	wallet, err := h.walletRepo.GetWallet(ctx, custXId, from.Code)
+	if err != nil {
+		log.WithContext(ctx).Errorf("[Handler CreateConversionQuote] error when query get wallet, error: %v", err)
+		httpErrorWrite(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	target, err := h.walletRepo.GetWallet(ctx, custXId, to.Code)
+	if err != nil {
+		log.WithContext(ctx).Errorf("[Handler CreateConversionQuote] error when query get wallet, error: %v", err)
+		httpErrorWrite(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if wallet.ID == "" || target.ID == "" {
+		httpErrorWrite(w, models.ErrWalletNotFound.Error(), http.StatusNotFound)
+		return
+	}
+	current := h.settings.Load()
+	if !current.Kyc.Allowed(wallet.KycLevel, capabilityConversion) {
+		httpErrorWrite(w, "your kyc level "+wallet.KycLevel+" cannot convert currency", http.StatusForbidden)
+		return
+	}
+
+	marketRate, err := h.fxRepo.GetRate(from.Code, to.Code)
+	if err != nil {
+		log.WithContext(ctx).Warnf("[Handler CreateConversionQuote] error when query fx rate, error: %v", err)
+		code := http.StatusInternalServerError
+		if errors.Is(err, models.ErrRateNotFound) {
+			code = http.StatusUnprocessableEntity
+		}
+		httpErrorWrite(w, err.Error(), code)
+		return
+	}
+
+	rate, toAmount := cfg.Convert(marketRate, amount, from, to)
+	quote := entity.FxQuote{
+		CustomerXId:  custXId,
+		FromCurrency: from.Code,
+		ToCurrency:   to.Code,
+		Rate:         rate,
+		FromAmount:   amount,
+		ToAmount:     toAmount,
+		ExpiresAt:    time.Now().Add(time.Duration(cfg.QuoteTTL) * time.Second),
+	}
+	err = h.fxRepo.CreateQuote(&quote)
+	if err != nil {
+		log.WithContext(ctx).Errorf("[Handler CreateConversionQuote] error when insert quote, error: %v", err)
+		httpErrorWrite(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	httpResponseWrite(w, response.ResponseAPI{
+		Status: "success",
+		Data: ResponseFxQuote{
+			ID:           quote.ID,
+			FromCurrency: quote.FromCurrency,
+			ToCurrency:   quote.ToCurrency,
+			Rate:         quote.Rate,
+			FromAmount:   quote.FromAmount,
+			ToAmount:     quote.ToAmount,
+			ExpiresAt:    quote.ExpiresAt.String(),
+		},
+	}, http.StatusOK)
+}
+
+func (h *handlerFx) CreateConversion(w http.ResponseWriter, r *http.Request) {
+	ctx := activity.NewRequestContext(r.Context(), "Handler.CreateConversion")
+	cus := r.Context().Value(middleware.Customer).(jwt.MapClaims)
+	custXId := cus["customer_xid"].(string)
+
+	quoteID := r.FormValue("quote_id")
+	if _, err := uuid.Parse(quoteID); err != nil {
+		httpErrorWrite(w, "quote_id must be a uuid", http.StatusBadRequest)
+		return
+	}
+
+	quote, err := h.fxRepo.GetQuote(custXId, quoteID)
+	if err != nil {
+		log.WithContext(ctx).Errorf("[Handler CreateConversion] error when query quote, error: %v", err)
+		httpErrorWrite(w, err.Error(), conversionErrorCode(err))
+		return
+	}
+	target, err := h.walletRepo.GetWallet(ctx, custXId, quote.ToCurrency)
+	if err != nil {
+		log.WithContext(ctx).Errorf("[Handler CreateConversion] error when query get wallet, error: %v", err)
+		httpErrorWrite(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if target.ID == "" {
+		httpErrorWrite(w, models.ErrWalletNotFound.Error(), http.StatusNotFound)
+		return
+	}
+	current := h.settings.Load()
+	limitCheck, code, err := checkLimit(ctx, h.walletRepo, current.Limit, target, models.HistoryTypeConversionIn, quote.ToAmount, true)
+	if err != nil {
+		log.WithContext(ctx).Warnf("[Handler CreateConversion] conversion rejected by the target wallet limits, error: %v", err)
+		httpErrorWrite(w, err.Error(), code)
+		return
+	}
+
+	res, err := h.fxRepo.Convert(models.ParamConvert{
+		CustomerXId: custXId,
+		QuoteID:     quoteID,
+		Limit:       limitCheck,
+	})
+	if err != nil {
+		log.WithContext(ctx).Errorf("[Handler CreateConversion] error when convert, error: %v", err)
+		httpErrorWrite(w, err.Error(), conversionErrorCode(err))
+		return
+	}
+
+	httpResponseWrite(w, response.ResponseAPI{
+		Status: "success",
+		Data: ResponseConversion{
+			ID:           res.ID,
+			QuoteID:      res.QuoteID,
+			FromWalletID: res.FromWalletID,
+			ToWalletID:   res.ToWalletID,
+			FromAmount:   res.FromAmount,
+			ToAmount:     res.ToAmount,
+			Rate:         res.Rate,
+			ConvertedAt:  res.CreatedAt.String(),
+		},
+	}, http.StatusOK)
+}
+
+// conversionErrorCode is the http status of a failed conversion
+func conversionErrorCode(err error) int {
+	switch {
+	case errors.Is(err, models.ErrQuoteNotFound), errors.Is(err, models.ErrWalletNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, models.ErrQuoteUsed), errors.Is(err, models.ErrQuoteExpired),
+		errors.Is(err, models.ErrWalletDisabled), errors.Is(err, models.ErrInsufficientFund):
+		return http.StatusUnprocessableEntity
+	}
+	return limitErrorCode(err)
+}
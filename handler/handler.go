@@ -2,13 +2,16 @@ package handler
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 	"strconv"
 
 	"github.com/ahmadmirdas/julo-test/repository/database/models"
 	"github.com/ahmadmirdas/julo-test/server/middleware"
+	"github.com/ahmadmirdas/julo-test/service"
 	"github.com/ahmadmirdas/julo-test/utils/activity"
 	"github.com/ahmadmirdas/julo-test/utils/log"
+	"github.com/ahmadmirdas/julo-test/utils/metrics"
 	"github.com/ahmadmirdas/julo-test/utils/response"
 	"github.com/golang-jwt/jwt/v4"
 )
@@ -18,7 +21,8 @@ const (
 )
 
 type handlerWallet struct {
-	walletRepo models.WalletDBRepo
+	walletRepo    models.WalletDBRepo
+	walletService service.WalletService
 }
 
 type HandlerWallet interface {
@@ -30,14 +34,15 @@ type HandlerWallet interface {
 	DisableWallet(w http.ResponseWriter, r *http.Request)
 }
 
-func NewHandlerWallet(walletRepo models.WalletDBRepo) HandlerWallet {
+func NewHandlerWallet(walletRepo models.WalletDBRepo, walletService service.WalletService) HandlerWallet {
 	return &handlerWallet{
-		walletRepo: walletRepo,
+		walletRepo:    walletRepo,
+		walletService: walletService,
 	}
 }
 
 func (h *handlerWallet) InitAccountWallet(w http.ResponseWriter, r *http.Request) {
-	ctx := activity.NewContext("Handler.InitAccountWallet")
+	ctx := activity.NewRequestContext(r.Context(), "Handler.InitAccountWallet")
 	customerXId := r.FormValue("customer_xid")
 	if customerXId == "" {
 		log.WithContext(ctx).Warn("[Handler InitAccountWallet] customer xid is required")
@@ -70,13 +75,30 @@ func (h *handlerWallet) InitAccountWallet(w http.ResponseWriter, r *http.Request
 }
 
 func (h *handlerWallet) EnableWallet(w http.ResponseWriter, r *http.Request) {
-	ctx := activity.NewContext("Handler.EnableWallet")
+	ctx := activity.NewRequestContext(r.Context(), "Handler.EnableWallet")
 	cus := r.Context().Value(middleware.Customer).(jwt.MapClaims)
 	custXId := cus["customer_xid"].(string)
 
-	res, err := h.walletRepo.EnableWallet(custXId)
+	cur, err := requestCurrency(r)
 	if err != nil {
-		log.WithContext(ctx).Errorf("[Handler EnableWallet] error when enable wallet, error: ", err)
+		log.WithContext(ctx).Warnf("[Handler EnableWallet] invalid currency, error: %v", err)
+		httpResponseWrite(w, response.ResponseAPI{
+			Error_: &response.ApiError{
+				Code:    http.StatusBadRequest,
+				Message: err.Error(),
+			},
+		}, http.StatusBadRequest)
+		return
+	}
+
+	res, err := h.walletService.Enable(ctx, custXId, cur.Code)
+	if errors.Is(err, models.ErrWalletFrozen) {
+		log.WithContext(ctx).Warnf("[Handler EnableWallet] wallet of %s is frozen", custXId)
+		httpErrorWrite(w, err.Error(), http.StatusForbidden)
+		return
+	}
+	if err != nil {
+		log.WithContext(ctx).Errorf("[Handler EnableWallet] error when enable wallet, error: %v", err)
 		httpResponseWrite(w, response.ResponseAPI{
 			Error_: &response.ApiError{
 				Code:    http.StatusInternalServerError,
@@ -98,18 +120,31 @@ func (h *handlerWallet) EnableWallet(w http.ResponseWriter, r *http.Request) {
 			Status:    status,
 			EnabledAt: res.EnabledAt.String(),
 			Balance:   res.Balance,
+			Currency:  res.Currency,
 		},
 	}, http.StatusOK)
 }
 
 func (h *handlerWallet) ViewWalletBalance(w http.ResponseWriter, r *http.Request) {
-	ctx := activity.NewContext("Handler.ViewWalletBalance")
+	ctx := activity.NewRequestContext(r.Context(), "Handler.ViewWalletBalance")
 	cus := r.Context().Value(middleware.Customer).(jwt.MapClaims)
 	custXId := cus["customer_xid"].(string)
 
-	wallet, err := h.walletRepo.GetWallet(custXId)
+	cur, err := requestCurrency(r)
 	if err != nil {
-		log.WithContext(ctx).Errorf("[Handler ViewWalletBalance] error when query get wallet, error: ", err)
+		log.WithContext(ctx).Warnf("[Handler ViewWalletBalance] invalid currency, error: %v", err)
+		httpResponseWrite(w, response.ResponseAPI{
+			Error_: &response.ApiError{
+				Code:    http.StatusBadRequest,
+				Message: err.Error(),
+			},
+		}, http.StatusBadRequest)
+		return
+	}
+
+	wallet, err := h.walletRepo.GetWallet(ctx, custXId, cur.Code)
+	if err != nil {
+		log.WithContext(ctx).Errorf("[Handler ViewWalletBalance] error when query get wallet, error: %v", err)
 		httpResponseWrite(w, response.ResponseAPI{
 			Error_: &response.ApiError{
 				Code:    http.StatusInternalServerError,
@@ -138,33 +173,36 @@ func (h *handlerWallet) ViewWalletBalance(w http.ResponseWriter, r *http.Request
 			Status:    status,
 			EnabledAt: wallet.EnabledAt.String(),
 			Balance:   wallet.Balance,
+			Currency:  wallet.Currency,
 		},
 	}, http.StatusOK)
 }
 
 func (h *handlerWallet) DepositWallet(w http.ResponseWriter, r *http.Request) {
-	ctx := activity.NewContext("Handler.DepositWallet")
+	ctx := activity.NewRequestContext(r.Context(), "Handler.DepositWallet")
 	cus := r.Context().Value(middleware.Customer).(jwt.MapClaims)
 	custXId := cus["customer_xid"].(string)
 
-	amountStr := r.FormValue("amount")
-	referenceId := r.FormValue("reference_id")
-
-	amount, err := strconv.ParseFloat(amountStr, 64)
+	cur, err := requestCurrency(r)
 	if err != nil {
-		log.WithContext(ctx).Error("[Handler DepositWallet] invalid amount, error : %v", err)
+		log.WithContext(ctx).Warnf("[Handler DepositWallet] invalid currency, error: %v", err)
+		metrics.ObserveFailure(models.HistoryTypeDeposit, metrics.FailureInvalidRequest)
 		httpResponseWrite(w, response.ResponseAPI{
 			Error_: &response.ApiError{
-				Code:    http.StatusInternalServerError,
+				Code:    http.StatusBadRequest,
 				Message: err.Error(),
 			},
-		}, http.StatusInternalServerError)
+		}, http.StatusBadRequest)
 		return
 	}
 
-	wallet, err := h.walletRepo.GetWallet(custXId)
+	amountStr := r.FormValue("amount")
+	referenceId := r.FormValue("reference_id")
+
+	amount, err := strconv.ParseFloat(amountStr, 64)
 	if err != nil {
-		log.WithContext(ctx).Errorf("[Handler DepositWallet] error when query get wallet, error: ", err)
+		log.WithContext(ctx).Errorf("[Handler DepositWallet] invalid amount, error : %v", err)
+		metrics.ObserveFailure(models.HistoryTypeDeposit, metrics.FailureInvalidRequest)
 		httpResponseWrite(w, response.ResponseAPI{
 			Error_: &response.ApiError{
 				Code:    http.StatusInternalServerError,
@@ -174,33 +212,21 @@ func (h *handlerWallet) DepositWallet(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if !wallet.IsEnabled {
-		log.WithContext(ctx).Error("[Handler DepositWallet] your wallet is disabled, cannot deposit")
-		httpResponseWrite(w, response.ResponseAPI{
-			Error_: &response.ApiError{
-				Code:    http.StatusInternalServerError,
-				Message: "your wallet is disabled, cannot deposit",
-			},
-		}, http.StatusInternalServerError)
-		return
-	}
-
-	param := models.ParamWalletDeposit{
-		WalletID:    wallet.ID,
-		Balance:     wallet.Balance,
+	res, err := h.walletService.Deposit(ctx, service.ParamTransaction{
 		CustomerXId: custXId,
+		Currency:    cur,
+		PocketID:    r.FormValue("pocket_id"),
 		Amount:      amount,
 		ReferenceID: referenceId,
-	}
-	res, err := h.walletRepo.WalletDeposit(param)
+	})
 	if err != nil {
-		log.WithContext(ctx).Errorf("[Handler DepositWallet] error when query wallet deposit, error: %v", err)
-		httpResponseWrite(w, response.ResponseAPI{
-			Error_: &response.ApiError{
-				Code:    http.StatusInternalServerError,
-				Message: err.Error(),
-			},
-		}, http.StatusInternalServerError)
+		code := walletErrorCode(err)
+		if code == http.StatusInternalServerError {
+			log.WithContext(ctx).Errorf("[Handler DepositWallet] error when deposit, error: %v", err)
+		} else {
+			log.WithContext(ctx).Warnf("[Handler DepositWallet] deposit rejected, error: %v", err)
+		}
+		httpErrorWrite(w, err.Error(), code)
 		return
 	}
 
@@ -212,34 +238,37 @@ func (h *handlerWallet) DepositWallet(w http.ResponseWriter, r *http.Request) {
 			Status:      res.Status,
 			DepositAt:   res.CreatedAt.String(),
 			Amount:      res.Amount,
+			Currency:    res.Currency,
 			ReferenceId: res.ReferenceID,
 		},
 	}, http.StatusOK)
 }
 
 func (h *handlerWallet) WithdrawWallet(w http.ResponseWriter, r *http.Request) {
-	ctx := activity.NewContext("Handler.WithdrawWallet")
+	ctx := activity.NewRequestContext(r.Context(), "Handler.WithdrawWallet")
 	cus := r.Context().Value(middleware.Customer).(jwt.MapClaims)
 	custXId := cus["customer_xid"].(string)
 
-	amountStr := r.FormValue("amount")
-	referenceId := r.FormValue("reference_id")
-
-	amount, err := strconv.ParseFloat(amountStr, 64)
+	cur, err := requestCurrency(r)
 	if err != nil {
-		log.WithContext(ctx).Error("[Handler WithdrawWallet] invalid amount, error : %v", err)
+		log.WithContext(ctx).Warnf("[Handler WithdrawWallet] invalid currency, error: %v", err)
+		metrics.ObserveFailure(models.HistoryTypeWithdraw, metrics.FailureInvalidRequest)
 		httpResponseWrite(w, response.ResponseAPI{
 			Error_: &response.ApiError{
-				Code:    http.StatusInternalServerError,
+				Code:    http.StatusBadRequest,
 				Message: err.Error(),
 			},
-		}, http.StatusInternalServerError)
+		}, http.StatusBadRequest)
 		return
 	}
 
-	wallet, err := h.walletRepo.GetWallet(custXId)
+	amountStr := r.FormValue("amount")
+	referenceId := r.FormValue("reference_id")
+
+	amount, err := strconv.ParseFloat(amountStr, 64)
 	if err != nil {
-		log.WithContext(ctx).Errorf("[Handler WithdrawWallet] error when query get wallet, error: ", err)
+		log.WithContext(ctx).Errorf("[Handler WithdrawWallet] invalid amount, error : %v", err)
+		metrics.ObserveFailure(models.HistoryTypeWithdraw, metrics.FailureInvalidRequest)
 		httpResponseWrite(w, response.ResponseAPI{
 			Error_: &response.ApiError{
 				Code:    http.StatusInternalServerError,
@@ -249,58 +278,61 @@ func (h *handlerWallet) WithdrawWallet(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if !wallet.IsEnabled {
-		log.WithContext(ctx).Error("[Handler WithdrawWallet] your wallet is disabled, cannot withdraw")
-		httpResponseWrite(w, response.ResponseAPI{
-			Error_: &response.ApiError{
-				Code:    http.StatusInternalServerError,
-				Message: "your wallet is disabled, cannot withdraw",
-			},
-		}, http.StatusInternalServerError)
-		return
-	}
-
-	param := models.ParamWalletWithdraw{
-		WalletID:    wallet.ID,
-		Balance:     wallet.Balance,
+	res, withdrawFee, err := h.walletService.Withdraw(ctx, service.ParamTransaction{
 		CustomerXId: custXId,
+		Currency:    cur,
+		PocketID:    r.FormValue("pocket_id"),
 		Amount:      amount,
 		ReferenceID: referenceId,
-	}
-	res, err := h.walletRepo.WalletWithdraw(param)
+	})
 	if err != nil {
-		log.WithContext(ctx).Errorf("[Handler WithdrawWallet] error when query withdraw wallet, error: %v", err)
-		httpResponseWrite(w, response.ResponseAPI{
-			Error_: &response.ApiError{
-				Code:    http.StatusInternalServerError,
-				Message: err.Error(),
-			},
-		}, http.StatusInternalServerError)
+		code := walletErrorCode(err)
+		if code == http.StatusInternalServerError {
+			log.WithContext(ctx).Errorf("[Handler WithdrawWallet] error when withdraw, error: %v", err)
+		} else {
+			log.WithContext(ctx).Warnf("[Handler WithdrawWallet] withdraw rejected, error: %v", err)
+		}
+		httpErrorWrite(w, err.Error(), code)
 		return
 	}
 
 	httpResponseWrite(w, response.ResponseAPI{
 		Status: "success",
 		Data: ResponseWithdrawWallet{
-			ID:          res.ID,
-			WithdrawnBy: res.Wallet.OwnedBy,
-			Status:      res.Status,
-			WithdrawnAt: res.CreatedAt.String(),
-			Amount:      res.Amount,
-			ReferenceId: res.ReferenceID,
+			ID:           res.ID,
+			WithdrawnBy:  res.Wallet.OwnedBy,
+			Status:       res.Status,
+			WithdrawnAt:  res.CreatedAt.String(),
+			Amount:       res.Amount,
+			Currency:     res.Currency,
+			ReferenceId:  res.ReferenceID,
+			Fee:          withdrawFee,
+			TotalDebited: res.Amount + withdrawFee,
 		},
 	}, http.StatusOK)
 }
 
 func (h *handlerWallet) DisableWallet(w http.ResponseWriter, r *http.Request) {
-	ctx := activity.NewContext("Handler.DisableWallet")
+	ctx := activity.NewRequestContext(r.Context(), "Handler.DisableWallet")
 	cus := r.Context().Value(middleware.Customer).(jwt.MapClaims)
 	custXId := cus["customer_xid"].(string)
 
 	formDisabled := r.FormValue("is_disabled")
 	isDisabled, err := strconv.ParseBool(formDisabled)
 	if err != nil {
-		log.WithContext(ctx).Errorf("[Handler DisableWallet] error parse is_disabled to bool, error: ", err)
+		log.WithContext(ctx).Errorf("[Handler DisableWallet] error parse is_disabled to bool, error: %v", err)
+		httpResponseWrite(w, response.ResponseAPI{
+			Error_: &response.ApiError{
+				Code:    http.StatusBadRequest,
+				Message: err.Error(),
+			},
+		}, http.StatusBadRequest)
+		return
+	}
+
+	cur, err := requestCurrency(r)
+	if err != nil {
+		log.WithContext(ctx).Warnf("[Handler DisableWallet] invalid currency, error: %v", err)
 		httpResponseWrite(w, response.ResponseAPI{
 			Error_: &response.ApiError{
 				Code:    http.StatusBadRequest,
@@ -314,9 +346,9 @@ func (h *handlerWallet) DisableWallet(w http.ResponseWriter, r *http.Request) {
 	if !isDisabled {
 		isDisabled = true
 	}
-	res, err := h.walletRepo.UpdateStatusWallet(custXId, isEnabled)
+	res, err := h.walletRepo.UpdateStatusWallet(ctx, custXId, cur.Code, isEnabled)
 	if err != nil {
-		log.WithContext(ctx).Errorf("[Handler DisableWallet] error when enable wallet, error: ", err)
+		log.WithContext(ctx).Errorf("[Handler DisableWallet] error when enable wallet, error: %v", err)
 		httpResponseWrite(w, response.ResponseAPI{
 			Error_: &response.ApiError{
 				Code:    http.StatusInternalServerError,
@@ -338,12 +370,37 @@ func (h *handlerWallet) DisableWallet(w http.ResponseWriter, r *http.Request) {
 			Status:    status,
 			EnabledAt: res.DisabledAt.String(),
 			Balance:   res.Balance,
+			Currency:  res.Currency,
 		},
 	}, http.StatusOK)
 }
 
+// walletErrorCode is the http status of a deposit or withdrawal the wallet service refused or failed
+func walletErrorCode(err error) int {
+	switch {
+	case errors.Is(err, models.ErrPocketNotFound):
+		return http.StatusNotFound
+	case service.IsKycError(err):
+		return http.StatusForbidden
+	case service.IsRequestError(err), service.IsLimitError(err):
+		return http.StatusBadRequest
+	case errors.Is(err, models.ErrInsufficientFund):
+		return http.StatusUnprocessableEntity
+	}
+	return http.StatusInternalServerError
+}
+
 func httpResponseWrite(rw http.ResponseWriter, data interface{}, statusCode int) {
 	rw.Header().Set("Content-type", "application/json")
 	rw.WriteHeader(statusCode)
 	json.NewEncoder(rw).Encode(data)
 }
+
+func httpErrorWrite(rw http.ResponseWriter, message string, statusCode int) {
+	httpResponseWrite(rw, response.ResponseAPI{
+		Error_: &response.ApiError{
+			Code:    statusCode,
+			Message: message,
+		},
+	}, statusCode)
+}
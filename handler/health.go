@@ -0,0 +1,58 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/ahmadmirdas/julo-test/service"
+	"github.com/ahmadmirdas/julo-test/utils/activity"
+	"github.com/ahmadmirdas/julo-test/utils/log"
+	"github.com/ahmadmirdas/julo-test/utils/response"
+)
+
+type handlerHealth struct {
+	healthChecker *service.HealthChecker
+}
+
+type HandlerHealth interface {
+	Liveness(w http.ResponseWriter, r *http.Request)
+	Readiness(w http.ResponseWriter, r *http.Request)
+}
+
+func NewHandlerHealth(healthChecker *service.HealthChecker) HandlerHealth {
+	return &handlerHealth{
+		healthChecker: healthChecker,
+	}
+}
+
+// Liveness answers as long as the process serves http
+func (h *handlerHealth) Liveness(w http.ResponseWriter, r *http.Request) {
+	httpResponseWrite(w, response.ResponseAPI{
+		Status: "success",
+		Data: service.HealthReport{
+			Status: service.HealthStatusOK,
+		},
+	}, http.StatusOK)
+}
+
+// Readiness answers 503 when a dependency check fails or the instance is shutting down
+func (h *handlerHealth) Readiness(w http.ResponseWriter, r *http.Request) {
+	ctx := activity.NewRequestContext(r.Context(), "Handler.Readiness")
+
+	report := h.healthChecker.Ready(ctx)
+	if report.Status != service.HealthStatusOK {
+		log.WithContext(ctx).Warnf("[Handler Readiness] instance is not ready, checks: %+v", report.Checks)
+		httpResponseWrite(w, response.ResponseAPI{
+			Data: report,
+			Error_: &response.ApiError{
+				Code:    http.StatusServiceUnavailable,
+				Message: "instance is not ready",
+			},
+		}, http.StatusServiceUnavailable)
+		return
+	}
+
+	httpResponseWrite(w, response.ResponseAPI{
+		Status: "success",
+		Data:   report,
+	}, http.StatusOK)
+}
@@ -0,0 +1,146 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/ahmadmirdas/julo-test/repository/database/models"
+	"github.com/ahmadmirdas/julo-test/repository/database/models/entity"
+	"github.com/ahmadmirdas/julo-test/utils/activity"
+	"github.com/ahmadmirdas/julo-test/utils/kyc"
+	"github.com/ahmadmirdas/julo-test/utils/log"
+	"github.com/ahmadmirdas/julo-test/utils/response"
+)
+
+type ResponseKyc struct {
+	OwnedBy   string              `json:"owned_by"`
+	KycLevel  string              `json:"kyc_level"`
+	WalletIDs []string            `json:"wallet_ids"`
+	History   []entity.KycHistory `json:"history,omitempty"`
+}
+
+type handlerKyc struct {
+	walletRepo models.WalletDBRepo
+}
+
+type HandlerKyc interface {
+	ViewKycLevel(w http.ResponseWriter, r *http.Request)
+	UpdateKycLevel(w http.ResponseWriter, r *http.Request)
+}
+
+func NewHandlerKyc(walletRepo models.WalletDBRepo) HandlerKyc {
+	return &handlerKyc{
+		walletRepo: walletRepo,
+	}
+}
+
+func (h *handlerKyc) ViewKycLevel(w http.ResponseWriter, r *http.Request) {
+	ctx := activity.NewRequestContext(r.Context(), "Handler.ViewKycLevel")
+	custXId, ok := uuidVar(w, r, "customer_xid")
+	if !ok {
+		return
+	}
+
+	wallets, err := h.walletRepo.GetWallets(ctx, custXId)
+	if err != nil {
+		log.WithContext(ctx).Errorf("[Handler ViewKycLevel] error when query get wallet, error: %v", err)
+		httpResponseWrite(w, response.ResponseAPI{
+			Error_: &response.ApiError{
+				Code:    http.StatusInternalServerError,
+				Message: err.Error(),
+			},
+		}, http.StatusInternalServerError)
+		return
+	}
+	if len(wallets) == 0 {
+		httpResponseWrite(w, response.ResponseAPI{
+			Error_: &response.ApiError{
+				Code:    http.StatusNotFound,
+				Message: "wallet not found",
+			},
+		}, http.StatusNotFound)
+		return
+	}
+
+	histories, err := h.walletRepo.GetKycHistory(ctx, custXId)
+	if err != nil {
+		log.WithContext(ctx).Errorf("[Handler ViewKycLevel] error when query kyc history, error: %v", err)
+		httpResponseWrite(w, response.ResponseAPI{
+			Error_: &response.ApiError{
+				Code:    http.StatusInternalServerError,
+				Message: err.Error(),
+			},
+		}, http.StatusInternalServerError)
+		return
+	}
+
+	httpResponseWrite(w, response.ResponseAPI{
+		Status: "success",
+		Data: ResponseKyc{
+			OwnedBy:   custXId,
+			KycLevel:  wallets[0].KycLevel,
+			WalletIDs: walletIDs(wallets),
+			History:   histories,
+		},
+	}, http.StatusOK)
+}
+
+func (h *handlerKyc) UpdateKycLevel(w http.ResponseWriter, r *http.Request) {
+	actorID, _ := activity.GetActorID(r.Context())
+	ctx := activity.WithActorID(activity.NewRequestContext(r.Context(), "Handler.UpdateKycLevel"), actorID)
+	custXId, ok := uuidVar(w, r, "customer_xid")
+	if !ok {
+		return
+	}
+
+	kycLevel := r.FormValue("kyc_level")
+	if !kyc.IsValidLevel(kycLevel) {
+		log.WithContext(ctx).Warnf("[Handler UpdateKycLevel] invalid kyc level %s", kycLevel)
+		httpResponseWrite(w, response.ResponseAPI{
+			Error_: &response.ApiError{
+				Code:    http.StatusBadRequest,
+				Message: "kyc_level must be one of unverified, basic or full",
+			},
+		}, http.StatusBadRequest)
+		return
+	}
+
+	wallets, err := h.walletRepo.UpdateKycLevel(ctx, models.ParamUpdateKycLevel{
+		CustomerXId: custXId,
+		KycLevel:    kycLevel,
+		Actor:       actorID,
+		Reason:      r.FormValue("reason"),
+	})
+	if err != nil {
+		log.WithContext(ctx).Errorf("[Handler UpdateKycLevel] error when update kyc level, error: %v", err)
+		code := http.StatusInternalServerError
+		if errors.Is(err, models.ErrWalletNotFound) {
+			code = http.StatusNotFound
+		}
+		httpResponseWrite(w, response.ResponseAPI{
+			Error_: &response.ApiError{
+				Code:    code,
+				Message: err.Error(),
+			},
+		}, code)
+		return
+	}
+
+	log.WithContext(ctx).Infof("[Handler UpdateKycLevel] customer %s kyc level changed to %s", custXId, kycLevel)
+	httpResponseWrite(w, response.ResponseAPI{
+		Status: "success",
+		Data: ResponseKyc{
+			OwnedBy:   custXId,
+			KycLevel:  kycLevel,
+			WalletIDs: walletIDs(wallets),
+		},
+	}, http.StatusOK)
+}
+
+func walletIDs(wallets []entity.Wallet) []string {
+	ids := make([]string, 0, len(wallets))
+	for _, wallet := range wallets {
+		ids = append(ids, wallet.ID)
+	}
+	return ids
+}
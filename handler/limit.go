@@ -0,0 +1,30 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/ahmadmirdas/julo-test/repository/database/models"
+	"github.com/ahmadmirdas/julo-test/repository/database/models/entity"
+	"github.com/ahmadmirdas/julo-test/service"
+	"github.com/ahmadmirdas/julo-test/utils/limit"
+)
+
+// checkLimit validates amount against the wallet limits and returns the check the repository runs again
+// within the transaction, or the http status to respond with on failure
+func checkLimit(ctx context.Context, walletRepo models.WalletDBRepo, limitEngine *limit.Engine, wallet *entity.Wallet, txType string, amount float64, credit bool) (*models.LimitCheck, int, error) {
+	check, err := service.CheckLimit(ctx, walletRepo, limitEngine, wallet, txType, amount, credit)
+	if err != nil {
+		return nil, limitErrorCode(err), err
+	}
+
+	return check, http.StatusOK, nil
+}
+
+// limitErrorCode is the http status of a limit check failure, which the repository may also return
+func limitErrorCode(err error) int {
+	if service.IsLimitError(err) {
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
@@ -0,0 +1,163 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/ahmadmirdas/julo-test/config"
+	"github.com/ahmadmirdas/julo-test/repository/database/models"
+	"github.com/ahmadmirdas/julo-test/server/middleware"
+	"github.com/ahmadmirdas/julo-test/utils/activity"
+	"github.com/ahmadmirdas/julo-test/utils/currency"
+	"github.com/ahmadmirdas/julo-test/utils/log"
+	"github.com/ahmadmirdas/julo-test/utils/response"
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/gorilla/mux"
+)
+
+type handlerLoan struct {
+	loanRepo models.LoanDBRepo
+}
+
+type HandlerLoan interface {
+	DisburseLoan(w http.ResponseWriter, r *http.Request)
+	ViewLoan(w http.ResponseWriter, r *http.Request)
+	ListLoans(w http.ResponseWriter, r *http.Request)
+}
+
+func NewHandlerLoan(loanRepo models.LoanDBRepo) HandlerLoan {
+	return &handlerLoan{
+		loanRepo: loanRepo,
+	}
+}
+
+// DisburseLoan credits a loan into the customer main wallet with an equal installment schedule,
+// interest_rate is a flat monthly percentage of the principal
+func (h *handlerLoan) DisburseLoan(w http.ResponseWriter, r *http.Request) {
+	actorID, _ := activity.GetActorID(r.Context())
+	ctx := activity.WithActorID(activity.NewRequestContext(r.Context(), "Handler.DisburseLoan"), actorID)
+	cfg := config.Config.LoanCfg
+
+	custXId := r.FormValue("customer_xid")
+	referenceId := r.FormValue("reference_id")
+	if custXId == "" || referenceId == "" {
+		httpErrorWrite(w, "customer_xid and reference_id are required", http.StatusBadRequest)
+		return
+	}
+	cur, err := requestCurrency(r)
+	if err != nil {
+		httpErrorWrite(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	principal, err := strconv.ParseFloat(r.FormValue("amount"), 64)
+	if err != nil || principal <= 0 || cur.Validate(principal) != nil {
+		httpErrorWrite(w, "amount must be a positive amount in "+cur.Code, http.StatusBadRequest)
+		return
+	}
+	tenor, err := strconv.Atoi(r.FormValue("tenor"))
+	if err != nil || tenor <= 0 || (cfg.MaxTenor > 0 && tenor > cfg.MaxTenor) {
+		httpErrorWrite(w, "tenor must be a positive number of months within the allowed maximum", http.StatusBadRequest)
+		return
+	}
+	var interestRate float64
+	if rate := r.FormValue("interest_rate"); rate != "" {
+		interestRate, err = strconv.ParseFloat(rate, 64)
+		if err != nil || interestRate < 0 {
+			httpErrorWrite(w, "interest_rate must be a non negative percentage", http.StatusBadRequest)
+			return
+		}
+	}
+	firstDueDate := time.Now().AddDate(0, 1, 0)
+	if due := r.FormValue("first_due_date"); due != "" {
+		firstDueDate, err = time.Parse("2006-01-02", due)
+		if err != nil {
+			httpErrorWrite(w, "first_due_date must be formatted as YYYY-MM-DD", http.StatusBadRequest)
+			return
+		}
+	}
+
+	loan, err := h.loanRepo.DisburseLoan(ctx, models.ParamDisburseLoan{
+		CustomerXId:  custXId,
+		Currency:     cur.Code,
+		Principal:    principal,
+		ReferenceID:  referenceId,
+		Installments: buildSchedule(cur, principal, interestRate, tenor, firstDueDate),
+	})
+	if err != nil {
+		log.WithContext(ctx).Errorf("[Handler DisburseLoan] error when disburse loan, error: %v", err)
+		code := http.StatusInternalServerError
+		switch {
+		case errors.Is(err, models.ErrWalletNotFound), errors.Is(err, models.ErrWalletDisabled):
+			code = http.StatusUnprocessableEntity
+		case errors.Is(err, models.ErrLoanReferenceConflict):
+			code = http.StatusConflict
+		}
+		httpErrorWrite(w, err.Error(), code)
+		return
+	}
+
+	log.WithContext(ctx).Infof("[Handler DisburseLoan] loan %s of %v %s disbursed to %s", loan.ID, loan.Principal, loan.Currency, custXId)
+	httpResponseWrite(w, response.ResponseAPI{
+		Status: "success",
+		Data:   loan,
+	}, http.StatusCreated)
+}
+
+func (h *handlerLoan) ViewLoan(w http.ResponseWriter, r *http.Request) {
+	ctx := activity.NewRequestContext(r.Context(), "Handler.ViewLoan")
+
+	loan, err := h.loanRepo.GetLoan(ctx, mux.Vars(r)["loan_id"])
+	if err != nil {
+		log.WithContext(ctx).Errorf("[Handler ViewLoan] error when query loan, error: %v", err)
+		code := http.StatusInternalServerError
+		if errors.Is(err, models.ErrLoanNotFound) {
+			code = http.StatusNotFound
+		}
+		httpErrorWrite(w, err.Error(), code)
+		return
+	}
+
+	httpResponseWrite(w, response.ResponseAPI{
+		Status: "success",
+		Data:   loan,
+	}, http.StatusOK)
+}
+
+func (h *handlerLoan) ListLoans(w http.ResponseWriter, r *http.Request) {
+	ctx := activity.NewRequestContext(r.Context(), "Handler.ListLoans")
+	cus := r.Context().Value(middleware.Customer).(jwt.MapClaims)
+	custXId := cus["customer_xid"].(string)
+
+	loans, err := h.loanRepo.GetLoans(ctx, custXId)
+	if err != nil {
+		log.WithContext(ctx).Errorf("[Handler ListLoans] error when query loans, error: %v", err)
+		httpErrorWrite(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	httpResponseWrite(w, response.ResponseAPI{
+		Status: "success",
+		Data:   loans,
+	}, http.StatusOK)
+}
+
+// buildSchedule splits principal plus flat interest into monthly installments,
+// the last installment absorbs the rounding difference
+func buildSchedule(cur currency.Currency, principal, interestRate float64, tenor int, firstDueDate time.Time) []models.ParamInstallment {
+	total := cur.Round(principal * (1 + interestRate/100*float64(tenor)))
+	amount := cur.Floor(total / float64(tenor))
+
+	installments := make([]models.ParamInstallment, 0, tenor)
+	for i := 0; i < tenor; i++ {
+		if i == tenor-1 {
+			amount = cur.Round(total - amount*float64(tenor-1))
+		}
+		installments = append(installments, models.ParamInstallment{
+			DueDate: firstDueDate.AddDate(0, i, 0),
+			Amount:  amount,
+		})
+	}
+	return installments
+}
@@ -0,0 +1,235 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/ahmadmirdas/julo-test/repository/database/models"
+	"github.com/ahmadmirdas/julo-test/repository/database/models/entity"
+	"github.com/ahmadmirdas/julo-test/server/middleware"
+	"github.com/ahmadmirdas/julo-test/service"
+	"github.com/ahmadmirdas/julo-test/utils/activity"
+	"github.com/ahmadmirdas/julo-test/utils/log"
+	"github.com/ahmadmirdas/julo-test/utils/response"
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+type ResponsePocket struct {
+	ID       string  `json:"id"`
+	WalletID string  `json:"wallet_id"`
+	Name     string  `json:"name"`
+	Status   string  `json:"status"`
+	Balance  float64 `json:"balance"`
+	Currency string  `json:"currency"`
+}
+
+type ResponsePocketTransfer struct {
+	ID            string  `json:"id"`
+	TransferredBy string  `json:"transferred_by"`
+	Status        string  `json:"status"`
+	TransferredAt string  `json:"transferred_at"`
+	Amount        float64 `json:"amount"`
+	Currency      string  `json:"currency"`
+	ReferenceId   string  `json:"reference_id"`
+}
+
+type handlerPocket struct {
+	pocketRepo      models.PocketDBRepo
+	transferService service.TransferService
+}
+
+type HandlerPocket interface {
+	CreatePocket(w http.ResponseWriter, r *http.Request)
+	ListPockets(w http.ResponseWriter, r *http.Request)
+	RenamePocket(w http.ResponseWriter, r *http.Request)
+	ClosePocket(w http.ResponseWriter, r *http.Request)
+	TransferPocket(w http.ResponseWriter, r *http.Request)
+}
+
+func NewHandlerPocket(pocketRepo models.PocketDBRepo, transferService service.TransferService) HandlerPocket {
+	return &handlerPocket{
+		pocketRepo:      pocketRepo,
+		transferService: transferService,
+	}
+}
+
+func (h *handlerPocket) CreatePocket(w http.ResponseWriter, r *http.Request) {
+	ctx := activity.NewRequestContext(r.Context(), "Handler.CreatePocket")
+	cus := r.Context().Value(middleware.Customer).(jwt.MapClaims)
+	custXId := cus["customer_xid"].(string)
+
+	name := r.FormValue("name")
+	if name == "" {
+		httpErrorWrite(w, "name is required", http.StatusBadRequest)
+		return
+	}
+	cur, err := requestCurrency(r)
+	if err != nil {
+		httpErrorWrite(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	pocket, err := h.pocketRepo.CreatePocket(ctx, models.ParamCreatePocket{
+		CustomerXId: custXId,
+		Currency:    cur.Code,
+		Name:        name,
+	})
+	if err != nil {
+		log.WithContext(ctx).Errorf("[Handler CreatePocket] error when create pocket, error: %v", err)
+		httpErrorWrite(w, err.Error(), pocketErrorCode(err))
+		return
+	}
+
+	httpResponseWrite(w, response.ResponseAPI{
+		Status: "success",
+		Data:   newResponsePocket(pocket),
+	}, http.StatusCreated)
+}
+
+func (h *handlerPocket) ListPockets(w http.ResponseWriter, r *http.Request) {
+	ctx := activity.NewRequestContext(r.Context(), "Handler.ListPockets")
+	cus := r.Context().Value(middleware.Customer).(jwt.MapClaims)
+	custXId := cus["customer_xid"].(string)
+
+	pockets, err := h.pocketRepo.GetPockets(ctx, custXId)
+	if err != nil {
+		log.WithContext(ctx).Errorf("[Handler ListPockets] error when query pockets, error: %v", err)
+		httpErrorWrite(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	res := make([]ResponsePocket, 0, len(pockets))
+	for i := range pockets {
+		res = append(res, newResponsePocket(&pockets[i]))
+	}
+	httpResponseWrite(w, response.ResponseAPI{
+		Status: "success",
+		Data:   res,
+	}, http.StatusOK)
+}
+
+func (h *handlerPocket) RenamePocket(w http.ResponseWriter, r *http.Request) {
+	ctx := activity.NewRequestContext(r.Context(), "Handler.RenamePocket")
+	cus := r.Context().Value(middleware.Customer).(jwt.MapClaims)
+	custXId := cus["customer_xid"].(string)
+
+	name := r.FormValue("name")
+	if name == "" {
+		httpErrorWrite(w, "name is required", http.StatusBadRequest)
+		return
+	}
+
+	pocket, err := h.pocketRepo.RenamePocket(ctx, custXId, mux.Vars(r)["pocket_id"], name)
+	if err != nil {
+		log.WithContext(ctx).Errorf("[Handler RenamePocket] error when rename pocket, error: %v", err)
+		httpErrorWrite(w, err.Error(), pocketErrorCode(err))
+		return
+	}
+
+	httpResponseWrite(w, response.ResponseAPI{
+		Status: "success",
+		Data:   newResponsePocket(pocket),
+	}, http.StatusOK)
+}
+
+// ClosePocket closes a pocket, its remaining balance is moved back to the main wallet
+func (h *handlerPocket) ClosePocket(w http.ResponseWriter, r *http.Request) {
+	ctx := activity.NewRequestContext(r.Context(), "Handler.ClosePocket")
+	cus := r.Context().Value(middleware.Customer).(jwt.MapClaims)
+	custXId := cus["customer_xid"].(string)
+
+	pocket, err := h.pocketRepo.ClosePocket(ctx, custXId, mux.Vars(r)["pocket_id"])
+	if err != nil {
+		log.WithContext(ctx).Errorf("[Handler ClosePocket] error when close pocket, error: %v", err)
+		httpErrorWrite(w, err.Error(), pocketErrorCode(err))
+		return
+	}
+
+	httpResponseWrite(w, response.ResponseAPI{
+		Status: "success",
+		Data:   newResponsePocket(pocket),
+	}, http.StatusOK)
+}
+
+// TransferPocket moves money between own pockets, an empty pocket id refers to the main wallet
+func (h *handlerPocket) TransferPocket(w http.ResponseWriter, r *http.Request) {
+	ctx := activity.NewRequestContext(r.Context(), "Handler.TransferPocket")
+	cus := r.Context().Value(middleware.Customer).(jwt.MapClaims)
+	custXId := cus["customer_xid"].(string)
+
+	cur, err := requestCurrency(r)
+	if err != nil {
+		httpErrorWrite(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	amount, err := strconv.ParseFloat(r.FormValue("amount"), 64)
+	if err != nil {
+		httpErrorWrite(w, "amount must be a number", http.StatusBadRequest)
+		return
+	}
+	if err := cur.Validate(amount); err != nil {
+		httpErrorWrite(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	referenceId := r.FormValue("reference_id")
+	if referenceId == "" {
+		referenceId = uuid.New().String()
+	}
+
+	res, err := h.transferService.Transfer(ctx, service.ParamTransfer{
+		CustomerXId:  custXId,
+		Currency:     cur.Code,
+		FromPocketID: r.FormValue("from_pocket_id"),
+		ToPocketID:   r.FormValue("to_pocket_id"),
+		Amount:       amount,
+		ReferenceID:  referenceId,
+	})
+	if err != nil {
+		log.WithContext(ctx).Errorf("[Handler TransferPocket] error when transfer between pockets, error: %v", err)
+		httpErrorWrite(w, err.Error(), pocketErrorCode(err))
+		return
+	}
+
+	httpResponseWrite(w, response.ResponseAPI{
+		Status: "success",
+		Data: ResponsePocketTransfer{
+			ID:            res.ID,
+			TransferredBy: res.Wallet.OwnedBy,
+			Status:        res.Status,
+			TransferredAt: res.CreatedAt.String(),
+			Amount:        res.Amount,
+			Currency:      res.Currency,
+			ReferenceId:   res.ReferenceID,
+		},
+	}, http.StatusOK)
+}
+
+func newResponsePocket(pocket *entity.Wallet) ResponsePocket {
+	status := "open"
+	if !pocket.ClosedAt.IsZero() {
+		status = "closed"
+	}
+	return ResponsePocket{
+		ID:       pocket.ID,
+		WalletID: pocket.ParentID,
+		Name:     pocket.Name,
+		Status:   status,
+		Balance:  pocket.Balance,
+		Currency: pocket.Currency,
+	}
+}
+
+func pocketErrorCode(err error) int {
+	switch {
+	case errors.Is(err, models.ErrPocketNotFound), errors.Is(err, models.ErrWalletNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, service.ErrTransferNotAllowed):
+		return http.StatusForbidden
+	case service.IsClientError(err):
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
@@ -0,0 +1,39 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+// pagination reads limit and offset, limit defaults to 50 and is capped at 500
+func pagination(r *http.Request) (int, int, error) {
+	limit, offset := 50, 0
+	var err error
+	if v := r.FormValue("limit"); v != "" {
+		limit, err = strconv.Atoi(v)
+		if err != nil || limit <= 0 || limit > 500 {
+			return 0, 0, errors.New("limit must be between 1 and 500")
+		}
+	}
+	if v := r.FormValue("offset"); v != "" {
+		offset, err = strconv.Atoi(v)
+		if err != nil || offset < 0 {
+			return 0, 0, errors.New("offset must be a non negative number")
+		}
+	}
+	return limit, offset, nil
+}
+
+// uuidVar reads a uuid path variable, an invalid one is answered with not found
+func uuidVar(w http.ResponseWriter, r *http.Request, name string) (string, bool) {
+	id := mux.Vars(r)[name]
+	if _, err := uuid.Parse(id); err != nil {
+		httpErrorWrite(w, name+" not found", http.StatusNotFound)
+		return "", false
+	}
+	return id, true
+}
@@ -0,0 +1,254 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/ahmadmirdas/julo-test/repository/database/models"
+	"github.com/ahmadmirdas/julo-test/repository/database/models/entity"
+	"github.com/ahmadmirdas/julo-test/server/middleware"
+	"github.com/ahmadmirdas/julo-test/utils/activity"
+	"github.com/ahmadmirdas/julo-test/utils/log"
+	"github.com/ahmadmirdas/julo-test/utils/response"
+	"github.com/ahmadmirdas/julo-test/utils/schedule"
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/gorilla/mux"
+)
+
+type ResponseSchedule struct {
+	ID           string  `json:"id"`
+	Status       string  `json:"status"`
+	Currency     string  `json:"currency"`
+	FromPocketID string  `json:"from_pocket_id"`
+	ToPocketID   string  `json:"to_pocket_id"`
+	Amount       float64 `json:"amount"`
+	Cron         string  `json:"cron,omitempty"`
+	NextRunAt    string  `json:"next_run_at"`
+	LastRunAt    string  `json:"last_run_at,omitempty"`
+	LastStatus   string  `json:"last_status,omitempty"`
+	LastError    string  `json:"last_error,omitempty"`
+}
+
+type handlerSchedule struct {
+	scheduleRepo models.ScheduleDBRepo
+	pocketRepo   models.PocketDBRepo
+}
+
+type HandlerSchedule interface {
+	CreateSchedule(w http.ResponseWriter, r *http.Request)
+	ListSchedules(w http.ResponseWriter, r *http.Request)
+	UpdateScheduleStatus(w http.ResponseWriter, r *http.Request)
+	CancelSchedule(w http.ResponseWriter, r *http.Request)
+}
+
+func NewHandlerSchedule(scheduleRepo models.ScheduleDBRepo, pocketRepo models.PocketDBRepo) HandlerSchedule {
+	return &handlerSchedule{
+		scheduleRepo: scheduleRepo,
+		pocketRepo:   pocketRepo,
+	}
+}
+
+// CreateSchedule schedules a transfer between own pockets, either once at run_at (RFC 3339)
+// or recurring on a cron expression. An empty pocket id refers to the main wallet.
+func (h *handlerSchedule) CreateSchedule(w http.ResponseWriter, r *http.Request) {
+	ctx := activity.NewRequestContext(r.Context(), "Handler.CreateSchedule")
+	cus := r.Context().Value(middleware.Customer).(jwt.MapClaims)
+	custXId := cus["customer_xid"].(string)
+
+	cur, err := requestCurrency(r)
+	if err != nil {
+		httpErrorWrite(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	amount, err := strconv.ParseFloat(r.FormValue("amount"), 64)
+	if err != nil || amount <= 0 || cur.Validate(amount) != nil {
+		httpErrorWrite(w, "amount must be a positive amount in "+cur.Code, http.StatusBadRequest)
+		return
+	}
+
+	fromPocketID := r.FormValue("from_pocket_id")
+	toPocketID := r.FormValue("to_pocket_id")
+	if fromPocketID == toPocketID {
+		httpErrorWrite(w, models.ErrPocketSameWallet.Error(), http.StatusBadRequest)
+		return
+	}
+	for _, pocketID := range []string{fromPocketID, toPocketID} {
+		if pocketID == "" {
+			continue
+		}
+		pocket, err := h.pocketRepo.GetPocket(ctx, custXId, pocketID)
+		if err != nil {
+			log.WithContext(ctx).Errorf("[Handler CreateSchedule] error when query pocket, error: %v", err)
+			httpErrorWrite(w, err.Error(), pocketErrorCode(err))
+			return
+		}
+		if pocket.Currency != cur.Code {
+			httpErrorWrite(w, models.ErrPocketCurrencyMatch.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	cronExpr := r.FormValue("cron")
+	runAt := r.FormValue("run_at")
+	var nextRunAt time.Time
+	switch {
+	case cronExpr != "" && runAt != "":
+		httpErrorWrite(w, "only one of run_at or cron can be set", http.StatusBadRequest)
+		return
+	case cronExpr != "":
+		nextRunAt, err = schedule.Next(cronExpr, time.Now())
+		if err != nil {
+			httpErrorWrite(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	case runAt != "":
+		nextRunAt, err = time.Parse(time.RFC3339, runAt)
+		if err != nil || !nextRunAt.After(time.Now()) {
+			httpErrorWrite(w, "run_at must be a future RFC 3339 timestamp", http.StatusBadRequest)
+			return
+		}
+	default:
+		httpErrorWrite(w, "run_at or cron is required", http.StatusBadRequest)
+		return
+	}
+
+	res, err := h.scheduleRepo.CreateSchedule(ctx, models.ParamCreateSchedule{
+		CustomerXId:  custXId,
+		Currency:     cur.Code,
+		FromPocketID: fromPocketID,
+		ToPocketID:   toPocketID,
+		Amount:       amount,
+		CronExpr:     cronExpr,
+		RunAt:        nextRunAt,
+	})
+	if err != nil {
+		log.WithContext(ctx).Errorf("[Handler CreateSchedule] error when create schedule, error: %v", err)
+		httpErrorWrite(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	httpResponseWrite(w, response.ResponseAPI{
+		Status: "success",
+		Data:   newResponseSchedule(res),
+	}, http.StatusCreated)
+}
+
+func (h *handlerSchedule) ListSchedules(w http.ResponseWriter, r *http.Request) {
+	ctx := activity.NewRequestContext(r.Context(), "Handler.ListSchedules")
+	cus := r.Context().Value(middleware.Customer).(jwt.MapClaims)
+	custXId := cus["customer_xid"].(string)
+
+	schedules, err := h.scheduleRepo.GetSchedules(ctx, custXId)
+	if err != nil {
+		log.WithContext(ctx).Errorf("[Handler ListSchedules] error when query schedules, error: %v", err)
+		httpErrorWrite(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	res := make([]ResponseSchedule, 0, len(schedules))
+	for i := range schedules {
+		res = append(res, newResponseSchedule(&schedules[i]))
+	}
+	httpResponseWrite(w, response.ResponseAPI{
+		Status: "success",
+		Data:   res,
+	}, http.StatusOK)
+}
+
+// UpdateScheduleStatus pauses or resumes a schedule, a resumed recurring schedule skips the
+// occurrences missed while it was paused
+func (h *handlerSchedule) UpdateScheduleStatus(w http.ResponseWriter, r *http.Request) {
+	ctx := activity.NewRequestContext(r.Context(), "Handler.UpdateScheduleStatus")
+	cus := r.Context().Value(middleware.Customer).(jwt.MapClaims)
+	custXId := cus["customer_xid"].(string)
+	scheduleID := mux.Vars(r)["schedule_id"]
+
+	status := r.FormValue("status")
+	if status != models.ScheduleStatusActive && status != models.ScheduleStatusPaused {
+		httpErrorWrite(w, "status must be active or paused", http.StatusBadRequest)
+		return
+	}
+
+	param := models.ParamUpdateScheduleStatus{
+		CustomerXId: custXId,
+		ScheduleID:  scheduleID,
+		Status:      status,
+	}
+	if status == models.ScheduleStatusActive {
+		current, err := h.scheduleRepo.GetSchedule(ctx, custXId, scheduleID)
+		if err != nil {
+			log.WithContext(ctx).Errorf("[Handler UpdateScheduleStatus] error when query schedule, error: %v", err)
+			httpErrorWrite(w, err.Error(), scheduleErrorCode(err))
+			return
+		}
+		if current.CronExpr != "" && current.NextRunAt.Before(time.Now()) {
+			param.NextRunAt, err = schedule.Next(current.CronExpr, time.Now())
+			if err != nil {
+				httpErrorWrite(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+		}
+	}
+
+	res, err := h.scheduleRepo.UpdateScheduleStatus(ctx, param)
+	if err != nil {
+		log.WithContext(ctx).Errorf("[Handler UpdateScheduleStatus] error when update schedule, error: %v", err)
+		httpErrorWrite(w, err.Error(), scheduleErrorCode(err))
+		return
+	}
+
+	httpResponseWrite(w, response.ResponseAPI{
+		Status: "success",
+		Data:   newResponseSchedule(res),
+	}, http.StatusOK)
+}
+
+func (h *handlerSchedule) CancelSchedule(w http.ResponseWriter, r *http.Request) {
+	ctx := activity.NewRequestContext(r.Context(), "Handler.CancelSchedule")
+	cus := r.Context().Value(middleware.Customer).(jwt.MapClaims)
+	custXId := cus["customer_xid"].(string)
+
+	res, err := h.scheduleRepo.CancelSchedule(ctx, custXId, mux.Vars(r)["schedule_id"])
+	if err != nil {
+		log.WithContext(ctx).Errorf("[Handler CancelSchedule] error when cancel schedule, error: %v", err)
+		httpErrorWrite(w, err.Error(), scheduleErrorCode(err))
+		return
+	}
+
+	httpResponseWrite(w, response.ResponseAPI{
+		Status: "success",
+		Data:   newResponseSchedule(res),
+	}, http.StatusOK)
+}
+
+func newResponseSchedule(s *entity.TransferSchedule) ResponseSchedule {
+	res := ResponseSchedule{
+		ID:           s.ID,
+		Status:       s.Status,
+		Currency:     s.Currency,
+		FromPocketID: s.FromPocketID,
+		ToPocketID:   s.ToPocketID,
+		Amount:       s.Amount,
+		Cron:         s.CronExpr,
+		NextRunAt:    s.NextRunAt.Format(time.RFC3339),
+		LastStatus:   s.LastStatus,
+		LastError:    s.LastError,
+	}
+	if !s.LastRunAt.IsZero() {
+		res.LastRunAt = s.LastRunAt.Format(time.RFC3339)
+	}
+
+	return res
+}
+
+func scheduleErrorCode(err error) int {
+	switch {
+	case errors.Is(err, models.ErrScheduleNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, models.ErrScheduleFinished):
+		return http.StatusConflict
+	}
+	return http.StatusInternalServerError
+}
@@ -0,0 +1,196 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/ahmadmirdas/julo-test/config"
+	"github.com/ahmadmirdas/julo-test/repository/database/models"
+	"github.com/ahmadmirdas/julo-test/repository/database/models/entity"
+	"github.com/ahmadmirdas/julo-test/server/middleware"
+	"github.com/ahmadmirdas/julo-test/service"
+	"github.com/ahmadmirdas/julo-test/utils/activity"
+	"github.com/ahmadmirdas/julo-test/utils/log"
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// EventSnapshot is the first event of a stream opened without Last-Event-ID
+const EventSnapshot string = "snapshot"
+
+type handlerStream struct {
+	walletRepo models.WalletDBRepo
+	pocketRepo models.PocketDBRepo
+	outboxRepo models.OutboxDBRepo
+	hub        *service.EventHub
+}
+
+type HandlerStream interface {
+	StreamWallet(w http.ResponseWriter, r *http.Request)
+}
+
+func NewHandlerStream(walletRepo models.WalletDBRepo, pocketRepo models.PocketDBRepo, outboxRepo models.OutboxDBRepo, hub *service.EventHub) HandlerStream {
+	return &handlerStream{
+		walletRepo: walletRepo,
+		pocketRepo: pocketRepo,
+		outboxRepo: outboxRepo,
+		hub:        hub,
+	}
+}
+
+// StreamWallet pushes the customer wallet events as server-sent events. The event id is the outbox
+// sequence, a client reconnecting with Last-Event-ID (header or last_event_id query) gets every
+// event written after it, otherwise the stream starts with a snapshot of the wallets and pockets.
+func (h *handlerStream) StreamWallet(w http.ResponseWriter, r *http.Request) {
+	ctx := activity.NewRequestContext(r.Context(), "Handler.StreamWallet")
+	cfg := config.Config.StreamCfg
+	cus := r.Context().Value(middleware.Customer).(jwt.MapClaims)
+	custXId := cus["customer_xid"].(string)
+
+	lastEventID := r.Header.Get("Last-Event-ID")
+	if lastEventID == "" {
+		lastEventID = r.URL.Query().Get("last_event_id")
+	}
+	var lastSeq int64
+	if lastEventID != "" {
+		seq, err := strconv.ParseInt(lastEventID, 10, 64)
+		if err != nil || seq < 0 {
+			httpErrorWrite(w, "last event id must be an event sequence", http.StatusBadRequest)
+			return
+		}
+		lastSeq = seq
+	}
+
+	rc := http.NewResponseController(w)
+	// the stream outlives the server write timeout
+	if err := rc.SetWriteDeadline(time.Time{}); err != nil {
+		log.WithContext(ctx).Errorf("[Handler StreamWallet] streaming is not supported, error: %v", err)
+		httpErrorWrite(w, "streaming is not supported", http.StatusInternalServerError)
+		return
+	}
+
+	// subscribe before reading so no event written in between is missed
+	wake, unsubscribe := h.hub.Subscribe(custXId)
+	defer unsubscribe()
+
+	var snapshot *StreamSnapshot
+	if lastEventID == "" {
+		seq, err := h.outboxRepo.GetLatestSeq(ctx, custXId)
+		if err != nil {
+			log.WithContext(ctx).Errorf("[Handler StreamWallet] error when query latest event, error: %v", err)
+			httpErrorWrite(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		wallets, err := h.walletRepo.GetWallets(ctx, custXId)
+		if err != nil {
+			log.WithContext(ctx).Errorf("[Handler StreamWallet] error when query wallets, error: %v", err)
+			httpErrorWrite(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		pockets, err := h.pocketRepo.GetPockets(ctx, custXId)
+		if err != nil {
+			log.WithContext(ctx).Errorf("[Handler StreamWallet] error when query pockets, error: %v", err)
+			httpErrorWrite(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		lastSeq = seq
+		snapshot = newStreamSnapshot(wallets, pockets)
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	if snapshot != nil {
+		if err := writeEvent(w, strconv.FormatInt(lastSeq, 10), EventSnapshot, snapshot); err != nil {
+			return
+		}
+	}
+	if err := rc.Flush(); err != nil {
+		return
+	}
+
+	interval := time.Duration(cfg.Heartbeat) * time.Second
+	if interval <= 0 {
+		interval = 15 * time.Second
+	}
+	heartbeat := time.NewTicker(interval)
+	defer heartbeat.Stop()
+	for {
+		// catch up on every tick as well, in case a notification was lost on a reconnect
+		seq, err := h.sendEvents(ctx, w, custXId, lastSeq, cfg.BatchSize)
+		if err != nil {
+			log.WithContext(ctx).Warnf("[Handler StreamWallet] stream of %s closed, error: %v", custXId, err)
+			return
+		}
+		lastSeq = seq
+		if err := rc.Flush(); err != nil {
+			return
+		}
+
+		select {
+		case <-r.Context().Done():
+			return
+		case <-h.hub.Done():
+			return
+		case <-wake:
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(w, ": heartbeat\n\n"); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// sendEvents writes every event after lastSeq and returns the sequence of the last one written
+func (h *handlerStream) sendEvents(ctx context.Context, w http.ResponseWriter, custXId string, lastSeq int64, batchSize int) (int64, error) {
+	for {
+		events, err := h.outboxRepo.GetCustomerEvents(ctx, custXId, lastSeq, batchSize)
+		if err != nil {
+			return lastSeq, err
+		}
+		for i := range events {
+			if err := writeEvent(w, strconv.FormatInt(events[i].Seq, 10), events[i].Type, models.ToEvent(&events[i])); err != nil {
+				return lastSeq, err
+			}
+			lastSeq = events[i].Seq
+		}
+		if batchSize <= 0 || len(events) < batchSize {
+			return lastSeq, nil
+		}
+	}
+}
+
+// StreamSnapshot is the data of the snapshot event, wallets and pockets as the REST api returns them
+type StreamSnapshot struct {
+	Wallets []ResponseWallet `json:"wallets"`
+	Pockets []ResponsePocket `json:"pockets"`
+}
+
+func newStreamSnapshot(wallets, pockets []entity.Wallet) *StreamSnapshot {
+	snapshot := &StreamSnapshot{
+		Wallets: make([]ResponseWallet, 0, len(wallets)),
+		Pockets: make([]ResponsePocket, 0, len(pockets)),
+	}
+	for i := range wallets {
+		snapshot.Wallets = append(snapshot.Wallets, newResponseWallet(&wallets[i]))
+	}
+	for i := range pockets {
+		snapshot.Pockets = append(snapshot.Pockets, newResponsePocket(&pockets[i]))
+	}
+	return snapshot
+}
+
+func writeEvent(w http.ResponseWriter, id, event string, data interface{}) error {
+	body, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "id: %s\nevent: %s\ndata: %s\n\n", id, event, body)
+	return err
+}
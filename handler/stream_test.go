@@ -0,0 +1,101 @@
+package handler
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ahmadmirdas/julo-test/repository/database/models"
+	"github.com/ahmadmirdas/julo-test/repository/database/models/entity"
+	"github.com/ahmadmirdas/julo-test/server/middleware"
+	"github.com/ahmadmirdas/julo-test/service"
+	"github.com/golang-jwt/jwt/v4"
+)
+
+type fakePocketRepo struct {
+	models.PocketDBRepo
+	pockets []entity.Wallet
+}
+
+func (f *fakePocketRepo) GetPockets(ctx context.Context, customerXId string) ([]entity.Wallet, error) {
+	return f.pockets, nil
+}
+
+// fakeOutboxRepo has no event after the latest sequence
+type fakeOutboxRepo struct {
+	models.OutboxDBRepo
+	seq int64
+}
+
+func (f *fakeOutboxRepo) GetLatestSeq(ctx context.Context, customerXId string) (int64, error) {
+	return f.seq, nil
+}
+
+func (f *fakeOutboxRepo) GetCustomerEvents(ctx context.Context, customerXId string, afterSeq int64, limit int) ([]entity.OutboxEvent, error) {
+	return nil, nil
+}
+
+func TestStreamWalletSnapshot(t *testing.T) {
+	walletRepo := &fakeWalletRepo{wallet: entity.Wallet{
+		ID:        contractWallet,
+		OwnedBy:   contractCustomer,
+		IsEnabled: true,
+		EnabledAt: time.Now(),
+		Balance:   100000,
+		Currency:  "IDR",
+	}}
+	pocketRepo := &fakePocketRepo{pockets: []entity.Wallet{{
+		ID:       "5b0a6d6e-4c1f-4d57-9d7e-3f0b2a61c8a9",
+		ParentID: contractWallet,
+		OwnedBy:  contractCustomer,
+		Name:     "holiday",
+		Balance:  25000,
+		Currency: "IDR",
+	}}}
+	hub := service.NewEventHub(nil)
+	h := NewHandlerStream(walletRepo, pocketRepo, &fakeOutboxRepo{seq: 42}, hub)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h.StreamWallet(w, r.WithContext(context.WithValue(r.Context(), middleware.Customer, jwt.MapClaims{"customer_xid": contractCustomer})))
+	}))
+	t.Cleanup(srv.Close)
+	t.Cleanup(hub.Close)
+
+	res, err := http.Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		t.Fatalf("status = %d, want %d", res.StatusCode, http.StatusOK)
+	}
+
+	fields := map[string]string{}
+	scanner := bufio.NewScanner(res.Body)
+	for scanner.Scan() && scanner.Text() != "" {
+		name, value, _ := strings.Cut(scanner.Text(), ": ")
+		fields[name] = value
+	}
+	if fields["id"] != "42" || fields["event"] != EventSnapshot {
+		t.Fatalf("first event = %s %s, want 42 %s", fields["id"], fields["event"], EventSnapshot)
+	}
+
+	var snapshot StreamSnapshot
+	if err := json.Unmarshal([]byte(fields["data"]), &snapshot); err != nil {
+		t.Fatalf("decode snapshot: %v", err)
+	}
+	if len(snapshot.Wallets) != 1 || len(snapshot.Pockets) != 1 {
+		t.Fatalf("snapshot has %d wallets and %d pockets, want 1 and 1", len(snapshot.Wallets), len(snapshot.Pockets))
+	}
+	if wallet := snapshot.Wallets[0]; wallet.ID != contractWallet || wallet.Balance != 100000 || wallet.Currency != "IDR" || wallet.Status != "enabled" {
+		t.Errorf("wallet = %+v, want %s holding 100000 IDR", wallet, contractWallet)
+	}
+	if pocket := snapshot.Pockets[0]; pocket.WalletID != contractWallet || pocket.Name != "holiday" || pocket.Balance != 25000 || pocket.Currency != "IDR" {
+		t.Errorf("pocket = %+v, want holiday holding 25000 IDR", pocket)
+	}
+}
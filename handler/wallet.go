@@ -1,11 +1,14 @@
 package handler
 
+import "github.com/ahmadmirdas/julo-test/repository/database/models/entity"
+
 type ResponseWallet struct {
 	ID        string  `json:"id"`
 	OwnedBy   string  `json:"owned_by"`
 	Status    string  `json:"status"`
 	EnabledAt string  `json:"enabled_at"`
 	Balance   float64 `json:"balance"`
+	Currency  string  `json:"currency"`
 }
 
 type ResponseDepositWallet struct {
@@ -14,14 +17,33 @@ type ResponseDepositWallet struct {
 	Status      string  `json:"status"`
 	DepositAt   string  `json:"deposited_at"`
 	Amount      float64 `json:"amount"`
+	Currency    string  `json:"currency"`
 	ReferenceId string  `json:"reference_id"`
 }
 
 type ResponseWithdrawWallet struct {
-	ID          string  `json:"id"`
-	WithdrawnBy string  `json:"withdrawn_by"`
-	Status      string  `json:"status"`
-	WithdrawnAt string  `json:"withdrawn_at"`
-	Amount      float64 `json:"amount"`
-	ReferenceId string  `json:"reference_id"`
+	ID           string  `json:"id"`
+	WithdrawnBy  string  `json:"withdrawn_by"`
+	Status       string  `json:"status"`
+	WithdrawnAt  string  `json:"withdrawn_at"`
+	Amount       float64 `json:"amount"`
+	Currency     string  `json:"currency"`
+	ReferenceId  string  `json:"reference_id"`
+	Fee          float64 `json:"fee"`
+	TotalDebited float64 `json:"total_debited"`
+}
+
+func newResponseWallet(wallet *entity.Wallet) ResponseWallet {
+	status := "enabled"
+	if !wallet.IsEnabled {
+		status = "disabled"
+	}
+	return ResponseWallet{
+		ID:        wallet.ID,
+		OwnedBy:   wallet.OwnedBy,
+		Status:    status,
+		EnabledAt: wallet.EnabledAt.String(),
+		Balance:   wallet.Balance,
+		Currency:  wallet.Currency,
+	}
 }
@@ -0,0 +1,289 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/ahmadmirdas/julo-test/repository/database/models"
+	"github.com/ahmadmirdas/julo-test/repository/database/models/entity"
+	"github.com/ahmadmirdas/julo-test/utils/activity"
+	"github.com/ahmadmirdas/julo-test/utils/log"
+	"github.com/ahmadmirdas/julo-test/utils/response"
+	"github.com/ahmadmirdas/julo-test/utils/webhook"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+// ResponseWebhookEndpoint carries the signing secret only in the response creating the endpoint
+type ResponseWebhookEndpoint struct {
+	*entity.WebhookEndpoint
+	Secret string `json:"secret,omitempty"`
+}
+
+type handlerWebhook struct {
+	webhookRepo models.WebhookDBRepo
+	jobType     string
+	maxAttempts int
+}
+
+type HandlerWebhook interface {
+	CreateWebhook(w http.ResponseWriter, r *http.Request)
+	ListWebhooks(w http.ResponseWriter, r *http.Request)
+	DisableWebhook(w http.ResponseWriter, r *http.Request)
+	ListDeliveries(w http.ResponseWriter, r *http.Request)
+	ViewDelivery(w http.ResponseWriter, r *http.Request)
+	ReplayDelivery(w http.ResponseWriter, r *http.Request)
+	LinkCustomer(w http.ResponseWriter, r *http.Request)
+	UnlinkCustomer(w http.ResponseWriter, r *http.Request)
+}
+
+// NewHandlerWebhook needs the job type and attempts used to deliver webhooks so replays are queued like new deliveries
+func NewHandlerWebhook(webhookRepo models.WebhookDBRepo, jobType string, maxAttempts int) HandlerWebhook {
+	return &handlerWebhook{
+		webhookRepo: webhookRepo,
+		jobType:     jobType,
+		maxAttempts: maxAttempts,
+	}
+}
+
+// CreateWebhook registers a partner endpoint, event_types is a comma separated list of event
+// types or namespaces such as wallet.*, empty subscribes to every event. An endpoint only receives
+// the events of customers linked to its partner, customer_xid narrows it to one of them.
+func (h *handlerWebhook) CreateWebhook(w http.ResponseWriter, r *http.Request) {
+	actorID, _ := activity.GetActorID(r.Context())
+	ctx := activity.WithActorID(activity.NewRequestContext(r.Context(), "Handler.CreateWebhook"), actorID)
+
+	partnerID := r.FormValue("partner_id")
+	if partnerID == "" {
+		httpErrorWrite(w, "partner_id is required", http.StatusBadRequest)
+		return
+	}
+	endpointURL, err := url.Parse(r.FormValue("url"))
+	if err != nil || (endpointURL.Scheme != "http" && endpointURL.Scheme != "https") || endpointURL.Host == "" {
+		httpErrorWrite(w, "url must be an absolute http or https url", http.StatusBadRequest)
+		return
+	}
+	customerXId := r.FormValue("customer_xid")
+	if customerXId != "" {
+		if _, err := uuid.Parse(customerXId); err != nil {
+			httpErrorWrite(w, "customer_xid must be a uuid", http.StatusBadRequest)
+			return
+		}
+	}
+	var eventTypes []string
+	for _, eventType := range strings.Split(r.FormValue("event_types"), ",") {
+		if eventType = strings.TrimSpace(eventType); eventType != "" {
+			eventTypes = append(eventTypes, eventType)
+		}
+	}
+	secret, err := webhook.NewSecret()
+	if err != nil {
+		log.WithContext(ctx).Errorf("[Handler CreateWebhook] error when generate secret, error: %v", err)
+		httpErrorWrite(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	endpoint, err := h.webhookRepo.CreateEndpoint(ctx, models.ParamCreateWebhook{
+		PartnerID:   partnerID,
+		URL:         endpointURL.String(),
+		Secret:      secret,
+		EventTypes:  eventTypes,
+		CustomerXId: customerXId,
+		CreatedBy:   actorID,
+	})
+	if err != nil {
+		log.WithContext(ctx).Errorf("[Handler CreateWebhook] error when create webhook, error: %v", err)
+		httpErrorWrite(w, err.Error(), webhookErrorCode(err))
+		return
+	}
+
+	httpResponseWrite(w, response.ResponseAPI{
+		Status: "success",
+		Data:   ResponseWebhookEndpoint{WebhookEndpoint: endpoint, Secret: endpoint.Secret},
+	}, http.StatusCreated)
+}
+
+func (h *handlerWebhook) ListWebhooks(w http.ResponseWriter, r *http.Request) {
+	ctx := activity.NewRequestContext(r.Context(), "Handler.ListWebhooks")
+
+	endpoints, err := h.webhookRepo.GetEndpoints(ctx, r.FormValue("partner_id"))
+	if err != nil {
+		log.WithContext(ctx).Errorf("[Handler ListWebhooks] error when query webhooks, error: %v", err)
+		httpErrorWrite(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	httpResponseWrite(w, response.ResponseAPI{
+		Status: "success",
+		Data:   endpoints,
+	}, http.StatusOK)
+}
+
+func (h *handlerWebhook) DisableWebhook(w http.ResponseWriter, r *http.Request) {
+	actorID, _ := activity.GetActorID(r.Context())
+	ctx := activity.WithActorID(activity.NewRequestContext(r.Context(), "Handler.DisableWebhook"), actorID)
+
+	endpointID, ok := uuidVar(w, r, "webhook_id")
+	if !ok {
+		return
+	}
+	endpoint, err := h.webhookRepo.DisableEndpoint(ctx, endpointID)
+	if err != nil {
+		log.WithContext(ctx).Errorf("[Handler DisableWebhook] error when disable webhook, error: %v", err)
+		httpErrorWrite(w, err.Error(), webhookErrorCode(err))
+		return
+	}
+
+	httpResponseWrite(w, response.ResponseAPI{
+		Status: "success",
+		Data:   endpoint,
+	}, http.StatusOK)
+}
+
+// ListDeliveries pages through the deliveries of an endpoint, newest first, optionally by status
+func (h *handlerWebhook) ListDeliveries(w http.ResponseWriter, r *http.Request) {
+	ctx := activity.NewRequestContext(r.Context(), "Handler.ListDeliveries")
+	endpointID, ok := uuidVar(w, r, "webhook_id")
+	if !ok {
+		return
+	}
+
+	_, err := h.webhookRepo.GetEndpoint(ctx, endpointID)
+	if err != nil {
+		httpErrorWrite(w, err.Error(), webhookErrorCode(err))
+		return
+	}
+	limit, offset, err := pagination(r)
+	if err != nil {
+		httpErrorWrite(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	deliveries, err := h.webhookRepo.GetDeliveries(ctx, models.ParamListDeliveries{
+		EndpointID: endpointID,
+		Status:     r.FormValue("status"),
+		Limit:      limit,
+		Offset:     offset,
+	})
+	if err != nil {
+		log.WithContext(ctx).Errorf("[Handler ListDeliveries] error when query deliveries, error: %v", err)
+		httpErrorWrite(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	httpResponseWrite(w, response.ResponseAPI{
+		Status: "success",
+		Data:   deliveries,
+	}, http.StatusOK)
+}
+
+// ViewDelivery returns a delivery with every attempt and its response code
+func (h *handlerWebhook) ViewDelivery(w http.ResponseWriter, r *http.Request) {
+	ctx := activity.NewRequestContext(r.Context(), "Handler.ViewDelivery")
+
+	deliveryID, ok := uuidVar(w, r, "delivery_id")
+	if !ok {
+		return
+	}
+	delivery, err := h.webhookRepo.GetDelivery(ctx, deliveryID)
+	if err != nil {
+		log.WithContext(ctx).Errorf("[Handler ViewDelivery] error when query delivery, error: %v", err)
+		httpErrorWrite(w, err.Error(), webhookErrorCode(err))
+		return
+	}
+
+	httpResponseWrite(w, response.ResponseAPI{
+		Status: "success",
+		Data:   delivery,
+	}, http.StatusOK)
+}
+
+// ReplayDelivery queues a dead delivery again
+func (h *handlerWebhook) ReplayDelivery(w http.ResponseWriter, r *http.Request) {
+	actorID, _ := activity.GetActorID(r.Context())
+	ctx := activity.WithActorID(activity.NewRequestContext(r.Context(), "Handler.ReplayDelivery"), actorID)
+
+	deliveryID, ok := uuidVar(w, r, "delivery_id")
+	if !ok {
+		return
+	}
+	delivery, err := h.webhookRepo.ReplayDelivery(ctx, models.ParamReplayDelivery{
+		DeliveryID:  deliveryID,
+		JobType:     h.jobType,
+		MaxAttempts: h.maxAttempts,
+	})
+	if err != nil {
+		log.WithContext(ctx).Errorf("[Handler ReplayDelivery] error when replay delivery, error: %v", err)
+		httpErrorWrite(w, err.Error(), webhookErrorCode(err))
+		return
+	}
+	log.WithContext(ctx).Infof("[Handler ReplayDelivery] delivery %s queued for replay", delivery.ID)
+
+	httpResponseWrite(w, response.ResponseAPI{
+		Status: "success",
+		Data:   delivery,
+	}, http.StatusAccepted)
+}
+
+// LinkCustomer lets the endpoints of a partner receive the events of a customer
+func (h *handlerWebhook) LinkCustomer(w http.ResponseWriter, r *http.Request) {
+	actorID, _ := activity.GetActorID(r.Context())
+	ctx := activity.WithActorID(activity.NewRequestContext(r.Context(), "Handler.LinkCustomer"), actorID)
+
+	customerXId := r.FormValue("customer_xid")
+	if _, err := uuid.Parse(customerXId); err != nil {
+		httpErrorWrite(w, "customer_xid must be a uuid", http.StatusBadRequest)
+		return
+	}
+
+	link, err := h.webhookRepo.LinkCustomer(ctx, models.ParamLinkCustomer{
+		PartnerID:   mux.Vars(r)["partner_id"],
+		CustomerXId: customerXId,
+		CreatedBy:   actorID,
+	})
+	if err != nil {
+		log.WithContext(ctx).Errorf("[Handler LinkCustomer] error when link customer, error: %v", err)
+		httpErrorWrite(w, err.Error(), webhookErrorCode(err))
+		return
+	}
+
+	httpResponseWrite(w, response.ResponseAPI{
+		Status: "success",
+		Data:   link,
+	}, http.StatusOK)
+}
+
+// UnlinkCustomer stops the events of a customer from reaching a partner
+func (h *handlerWebhook) UnlinkCustomer(w http.ResponseWriter, r *http.Request) {
+	actorID, _ := activity.GetActorID(r.Context())
+	ctx := activity.WithActorID(activity.NewRequestContext(r.Context(), "Handler.UnlinkCustomer"), actorID)
+
+	customerXId, ok := uuidVar(w, r, "customer_xid")
+	if !ok {
+		return
+	}
+	err := h.webhookRepo.UnlinkCustomer(ctx, mux.Vars(r)["partner_id"], customerXId)
+	if err != nil {
+		log.WithContext(ctx).Errorf("[Handler UnlinkCustomer] error when unlink customer, error: %v", err)
+		httpErrorWrite(w, err.Error(), webhookErrorCode(err))
+		return
+	}
+
+	httpResponseWrite(w, response.ResponseAPI{
+		Status: "success",
+	}, http.StatusOK)
+}
+
+func webhookErrorCode(err error) int {
+	switch {
+	case errors.Is(err, models.ErrWebhookNotFound), errors.Is(err, models.ErrDeliveryNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, models.ErrDeliveryNotReplayable):
+		return http.StatusConflict
+	case errors.Is(err, models.ErrCustomerNotLinked):
+		return http.StatusUnprocessableEntity
+	}
+	return http.StatusInternalServerError
+}
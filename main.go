@@ -1,9 +1,102 @@
 package main
 
 import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/ahmadmirdas/julo-test/config"
 	"github.com/ahmadmirdas/julo-test/server"
+	"github.com/sirupsen/logrus"
 )
 
+const usage = `Usage:
+  julo-test [-config file] [serve]              run the http and gRPC servers
+  julo-test [-config file] config print [-redacted]
+                                                print the effective configuration
+  julo-test [-config file] config validate      report every invalid configuration value
+  julo-test [-config file] migrate up|down|status|version
+                                                apply, roll back or list the embedded migrations
+  julo-test [-config file] admin [-actor name] <command> [flags]
+                                                support commands, audited, see admin -h
+
+Every configuration key is overridden by its JT_ environment variable, JT_POSTGRES_PASSWORD
+for postgres.password, and JT_<KEY>_FILE reads a value from a file. -config defaults to
+JT_CONFIG, then config/app/application.<JT_ENV>.yml.
+`
+
 func main() {
-	server.RunServer()
+	configPath := flag.String("config", "", "configuration file")
+	flag.Usage = func() { fmt.Fprint(flag.CommandLine.Output(), usage) }
+	flag.Parse()
+
+	if err := run(*configPath, flag.Args()); err != nil {
+		logrus.Errorf("%v", err)
+		os.Exit(1)
+	}
+}
+
+func run(configPath string, args []string) error {
+	if err := config.Load(configPath); err != nil {
+		return err
+	}
+
+	command := "serve"
+	if len(args) > 0 {
+		command, args = args[0], args[1:]
+	}
+	switch command {
+	case "serve":
+		if err := validateConfig(); err != nil {
+			return err
+		}
+		return server.RunServer()
+	case "config":
+		return runConfig(args)
+	case "migrate":
+		// migrations only connect to postgres, the other keys may not be set where they run
+		if err := config.Config.ValidatePostgres(); err != nil {
+			return fmt.Errorf("invalid configuration:\n%w", err)
+		}
+		return runMigrate(args)
+	case "admin":
+		if err := validateConfig(); err != nil {
+			return err
+		}
+		return runAdmin(args)
+	default:
+		flag.Usage()
+		return fmt.Errorf("unknown command %q", command)
+	}
+}
+
+func runConfig(args []string) error {
+	if len(args) == 0 {
+		flag.Usage()
+		return fmt.Errorf("config needs a subcommand")
+	}
+
+	switch args[0] {
+	case "print":
+		fs := flag.NewFlagSet("config print", flag.ExitOnError)
+		redacted := fs.Bool("redacted", false, "hide secret values")
+		fs.Parse(args[1:])
+		return config.Print(os.Stdout, *redacted)
+	case "validate":
+		if err := validateConfig(); err != nil {
+			return err
+		}
+		fmt.Println("configuration is valid")
+		return nil
+	default:
+		flag.Usage()
+		return fmt.Errorf("unknown config subcommand %q", args[0])
+	}
+}
+
+func validateConfig() error {
+	if err := config.Config.Validate(); err != nil {
+		return fmt.Errorf("invalid configuration:\n%w", err)
+	}
+	return nil
 }
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ahmadmirdas/julo-test/config/database"
+	"github.com/ahmadmirdas/julo-test/repository/database/migration"
+)
+
+// runMigrate applies the embedded migrations with goose on the configured database
+func runMigrate(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("migrate needs one of up, down, status or version")
+	}
+
+	migrator, err := migration.NewMigrator(database.DSN(database.ConfigParam()))
+	if err != nil {
+		return err
+	}
+	defer migrator.Close()
+	ctx := context.Background()
+
+	switch args[0] {
+	case "up":
+		return migrator.Up(ctx)
+	case "down":
+		return migrator.Down(ctx)
+	case "status":
+		return migrator.Status(ctx)
+	case "version":
+		version, err := migrator.Version(ctx)
+		if err != nil {
+			return err
+		}
+		latest, err := migration.Latest()
+		if err != nil {
+			return err
+		}
+		fmt.Printf("database version %d, latest migration %d\n", version, latest)
+	default:
+		return fmt.Errorf("unknown migrate subcommand %q", args[0])
+	}
+	return nil
+}
@@ -0,0 +1,9 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE wallet ADD COLUMN segment VARCHAR NOT NULL DEFAULT 'regular';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE wallet DROP COLUMN segment;
+-- +goose StatementEnd
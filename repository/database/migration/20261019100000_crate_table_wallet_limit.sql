@@ -0,0 +1,27 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE wallet ADD COLUMN max_balance FLOAT NULL;
+
+CREATE TABLE wallet_limit
+(
+    wallet_id uuid NOT NULL,
+    type VARCHAR NOT NULL,
+    max_amount FLOAT NULL,
+    daily_amount FLOAT NULL,
+    daily_count INT NULL,
+    monthly_amount FLOAT NULL,
+    monthly_count INT NULL,
+
+    PRIMARY KEY (wallet_id, type),
+    CONSTRAINT fk_wallet_limit_wallet_id FOREIGN KEY (wallet_id) REFERENCES "wallet" (id)
+);
+
+CREATE INDEX idx_history_wallet_id_type_created_at ON history(wallet_id, type, created_at);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX idx_history_wallet_id_type_created_at;
+DROP TABLE wallet_limit;
+ALTER TABLE wallet DROP COLUMN max_balance;
+-- +goose StatementEnd
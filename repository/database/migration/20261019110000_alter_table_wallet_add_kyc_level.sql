@@ -0,0 +1,28 @@
+-- +goose Up
+-- +goose StatementBegin
+-- wallets opened before kyc levels keep the access they had, new wallets start unverified
+ALTER TABLE wallet ADD COLUMN kyc_level VARCHAR NOT NULL DEFAULT 'basic';
+ALTER TABLE wallet ALTER COLUMN kyc_level SET DEFAULT 'unverified';
+
+CREATE TABLE kyc_history
+(
+    id uuid DEFAULT gen_random_uuid (),
+    wallet_id uuid NOT NULL,
+    from_level VARCHAR NOT NULL,
+    to_level VARCHAR NOT NULL,
+    actor VARCHAR NOT NULL,
+    reason VARCHAR NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+
+    PRIMARY KEY (id),
+    CONSTRAINT fk_kyc_history_wallet_id FOREIGN KEY (wallet_id) REFERENCES "wallet" (id)
+);
+
+CREATE INDEX idx_kyc_history_wallet_id ON kyc_history(wallet_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE kyc_history;
+ALTER TABLE wallet DROP COLUMN kyc_level;
+-- +goose StatementEnd
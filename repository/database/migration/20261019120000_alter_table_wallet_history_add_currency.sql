@@ -0,0 +1,17 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE wallet ADD COLUMN currency VARCHAR(3) NOT NULL DEFAULT 'IDR';
+ALTER TABLE wallet DROP CONSTRAINT wallet_owned_by_key;
+ALTER TABLE wallet ADD CONSTRAINT uq_wallet_owned_by_currency UNIQUE (owned_by, currency);
+
+ALTER TABLE history ADD COLUMN currency VARCHAR(3) NOT NULL DEFAULT 'IDR';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE history DROP COLUMN currency;
+
+ALTER TABLE wallet DROP CONSTRAINT uq_wallet_owned_by_currency;
+ALTER TABLE wallet ADD CONSTRAINT wallet_owned_by_key UNIQUE (owned_by);
+ALTER TABLE wallet DROP COLUMN currency;
+-- +goose StatementEnd
@@ -0,0 +1,54 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE fx_rate
+(
+    base_currency VARCHAR(3) NOT NULL,
+    quote_currency VARCHAR(3) NOT NULL,
+    rate FLOAT NOT NULL,
+    updated_by VARCHAR NOT NULL,
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+
+    PRIMARY KEY (base_currency, quote_currency),
+    CONSTRAINT chk_fx_rate_positive CHECK (rate > 0)
+);
+
+CREATE TABLE fx_quote
+(
+    id uuid DEFAULT gen_random_uuid (),
+    customer_xid uuid NOT NULL,
+    from_currency VARCHAR(3) NOT NULL,
+    to_currency VARCHAR(3) NOT NULL,
+    rate FLOAT NOT NULL,
+    from_amount FLOAT NOT NULL,
+    to_amount FLOAT NOT NULL,
+    expires_at TIMESTAMP NOT NULL,
+    used_at TIMESTAMP NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+
+    PRIMARY KEY (id)
+);
+
+CREATE TABLE conversion
+(
+    id uuid DEFAULT gen_random_uuid (),
+    quote_id uuid NOT NULL UNIQUE,
+    from_wallet_id uuid NOT NULL,
+    to_wallet_id uuid NOT NULL,
+    from_amount FLOAT NOT NULL,
+    to_amount FLOAT NOT NULL,
+    rate FLOAT NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+
+    PRIMARY KEY (id),
+    CONSTRAINT fk_conversion_quote_id FOREIGN KEY (quote_id) REFERENCES "fx_quote" (id),
+    CONSTRAINT fk_conversion_from_wallet_id FOREIGN KEY (from_wallet_id) REFERENCES "wallet" (id),
+    CONSTRAINT fk_conversion_to_wallet_id FOREIGN KEY (to_wallet_id) REFERENCES "wallet" (id)
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE conversion;
+DROP TABLE fx_quote;
+DROP TABLE fx_rate;
+-- +goose StatementEnd
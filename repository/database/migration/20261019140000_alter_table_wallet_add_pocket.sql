@@ -0,0 +1,28 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE wallet ADD COLUMN parent_id uuid NULL;
+ALTER TABLE wallet ADD COLUMN name VARCHAR NULL;
+ALTER TABLE wallet ADD COLUMN closed_at TIMESTAMP NULL;
+ALTER TABLE wallet ADD CONSTRAINT fk_wallet_parent_id FOREIGN KEY (parent_id) REFERENCES "wallet" (id);
+
+-- only the main wallet is unique per currency, pockets live under it
+ALTER TABLE wallet DROP CONSTRAINT uq_wallet_owned_by_currency;
+CREATE UNIQUE INDEX uq_wallet_owned_by_currency ON wallet(owned_by, currency) WHERE parent_id IS NULL;
+CREATE INDEX idx_wallet_parent_id ON wallet(parent_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX idx_wallet_parent_id;
+DROP INDEX uq_wallet_owned_by_currency;
+DELETE FROM history WHERE wallet_id IN (SELECT id FROM wallet WHERE parent_id IS NOT NULL);
+DELETE FROM kyc_history WHERE wallet_id IN (SELECT id FROM wallet WHERE parent_id IS NOT NULL);
+DELETE FROM wallet_limit WHERE wallet_id IN (SELECT id FROM wallet WHERE parent_id IS NOT NULL);
+DELETE FROM wallet WHERE parent_id IS NOT NULL;
+ALTER TABLE wallet ADD CONSTRAINT uq_wallet_owned_by_currency UNIQUE (owned_by, currency);
+
+ALTER TABLE wallet DROP CONSTRAINT fk_wallet_parent_id;
+ALTER TABLE wallet DROP COLUMN closed_at;
+ALTER TABLE wallet DROP COLUMN name;
+ALTER TABLE wallet DROP COLUMN parent_id;
+-- +goose StatementEnd
@@ -0,0 +1,44 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE loan
+(
+    id uuid DEFAULT gen_random_uuid (),
+    customer_xid uuid NOT NULL,
+    wallet_id uuid NOT NULL,
+    principal FLOAT NOT NULL,
+    currency VARCHAR(3) NOT NULL,
+    status VARCHAR NOT NULL DEFAULT 'active',
+    reference_id uuid NOT NULL UNIQUE,
+    disbursed_at TIMESTAMP NOT NULL DEFAULT NOW(),
+
+    PRIMARY KEY (id),
+    CONSTRAINT fk_loan_wallet_id FOREIGN KEY (wallet_id) REFERENCES "wallet" (id)
+);
+
+CREATE INDEX idx_loan_customer_xid ON loan(customer_xid);
+
+CREATE TABLE loan_installment
+(
+    id uuid DEFAULT gen_random_uuid (),
+    loan_id uuid NOT NULL,
+    sequence INT NOT NULL,
+    due_date DATE NOT NULL,
+    amount FLOAT NOT NULL,
+    paid_amount FLOAT NOT NULL DEFAULT 0,
+    status VARCHAR NOT NULL DEFAULT 'scheduled',
+    paid_at TIMESTAMP NULL,
+    next_attempt_at TIMESTAMP NOT NULL DEFAULT NOW(),
+
+    PRIMARY KEY (id),
+    CONSTRAINT uq_loan_installment_sequence UNIQUE (loan_id, sequence),
+    CONSTRAINT fk_loan_installment_loan_id FOREIGN KEY (loan_id) REFERENCES "loan" (id)
+);
+
+CREATE INDEX idx_loan_installment_due ON loan_installment(next_attempt_at, due_date) WHERE status <> 'paid';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE loan_installment;
+DROP TABLE loan;
+-- +goose StatementEnd
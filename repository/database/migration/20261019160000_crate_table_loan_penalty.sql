@@ -0,0 +1,23 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE loan_installment ADD COLUMN penalty_amount FLOAT NOT NULL DEFAULT 0;
+
+CREATE TABLE loan_penalty
+(
+    id uuid DEFAULT gen_random_uuid (),
+    installment_id uuid NOT NULL,
+    accrual_date DATE NOT NULL,
+    amount FLOAT NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+
+    PRIMARY KEY (id),
+    CONSTRAINT uq_loan_penalty_accrual_date UNIQUE (installment_id, accrual_date),
+    CONSTRAINT fk_loan_penalty_installment_id FOREIGN KEY (installment_id) REFERENCES "loan_installment" (id)
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE loan_penalty;
+ALTER TABLE loan_installment DROP COLUMN penalty_amount;
+-- +goose StatementEnd
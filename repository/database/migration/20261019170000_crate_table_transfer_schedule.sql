@@ -0,0 +1,33 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE transfer_schedule
+(
+    id uuid DEFAULT gen_random_uuid (),
+    customer_xid uuid NOT NULL,
+    currency VARCHAR(3) NOT NULL,
+    from_pocket_id uuid NULL,
+    to_pocket_id uuid NULL,
+    amount FLOAT NOT NULL,
+    cron_expr VARCHAR NULL,
+    status VARCHAR NOT NULL DEFAULT 'active',
+    next_run_at TIMESTAMP NOT NULL,
+    run_reference_id uuid NOT NULL DEFAULT gen_random_uuid (),
+    attempts INT NOT NULL DEFAULT 0,
+    last_run_at TIMESTAMP NULL,
+    last_status VARCHAR NULL,
+    last_error VARCHAR NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+
+    PRIMARY KEY (id),
+    CONSTRAINT fk_transfer_schedule_from_pocket_id FOREIGN KEY (from_pocket_id) REFERENCES "wallet" (id),
+    CONSTRAINT fk_transfer_schedule_to_pocket_id FOREIGN KEY (to_pocket_id) REFERENCES "wallet" (id)
+);
+
+CREATE INDEX idx_transfer_schedule_customer_xid ON transfer_schedule(customer_xid);
+CREATE INDEX idx_transfer_schedule_due ON transfer_schedule(next_run_at) WHERE status = 'active';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE transfer_schedule;
+-- +goose StatementEnd
@@ -0,0 +1,31 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE outbox_event
+(
+    id uuid DEFAULT gen_random_uuid (),
+    seq BIGSERIAL NOT NULL UNIQUE,
+    xid xid8 NOT NULL DEFAULT pg_current_xact_id(),
+    type VARCHAR NOT NULL,
+    version INT NOT NULL,
+    aggregate_type VARCHAR NOT NULL,
+    aggregate_id VARCHAR NOT NULL,
+    customer_xid uuid NULL,
+    payload JSONB NOT NULL,
+    status VARCHAR NOT NULL DEFAULT 'pending',
+    attempts INT NOT NULL DEFAULT 0,
+    last_error VARCHAR NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    published_at TIMESTAMP NULL,
+
+    PRIMARY KEY (id)
+);
+
+CREATE INDEX idx_outbox_event_pending ON outbox_event(seq) WHERE status = 'pending';
+CREATE INDEX idx_outbox_event_customer_xid ON outbox_event(customer_xid, seq);
+CREATE INDEX idx_outbox_event_customer_xid_xid ON outbox_event(customer_xid, xid, seq);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE outbox_event;
+-- +goose StatementEnd
@@ -0,0 +1,77 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE webhook_endpoint
+(
+    id uuid DEFAULT gen_random_uuid (),
+    partner_id VARCHAR NOT NULL,
+    url VARCHAR NOT NULL,
+    secret VARCHAR NOT NULL,
+    event_types VARCHAR[] NOT NULL DEFAULT '{}',
+    customer_xid uuid NULL,
+    is_enabled BOOLEAN NOT NULL DEFAULT true,
+    created_by VARCHAR NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    disabled_at TIMESTAMP NULL,
+
+    PRIMARY KEY (id)
+);
+
+CREATE INDEX idx_webhook_endpoint_partner_id ON webhook_endpoint(partner_id);
+
+CREATE TABLE webhook_delivery
+(
+    id uuid DEFAULT gen_random_uuid (),
+    endpoint_id uuid NOT NULL,
+    event_id uuid NOT NULL,
+    event_type VARCHAR NOT NULL,
+    payload JSONB NOT NULL,
+    status VARCHAR NOT NULL DEFAULT 'pending',
+    attempts INT NOT NULL DEFAULT 0,
+    last_response_code INT NULL,
+    last_error VARCHAR NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    delivered_at TIMESTAMP NULL,
+
+    PRIMARY KEY (id),
+    CONSTRAINT uq_webhook_delivery_event UNIQUE (endpoint_id, event_id),
+    CONSTRAINT fk_webhook_delivery_endpoint_id FOREIGN KEY (endpoint_id) REFERENCES "webhook_endpoint" (id)
+);
+
+CREATE INDEX idx_webhook_delivery_status ON webhook_delivery(endpoint_id, status, created_at);
+
+CREATE TABLE webhook_attempt
+(
+    id uuid DEFAULT gen_random_uuid (),
+    delivery_id uuid NOT NULL,
+    attempt INT NOT NULL,
+    response_code INT NULL,
+    error VARCHAR NULL,
+    duration_ms INT NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+
+    PRIMARY KEY (id),
+    CONSTRAINT fk_webhook_attempt_delivery_id FOREIGN KEY (delivery_id) REFERENCES "webhook_delivery" (id)
+);
+
+CREATE INDEX idx_webhook_attempt_delivery_id ON webhook_attempt(delivery_id);
+
+CREATE TABLE webhook_partner_customer
+(
+    partner_id VARCHAR NOT NULL,
+    customer_xid uuid NOT NULL,
+    created_by VARCHAR NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+
+    PRIMARY KEY (partner_id, customer_xid)
+);
+
+CREATE INDEX idx_webhook_partner_customer_customer_xid ON webhook_partner_customer(customer_xid);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE webhook_partner_customer;
+DROP TABLE webhook_attempt;
+DROP TABLE webhook_delivery;
+DROP TABLE webhook_endpoint;
+-- +goose StatementEnd
@@ -0,0 +1,21 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE FUNCTION notify_outbox_event() RETURNS trigger AS $$
+BEGIN
+    IF NEW.customer_xid IS NOT NULL THEN
+        PERFORM pg_notify('outbox_event', NEW.customer_xid::text);
+    END IF;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER trg_outbox_event_notify
+    AFTER INSERT ON outbox_event
+    FOR EACH ROW EXECUTE FUNCTION notify_outbox_event();
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TRIGGER trg_outbox_event_notify ON outbox_event;
+DROP FUNCTION notify_outbox_event();
+-- +goose StatementEnd
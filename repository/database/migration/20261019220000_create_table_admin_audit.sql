@@ -0,0 +1,31 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE wallet ADD COLUMN frozen_at TIMESTAMP NULL;
+ALTER TABLE wallet ADD COLUMN enabled_before_freeze BOOLEAN NOT NULL DEFAULT FALSE;
+
+CREATE TABLE admin_audit
+(
+    id uuid DEFAULT gen_random_uuid (),
+    actor VARCHAR NOT NULL,
+    action VARCHAR NOT NULL,
+    customer_xid uuid NULL,
+    wallet_id uuid NULL,
+    reason VARCHAR NULL,
+    detail JSONB NULL,
+    result VARCHAR NOT NULL,
+    error VARCHAR NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+
+    PRIMARY KEY (id)
+);
+
+CREATE INDEX idx_admin_audit_customer_xid ON admin_audit(customer_xid, created_at);
+CREATE INDEX idx_admin_audit_actor ON admin_audit(actor, created_at);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE admin_audit;
+ALTER TABLE wallet DROP COLUMN enabled_before_freeze;
+ALTER TABLE wallet DROP COLUMN frozen_at;
+-- +goose StatementEnd
@@ -0,0 +1,44 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE wallet ADD COLUMN created_at TIMESTAMP NOT NULL DEFAULT NOW();
+UPDATE wallet SET created_at = COALESCE(
+    (SELECT MIN(history.created_at) FROM history WHERE history.wallet_id = wallet.id),
+    LEAST(enabled_at, disabled_at),
+    created_at
+);
+CREATE INDEX idx_wallet_created_at ON wallet(created_at);
+
+CREATE TABLE admin_adjustment
+(
+    id uuid DEFAULT gen_random_uuid (),
+    kind VARCHAR NOT NULL DEFAULT 'adjustment',
+    reversal_of uuid NULL,
+    wallet_id uuid NOT NULL,
+    customer_xid uuid NOT NULL,
+    currency VARCHAR(3) NOT NULL,
+    amount FLOAT NOT NULL,
+    reason VARCHAR NOT NULL,
+    reference_id uuid NOT NULL UNIQUE,
+    status VARCHAR NOT NULL DEFAULT 'pending',
+    requested_by VARCHAR NOT NULL,
+    requested_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    reviewed_by VARCHAR NULL,
+    reviewed_at TIMESTAMP NULL,
+    review_note VARCHAR NULL,
+    history_id uuid NULL,
+
+    PRIMARY KEY (id),
+    CONSTRAINT fk_admin_adjustment_wallet_id FOREIGN KEY (wallet_id) REFERENCES "wallet" (id),
+    CONSTRAINT fk_admin_adjustment_history_id FOREIGN KEY (history_id) REFERENCES "history" (id),
+    CONSTRAINT fk_admin_adjustment_reversal_of FOREIGN KEY (reversal_of) REFERENCES "history" (id)
+);
+
+CREATE INDEX idx_admin_adjustment_status ON admin_adjustment(status, requested_at);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE admin_adjustment;
+DROP INDEX idx_wallet_created_at;
+ALTER TABLE wallet DROP COLUMN created_at;
+-- +goose StatementEnd
@@ -0,0 +1,79 @@
+// Package migration embeds the goose SQL migrations of this directory and runs them with goose on the
+// configured database. The goose_db_version table is shared with the goose command line tool, so a
+// database migrated by either is understood by the other.
+package migration
+
+import (
+	"context"
+	"embed"
+	"time"
+
+	"github.com/go-pg/pg/v10"
+	"github.com/pressly/goose/v3"
+)
+
+//go:embed *.sql
+var FS embed.FS
+
+// TableName is the goose version table
+var TableName string = "goose_db_version"
+
+// dir is the directory of the migrations within FS
+const dir = "."
+
+func init() {
+	goose.SetBaseFS(FS)
+	goose.SetTableName(TableName)
+	if err := goose.SetDialect(string(goose.DialectPostgres)); err != nil {
+		panic(err)
+	}
+}
+
+// Latest returns the version of the newest embedded migration
+func Latest() (int64, error) {
+	migrations, err := goose.CollectMigrations(dir, 0, goose.MaxVersion)
+	if err != nil {
+		return 0, err
+	}
+	last, err := migrations.Last()
+	if err != nil {
+		return 0, err
+	}
+	return last.Version, nil
+}
+
+// CurrentVersion returns the version the database is migrated to the way goose reads it, a version
+// rolled back by an older goose is skipped. It reads with the application connection so the health
+// check does not need a second pool.
+func CurrentVersion(ctx context.Context, db pg.DBI) (int64, error) {
+	var rows []versionRow
+	_, err := db.QueryContext(ctx, &rows, `SELECT version_id, is_applied, tstamp FROM `+TableName+` ORDER BY id DESC`)
+	if err != nil {
+		return 0, err
+	}
+	return currentVersion(rows), nil
+}
+
+// versionRow is a row of the goose version table
+type versionRow struct {
+	VersionID int64
+	IsApplied bool
+	Tstamp    time.Time
+}
+
+// currentVersion returns the highest applied version of rows ordered newest first, only the newest row
+// of a version counts
+func currentVersion(rows []versionRow) int64 {
+	var current int64
+	seen := make(map[int64]bool)
+	for _, row := range rows {
+		if seen[row.VersionID] {
+			continue
+		}
+		seen[row.VersionID] = true
+		if row.IsApplied && row.VersionID > current {
+			current = row.VersionID
+		}
+	}
+	return current
+}
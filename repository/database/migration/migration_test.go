@@ -0,0 +1,183 @@
+package migration
+
+import (
+	"fmt"
+	"io/fs"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/pressly/goose/v3"
+)
+
+func TestLatest(t *testing.T) {
+	names, err := fs.Glob(FS, "*.sql")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var want int64
+	for _, name := range names {
+		version, err := goose.NumericComponent(name)
+		if err != nil {
+			t.Fatalf("migration %s: %v", name, err)
+		}
+		if version > want {
+			want = version
+		}
+	}
+
+	got, err := Latest()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != want {
+		t.Errorf("Latest() = %d, want %d", got, want)
+	}
+}
+
+// TestMigrationsAnnotated checks what goose only reports when a migration runs: every file has an up
+// section and its multi statement blocks are closed
+func TestMigrationsAnnotated(t *testing.T) {
+	names, err := fs.Glob(FS, "*.sql")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range names {
+		t.Run(name, func(t *testing.T) {
+			content, err := FS.ReadFile(name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, problem := range annotationProblems(string(content)) {
+				t.Error(problem)
+			}
+		})
+	}
+}
+
+func TestAnnotationProblems(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    []string
+	}{
+		{
+			name:    "up and down",
+			content: "-- +goose Up\nCREATE TABLE t (id int);\n-- +goose Down\nDROP TABLE t;\n",
+		},
+		{
+			name:    "up only",
+			content: "-- +goose Up\nALTER TABLE t ADD COLUMN c int;\n",
+		},
+		{
+			name:    "closed statement block",
+			content: "-- +goose Up\n-- +goose StatementBegin\nCREATE FUNCTION f() RETURNS int AS $$ SELECT 1; $$ LANGUAGE sql;\n-- +goose StatementEnd\n",
+		},
+		{
+			name:    "indented annotations",
+			content: "  -- +goose Up\nCREATE TABLE t (id int);\n\t-- +goose Down\nDROP TABLE t;\n",
+		},
+		{
+			name:    "no up section",
+			content: "CREATE TABLE t (id int);\n-- +goose Down\nDROP TABLE t;\n",
+			want:    []string{"0 up and 1 down sections, want exactly one up and at most one down"},
+		},
+		{
+			name:    "two down sections",
+			content: "-- +goose Up\nSELECT 1;\n-- +goose Down\nSELECT 2;\n-- +goose Down\nSELECT 3;\n",
+			want:    []string{"1 up and 2 down sections, want exactly one up and at most one down"},
+		},
+		{
+			name:    "unclosed statement block",
+			content: "-- +goose Up\n-- +goose StatementBegin\nSELECT 1;\n",
+			want:    []string{"StatementBegin is not closed"},
+		},
+		{
+			name:    "nested statement block",
+			content: "-- +goose Up\n-- +goose StatementBegin\n-- +goose StatementBegin\nSELECT 1;\n-- +goose StatementEnd\n-- +goose StatementEnd\n",
+			want:    []string{"StatementBegin inside an open statement"},
+		},
+		{
+			name:    "statement end without begin",
+			content: "-- +goose Up\nSELECT 1;\n-- +goose StatementEnd\n",
+			want:    []string{"StatementEnd without StatementBegin"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := annotationProblems(tt.content); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("annotationProblems() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// annotationProblems lists the goose annotation mistakes of a migration
+func annotationProblems(content string) []string {
+	var problems []string
+	var up, down, open int
+	for _, line := range strings.Split(content, "\n") {
+		switch strings.TrimSpace(line) {
+		case "-- +goose Up":
+			up++
+		case "-- +goose Down":
+			down++
+		case "-- +goose StatementBegin":
+			if open > 0 {
+				problems = append(problems, "StatementBegin inside an open statement")
+			}
+			open++
+		case "-- +goose StatementEnd":
+			if open == 0 {
+				problems = append(problems, "StatementEnd without StatementBegin")
+				continue
+			}
+			open--
+		}
+	}
+	if up != 1 || down > 1 {
+		problems = append(problems, fmt.Sprintf("%d up and %d down sections, want exactly one up and at most one down", up, down))
+	}
+	if open != 0 {
+		problems = append(problems, "StatementBegin is not closed")
+	}
+	return problems
+}
+
+func TestCurrentVersion(t *testing.T) {
+	tests := []struct {
+		name string
+		rows []versionRow
+		want int64
+	}{
+		{name: "empty table", want: 0},
+		{name: "goose initial row only", rows: []versionRow{{VersionID: 0, IsApplied: true}}, want: 0},
+		{
+			name: "highest applied",
+			rows: []versionRow{{VersionID: 3, IsApplied: true}, {VersionID: 2, IsApplied: true}, {VersionID: 1, IsApplied: true}},
+			want: 3,
+		},
+		{
+			name: "rolled back by an older goose",
+			rows: []versionRow{{VersionID: 3, IsApplied: false}, {VersionID: 3, IsApplied: true}, {VersionID: 2, IsApplied: true}},
+			want: 2,
+		},
+		{
+			name: "applied again after a rollback",
+			rows: []versionRow{{VersionID: 3, IsApplied: true}, {VersionID: 3, IsApplied: false}, {VersionID: 3, IsApplied: true}, {VersionID: 2, IsApplied: true}},
+			want: 3,
+		},
+		{
+			name: "applied out of order",
+			rows: []versionRow{{VersionID: 2, IsApplied: true}, {VersionID: 3, IsApplied: true}},
+			want: 3,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := currentVersion(tt.rows); got != tt.want {
+				t.Errorf("currentVersion() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
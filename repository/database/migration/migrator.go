@@ -0,0 +1,73 @@
+package migration
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/pressly/goose/v3"
+
+	// goose runs on database/sql, go-pg has no driver for it
+	_ "github.com/lib/pq"
+)
+
+// lockID is the advisory lock serializing migrations of concurrent instances
+const lockID int64 = 1870327213
+
+// Migrator applies the embedded migrations with goose, concurrent instances wait for each other on an
+// advisory lock
+type Migrator struct {
+	db *sql.DB
+}
+
+// NewMigrator opens its own database/sql connection to dsn, see database.DSN
+func NewMigrator(dsn string) (*Migrator, error) {
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{db: db}, nil
+}
+
+func (m *Migrator) Close() error {
+	return m.db.Close()
+}
+
+// Version returns the version the database is migrated to
+func (m *Migrator) Version(ctx context.Context) (int64, error) {
+	return goose.GetDBVersionContext(ctx, m.db)
+}
+
+// Status prints every embedded migration with the time it was applied
+func (m *Migrator) Status(ctx context.Context) error {
+	return goose.StatusContext(ctx, m.db, dir)
+}
+
+// Up applies every pending migration in version order
+func (m *Migrator) Up(ctx context.Context) error {
+	return m.locked(ctx, func() error {
+		return goose.UpContext(ctx, m.db, dir)
+	})
+}
+
+// Down rolls back the current version
+func (m *Migrator) Down(ctx context.Context) error {
+	return m.locked(ctx, func() error {
+		return goose.DownContext(ctx, m.db, dir)
+	})
+}
+
+// locked runs fn while holding the migration lock on a connection of its own, fn migrates on the others
+func (m *Migrator) locked(ctx context.Context, fn func() error) error {
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, `SELECT pg_advisory_lock($1)`, lockID); err != nil {
+		return err
+	}
+	defer conn.ExecContext(context.Background(), `SELECT pg_advisory_unlock($1)`, lockID)
+
+	return fn()
+}
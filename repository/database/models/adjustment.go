@@ -0,0 +1,47 @@
+package models
+
+import (
+	"errors"
+
+	"github.com/ahmadmirdas/julo-test/repository/database/models/entity"
+)
+
+var (
+	ErrAdjustmentNotFound = errors.New("adjustment not found")
+	ErrAdjustmentReviewed = errors.New("adjustment is already reviewed")
+	ErrSelfReview         = errors.New("an adjustment must be reviewed by someone other than its requester")
+)
+
+var (
+	AdjustmentKindAdjustment string = "adjustment"
+	AdjustmentKindReversal   string = "reversal"
+
+	AdjustmentStatusPending  string = "pending"
+	AdjustmentStatusApproved string = "approved"
+	AdjustmentStatusRejected string = "rejected"
+)
+
+// ParamGetAdjustments filters adjustments, empty filters match everything
+type ParamGetAdjustments struct {
+	Status      string
+	CustomerXId string
+	Limit       int
+	Offset      int
+}
+
+// ParamReviewAdjustment approves or rejects a pending adjustment, an approval posts it to the wallet.
+// Audit, when set, builds the audit row written in the same transaction.
+type ParamReviewAdjustment struct {
+	ID       string
+	Reviewer string
+	Approve  bool
+	Note     string
+	Audit    func(adjustment *entity.AdminAdjustment) *entity.AdminAudit
+}
+
+// ParamCreateAdjustment stores a pending adjustment or reversal, Audit, when set, builds the audit row
+// written in the same transaction
+type ParamCreateAdjustment struct {
+	Adjustment *entity.AdminAdjustment
+	Audit      func(adjustment *entity.AdminAdjustment) *entity.AdminAudit
+}
@@ -0,0 +1,151 @@
+package models
+
+import (
+	"context"
+	"time"
+
+	"github.com/ahmadmirdas/julo-test/repository/database/models/entity"
+	"github.com/ahmadmirdas/julo-test/utils/metrics"
+	"github.com/go-pg/pg/v10"
+)
+
+type AdjustmentDBRepo interface {
+	CreateAdjustment(ctx context.Context, param ParamCreateAdjustment) (*entity.AdminAdjustment, error)
+	GetAdjustment(ctx context.Context, id string) (*entity.AdminAdjustment, error)
+	GetAdjustments(ctx context.Context, param ParamGetAdjustments) ([]entity.AdminAdjustment, error)
+	ReviewAdjustment(ctx context.Context, param ParamReviewAdjustment) (*entity.AdminAdjustment, error)
+}
+
+type dbAdjustmentRepo struct {
+	dbConn *pg.DB
+}
+
+func NewDBAdjustmentRepo(c *pg.DB) AdjustmentDBRepo {
+	return &dbAdjustmentRepo{dbConn: c}
+}
+
+// CreateAdjustment stores a pending adjustment or reversal, the balance is untouched until it is approved
+func (p *dbAdjustmentRepo) CreateAdjustment(ctx context.Context, param ParamCreateAdjustment) (*entity.AdminAdjustment, error) {
+	adjustment := param.Adjustment
+	if adjustment.Kind == "" {
+		adjustment.Kind = AdjustmentKindAdjustment
+	}
+	adjustment.Status = AdjustmentStatusPending
+	err := p.dbConn.RunInTransaction(ctx, func(tx *pg.Tx) error {
+		if _, err := tx.Model(adjustment).Returning("*").Insert(); err != nil {
+			return err
+		}
+		if param.Audit == nil {
+			return nil
+		}
+		return recordAudit(tx, param.Audit(adjustment))
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return adjustment, nil
+}
+
+func (p *dbAdjustmentRepo) GetAdjustment(ctx context.Context, id string) (*entity.AdminAdjustment, error) {
+	var adjustment entity.AdminAdjustment
+	err := p.dbConn.ModelContext(ctx, &adjustment).
+		Where("id = ?", id).
+		Select()
+	if err == pg.ErrNoRows {
+		return nil, ErrAdjustmentNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &adjustment, nil
+}
+
+// GetAdjustments returns a page of adjustments, oldest request first so pending ones are reviewed in order
+func (p *dbAdjustmentRepo) GetAdjustments(ctx context.Context, param ParamGetAdjustments) ([]entity.AdminAdjustment, error) {
+	var adjustments []entity.AdminAdjustment
+	q := p.dbConn.ModelContext(ctx, &adjustments).
+		Order("requested_at", "id").
+		Limit(param.Limit).
+		Offset(param.Offset)
+	if param.Status != "" {
+		q = q.Where("status = ?", param.Status)
+	}
+	if param.CustomerXId != "" {
+		q = q.Where("customer_xid = ?", param.CustomerXId)
+	}
+	if err := q.Select(); err != nil {
+		return nil, err
+	}
+
+	return adjustments, nil
+}
+
+// ReviewAdjustment settles a pending adjustment once, an approval that cannot be posted, a debit above the
+// balance for instance, leaves it pending
+func (p *dbAdjustmentRepo) ReviewAdjustment(ctx context.Context, param ParamReviewAdjustment) (*entity.AdminAdjustment, error) {
+	var adjustment entity.AdminAdjustment
+	var history *entity.History
+	err := p.dbConn.RunInTransaction(ctx, func(tx *pg.Tx) error {
+		err := tx.Model(&adjustment).
+			Where("id = ?", param.ID).
+			For("UPDATE").
+			Select()
+		if err == pg.ErrNoRows {
+			return ErrAdjustmentNotFound
+		}
+		if err != nil {
+			return err
+		}
+		if adjustment.Status != AdjustmentStatusPending {
+			return ErrAdjustmentReviewed
+		}
+		if adjustment.RequestedBy == param.Reviewer {
+			return ErrSelfReview
+		}
+
+		adjustment.Status = AdjustmentStatusRejected
+		if param.Approve {
+			if adjustment.Kind == AdjustmentKindReversal {
+				history, err = reverseTransaction(tx, ParamReverseTransaction{HistoryID: adjustment.ReversalOf})
+			} else {
+				history, err = adjustBalance(tx, ParamAdjustBalance{
+					WalletID:    adjustment.WalletID,
+					Currency:    adjustment.Currency,
+					Amount:      adjustment.Amount,
+					ReferenceID: adjustment.ReferenceID,
+				})
+			}
+			if err != nil {
+				return err
+			}
+			adjustment.Status = AdjustmentStatusApproved
+			adjustment.HistoryID = history.ID
+		}
+		adjustment.ReviewedBy = param.Reviewer
+		adjustment.ReviewedAt = time.Now()
+		adjustment.ReviewNote = param.Note
+
+		_, err = tx.Model(&adjustment).
+			Set("status = ?status").
+			Set("reviewed_by = ?reviewed_by").
+			Set("reviewed_at = ?reviewed_at").
+			Set("review_note = ?review_note").
+			Set("history_id = ?history_id").
+			WherePK().
+			Update()
+		if err != nil || param.Audit == nil {
+			return err
+		}
+		return recordAudit(tx, param.Audit(&adjustment))
+	})
+	if err != nil {
+		return nil, err
+	}
+	if history != nil {
+		metrics.ObserveTransaction(history.Type, history.Currency, history.Amount)
+	}
+
+	return &adjustment, nil
+}
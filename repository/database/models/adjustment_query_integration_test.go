@@ -0,0 +1,96 @@
+//go:build integration
+
+package models
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/ahmadmirdas/julo-test/repository/database/models/entity"
+	"github.com/google/uuid"
+)
+
+func TestReviewAdjustment(t *testing.T) {
+	ctx := context.Background()
+	db := testDB(t)
+	walletRepo := &dbWalletRepo{dbConn: db}
+	repo := &dbAdjustmentRepo{dbConn: db}
+
+	customerXId := uuid.New().String()
+	wallet, err := walletRepo.EnableWallet(ctx, customerXId, "IDR")
+	if err != nil {
+		t.Fatalf("enable wallet: %v", err)
+	}
+	request := func(t *testing.T) *entity.AdminAdjustment {
+		t.Helper()
+		adjustment, err := repo.CreateAdjustment(ctx, ParamCreateAdjustment{Adjustment: &entity.AdminAdjustment{
+			WalletID:    wallet.ID,
+			CustomerXId: customerXId,
+			Currency:    wallet.Currency,
+			Amount:      1000,
+			Reason:      "goodwill credit",
+			ReferenceID: uuid.New().String(),
+			RequestedBy: "alice",
+		}})
+		if err != nil {
+			t.Fatalf("request adjustment: %v", err)
+		}
+		return adjustment
+	}
+
+	tests := []struct {
+		name       string
+		reviewer   string
+		approve    bool
+		wantErr    error
+		wantStatus string
+	}{
+		{name: "requester approves", reviewer: "alice", approve: true, wantErr: ErrSelfReview, wantStatus: AdjustmentStatusPending},
+		{name: "requester rejects", reviewer: "alice", wantErr: ErrSelfReview, wantStatus: AdjustmentStatusPending},
+		{name: "other staff rejects", reviewer: "bob", wantStatus: AdjustmentStatusRejected},
+		{name: "other staff approves", reviewer: "bob", approve: true, wantStatus: AdjustmentStatusApproved},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			adjustment := request(t)
+			_, err := repo.ReviewAdjustment(ctx, ParamReviewAdjustment{ID: adjustment.ID, Reviewer: tt.reviewer, Approve: tt.approve, Note: "checked"})
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("ReviewAdjustment() error = %v, want %v", err, tt.wantErr)
+			}
+
+			stored, err := repo.GetAdjustment(ctx, adjustment.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if stored.Status != tt.wantStatus {
+				t.Errorf("adjustment is %s, want %s", stored.Status, tt.wantStatus)
+			}
+			if tt.wantErr != nil && (stored.ReviewedBy != "" || stored.HistoryID != "") {
+				t.Errorf("refused review recorded reviewer %q and history %q", stored.ReviewedBy, stored.HistoryID)
+			}
+			if tt.wantStatus == AdjustmentStatusApproved && stored.HistoryID == "" {
+				t.Error("approved adjustment has no history")
+			}
+		})
+	}
+
+	t.Run("reviewed twice", func(t *testing.T) {
+		adjustment := request(t)
+		param := ParamReviewAdjustment{ID: adjustment.ID, Reviewer: "bob", Note: "duplicate"}
+		if _, err := repo.ReviewAdjustment(ctx, param); err != nil {
+			t.Fatalf("first review: %v", err)
+		}
+		param.Reviewer = "carol"
+		if _, err := repo.ReviewAdjustment(ctx, param); !errors.Is(err, ErrAdjustmentReviewed) {
+			t.Errorf("second review error = %v, want %v", err, ErrAdjustmentReviewed)
+		}
+	})
+
+	t.Run("unknown adjustment", func(t *testing.T) {
+		_, err := repo.ReviewAdjustment(ctx, ParamReviewAdjustment{ID: uuid.New().String(), Reviewer: "bob"})
+		if !errors.Is(err, ErrAdjustmentNotFound) {
+			t.Errorf("ReviewAdjustment() error = %v, want %v", err, ErrAdjustmentNotFound)
+		}
+	})
+}
@@ -0,0 +1,15 @@
+package models
+
+var (
+	AuditResultSuccess string = "success"
+	AuditResultFailure string = "failure"
+)
+
+// ParamGetAuditLogs filters the audit log, empty filters match everything
+type ParamGetAuditLogs struct {
+	CustomerXId string
+	Actor       string
+	Action      string
+	Limit       int
+	Offset      int
+}
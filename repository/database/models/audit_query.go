@@ -0,0 +1,59 @@
+package models
+
+import (
+	"context"
+
+	"github.com/ahmadmirdas/julo-test/repository/database/models/entity"
+	"github.com/go-pg/pg/v10"
+)
+
+type AuditDBRepo interface {
+	RecordAudit(ctx context.Context, audit *entity.AdminAudit) error
+	GetAuditLogs(ctx context.Context, param ParamGetAuditLogs) ([]entity.AdminAudit, error)
+}
+
+type dbAuditRepo struct {
+	dbConn *pg.DB
+}
+
+func NewDBAuditRepo(c *pg.DB) AuditDBRepo {
+	return &dbAuditRepo{dbConn: c}
+}
+
+func (p *dbAuditRepo) RecordAudit(ctx context.Context, audit *entity.AdminAudit) error {
+	_, err := p.dbConn.ModelContext(ctx, audit).Returning("*").Insert()
+	return err
+}
+
+// recordAudit writes audit within tx so the row commits or rolls back with the action it describes,
+// a nil audit writes nothing
+func recordAudit(tx *pg.Tx, audit *entity.AdminAudit) error {
+	if audit == nil {
+		return nil
+	}
+	_, err := tx.Model(audit).Returning("*").Insert()
+	return err
+}
+
+// GetAuditLogs returns a page of the audit log, newest first
+func (p *dbAuditRepo) GetAuditLogs(ctx context.Context, param ParamGetAuditLogs) ([]entity.AdminAudit, error) {
+	var audits []entity.AdminAudit
+	q := p.dbConn.ModelContext(ctx, &audits).
+		Order("created_at DESC").
+		Limit(param.Limit).
+		Offset(param.Offset)
+	if param.CustomerXId != "" {
+		q = q.Where("customer_xid = ?", param.CustomerXId)
+	}
+	if param.Actor != "" {
+		q = q.Where("actor = ?", param.Actor)
+	}
+	if param.Action != "" {
+		q = q.Where("action = ?", param.Action)
+	}
+	if err := q.Select(); err != nil {
+		return nil, err
+	}
+
+	return audits, nil
+}
@@ -0,0 +1,25 @@
+package entity
+
+import "time"
+
+// AdminAdjustment is a manual balance correction, or the reversal of the transaction ReversalOf,
+// requested by one staff member and approved or rejected by another
+type AdminAdjustment struct {
+	tableName   struct{}  `pg:"admin_adjustment"`
+	ID          string    `json:"id"                    pg:"id,pk"`
+	Kind        string    `json:"kind"                  pg:"kind"`
+	ReversalOf  string    `json:"reversal_of,omitempty" pg:"reversal_of"`
+	WalletID    string    `json:"wallet_id"             pg:"wallet_id"`
+	CustomerXId string    `json:"customer_xid"          pg:"customer_xid"`
+	Currency    string    `json:"currency"              pg:"currency"`
+	Amount      float64   `json:"amount"                pg:"amount"`
+	Reason      string    `json:"reason"                pg:"reason"`
+	ReferenceID string    `json:"reference_id"          pg:"reference_id"`
+	Status      string    `json:"status"                pg:"status"`
+	RequestedBy string    `json:"requested_by"          pg:"requested_by"`
+	RequestedAt time.Time `json:"requested_at"          pg:"requested_at"`
+	ReviewedBy  string    `json:"reviewed_by,omitempty" pg:"reviewed_by"`
+	ReviewedAt  time.Time `json:"reviewed_at,omitempty" pg:"reviewed_at"`
+	ReviewNote  string    `json:"review_note,omitempty" pg:"review_note"`
+	HistoryID   string    `json:"history_id,omitempty"  pg:"history_id"`
+}
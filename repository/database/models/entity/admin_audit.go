@@ -0,0 +1,21 @@
+package entity
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// AdminAudit records an action of the support staff, successful or not
+type AdminAudit struct {
+	tableName   struct{}        `pg:"admin_audit"`
+	ID          string          `json:"id"           pg:"id,pk"`
+	Actor       string          `json:"actor"        pg:"actor"`
+	Action      string          `json:"action"       pg:"action"`
+	CustomerXId string          `json:"customer_xid" pg:"customer_xid"`
+	WalletID    string          `json:"wallet_id"    pg:"wallet_id"`
+	Reason      string          `json:"reason"       pg:"reason"`
+	Detail      json.RawMessage `json:"detail"       pg:"detail,type:jsonb"`
+	Result      string          `json:"result"       pg:"result"`
+	Error       string          `json:"error"        pg:"error"`
+	CreatedAt   time.Time       `json:"created_at"   pg:"created_at"`
+}
@@ -0,0 +1,39 @@
+package entity
+
+import "time"
+
+// FxRate is the price of one BaseCurrency unit in QuoteCurrency
+type FxRate struct {
+	tableName     struct{}  `pg:"fx_rate"`
+	BaseCurrency  string    `json:"base_currency"  pg:"base_currency,pk"`
+	QuoteCurrency string    `json:"quote_currency" pg:"quote_currency,pk"`
+	Rate          float64   `json:"rate"           pg:"rate"`
+	UpdatedBy     string    `json:"updated_by"     pg:"updated_by"`
+	UpdatedAt     time.Time `json:"updated_at"     pg:"updated_at"`
+}
+
+type FxQuote struct {
+	tableName    struct{}  `pg:"fx_quote"`
+	ID           string    `json:"id" pg:"id,pk"`
+	CustomerXId  string    `json:"-"  pg:"customer_xid"`
+	FromCurrency string    `json:"-"  pg:"from_currency"`
+	ToCurrency   string    `json:"-"  pg:"to_currency"`
+	Rate         float64   `json:"-"  pg:"rate"`
+	FromAmount   float64   `json:"-"  pg:"from_amount"`
+	ToAmount     float64   `json:"-"  pg:"to_amount"`
+	ExpiresAt    time.Time `json:"-"  pg:"expires_at"`
+	UsedAt       time.Time `json:"-"  pg:"used_at"`
+	CreatedAt    time.Time `json:"-"  pg:"created_at"`
+}
+
+type Conversion struct {
+	tableName    struct{}  `pg:"conversion"`
+	ID           string    `json:"id" pg:"id,pk"`
+	QuoteID      string    `json:"-"  pg:"quote_id"`
+	FromWalletID string    `json:"-"  pg:"from_wallet_id"`
+	ToWalletID   string    `json:"-"  pg:"to_wallet_id"`
+	FromAmount   float64   `json:"-"  pg:"from_amount"`
+	ToAmount     float64   `json:"-"  pg:"to_amount"`
+	Rate         float64   `json:"-"  pg:"rate"`
+	CreatedAt    time.Time `json:"-"  pg:"created_at"`
+}
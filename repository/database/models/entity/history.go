@@ -9,6 +9,7 @@ type History struct {
 	Wallet      *Wallet   `json:"-"  pg:"fk:wallet_id"`
 	Status      string    `json:"-"  pg:"status"`
 	Amount      float64   `json:"-"  pg:"amount"`
+	Currency    string    `json:"-"  pg:"currency"`
 	Type        string    `json:"-"  pg:"type"`
 	ReferenceID string    `json:"-"  pg:"reference_id"`
 	CreatedAt   time.Time `json:"-"  pg:"created_at"`
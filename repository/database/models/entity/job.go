@@ -0,0 +1,23 @@
+package entity
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Job is a unit of background work, a running job whose LockedUntil has passed is visible again to other workers
+type Job struct {
+	tableName   struct{}        `pg:"jobs"`
+	ID          string          `json:"id"           pg:"id,pk"`
+	Type        string          `json:"type"         pg:"type"`
+	Payload     json.RawMessage `json:"payload"      pg:"payload,type:jsonb"`
+	Status      string          `json:"status"       pg:"status"`
+	Attempts    int             `json:"attempts"     pg:"attempts,use_zero"`
+	MaxAttempts int             `json:"max_attempts" pg:"max_attempts"`
+	RunAt       time.Time       `json:"run_at"       pg:"run_at"`
+	LockedUntil time.Time       `json:"locked_until" pg:"locked_until"`
+	UniqueKey   string          `json:"unique_key"   pg:"unique_key"`
+	LastError   string          `json:"last_error"   pg:"last_error"`
+	CreatedAt   time.Time       `json:"created_at"   pg:"created_at"`
+	UpdatedAt   time.Time       `json:"updated_at"   pg:"updated_at"`
+}
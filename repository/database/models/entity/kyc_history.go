@@ -0,0 +1,14 @@
+package entity
+
+import "time"
+
+type KycHistory struct {
+	tableName struct{}  `pg:"kyc_history"`
+	ID        string    `json:"id"         pg:"id,pk"`
+	WalletID  string    `json:"wallet_id"  pg:"wallet_id"`
+	FromLevel string    `json:"from_level" pg:"from_level"`
+	ToLevel   string    `json:"to_level"   pg:"to_level"`
+	Actor     string    `json:"actor"      pg:"actor"`
+	Reason    string    `json:"reason"     pg:"reason"`
+	CreatedAt time.Time `json:"created_at" pg:"created_at"`
+}
@@ -0,0 +1,36 @@
+package entity
+
+import "time"
+
+type Loan struct {
+	tableName    struct{}           `pg:"loan"`
+	ID           string             `json:"id"           pg:"id,pk"`
+	CustomerXId  string             `json:"customer_xid" pg:"customer_xid"`
+	WalletID     string             `json:"wallet_id"    pg:"wallet_id"`
+	Principal    float64            `json:"principal"    pg:"principal"`
+	Currency     string             `json:"currency"     pg:"currency"`
+	Status       string             `json:"status"       pg:"status"`
+	ReferenceID  string             `json:"reference_id" pg:"reference_id"`
+	DisbursedAt  time.Time          `json:"disbursed_at" pg:"disbursed_at"`
+	Installments []*LoanInstallment `json:"installments" pg:"rel:has-many"`
+}
+
+type LoanInstallment struct {
+	tableName     struct{}  `pg:"loan_installment"`
+	ID            string    `json:"id"          pg:"id,pk"`
+	LoanID        string    `json:"-"           pg:"loan_id"`
+	Loan          *Loan     `json:"-"           pg:"rel:has-one"`
+	Sequence      int       `json:"sequence"    pg:"sequence"`
+	DueDate       time.Time `json:"due_date"    pg:"due_date,type:date"`
+	Amount        float64   `json:"amount"      pg:"amount"`
+	Penalty       float64   `json:"penalty"     pg:"penalty_amount,use_zero"`
+	PaidAmount    float64   `json:"paid_amount" pg:"paid_amount,use_zero"`
+	Status        string    `json:"status"      pg:"status"`
+	PaidAt        time.Time `json:"paid_at"     pg:"paid_at"`
+	NextAttemptAt time.Time `json:"-"           pg:"next_attempt_at"`
+}
+
+// Outstanding is the installment amount plus accrued penalty not paid yet
+func (i *LoanInstallment) Outstanding() float64 {
+	return i.Amount + i.Penalty - i.PaidAmount
+}
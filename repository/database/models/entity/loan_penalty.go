@@ -0,0 +1,12 @@
+package entity
+
+import "time"
+
+type LoanPenalty struct {
+	tableName     struct{}  `pg:"loan_penalty"`
+	ID            string    `json:"id"           pg:"id,pk"`
+	InstallmentID string    `json:"-"            pg:"installment_id"`
+	AccrualDate   time.Time `json:"accrual_date" pg:"accrual_date,type:date"`
+	Amount        float64   `json:"amount"       pg:"amount"`
+	CreatedAt     time.Time `json:"created_at"   pg:"created_at"`
+}
@@ -0,0 +1,24 @@
+package entity
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// OutboxEvent is a domain event written in the same transaction as the change it describes
+type OutboxEvent struct {
+	tableName     struct{}        `pg:"outbox_event"`
+	ID            string          `json:"id"             pg:"id,pk"`
+	Seq           int64           `json:"seq"            pg:"seq"`
+	Type          string          `json:"type"           pg:"type"`
+	Version       int             `json:"version"        pg:"version"`
+	AggregateType string          `json:"aggregate_type" pg:"aggregate_type"`
+	AggregateID   string          `json:"aggregate_id"   pg:"aggregate_id"`
+	CustomerXId   string          `json:"customer_xid"   pg:"customer_xid"`
+	Payload       json.RawMessage `json:"payload"        pg:"payload,type:jsonb"`
+	Status        string          `json:"status"         pg:"status"`
+	Attempts      int             `json:"attempts"       pg:"attempts,use_zero"`
+	LastError     string          `json:"last_error"     pg:"last_error"`
+	CreatedAt     time.Time       `json:"created_at"     pg:"created_at"`
+	PublishedAt   time.Time       `json:"published_at"   pg:"published_at"`
+}
@@ -0,0 +1,24 @@
+package entity
+
+import "time"
+
+// TransferSchedule is a one-off transfer between own pockets when CronExpr is empty, otherwise a recurring one.
+// RunReferenceID is the reference of the pending occurrence so a retried occurrence is never transferred twice.
+type TransferSchedule struct {
+	tableName      struct{}  `pg:"transfer_schedule"`
+	ID             string    `json:"id"               pg:"id,pk"`
+	CustomerXId    string    `json:"customer_xid"     pg:"customer_xid"`
+	Currency       string    `json:"currency"         pg:"currency"`
+	FromPocketID   string    `json:"from_pocket_id"   pg:"from_pocket_id"`
+	ToPocketID     string    `json:"to_pocket_id"     pg:"to_pocket_id"`
+	Amount         float64   `json:"amount"           pg:"amount"`
+	CronExpr       string    `json:"cron_expr"        pg:"cron_expr"`
+	Status         string    `json:"status"           pg:"status"`
+	NextRunAt      time.Time `json:"next_run_at"      pg:"next_run_at"`
+	RunReferenceID string    `json:"run_reference_id" pg:"run_reference_id"`
+	Attempts       int       `json:"attempts"         pg:"attempts,use_zero"`
+	LastRunAt      time.Time `json:"last_run_at"      pg:"last_run_at"`
+	LastStatus     string    `json:"last_status"      pg:"last_status"`
+	LastError      string    `json:"last_error"       pg:"last_error"`
+	CreatedAt      time.Time `json:"created_at"       pg:"created_at"`
+}
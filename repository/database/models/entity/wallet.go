@@ -8,6 +8,17 @@ type Wallet struct {
 	OwnedBy    string    `json:"-"  pg:"owned_by"`
 	IsEnabled  bool      `json:"-"  pg:"is_enabled"`
 	Balance    float64   `json:"-"  pg:"balance"`
+	Currency   string    `json:"-"  pg:"currency"`
+	Segment    string    `json:"-"  pg:"segment"`
+	MaxBalance float64   `json:"-"  pg:"max_balance"`
+	KycLevel   string    `json:"-"  pg:"kyc_level"`
 	EnabledAt  time.Time `json:"-"  pg:"enabled_at"`
 	DisabledAt time.Time `json:"-"  pg:"disabled_at"`
+	ParentID   string    `json:"-"  pg:"parent_id"`
+	Name       string    `json:"-"  pg:"name"`
+	ClosedAt   time.Time `json:"-"  pg:"closed_at"`
+	FrozenAt   time.Time `json:"-"  pg:"frozen_at"`
+	// EnabledBeforeFreeze is the state unfreezing restores
+	EnabledBeforeFreeze bool      `json:"-"  pg:"enabled_before_freeze,use_zero"`
+	CreatedAt           time.Time `json:"-"  pg:"created_at"`
 }
@@ -0,0 +1,13 @@
+package entity
+
+// WalletLimit overrides the tier limits of a wallet for one transaction type
+type WalletLimit struct {
+	tableName     struct{} `pg:"wallet_limit"`
+	WalletID      string   `json:"wallet_id" pg:"wallet_id,pk"`
+	Type          string   `json:"type"      pg:"type,pk"`
+	MaxAmount     float64  `json:"-"         pg:"max_amount"`
+	DailyAmount   float64  `json:"-"         pg:"daily_amount"`
+	DailyCount    int      `json:"-"         pg:"daily_count"`
+	MonthlyAmount float64  `json:"-"         pg:"monthly_amount"`
+	MonthlyCount  int      `json:"-"         pg:"monthly_count"`
+}
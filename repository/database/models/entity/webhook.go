@@ -0,0 +1,59 @@
+package entity
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// WebhookEndpoint receives the events matching EventTypes, an empty list matches every event
+type WebhookEndpoint struct {
+	tableName   struct{}  `pg:"webhook_endpoint"`
+	ID          string    `json:"id"           pg:"id,pk"`
+	PartnerID   string    `json:"partner_id"   pg:"partner_id"`
+	URL         string    `json:"url"          pg:"url"`
+	Secret      string    `json:"-"            pg:"secret"`
+	EventTypes  []string  `json:"event_types"  pg:"event_types,array"`
+	CustomerXId string    `json:"customer_xid" pg:"customer_xid"`
+	IsEnabled   bool      `json:"is_enabled"   pg:"is_enabled,use_zero"`
+	CreatedBy   string    `json:"created_by"   pg:"created_by"`
+	CreatedAt   time.Time `json:"created_at"   pg:"created_at"`
+	DisabledAt  time.Time `json:"disabled_at"  pg:"disabled_at"`
+}
+
+// WebhookPartnerCustomer lets a partner receive the events of a customer
+type WebhookPartnerCustomer struct {
+	tableName   struct{}  `pg:"webhook_partner_customer"`
+	PartnerID   string    `json:"partner_id"   pg:"partner_id,pk"`
+	CustomerXId string    `json:"customer_xid" pg:"customer_xid,pk"`
+	CreatedBy   string    `json:"created_by"   pg:"created_by"`
+	CreatedAt   time.Time `json:"created_at"   pg:"created_at"`
+}
+
+type WebhookDelivery struct {
+	tableName        struct{}          `pg:"webhook_delivery"`
+	ID               string            `json:"id"                 pg:"id,pk"`
+	EndpointID       string            `json:"endpoint_id"        pg:"endpoint_id"`
+	Endpoint         *WebhookEndpoint  `json:"-"                  pg:"rel:has-one"`
+	EventID          string            `json:"event_id"           pg:"event_id"`
+	EventType        string            `json:"event_type"         pg:"event_type"`
+	Payload          json.RawMessage   `json:"payload"            pg:"payload,type:jsonb"`
+	Status           string            `json:"status"             pg:"status"`
+	Attempts         int               `json:"attempts"           pg:"attempts,use_zero"`
+	LastResponseCode int               `json:"last_response_code" pg:"last_response_code"`
+	LastError        string            `json:"last_error"         pg:"last_error"`
+	CreatedAt        time.Time         `json:"created_at"         pg:"created_at"`
+	DeliveredAt      time.Time         `json:"delivered_at"       pg:"delivered_at"`
+	AttemptLogs      []*WebhookAttempt `json:"attempt_logs"       pg:"rel:has-many,join_fk:delivery_id"`
+}
+
+// WebhookAttempt is one delivery try, ResponseCode is zero when no response was received
+type WebhookAttempt struct {
+	tableName    struct{}  `pg:"webhook_attempt"`
+	ID           string    `json:"id"            pg:"id,pk"`
+	DeliveryID   string    `json:"-"             pg:"delivery_id"`
+	Attempt      int       `json:"attempt"       pg:"attempt"`
+	ResponseCode int       `json:"response_code" pg:"response_code"`
+	Error        string    `json:"error"         pg:"error"`
+	DurationMs   int64     `json:"duration_ms"   pg:"duration_ms,use_zero"`
+	CreatedAt    time.Time `json:"created_at"    pg:"created_at"`
+}
@@ -0,0 +1,17 @@
+package models
+
+import "errors"
+
+var (
+	ErrQuoteNotFound = errors.New("conversion quote not found")
+	ErrQuoteUsed     = errors.New("conversion quote has already been used")
+	ErrQuoteExpired  = errors.New("conversion quote has expired")
+	ErrRateNotFound  = errors.New("fx rate not found")
+)
+
+// ParamConvert executes a quote, Limit is checked against the target wallet when it is credited
+type ParamConvert struct {
+	CustomerXId string
+	QuoteID     string
+	Limit       *LimitCheck
+}
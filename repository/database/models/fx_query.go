@@ -0,0 +1,244 @@
+package models
+
+import (
+	"context"
+	"time"
+
+	"github.com/ahmadmirdas/julo-test/repository/database/models/entity"
+	"github.com/go-pg/pg/v10"
+)
+
+type FxDBRepo interface {
+	UpsertRates(rates []entity.FxRate) error
+	GetRates() ([]entity.FxRate, error)
+	GetRate(from, to string) (float64, error)
+	GetQuote(customerXId, quoteID string) (*entity.FxQuote, error)
+	CreateQuote(quote *entity.FxQuote) error
+	Convert(param ParamConvert) (*entity.Conversion, error)
+}
+
+type dbFxRepo struct {
+	dbConn *pg.DB
+}
+
+func NewDBFxRepo(c *pg.DB) FxDBRepo {
+	return &dbFxRepo{dbConn: c}
+}
+
+func (p *dbFxRepo) UpsertRates(rates []entity.FxRate) error {
+	if len(rates) == 0 {
+		return nil
+	}
+
+	return p.dbConn.RunInTransaction(context.Background(), func(tx *pg.Tx) error {
+		_, err := tx.Model(&rates).
+			OnConflict("(base_currency, quote_currency) DO UPDATE").
+			Set("rate = EXCLUDED.rate").
+			Set("updated_by = EXCLUDED.updated_by").
+			Set("updated_at = EXCLUDED.updated_at").
+			Insert()
+		if err != nil {
+			return err
+		}
+
+		return recordEvent(tx, EventFxRatesUpdated, AggregateFx, "rates", "", rates)
+	})
+}
+
+func (p *dbFxRepo) GetRates() ([]entity.FxRate, error) {
+	var rates []entity.FxRate
+	err := p.dbConn.Model(&rates).
+		Order("base_currency", "quote_currency").
+		Select()
+	if err != nil {
+		return nil, err
+	}
+
+	return rates, nil
+}
+
+// GetRate returns the market rate of from in to, using the inverse pair when only that one is stored
+func (p *dbFxRepo) GetRate(from, to string) (float64, error) {
+	var rates []entity.FxRate
+	err := p.dbConn.Model(&rates).
+		WhereOr("base_currency = ? AND quote_currency = ?", from, to).
+		WhereOr("base_currency = ? AND quote_currency = ?", to, from).
+		Select()
+	if err != nil {
+		return 0, err
+	}
+
+	for _, rate := range rates {
+		if rate.BaseCurrency == from {
+			return rate.Rate, nil
+		}
+	}
+	if len(rates) > 0 {
+		return 1 / rates[0].Rate, nil
+	}
+
+	return 0, ErrRateNotFound
+}
+
+// GetQuote returns a quote of the customer, used or not
+func (p *dbFxRepo) GetQuote(customerXId, quoteID string) (*entity.FxQuote, error) {
+	var quote entity.FxQuote
+	err := p.dbConn.Model(&quote).
+		Where("id = ?", quoteID).
+		Where("customer_xid = ?", customerXId).
+		Select()
+	if err == pg.ErrNoRows {
+		return nil, ErrQuoteNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &quote, nil
+}
+
+func (p *dbFxRepo) CreateQuote(quote *entity.FxQuote) error {
+	return p.dbConn.RunInTransaction(context.Background(), func(tx *pg.Tx) error {
+		_, err := tx.Model(quote).Insert()
+		if err != nil {
+			return err
+		}
+
+		return recordEvent(tx, EventFxQuoteCreated, AggregateFx, quote.ID, quote.CustomerXId, FxQuoteEventData{
+			QuoteID:      quote.ID,
+			CustomerXId:  quote.CustomerXId,
+			FromCurrency: quote.FromCurrency,
+			ToCurrency:   quote.ToCurrency,
+			Rate:         quote.Rate,
+			FromAmount:   quote.FromAmount,
+			ToAmount:     quote.ToAmount,
+			ExpiresAt:    quote.ExpiresAt,
+		})
+	})
+}
+
+// Convert executes a quote by debiting the source wallet and crediting the target wallet atomically,
+// after running the limit check of the target wallet against its usage within the transaction
+func (p *dbFxRepo) Convert(param ParamConvert) (*entity.Conversion, error) {
+	var conversion entity.Conversion
+	err := p.dbConn.RunInTransaction(context.Background(), func(tx *pg.Tx) error {
+		var quote entity.FxQuote
+		err := tx.Model(&quote).
+			Where("id = ?", param.QuoteID).
+			Where("customer_xid = ?", param.CustomerXId).
+			For("UPDATE").
+			Select()
+		if err == pg.ErrNoRows {
+			return ErrQuoteNotFound
+		}
+		if err != nil {
+			return err
+		}
+		if !quote.UsedAt.IsZero() {
+			return ErrQuoteUsed
+		}
+		if time.Now().After(quote.ExpiresAt) {
+			return ErrQuoteExpired
+		}
+
+		// wallets are always locked in currency order so opposite conversions cannot deadlock
+		first, second := quote.FromCurrency, quote.ToCurrency
+		if first > second {
+			first, second = second, first
+		}
+		wallets := make(map[string]*entity.Wallet, 2)
+		for _, currency := range []string{first, second} {
+			wallets[currency], err = lockWallet(tx, param.CustomerXId, currency)
+			if err != nil {
+				return err
+			}
+		}
+		from, to := wallets[quote.FromCurrency], wallets[quote.ToCurrency]
+		if from.Balance < quote.FromAmount {
+			return ErrInsufficientFund
+		}
+		if err := checkLimit(tx, param.CustomerXId, quote.ToCurrency, param.Limit); err != nil {
+			return err
+		}
+
+		_, err = tx.Model(from).
+			Set("balance = balance - ?", quote.FromAmount).
+			WherePK().
+			Update()
+		if err != nil {
+			return err
+		}
+		_, err = tx.Model(to).
+			Set("balance = balance + ?", quote.ToAmount).
+			WherePK().
+			Update()
+		if err != nil {
+			return err
+		}
+
+		conversion = entity.Conversion{
+			QuoteID:      quote.ID,
+			FromWalletID: from.ID,
+			ToWalletID:   to.ID,
+			FromAmount:   quote.FromAmount,
+			ToAmount:     quote.ToAmount,
+			Rate:         quote.Rate,
+		}
+		_, err = tx.Model(&conversion).Insert()
+		if err != nil {
+			return err
+		}
+
+		histories := []entity.History{
+			{
+				WalletID:    from.ID,
+				Status:      HistoryStatusSuccess,
+				Type:        HistoryTypeConversionOut,
+				Amount:      quote.FromAmount,
+				Currency:    quote.FromCurrency,
+				ReferenceID: conversion.ID,
+			},
+			{
+				WalletID:    to.ID,
+				Status:      HistoryStatusSuccess,
+				Type:        HistoryTypeConversionIn,
+				Amount:      quote.ToAmount,
+				Currency:    quote.ToCurrency,
+				ReferenceID: conversion.ID,
+			},
+		}
+		_, err = tx.Model(&histories).Insert()
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.Model(&quote).
+			Set("used_at = ?", time.Now()).
+			WherePK().
+			Update()
+		if err != nil {
+			return err
+		}
+
+		return recordTransactionEvent(tx, EventWalletConverted, TransactionEventData{
+			TransactionID:       histories[0].ID,
+			WalletID:            from.ID,
+			CustomerXId:         param.CustomerXId,
+			Type:                HistoryTypeConversionOut,
+			Amount:              quote.FromAmount,
+			Currency:            quote.FromCurrency,
+			ReferenceID:         conversion.ID,
+			Balance:             from.Balance - quote.FromAmount,
+			CounterpartWalletID: to.ID,
+			CounterpartBalance:  to.Balance + quote.ToAmount,
+			CounterpartAmount:   quote.ToAmount,
+			CounterpartCurrency: quote.ToCurrency,
+			Rate:                quote.Rate,
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &conversion, nil
+}
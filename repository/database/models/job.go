@@ -0,0 +1,32 @@
+package models
+
+import "time"
+
+var (
+	JobStatusPending string = "pending"
+	JobStatusRunning string = "running"
+	JobStatusDone    string = "done"
+	JobStatusDead    string = "dead"
+)
+
+// JobErrLockExpired is the last error of a job whose lock expired on its last attempt
+var JobErrLockExpired string = "lock expired on the last attempt, the worker stopped before finishing it"
+
+// ParamEnqueueJob adds a job of Type, Payload is marshalled to json. A job with the UniqueKey of
+// an existing job is not enqueued again.
+type ParamEnqueueJob struct {
+	Type        string
+	Payload     interface{}
+	RunAt       time.Time
+	MaxAttempts int
+	UniqueKey   string
+}
+
+// ParamFailJob records a failed attempt, the job goes back to pending at RetryAt or to dead
+// once its attempts are exhausted
+type ParamFailJob struct {
+	JobID    string
+	Attempts int
+	Error    string
+	RetryAt  time.Time
+}
@@ -0,0 +1,211 @@
+package models
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/ahmadmirdas/julo-test/repository/database/models/entity"
+	"github.com/go-pg/pg/v10"
+	"github.com/go-pg/pg/v10/orm"
+)
+
+type JobDBRepo interface {
+	EnqueueJob(ctx context.Context, param ParamEnqueueJob) (*entity.Job, error)
+	ClaimJob(ctx context.Context, types []string, visibility time.Duration) (*entity.Job, error)
+	BuryExpiredJobs(ctx context.Context, types []string) ([]entity.Job, error)
+	ExtendJob(ctx context.Context, jobID string, attempts int, visibility time.Duration) error
+	CompleteJob(ctx context.Context, jobID string, attempts int) error
+	FailJob(ctx context.Context, param ParamFailJob) (*entity.Job, error)
+	PurgeJobs(ctx context.Context, before time.Time) (int, error)
+}
+
+type dbJobRepo struct {
+	dbConn *pg.DB
+}
+
+func NewDBJobRepo(c *pg.DB) JobDBRepo {
+	return &dbJobRepo{dbConn: c}
+}
+
+// EnqueueJob returns nil without error when a job with the same unique key already exists
+func (p *dbJobRepo) EnqueueJob(ctx context.Context, param ParamEnqueueJob) (*entity.Job, error) {
+	return enqueueJob(ctx, p.dbConn, param)
+}
+
+// enqueueJob inserts a job with db, a transaction enqueues it only when the transaction commits
+func enqueueJob(ctx context.Context, db orm.DB, param ParamEnqueueJob) (*entity.Job, error) {
+	payload, err := json.Marshal(param.Payload)
+	if err != nil {
+		return nil, err
+	}
+
+	job := entity.Job{
+		Type:        param.Type,
+		Payload:     payload,
+		Status:      JobStatusPending,
+		MaxAttempts: param.MaxAttempts,
+		RunAt:       param.RunAt,
+		UniqueKey:   param.UniqueKey,
+	}
+	q := db.ModelContext(ctx, &job)
+	if param.UniqueKey != "" {
+		q = q.OnConflict("(unique_key) DO NOTHING")
+	}
+	res, err := q.Insert()
+	if err != nil {
+		return nil, err
+	}
+	if res.RowsAffected() == 0 {
+		return nil, nil
+	}
+
+	return &job, nil
+}
+
+// ClaimJob locks the oldest runnable job of types for visibility, a running job whose lock has
+// expired is claimed again while it has attempts left, see BuryExpiredJobs for the others. It returns
+// nil when there is nothing to run.
+func (p *dbJobRepo) ClaimJob(ctx context.Context, types []string, visibility time.Duration) (*entity.Job, error) {
+	if len(types) == 0 {
+		return nil, nil
+	}
+
+	var job entity.Job
+	_, err := p.dbConn.QueryOneContext(ctx, &job, `
+		UPDATE jobs SET
+			status = ?,
+			attempts = attempts + 1,
+			locked_until = NOW() + ? * INTERVAL '1 millisecond',
+			updated_at = NOW()
+		WHERE id = (
+			SELECT id FROM jobs
+			WHERE type IN (?)
+			AND ((status = ? AND run_at <= NOW()) OR (status = ? AND locked_until < NOW()))
+			AND attempts < max_attempts
+			ORDER BY run_at
+			LIMIT 1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING *`,
+		JobStatusRunning, visibility.Milliseconds(), pg.In(types), JobStatusPending, JobStatusRunning)
+	if err == pg.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &job, nil
+}
+
+// BuryExpiredJobs marks dead the running jobs of types whose lock expired on their last attempt, their
+// worker stopped without completing or failing them. The pending webhook delivery of a buried job is
+// marked dead in the same transaction so it can be replayed. It returns the jobs marked dead.
+func (p *dbJobRepo) BuryExpiredJobs(ctx context.Context, types []string) ([]entity.Job, error) {
+	if len(types) == 0 {
+		return nil, nil
+	}
+
+	var jobs []entity.Job
+	err := p.dbConn.RunInTransaction(ctx, func(tx *pg.Tx) error {
+		_, err := tx.Query(&jobs, `
+			UPDATE jobs SET
+				status = ?,
+				locked_until = NULL,
+				last_error = ?,
+				updated_at = NOW()
+			WHERE type IN (?)
+			AND status = ? AND locked_until < NOW()
+			AND attempts >= max_attempts
+			RETURNING *`,
+			JobStatusDead, JobErrLockExpired, pg.In(types), JobStatusRunning)
+		if err != nil {
+			return err
+		}
+
+		var deliveryIDs []string
+		for _, job := range jobs {
+			var payload WebhookJobPayload
+			if json.Unmarshal(job.Payload, &payload) == nil && payload.DeliveryID != "" {
+				deliveryIDs = append(deliveryIDs, payload.DeliveryID)
+			}
+		}
+		if len(deliveryIDs) == 0 {
+			return nil
+		}
+		_, err = tx.Model((*entity.WebhookDelivery)(nil)).
+			Set("status = ?", WebhookStatusDead).
+			Set("last_error = ?", JobErrLockExpired).
+			Where("id IN (?)", pg.In(deliveryIDs)).
+			Where("status = ?", WebhookStatusPending).
+			Update()
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return jobs, nil
+}
+
+// ExtendJob pushes the lock of a running job further while its handler is still working
+func (p *dbJobRepo) ExtendJob(ctx context.Context, jobID string, attempts int, visibility time.Duration) error {
+	_, err := p.dbConn.ModelContext(ctx, (*entity.Job)(nil)).
+		Set("locked_until = NOW() + ? * INTERVAL '1 millisecond'", visibility.Milliseconds()).
+		Where("id = ?", jobID).
+		Where("attempts = ?", attempts).
+		Where("status = ?", JobStatusRunning).
+		Update()
+	return err
+}
+
+// CompleteJob marks the attempt done, an attempt whose job was claimed again by another worker is ignored
+func (p *dbJobRepo) CompleteJob(ctx context.Context, jobID string, attempts int) error {
+	_, err := p.dbConn.ModelContext(ctx, (*entity.Job)(nil)).
+		Set("status = ?", JobStatusDone).
+		Set("locked_until = NULL").
+		Set("last_error = NULL").
+		Set("updated_at = NOW()").
+		Where("id = ?", jobID).
+		Where("attempts = ?", attempts).
+		Where("status = ?", JobStatusRunning).
+		Update()
+	return err
+}
+
+func (p *dbJobRepo) FailJob(ctx context.Context, param ParamFailJob) (*entity.Job, error) {
+	var job entity.Job
+	res, err := p.dbConn.ModelContext(ctx, &job).
+		Set("status = CASE WHEN attempts >= max_attempts THEN ? ELSE ? END", JobStatusDead, JobStatusPending).
+		Set("run_at = ?", param.RetryAt).
+		Set("locked_until = NULL").
+		Set("last_error = ?", param.Error).
+		Set("updated_at = NOW()").
+		Where("id = ?", param.JobID).
+		Where("attempts = ?", param.Attempts).
+		Where("status = ?", JobStatusRunning).
+		Returning("*").
+		Update()
+	if err != nil {
+		return nil, err
+	}
+	if res.RowsAffected() == 0 {
+		return nil, nil
+	}
+
+	return &job, nil
+}
+
+// PurgeJobs deletes jobs done before the given time, dead jobs are kept for inspection
+func (p *dbJobRepo) PurgeJobs(ctx context.Context, before time.Time) (int, error) {
+	res, err := p.dbConn.ModelContext(ctx, (*entity.Job)(nil)).
+		Where("status = ?", JobStatusDone).
+		Where("updated_at < ?", before).
+		Delete()
+	if err != nil {
+		return 0, err
+	}
+
+	return res.RowsAffected(), nil
+}
@@ -0,0 +1,211 @@
+//go:build integration
+
+package models
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ahmadmirdas/julo-test/repository/database/models/entity"
+	"github.com/google/uuid"
+)
+
+func testJobRepo(t *testing.T) (*dbJobRepo, string) {
+	t.Helper()
+	// a type of its own keeps the jobs of other tests and of a running server out of the claims
+	return &dbJobRepo{dbConn: testDB(t)}, "test." + uuid.New().String()
+}
+
+func enqueueTestJob(t *testing.T, repo *dbJobRepo, jobType string, maxAttempts int) *entity.Job {
+	t.Helper()
+	job, err := repo.EnqueueJob(context.Background(), ParamEnqueueJob{Type: jobType, RunAt: time.Now().Add(-time.Second), MaxAttempts: maxAttempts})
+	if err != nil {
+		t.Fatalf("enqueue: %v", err)
+	}
+	return job
+}
+
+func claimTestJob(t *testing.T, repo *dbJobRepo, jobType string, visibility time.Duration) *entity.Job {
+	t.Helper()
+	job, err := repo.ClaimJob(context.Background(), []string{jobType}, visibility)
+	if err != nil {
+		t.Fatalf("claim: %v", err)
+	}
+	return job
+}
+
+func TestClaimJob(t *testing.T) {
+	repo, jobType := testJobRepo(t)
+	enqueued := enqueueTestJob(t, repo, jobType, 2)
+
+	job := claimTestJob(t, repo, jobType, time.Minute)
+	if job == nil || job.ID != enqueued.ID {
+		t.Fatalf("claimed %+v, want job %s", job, enqueued.ID)
+	}
+	if job.Status != JobStatusRunning || job.Attempts != 1 {
+		t.Errorf("claimed job is %s on attempt %d, want running on attempt 1", job.Status, job.Attempts)
+	}
+	if again := claimTestJob(t, repo, jobType, time.Minute); again != nil {
+		t.Errorf("a locked job was claimed again: %+v", again)
+	}
+}
+
+func TestClaimJobAfterVisibilityTimeout(t *testing.T) {
+	tests := []struct {
+		name        string
+		maxAttempts int
+		reclaimed   bool
+	}{
+		{name: "attempts left", maxAttempts: 2, reclaimed: true},
+		{name: "last attempt", maxAttempts: 1, reclaimed: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo, jobType := testJobRepo(t)
+			enqueued := enqueueTestJob(t, repo, jobType, tt.maxAttempts)
+			if job := claimTestJob(t, repo, jobType, time.Millisecond); job == nil {
+				t.Fatal("nothing claimed")
+			}
+			time.Sleep(10 * time.Millisecond)
+
+			job := claimTestJob(t, repo, jobType, time.Minute)
+			if tt.reclaimed != (job != nil) {
+				t.Fatalf("reclaimed %+v, want reclaimed = %v", job, tt.reclaimed)
+			}
+			if job != nil && job.Attempts != 2 {
+				t.Errorf("reclaimed on attempt %d, want 2", job.Attempts)
+			}
+
+			buried, err := repo.BuryExpiredJobs(context.Background(), []string{jobType})
+			if err != nil {
+				t.Fatalf("bury: %v", err)
+			}
+			if tt.reclaimed {
+				if len(buried) != 0 {
+					t.Errorf("buried %+v, want none", buried)
+				}
+				return
+			}
+			if len(buried) != 1 || buried[0].ID != enqueued.ID || buried[0].Status != JobStatusDead {
+				t.Fatalf("buried %+v, want job %s dead", buried, enqueued.ID)
+			}
+			if buried[0].Attempts != 1 || buried[0].LastError != JobErrLockExpired {
+				t.Errorf("buried job has %d attempts and error %q", buried[0].Attempts, buried[0].LastError)
+			}
+		})
+	}
+}
+
+func TestBuryExpiredWebhookJob(t *testing.T) {
+	ctx := context.Background()
+	repo, jobType := testJobRepo(t)
+	webhookRepo := &dbWebhookRepo{dbConn: repo.dbConn}
+
+	partnerID, customerXId := uuid.New().String(), uuid.New().String()
+	if _, err := webhookRepo.LinkCustomer(ctx, ParamLinkCustomer{PartnerID: partnerID, CustomerXId: customerXId, CreatedBy: "test"}); err != nil {
+		t.Fatalf("link customer: %v", err)
+	}
+	endpoint, err := webhookRepo.CreateEndpoint(ctx, ParamCreateWebhook{PartnerID: partnerID, URL: "https://partner.test/hook", Secret: "secret", CreatedBy: "test"})
+	if err != nil {
+		t.Fatalf("create endpoint: %v", err)
+	}
+	_, err = webhookRepo.DispatchEvent(ctx, ParamDispatchEvent{
+		EventID:     uuid.New().String(),
+		EventType:   EventWalletDeposited,
+		CustomerXId: customerXId,
+		Payload:     []byte(`{}`),
+		JobType:     jobType,
+		MaxAttempts: 1,
+	})
+	if err != nil {
+		t.Fatalf("dispatch: %v", err)
+	}
+
+	if job := claimTestJob(t, repo, jobType, time.Millisecond); job == nil {
+		t.Fatal("nothing claimed")
+	}
+	time.Sleep(10 * time.Millisecond)
+	if buried, err := repo.BuryExpiredJobs(ctx, []string{jobType}); err != nil || len(buried) != 1 {
+		t.Fatalf("bury = %+v, %v, want one job", buried, err)
+	}
+
+	deliveries, err := webhookRepo.GetDeliveries(ctx, ParamListDeliveries{EndpointID: endpoint.ID, Limit: 10})
+	if err != nil {
+		t.Fatalf("deliveries: %v", err)
+	}
+	if len(deliveries) != 1 || deliveries[0].Status != WebhookStatusDead {
+		t.Fatalf("deliveries %+v, want one dead", deliveries)
+	}
+	if _, err := webhookRepo.ReplayDelivery(ctx, ParamReplayDelivery{DeliveryID: deliveries[0].ID, JobType: jobType, MaxAttempts: 1}); err != nil {
+		t.Errorf("replay of the buried delivery: %v", err)
+	}
+}
+
+func TestExtendJob(t *testing.T) {
+	repo, jobType := testJobRepo(t)
+	enqueueTestJob(t, repo, jobType, 2)
+	job := claimTestJob(t, repo, jobType, 20*time.Millisecond)
+	if job == nil {
+		t.Fatal("nothing claimed")
+	}
+
+	if err := repo.ExtendJob(context.Background(), job.ID, job.Attempts, time.Minute); err != nil {
+		t.Fatalf("extend: %v", err)
+	}
+	time.Sleep(40 * time.Millisecond)
+	if again := claimTestJob(t, repo, jobType, time.Minute); again != nil {
+		t.Errorf("an extended job was claimed again: %+v", again)
+	}
+	// a stale attempt does not extend the lock of the current one
+	if err := repo.ExtendJob(context.Background(), job.ID, job.Attempts-1, time.Hour); err != nil {
+		t.Fatalf("extend stale attempt: %v", err)
+	}
+	var current entity.Job
+	if err := repo.dbConn.Model(&current).Where("id = ?", job.ID).Select(); err != nil {
+		t.Fatalf("select: %v", err)
+	}
+	if current.LockedUntil.After(time.Now().Add(2 * time.Minute)) {
+		t.Errorf("stale attempt extended the lock to %s", current.LockedUntil)
+	}
+}
+
+func TestFailJob(t *testing.T) {
+	tests := []struct {
+		name        string
+		maxAttempts int
+		status      string
+	}{
+		{name: "retried", maxAttempts: 2, status: JobStatusPending},
+		{name: "dead letter", maxAttempts: 1, status: JobStatusDead},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo, jobType := testJobRepo(t)
+			enqueueTestJob(t, repo, jobType, tt.maxAttempts)
+			job := claimTestJob(t, repo, jobType, time.Minute)
+			if job == nil {
+				t.Fatal("nothing claimed")
+			}
+
+			retryAt := time.Now().Add(-time.Second)
+			failed, err := repo.FailJob(context.Background(), ParamFailJob{JobID: job.ID, Attempts: job.Attempts, Error: "upstream down", RetryAt: retryAt})
+			if err != nil {
+				t.Fatalf("fail: %v", err)
+			}
+			if failed == nil || failed.Status != tt.status || failed.LastError != "upstream down" {
+				t.Fatalf("failed job %+v, want %s with the error", failed, tt.status)
+			}
+
+			retried := claimTestJob(t, repo, jobType, time.Minute)
+			if (tt.status == JobStatusPending) != (retried != nil) {
+				t.Errorf("claimed %+v after a %s failure", retried, tt.status)
+			}
+			// the attempt is over, failing it again is ignored
+			again, err := repo.FailJob(context.Background(), ParamFailJob{JobID: job.ID, Attempts: job.Attempts, Error: "again", RetryAt: retryAt})
+			if err != nil || again != nil {
+				t.Errorf("failing a finished attempt returned %+v, %v", again, err)
+			}
+		})
+	}
+}
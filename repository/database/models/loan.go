@@ -0,0 +1,53 @@
+package models
+
+import (
+	"errors"
+	"time"
+
+	"github.com/ahmadmirdas/julo-test/utils/currency"
+)
+
+var (
+	LoanStatusActive  string = "active"
+	LoanStatusPaidOff string = "paid_off"
+
+	InstallmentStatusScheduled string = "scheduled"
+	InstallmentStatusPartial   string = "partial"
+	InstallmentStatusOverdue   string = "overdue"
+	InstallmentStatusPaid      string = "paid"
+)
+
+var (
+	ErrLoanNotFound          = errors.New("loan not found")
+	ErrLoanReferenceConflict = errors.New("reference_id is already used by another loan")
+)
+
+type ParamInstallment struct {
+	DueDate time.Time
+	Amount  float64
+}
+
+type ParamDisburseLoan struct {
+	CustomerXId  string
+	Currency     string
+	Principal    float64
+	ReferenceID  string
+	Installments []ParamInstallment
+}
+
+// ParamCollectInstallment auto-debits an installment, it becomes overdue when unpaid after OverdueAfter.
+// An installment left unpaid is not picked up again before RetryAt.
+type ParamCollectInstallment struct {
+	InstallmentID string
+	OverdueAfter  time.Time
+	RetryAt       time.Time
+}
+
+// PenaltyFunc returns the penalty of one overdue day from the unpaid installment amount and the penalty accrued so far
+type PenaltyFunc func(cur currency.Currency, unpaid, accrued float64) float64
+
+type ParamAccruePenalty struct {
+	InstallmentID string
+	AccrualDate   time.Time
+	Calculate     PenaltyFunc
+}
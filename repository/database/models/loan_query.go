@@ -0,0 +1,436 @@
+package models
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/ahmadmirdas/julo-test/repository/database/models/entity"
+	"github.com/ahmadmirdas/julo-test/utils/currency"
+	"github.com/ahmadmirdas/julo-test/utils/penalty"
+	"github.com/go-pg/pg/v10"
+)
+
+type LoanDBRepo interface {
+	DisburseLoan(ctx context.Context, param ParamDisburseLoan) (*entity.Loan, error)
+	GetLoan(ctx context.Context, loanID string) (*entity.Loan, error)
+	GetLoans(ctx context.Context, customerXId string) ([]entity.Loan, error)
+	GetDueInstallmentIDs(ctx context.Context, asOf time.Time, limit int) ([]string, error)
+	CollectInstallment(ctx context.Context, param ParamCollectInstallment) (*entity.LoanInstallment, error)
+	DeferInstallment(ctx context.Context, installmentID string, until time.Time) error
+	GetOverdueInstallmentIDs(ctx context.Context, overdueAfter, accrualDate time.Time, rules []penalty.Rule, limit int) ([]string, error)
+	AccruePenalty(ctx context.Context, param ParamAccruePenalty) (*entity.LoanPenalty, error)
+}
+
+type dbLoanRepo struct {
+	dbConn *pg.DB
+}
+
+func NewDBLoanRepo(c *pg.DB) LoanDBRepo {
+	return &dbLoanRepo{dbConn: c}
+}
+
+// DisburseLoan stores the loan with its repayment schedule and credits the principal to the main wallet.
+// A retry with the reference_id of a disbursed loan returns that loan without crediting it again.
+func (p *dbLoanRepo) DisburseLoan(ctx context.Context, param ParamDisburseLoan) (*entity.Loan, error) {
+	var loan entity.Loan
+	err := p.dbConn.RunInTransaction(ctx, func(tx *pg.Tx) error {
+		wallet, err := lockWallet(tx, param.CustomerXId, param.Currency)
+		if err != nil {
+			return err
+		}
+
+		// the wallet lock serializes disbursements of the customer, a retry finds the loan of the first request
+		err = tx.Model(&loan).
+			Relation("Installments", func(q *pg.Query) (*pg.Query, error) {
+				return q.Order("sequence"), nil
+			}).
+			Where("loan.reference_id = ?", param.ReferenceID).
+			Select()
+		if err == nil {
+			if loan.CustomerXId != param.CustomerXId || loan.Currency != wallet.Currency || loan.Principal != param.Principal {
+				return ErrLoanReferenceConflict
+			}
+			return nil
+		}
+		if err != pg.ErrNoRows {
+			return err
+		}
+
+		loan = entity.Loan{
+			CustomerXId: param.CustomerXId,
+			WalletID:    wallet.ID,
+			Principal:   param.Principal,
+			Currency:    wallet.Currency,
+			Status:      LoanStatusActive,
+			ReferenceID: param.ReferenceID,
+		}
+		_, err = tx.Model(&loan).Insert()
+		if pgErr, ok := err.(pg.Error); ok && pgErr.IntegrityViolation() {
+			return ErrLoanReferenceConflict
+		}
+		if err != nil {
+			return err
+		}
+
+		for i, installment := range param.Installments {
+			loan.Installments = append(loan.Installments, &entity.LoanInstallment{
+				LoanID:   loan.ID,
+				Sequence: i + 1,
+				DueDate:  installment.DueDate,
+				Amount:   installment.Amount,
+				Status:   InstallmentStatusScheduled,
+			})
+		}
+		_, err = tx.Model(&loan.Installments).Insert()
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.Model(wallet).
+			Set("balance = balance + ?", param.Principal).
+			WherePK().
+			Update()
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.Model(&entity.History{
+			WalletID:    wallet.ID,
+			Status:      HistoryStatusSuccess,
+			Type:        HistoryTypeLoanDisbursement,
+			Amount:      param.Principal,
+			Currency:    wallet.Currency,
+			ReferenceID: loan.ReferenceID,
+		}).Insert()
+		if err != nil {
+			return err
+		}
+
+		return recordEvent(tx, EventLoanDisbursed, AggregateLoan, loan.ID, loan.CustomerXId, LoanEventData{
+			LoanID:       loan.ID,
+			CustomerXId:  loan.CustomerXId,
+			WalletID:     wallet.ID,
+			Currency:     loan.Currency,
+			Principal:    loan.Principal,
+			Installments: len(loan.Installments),
+			Status:       loan.Status,
+			Balance:      wallet.Balance + param.Principal,
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &loan, nil
+}
+
+func (p *dbLoanRepo) GetLoan(ctx context.Context, loanID string) (*entity.Loan, error) {
+	var loan entity.Loan
+	err := p.dbConn.ModelContext(ctx, &loan).
+		Relation("Installments", func(q *pg.Query) (*pg.Query, error) {
+			return q.Order("sequence"), nil
+		}).
+		Where("loan.id = ?", loanID).
+		Select()
+	if err == pg.ErrNoRows {
+		return nil, ErrLoanNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &loan, nil
+}
+
+func (p *dbLoanRepo) GetLoans(ctx context.Context, customerXId string) ([]entity.Loan, error) {
+	var loans []entity.Loan
+	err := p.dbConn.ModelContext(ctx, &loans).
+		Relation("Installments", func(q *pg.Query) (*pg.Query, error) {
+			return q.Order("sequence"), nil
+		}).
+		Where("loan.customer_xid = ?", customerXId).
+		Order("loan.disbursed_at DESC").
+		Select()
+	if err != nil {
+		return nil, err
+	}
+
+	return loans, nil
+}
+
+// GetDueInstallmentIDs returns unpaid installments due on or before asOf that are ready for another attempt,
+// installments waiting the longest come first so ones that keep failing do not hold back the rest
+func (p *dbLoanRepo) GetDueInstallmentIDs(ctx context.Context, asOf time.Time, limit int) ([]string, error) {
+	var ids []string
+	err := p.dbConn.ModelContext(ctx, (*entity.LoanInstallment)(nil)).
+		Column("id").
+		Where("status <> ?", InstallmentStatusPaid).
+		Where("due_date <= ?", asOf.Format("2006-01-02")).
+		Where("next_attempt_at <= NOW()").
+		Order("next_attempt_at", "due_date", "sequence").
+		Limit(limit).
+		Select(&ids)
+	if err != nil {
+		return nil, err
+	}
+
+	return ids, nil
+}
+
+// CollectInstallment debits as much of the outstanding installment as the wallet balance allows.
+// Installments locked by another collector are skipped and returned as nil.
+func (p *dbLoanRepo) CollectInstallment(ctx context.Context, param ParamCollectInstallment) (*entity.LoanInstallment, error) {
+	var installment entity.LoanInstallment
+	skipped := false
+	err := p.dbConn.RunInTransaction(ctx, func(tx *pg.Tx) error {
+		err := tx.Model(&installment).
+			Relation("Loan").
+			Where("loan_installment.id = ?", param.InstallmentID).
+			Where("loan_installment.status <> ?", InstallmentStatusPaid).
+			For("UPDATE OF loan_installment SKIP LOCKED").
+			Select()
+		if err == pg.ErrNoRows {
+			skipped = true
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		var wallet entity.Wallet
+		err = tx.Model(&wallet).
+			Where("id = ?", installment.Loan.WalletID).
+			For("UPDATE").
+			Select()
+		if err != nil {
+			return err
+		}
+
+		cur, _ := currency.Get(installment.Loan.Currency)
+		outstanding := cur.Round(installment.Outstanding())
+		debit := outstanding
+		if wallet.Balance < debit {
+			debit = cur.Floor(wallet.Balance)
+		}
+		if !wallet.IsEnabled || debit < 0 {
+			debit = 0
+		}
+
+		if debit > 0 {
+			_, err = tx.Model(&wallet).
+				Set("balance = balance - ?", debit).
+				WherePK().
+				Update()
+			if err != nil {
+				return err
+			}
+
+			_, err = tx.Model(&entity.History{
+				WalletID:    wallet.ID,
+				Status:      HistoryStatusSuccess,
+				Type:        HistoryTypeLoanRepayment,
+				Amount:      debit,
+				Currency:    wallet.Currency,
+				ReferenceID: installment.ID,
+			}).Insert()
+			if err != nil {
+				return err
+			}
+		}
+
+		previousStatus := installment.Status
+		installment.PaidAmount = cur.Round(installment.PaidAmount + debit)
+		switch {
+		case installment.PaidAmount >= installment.Amount+installment.Penalty:
+			installment.Status = InstallmentStatusPaid
+			installment.PaidAt = time.Now()
+		case installment.DueDate.Before(param.OverdueAfter):
+			installment.Status = InstallmentStatusOverdue
+		case installment.PaidAmount > 0:
+			installment.Status = InstallmentStatusPartial
+		}
+		if installment.Status != InstallmentStatusPaid {
+			installment.NextAttemptAt = param.RetryAt
+		}
+		_, err = tx.Model(&installment).
+			Column("paid_amount", "status", "paid_at", "next_attempt_at").
+			WherePK().
+			Update()
+		if err != nil {
+			return err
+		}
+		if debit > 0 || installment.Status != previousStatus {
+			data := newInstallmentEventData(&installment, wallet.Balance-debit)
+			data.Debited = debit
+			err = recordEvent(tx, EventLoanInstallmentCollected, AggregateLoan, installment.LoanID, installment.Loan.CustomerXId, data)
+			if err != nil {
+				return err
+			}
+		}
+		if installment.Status != InstallmentStatusPaid {
+			return nil
+		}
+
+		unpaid, err := tx.Model((*entity.LoanInstallment)(nil)).
+			Where("loan_id = ?", installment.LoanID).
+			Where("status <> ?", InstallmentStatusPaid).
+			Count()
+		if err != nil || unpaid > 0 {
+			return err
+		}
+		_, err = tx.Model((*entity.Loan)(nil)).
+			Set("status = ?", LoanStatusPaidOff).
+			Where("id = ?", installment.LoanID).
+			Update()
+		if err != nil {
+			return err
+		}
+
+		return recordEvent(tx, EventLoanPaidOff, AggregateLoan, installment.LoanID, installment.Loan.CustomerXId, LoanEventData{
+			LoanID:      installment.LoanID,
+			CustomerXId: installment.Loan.CustomerXId,
+			WalletID:    wallet.ID,
+			Currency:    installment.Loan.Currency,
+			Principal:   installment.Loan.Principal,
+			Status:      LoanStatusPaidOff,
+			Balance:     wallet.Balance - debit,
+		})
+	})
+	if err != nil || skipped {
+		return nil, err
+	}
+
+	return &installment, nil
+}
+
+// DeferInstallment keeps the collector away from an installment until the given time, used after a failed attempt
+func (p *dbLoanRepo) DeferInstallment(ctx context.Context, installmentID string, until time.Time) error {
+	_, err := p.dbConn.ModelContext(ctx, (*entity.LoanInstallment)(nil)).
+		Set("next_attempt_at = ?", until).
+		Where("id = ?", installmentID).
+		Where("status <> ?", InstallmentStatusPaid).
+		Update()
+	return err
+}
+
+// GetOverdueInstallmentIDs returns unpaid installments due before overdueAfter without a penalty accrued on accrualDate.
+// Installments in a currency without rule, capped at the rule max_total or already charged a flat fee are left out,
+// so they do not fill every batch with installments that accrue nothing.
+func (p *dbLoanRepo) GetOverdueInstallmentIDs(ctx context.Context, overdueAfter, accrualDate time.Time, rules []penalty.Rule, limit int) ([]string, error) {
+	if len(rules) == 0 {
+		return nil, nil
+	}
+	values := make([]string, 0, len(rules))
+	args := make([]interface{}, 0, 3*len(rules))
+	for _, rule := range rules {
+		values = append(values, "(?, ?, ?::float)")
+		args = append(args, rule.Currency, rule.Kind, rule.MaxTotal)
+	}
+
+	var ids []string
+	err := p.dbConn.ModelContext(ctx, (*entity.LoanInstallment)(nil)).
+		Column("loan_installment.id").
+		Join("JOIN loan ON loan.id = loan_installment.loan_id").
+		Join("JOIN (VALUES "+strings.Join(values, ", ")+") AS penalty_rule (currency, kind, max_total) ON penalty_rule.currency = loan.currency", args...).
+		Where("loan_installment.status <> ?", InstallmentStatusPaid).
+		Where("loan_installment.due_date < ?", overdueAfter.Format("2006-01-02")).
+		Where("penalty_rule.max_total = 0 OR loan_installment.penalty_amount < penalty_rule.max_total").
+		Where("penalty_rule.kind <> ? OR loan_installment.penalty_amount = 0", penalty.KindFlat).
+		Where("NOT EXISTS (SELECT 1 FROM loan_penalty lp WHERE lp.installment_id = loan_installment.id AND lp.accrual_date = ?)",
+			accrualDate.Format("2006-01-02")).
+		Order("loan_installment.due_date", "loan_installment.sequence").
+		Limit(limit).
+		Select(&ids)
+	if err != nil {
+		return nil, err
+	}
+
+	return ids, nil
+}
+
+// AccruePenalty adds one day of late fee to an overdue installment and records it as a history line
+// on the borrower wallet. An installment already accrued on the date or locked elsewhere returns nil.
+func (p *dbLoanRepo) AccruePenalty(ctx context.Context, param ParamAccruePenalty) (*entity.LoanPenalty, error) {
+	var penalty *entity.LoanPenalty
+	err := p.dbConn.RunInTransaction(ctx, func(tx *pg.Tx) error {
+		var installment entity.LoanInstallment
+		err := tx.Model(&installment).
+			Relation("Loan").
+			Where("loan_installment.id = ?", param.InstallmentID).
+			Where("loan_installment.status <> ?", InstallmentStatusPaid).
+			For("UPDATE OF loan_installment SKIP LOCKED").
+			Select()
+		if err == pg.ErrNoRows {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		cur, _ := currency.Get(installment.Loan.Currency)
+		unpaid := cur.Round(installment.Amount - installment.PaidAmount)
+		amount := param.Calculate(cur, unpaid, installment.Penalty)
+		if amount <= 0 {
+			return nil
+		}
+
+		accrued := entity.LoanPenalty{
+			InstallmentID: installment.ID,
+			AccrualDate:   param.AccrualDate,
+			Amount:        amount,
+		}
+		res, err := tx.Model(&accrued).
+			OnConflict("(installment_id, accrual_date) DO NOTHING").
+			Insert()
+		if err != nil || res.RowsAffected() == 0 {
+			return err
+		}
+
+		_, err = tx.Model((*entity.LoanInstallment)(nil)).
+			Set("penalty_amount = penalty_amount + ?", amount).
+			Set("status = ?", InstallmentStatusOverdue).
+			Where("id = ?", installment.ID).
+			Update()
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.Model(&entity.History{
+			WalletID:    installment.Loan.WalletID,
+			Status:      HistoryStatusAccrued,
+			Type:        HistoryTypeLateFee,
+			Amount:      amount,
+			Currency:    installment.Loan.Currency,
+			ReferenceID: accrued.ID,
+		}).Insert()
+		if err != nil {
+			return err
+		}
+
+		var balance float64
+		err = tx.Model((*entity.Wallet)(nil)).
+			Column("balance").
+			Where("id = ?", installment.Loan.WalletID).
+			Select(&balance)
+		if err != nil {
+			return err
+		}
+		installment.Penalty += amount
+		installment.Status = InstallmentStatusOverdue
+		data := newInstallmentEventData(&installment, balance)
+		data.Amount = amount
+		err = recordEvent(tx, EventLoanPenaltyAccrued, AggregateLoan, installment.LoanID, installment.Loan.CustomerXId, data)
+		if err != nil {
+			return err
+		}
+
+		penalty = &accrued
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return penalty, nil
+}
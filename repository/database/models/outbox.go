@@ -0,0 +1,195 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/ahmadmirdas/julo-test/repository/database/models/entity"
+	"github.com/ahmadmirdas/julo-test/utils/outbox"
+	"github.com/go-pg/pg/v10/orm"
+)
+
+// EventVersion is bumped for an event type whenever its payload changes incompatibly
+const EventVersion = 1
+
+const (
+	OutboxStatusPending   string = "pending"
+	OutboxStatusPublished string = "published"
+	OutboxStatusFailed    string = "failed"
+
+	AggregateWallet   string = "wallet"
+	AggregateFx       string = "fx"
+	AggregateLoan     string = "loan"
+	AggregateSchedule string = "schedule"
+
+	EventWalletEnabled            string = "wallet.enabled"
+	EventWalletDisabled           string = "wallet.disabled"
+	EventWalletDeposited          string = "wallet.deposited"
+	EventWalletWithdrawn          string = "wallet.withdrawn"
+	EventWalletFeeCollected       string = "wallet.fee_collected"
+	EventWalletKycChanged         string = "wallet.kyc_level_changed"
+	EventWalletFrozen             string = "wallet.frozen"
+	EventWalletUnfrozen           string = "wallet.unfrozen"
+	EventWalletAdjusted           string = "wallet.adjusted"
+	EventWalletReversed           string = "wallet.reversed"
+	EventWalletConverted          string = "wallet.converted"
+	EventPocketCreated            string = "pocket.created"
+	EventPocketRenamed            string = "pocket.renamed"
+	EventPocketClosed             string = "pocket.closed"
+	EventPocketTransferred        string = "pocket.transferred"
+	EventFxRatesUpdated           string = "fx.rates_updated"
+	EventFxQuoteCreated           string = "fx.quote_created"
+	EventLoanDisbursed            string = "loan.disbursed"
+	EventLoanInstallmentCollected string = "loan.installment_collected"
+	EventLoanPenaltyAccrued       string = "loan.penalty_accrued"
+	EventLoanPaidOff              string = "loan.paid_off"
+	EventScheduleCreated          string = "schedule.created"
+	EventScheduleStatusChanged    string = "schedule.status_changed"
+	EventScheduleRan              string = "schedule.ran"
+)
+
+// WalletEventData is the payload of wallet and pocket lifecycle events
+type WalletEventData struct {
+	WalletID         string  `json:"wallet_id"`
+	CustomerXId      string  `json:"customer_xid"`
+	ParentID         string  `json:"parent_id,omitempty"`
+	Name             string  `json:"name,omitempty"`
+	Currency         string  `json:"currency"`
+	IsEnabled        bool    `json:"is_enabled"`
+	Balance          float64 `json:"balance"`
+	KycLevel         string  `json:"kyc_level"`
+	PreviousKycLevel string  `json:"previous_kyc_level,omitempty"`
+}
+
+// TransactionEventData is the payload of balance movements, Balance is the wallet balance after the movement
+type TransactionEventData struct {
+	TransactionID       string  `json:"transaction_id"`
+	WalletID            string  `json:"wallet_id"`
+	CustomerXId         string  `json:"customer_xid"`
+	Type                string  `json:"type"`
+	Amount              float64 `json:"amount"`
+	Fee                 float64 `json:"fee,omitempty"`
+	Currency            string  `json:"currency"`
+	ReferenceID         string  `json:"reference_id"`
+	Balance             float64 `json:"balance"`
+	CounterpartWalletID string  `json:"counterpart_wallet_id,omitempty"`
+	CounterpartBalance  float64 `json:"counterpart_balance,omitempty"`
+	CounterpartAmount   float64 `json:"counterpart_amount,omitempty"`
+	CounterpartCurrency string  `json:"counterpart_currency,omitempty"`
+	Rate                float64 `json:"rate,omitempty"`
+}
+
+// FxQuoteEventData is the payload of a conversion quote given to a customer
+type FxQuoteEventData struct {
+	QuoteID      string    `json:"quote_id"`
+	CustomerXId  string    `json:"customer_xid"`
+	FromCurrency string    `json:"from_currency"`
+	ToCurrency   string    `json:"to_currency"`
+	Rate         float64   `json:"rate"`
+	FromAmount   float64   `json:"from_amount"`
+	ToAmount     float64   `json:"to_amount"`
+	ExpiresAt    time.Time `json:"expires_at"`
+}
+
+// LoanEventData is the payload of loan events, installment fields are set for installment events only.
+// Balance is the borrower wallet balance after the event.
+type LoanEventData struct {
+	LoanID        string  `json:"loan_id"`
+	CustomerXId   string  `json:"customer_xid"`
+	WalletID      string  `json:"wallet_id"`
+	Currency      string  `json:"currency"`
+	Principal     float64 `json:"principal,omitempty"`
+	Installments  int     `json:"installments,omitempty"`
+	InstallmentID string  `json:"installment_id,omitempty"`
+	Sequence      int     `json:"sequence,omitempty"`
+	Amount        float64 `json:"amount,omitempty"`
+	Penalty       float64 `json:"penalty,omitempty"`
+	PaidAmount    float64 `json:"paid_amount,omitempty"`
+	Debited       float64 `json:"debited,omitempty"`
+	Status        string  `json:"status,omitempty"`
+	Balance       float64 `json:"balance"`
+}
+
+func newWalletEventData(wallet *entity.Wallet) WalletEventData {
+	return WalletEventData{
+		WalletID:    wallet.ID,
+		CustomerXId: wallet.OwnedBy,
+		ParentID:    wallet.ParentID,
+		Name:        wallet.Name,
+		Currency:    wallet.Currency,
+		IsEnabled:   wallet.IsEnabled,
+		Balance:     wallet.Balance,
+		KycLevel:    wallet.KycLevel,
+	}
+}
+
+// ToEvent returns the envelope of an outbox row as delivered to sinks and streams
+func ToEvent(e *entity.OutboxEvent) outbox.Event {
+	return outbox.Event{
+		ID:            e.ID,
+		Sequence:      e.Seq,
+		Type:          e.Type,
+		Version:       e.Version,
+		AggregateType: e.AggregateType,
+		AggregateID:   e.AggregateID,
+		CustomerXId:   e.CustomerXId,
+		OccurredAt:    e.CreatedAt,
+		Data:          e.Payload,
+	}
+}
+
+// recordEvent writes a domain event to the outbox with db, inside a transaction the event is
+// published only when the transaction commits
+func recordEvent(db orm.DB, eventType, aggregateType, aggregateID, customerXId string, data interface{}) error {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.Model(&entity.OutboxEvent{
+		Type:          eventType,
+		Version:       EventVersion,
+		AggregateType: aggregateType,
+		AggregateID:   aggregateID,
+		CustomerXId:   customerXId,
+		Payload:       payload,
+		Status:        OutboxStatusPending,
+	}).Insert()
+	return err
+}
+
+func newInstallmentEventData(installment *entity.LoanInstallment, balance float64) LoanEventData {
+	return LoanEventData{
+		LoanID:        installment.LoanID,
+		CustomerXId:   installment.Loan.CustomerXId,
+		WalletID:      installment.Loan.WalletID,
+		Currency:      installment.Loan.Currency,
+		InstallmentID: installment.ID,
+		Sequence:      installment.Sequence,
+		Amount:        installment.Amount,
+		Penalty:       installment.Penalty,
+		PaidAmount:    installment.PaidAmount,
+		Status:        installment.Status,
+		Balance:       balance,
+	}
+}
+
+// recordWalletEvent writes a lifecycle event of wallet
+func recordWalletEvent(db orm.DB, eventType string, wallet *entity.Wallet) error {
+	return recordEvent(db, eventType, AggregateWallet, wallet.ID, wallet.OwnedBy, newWalletEventData(wallet))
+}
+
+// recordTransactionEvent writes a balance movement of the wallet the transaction belongs to
+func recordTransactionEvent(db orm.DB, eventType string, data TransactionEventData) error {
+	return recordEvent(db, eventType, AggregateWallet, data.WalletID, data.CustomerXId, data)
+}
+
+// PublishFunc delivers events in order and returns how many were delivered before the first failure
+type PublishFunc func(events []entity.OutboxEvent) (int, error)
+
+// ParamRelayEvents relays up to Limit pending events, an event failing MaxAttempts times is set aside as failed
+type ParamRelayEvents struct {
+	Limit       int
+	MaxAttempts int
+	Publish     PublishFunc
+}
@@ -0,0 +1,128 @@
+package models
+
+import (
+	"context"
+	"time"
+
+	"github.com/ahmadmirdas/julo-test/repository/database/models/entity"
+	"github.com/go-pg/pg/v10"
+)
+
+// outboxRelayLock is the advisory lock key held by the single relay allowed to publish at a time
+const outboxRelayLock = 7391004
+
+// outboxVisible keeps the events written by transactions older than every one still in flight
+const outboxVisible = "xid < pg_snapshot_xmin(pg_current_snapshot())"
+
+type OutboxDBRepo interface {
+	RelayEvents(ctx context.Context, param ParamRelayEvents) (int, error)
+	GetCustomerEvents(ctx context.Context, customerXId string, afterSeq int64, limit int) ([]entity.OutboxEvent, error)
+	GetLatestSeq(ctx context.Context, customerXId string) (int64, error)
+}
+
+type dbOutboxRepo struct {
+	dbConn *pg.DB
+}
+
+func NewDBOutboxRepo(c *pg.DB) OutboxDBRepo {
+	return &dbOutboxRepo{dbConn: c}
+}
+
+// RelayEvents publishes the oldest pending events and marks the delivered ones published. Only one
+// relay across every instance publishes at a time, holding a session advisory lock on its own
+// connection, so events go out in commit order and the others return 0. Publishing happens outside
+// any transaction: a slow sink holds no row locks, and a relay stopping between publish and mark
+// publishes the events again.
+func (p *dbOutboxRepo) RelayEvents(ctx context.Context, param ParamRelayEvents) (int, error) {
+	conn := p.dbConn.Conn()
+	defer conn.Close()
+
+	var locked bool
+	_, err := conn.QueryOneContext(ctx, pg.Scan(&locked), "SELECT pg_try_advisory_lock(?)", outboxRelayLock)
+	if err != nil || !locked {
+		return 0, err
+	}
+	// unlocked even once ctx is done, the connection goes back to the pool holding the session lock otherwise
+	defer conn.ExecContext(context.Background(), "SELECT pg_advisory_unlock(?)", outboxRelayLock)
+
+	var events []entity.OutboxEvent
+	err = conn.ModelContext(ctx, &events).
+		Where("status = ?", OutboxStatusPending).
+		Where(outboxVisible).
+		OrderExpr("xid, seq").
+		Limit(param.Limit).
+		Select()
+	if err != nil || len(events) == 0 {
+		return 0, err
+	}
+
+	published, publishErr := param.Publish(events)
+	if published > 0 {
+		ids := make([]string, 0, published)
+		for _, event := range events[:published] {
+			ids = append(ids, event.ID)
+		}
+		_, err = conn.ModelContext(ctx, (*entity.OutboxEvent)(nil)).
+			Set("status = ?", OutboxStatusPublished).
+			Set("published_at = ?", time.Now()).
+			Where("id IN (?)", pg.In(ids)).
+			Update()
+		if err != nil {
+			return 0, err
+		}
+	}
+	if publishErr == nil || published >= len(events) {
+		return published, nil
+	}
+
+	// the failing event blocks the ones after it until it is delivered or set aside
+	_, err = conn.ModelContext(ctx, (*entity.OutboxEvent)(nil)).
+		Set("attempts = attempts + 1").
+		Set("last_error = ?", publishErr.Error()).
+		Set("status = CASE WHEN attempts + 1 >= ? THEN ? ELSE status END", param.MaxAttempts, OutboxStatusFailed).
+		Where("id = ?", events[published].ID).
+		Update()
+	return published, err
+}
+
+// GetCustomerEvents returns the events of a customer written after the event afterSeq, in commit
+// safe order. The seq is taken at insert, so a transaction holding a lower seq can commit after a
+// higher one has been streamed. Only events of transactions older than every transaction still in
+// flight are returned, ordered by writing transaction then seq, so no later read can add an event
+// before one already returned.
+func (p *dbOutboxRepo) GetCustomerEvents(ctx context.Context, customerXId string, afterSeq int64, limit int) ([]entity.OutboxEvent, error) {
+	var events []entity.OutboxEvent
+	err := p.dbConn.ModelContext(ctx, &events).
+		Where("customer_xid = ?", customerXId).
+		Where(outboxVisible).
+		Where("(xid, seq) > (COALESCE((SELECT xid FROM outbox_event WHERE seq = ?), '0'::xid8), ?)", afterSeq, afterSeq).
+		OrderExpr("xid, seq").
+		Limit(limit).
+		Select()
+	if err != nil {
+		return nil, err
+	}
+
+	return events, nil
+}
+
+// GetLatestSeq returns the last event of a customer GetCustomerEvents would return, 0 when there
+// is none. Events committed but not yet visible are sent again after a snapshot taken later.
+func (p *dbOutboxRepo) GetLatestSeq(ctx context.Context, customerXId string) (int64, error) {
+	var seq int64
+	err := p.dbConn.ModelContext(ctx, (*entity.OutboxEvent)(nil)).
+		Column("seq").
+		Where("customer_xid = ?", customerXId).
+		Where(outboxVisible).
+		OrderExpr("xid DESC, seq DESC").
+		Limit(1).
+		Select(&seq)
+	if err == pg.ErrNoRows {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	return seq, nil
+}
@@ -0,0 +1,30 @@
+package models
+
+import "errors"
+
+var (
+	ErrPocketNotFound      = errors.New("pocket not found")
+	ErrPocketSameWallet    = errors.New("cannot transfer to the same pocket")
+	ErrPocketCurrencyMatch = errors.New("pockets must have the same currency")
+)
+
+type ParamCreatePocket struct {
+	CustomerXId string
+	Currency    string
+	Name        string
+}
+
+// ParamPocketTransfer moves Amount between two wallets of the same customer, main wallet included
+type ParamPocketTransfer struct {
+	CustomerXId  string
+	FromWalletID string
+	ToWalletID   string
+	Amount       float64
+	ReferenceID  string
+	// Fee is debited from FromWalletID on top of Amount and credited to FeeAccount wallet owner
+	Fee        float64
+	FeeAccount string
+	// Currency and Limit, when set, check the limits again within the transfer transaction
+	Currency string
+	Limit    *LimitCheck
+}
@@ -0,0 +1,321 @@
+package models
+
+import (
+	"context"
+	"time"
+
+	"github.com/ahmadmirdas/julo-test/repository/database/models/entity"
+	"github.com/go-pg/pg/v10"
+	"github.com/google/uuid"
+)
+
+type PocketDBRepo interface {
+	CreatePocket(ctx context.Context, param ParamCreatePocket) (*entity.Wallet, error)
+	GetPockets(ctx context.Context, customerXId string) ([]entity.Wallet, error)
+	GetPocket(ctx context.Context, customerXId, pocketID string) (*entity.Wallet, error)
+	RenamePocket(ctx context.Context, customerXId, pocketID, name string) (*entity.Wallet, error)
+	ClosePocket(ctx context.Context, customerXId, pocketID string) (*entity.Wallet, error)
+	TransferPocket(ctx context.Context, param ParamPocketTransfer) (*entity.History, error)
+}
+
+type dbPocketRepo struct {
+	dbConn *pg.DB
+}
+
+func NewDBPocketRepo(c *pg.DB) PocketDBRepo {
+	return &dbPocketRepo{dbConn: c}
+}
+
+// CreatePocket opens a pocket under the main wallet of the customer in currency
+func (p *dbPocketRepo) CreatePocket(ctx context.Context, param ParamCreatePocket) (*entity.Wallet, error) {
+	var pocket entity.Wallet
+	err := p.dbConn.RunInTransaction(ctx, func(tx *pg.Tx) error {
+		main, err := lockWallet(tx, param.CustomerXId, param.Currency)
+		if err != nil {
+			return err
+		}
+
+		pocket = entity.Wallet{
+			OwnedBy:   param.CustomerXId,
+			ParentID:  main.ID,
+			Name:      param.Name,
+			Currency:  main.Currency,
+			Segment:   main.Segment,
+			KycLevel:  main.KycLevel,
+			IsEnabled: true,
+			EnabledAt: time.Now(),
+		}
+		_, err = tx.Model(&pocket).Insert()
+		if err != nil {
+			return err
+		}
+
+		return recordWalletEvent(tx, EventPocketCreated, &pocket)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &pocket, nil
+}
+
+// GetPockets returns every open pocket of a customer
+func (p *dbPocketRepo) GetPockets(ctx context.Context, customerXId string) ([]entity.Wallet, error) {
+	var pockets []entity.Wallet
+	err := p.dbConn.ModelContext(ctx, &pockets).
+		Where("owned_by = ?", customerXId).
+		Where("parent_id IS NOT NULL").
+		Where("closed_at IS NULL").
+		Order("currency", "name").
+		Select()
+	if err != nil {
+		return nil, err
+	}
+
+	return pockets, nil
+}
+
+func (p *dbPocketRepo) GetPocket(ctx context.Context, customerXId, pocketID string) (*entity.Wallet, error) {
+	var pocket entity.Wallet
+	err := p.dbConn.ModelContext(ctx, &pocket).
+		Where("id = ?", pocketID).
+		Where("owned_by = ?", customerXId).
+		Where("parent_id IS NOT NULL").
+		Select()
+	if err == pg.ErrNoRows {
+		return nil, ErrPocketNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &pocket, nil
+}
+
+func (p *dbPocketRepo) RenamePocket(ctx context.Context, customerXId, pocketID, name string) (*entity.Wallet, error) {
+	var pocket entity.Wallet
+	err := p.dbConn.RunInTransaction(ctx, func(tx *pg.Tx) error {
+		res, err := tx.Model(&pocket).
+			Set("name = ?", name).
+			Where("id = ?", pocketID).
+			Where("owned_by = ?", customerXId).
+			Where("parent_id IS NOT NULL").
+			Where("closed_at IS NULL").
+			Returning("*").
+			Update()
+		if err != nil {
+			return err
+		}
+		if res.RowsAffected() == 0 {
+			return ErrPocketNotFound
+		}
+
+		return recordWalletEvent(tx, EventPocketRenamed, &pocket)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &pocket, nil
+}
+
+// ClosePocket sweeps the remaining pocket balance back to the main wallet and closes the pocket
+func (p *dbPocketRepo) ClosePocket(ctx context.Context, customerXId, pocketID string) (*entity.Wallet, error) {
+	var pocket entity.Wallet
+	err := p.dbConn.RunInTransaction(ctx, func(tx *pg.Tx) error {
+		err := tx.Model(&pocket).
+			Where("id = ?", pocketID).
+			Where("owned_by = ?", customerXId).
+			Where("parent_id IS NOT NULL").
+			Where("closed_at IS NULL").
+			For("UPDATE").
+			Select()
+		if err == pg.ErrNoRows {
+			return ErrPocketNotFound
+		}
+		if err != nil {
+			return err
+		}
+
+		if pocket.Balance > 0 {
+			history, err := moveBalance(tx, pocket.ID, pocket.ParentID, pocket.Currency, pocket.Balance, uuid.New().String())
+			if err != nil {
+				return err
+			}
+
+			parent := entity.Wallet{ID: pocket.ParentID}
+			err = tx.Model(&parent).WherePK().Select()
+			if err != nil {
+				return err
+			}
+			err = recordTransactionEvent(tx, EventPocketTransferred, TransactionEventData{
+				TransactionID:       history.ID,
+				WalletID:            pocket.ID,
+				CustomerXId:         pocket.OwnedBy,
+				Type:                history.Type,
+				Amount:              history.Amount,
+				Currency:            history.Currency,
+				ReferenceID:         history.ReferenceID,
+				Balance:             0,
+				CounterpartWalletID: parent.ID,
+				CounterpartBalance:  parent.Balance,
+			})
+			if err != nil {
+				return err
+			}
+		}
+
+		pocket.Balance = 0
+		pocket.IsEnabled = false
+		pocket.ClosedAt = time.Now()
+		_, err = tx.Model(&pocket).
+			Set("is_enabled = ?is_enabled").
+			Set("closed_at = ?closed_at").
+			WherePK().
+			Update()
+		if err != nil {
+			return err
+		}
+
+		return recordWalletEvent(tx, EventPocketClosed, &pocket)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &pocket, nil
+}
+
+// TransferPocket moves money instantly between two wallets owned by the same customer, the fee is
+// debited from the sender and credited to the fee revenue wallet in the same transaction
+func (p *dbPocketRepo) TransferPocket(ctx context.Context, param ParamPocketTransfer) (*entity.History, error) {
+	if param.FromWalletID == param.ToWalletID {
+		return nil, ErrPocketSameWallet
+	}
+
+	var history *entity.History
+	err := p.dbConn.RunInTransaction(ctx, func(tx *pg.Tx) error {
+		// the main wallet is locked first, then both sides
+		if err := checkLimit(tx, param.CustomerXId, param.Currency, param.Limit); err != nil {
+			return err
+		}
+
+		var wallets []entity.Wallet
+		// locked in id order so opposite transfers cannot deadlock
+		err := tx.Model(&wallets).
+			Where("id IN (?, ?)", param.FromWalletID, param.ToWalletID).
+			Where("owned_by = ?", param.CustomerXId).
+			Order("id").
+			For("UPDATE").
+			Select()
+		if err != nil {
+			return err
+		}
+		if len(wallets) != 2 {
+			return ErrPocketNotFound
+		}
+
+		from, to := wallets[0], wallets[1]
+		if from.ID != param.FromWalletID {
+			from, to = to, from
+		}
+		if !from.IsEnabled || !to.IsEnabled {
+			return ErrWalletDisabled
+		}
+		if from.Currency != to.Currency {
+			return ErrPocketCurrencyMatch
+		}
+		if from.Balance < param.Amount+param.Fee {
+			return ErrInsufficientFund
+		}
+
+		history, err = moveBalance(tx, from.ID, to.ID, from.Currency, param.Amount, param.ReferenceID)
+		if err != nil {
+			return err
+		}
+
+		if param.Fee > 0 {
+			_, err = tx.Model((*entity.Wallet)(nil)).
+				Set("balance = balance - ?", param.Fee).
+				Where("id = ?", from.ID).
+				Update()
+			if err != nil {
+				return err
+			}
+			err = postFee(tx, from.ID, param.FeeAccount, from.Currency, param.Fee, param.ReferenceID)
+			if err != nil {
+				return err
+			}
+		}
+
+		return recordTransactionEvent(tx, EventPocketTransferred, TransactionEventData{
+			TransactionID:       history.ID,
+			WalletID:            from.ID,
+			CustomerXId:         from.OwnedBy,
+			Type:                history.Type,
+			Amount:              history.Amount,
+			Currency:            history.Currency,
+			ReferenceID:         history.ReferenceID,
+			Balance:             from.Balance - param.Amount - param.Fee,
+			CounterpartWalletID: to.ID,
+			CounterpartBalance:  to.Balance + param.Amount,
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var result entity.History
+	err = p.dbConn.ModelContext(ctx, &result).Relation("Wallet").
+		Where("history.id = ?", history.ID).
+		Select()
+	if err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+// moveBalance debits one wallet and credits another with a transfer history line on each side,
+// both wallets must already be locked by the caller, the debit history line is returned
+func moveBalance(tx *pg.Tx, fromWalletID, toWalletID, currency string, amount float64, referenceID string) (*entity.History, error) {
+	_, err := tx.Model((*entity.Wallet)(nil)).
+		Set("balance = balance - ?", amount).
+		Where("id = ?", fromWalletID).
+		Update()
+	if err != nil {
+		return nil, err
+	}
+	_, err = tx.Model((*entity.Wallet)(nil)).
+		Set("balance = balance + ?", amount).
+		Where("id = ?", toWalletID).
+		Update()
+	if err != nil {
+		return nil, err
+	}
+
+	histories := []entity.History{
+		{
+			WalletID:    fromWalletID,
+			Status:      HistoryStatusSuccess,
+			Type:        HistoryTypeTransfer,
+			Amount:      amount,
+			Currency:    currency,
+			ReferenceID: referenceID,
+		},
+		{
+			WalletID:    toWalletID,
+			Status:      HistoryStatusSuccess,
+			Type:        HistoryTypeTransferIn,
+			Amount:      amount,
+			Currency:    currency,
+			ReferenceID: referenceID,
+		},
+	}
+	_, err = tx.Model(&histories).Insert()
+	if err != nil {
+		return nil, err
+	}
+
+	return &histories[0], nil
+}
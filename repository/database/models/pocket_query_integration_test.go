@@ -0,0 +1,94 @@
+//go:build integration
+
+package models
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/ahmadmirdas/julo-test/repository/database/models/entity"
+	"github.com/google/uuid"
+)
+
+func TestTransferPocketFee(t *testing.T) {
+	ctx := context.Background()
+	db := testDB(t)
+	walletRepo := &dbWalletRepo{dbConn: db}
+	repo := &dbPocketRepo{dbConn: db}
+
+	tests := []struct {
+		name        string
+		amount      float64
+		fee         float64
+		wantErr     error
+		wantBalance float64
+		wantFees    int
+		wantRevenue float64
+	}{
+		{name: "without fee", amount: 3000, wantBalance: 7000},
+		{name: "with fee", amount: 3000, fee: 500, wantBalance: 6500, wantFees: 1, wantRevenue: 500},
+		{name: "fee over the balance", amount: 10000, fee: 500, wantErr: ErrInsufficientFund, wantBalance: 10000},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			customerXId := uuid.New().String()
+			feeAccount := "fee-revenue." + uuid.New().String()
+			main, err := walletRepo.EnableWallet(ctx, customerXId, "IDR")
+			if err != nil {
+				t.Fatalf("enable wallet: %v", err)
+			}
+			_, err = walletRepo.WalletDeposit(ctx, ParamWalletDeposit{
+				WalletID: main.ID, CustomerXId: customerXId, Amount: 10000, Currency: main.Currency, ReferenceID: uuid.New().String(),
+			})
+			if err != nil {
+				t.Fatalf("deposit: %v", err)
+			}
+			pocket, err := repo.CreatePocket(ctx, ParamCreatePocket{CustomerXId: customerXId, Currency: main.Currency, Name: "holiday"})
+			if err != nil {
+				t.Fatalf("create pocket: %v", err)
+			}
+
+			referenceID := uuid.New().String()
+			_, err = repo.TransferPocket(ctx, ParamPocketTransfer{
+				CustomerXId:  customerXId,
+				FromWalletID: main.ID,
+				ToWalletID:   pocket.ID,
+				Amount:       tt.amount,
+				ReferenceID:  referenceID,
+				Fee:          tt.fee,
+				FeeAccount:   feeAccount,
+			})
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("TransferPocket() error = %v, want %v", err, tt.wantErr)
+			}
+
+			stored, err := walletRepo.GetWalletByID(ctx, main.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if stored.Balance != tt.wantBalance {
+				t.Errorf("sender balance = %v, want %v", stored.Balance, tt.wantBalance)
+			}
+
+			fees, err := db.Model((*entity.History)(nil)).
+				Where("wallet_id = ?", main.ID).
+				Where("type = ?", HistoryTypeFee).
+				Where("reference_id = ?", referenceID).
+				Count()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if fees != tt.wantFees {
+				t.Errorf("%d fee history lines, want %d", fees, tt.wantFees)
+			}
+			revenue, err := walletRepo.GetTotalBalance(ctx, feeAccount, main.Currency)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if revenue != tt.wantRevenue {
+				t.Errorf("fee revenue = %v, want %v", revenue, tt.wantRevenue)
+			}
+		})
+	}
+}
@@ -0,0 +1,47 @@
+package models
+
+import (
+	"errors"
+	"time"
+
+	"github.com/ahmadmirdas/julo-test/repository/database/models/entity"
+)
+
+var (
+	ScheduleStatusActive    string = "active"
+	ScheduleStatusPaused    string = "paused"
+	ScheduleStatusCancelled string = "cancelled"
+	ScheduleStatusCompleted string = "completed"
+	ScheduleStatusFailed    string = "failed"
+
+	ScheduleRunSuccess  string = "success"
+	ScheduleRunRetrying string = "retrying"
+	ScheduleRunFailed   string = "failed"
+)
+
+var (
+	ErrScheduleNotFound = errors.New("schedule not found")
+	ErrScheduleFinished = errors.New("schedule is already finished")
+)
+
+type ParamCreateSchedule struct {
+	CustomerXId  string
+	Currency     string
+	FromPocketID string
+	ToPocketID   string
+	Amount       float64
+	CronExpr     string
+	RunAt        time.Time
+}
+
+// ParamUpdateScheduleStatus pauses, resumes or cancels a schedule, NextRunAt replaces the pending run when resuming
+type ParamUpdateScheduleStatus struct {
+	CustomerXId string
+	ScheduleID  string
+	Status      string
+	NextRunAt   time.Time
+}
+
+// ScheduleRunFunc executes a claimed schedule and records the outcome on it, transferred reports
+// whether the pending occurrence was already transferred by an earlier attempt
+type ScheduleRunFunc func(schedule *entity.TransferSchedule, transferred bool) error
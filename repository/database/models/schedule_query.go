@@ -0,0 +1,205 @@
+package models
+
+import (
+	"context"
+	"time"
+
+	"github.com/ahmadmirdas/julo-test/repository/database/models/entity"
+	"github.com/go-pg/pg/v10"
+)
+
+type ScheduleDBRepo interface {
+	CreateSchedule(ctx context.Context, param ParamCreateSchedule) (*entity.TransferSchedule, error)
+	GetSchedules(ctx context.Context, customerXId string) ([]entity.TransferSchedule, error)
+	GetSchedule(ctx context.Context, customerXId, scheduleID string) (*entity.TransferSchedule, error)
+	UpdateScheduleStatus(ctx context.Context, param ParamUpdateScheduleStatus) (*entity.TransferSchedule, error)
+	CancelSchedule(ctx context.Context, customerXId, scheduleID string) (*entity.TransferSchedule, error)
+	GetDueScheduleIDs(ctx context.Context, asOf time.Time, limit int) ([]string, error)
+	RunSchedule(ctx context.Context, scheduleID string, asOf time.Time, run ScheduleRunFunc) (*entity.TransferSchedule, error)
+}
+
+type dbScheduleRepo struct {
+	dbConn *pg.DB
+}
+
+func NewDBScheduleRepo(c *pg.DB) ScheduleDBRepo {
+	return &dbScheduleRepo{dbConn: c}
+}
+
+func (p *dbScheduleRepo) CreateSchedule(ctx context.Context, param ParamCreateSchedule) (*entity.TransferSchedule, error) {
+	schedule := entity.TransferSchedule{
+		CustomerXId:  param.CustomerXId,
+		Currency:     param.Currency,
+		FromPocketID: param.FromPocketID,
+		ToPocketID:   param.ToPocketID,
+		Amount:       param.Amount,
+		CronExpr:     param.CronExpr,
+		Status:       ScheduleStatusActive,
+		NextRunAt:    param.RunAt,
+	}
+	err := p.dbConn.RunInTransaction(ctx, func(tx *pg.Tx) error {
+		_, err := tx.Model(&schedule).Returning("*").Insert()
+		if err != nil {
+			return err
+		}
+
+		return recordScheduleEvent(tx, EventScheduleCreated, &schedule)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &schedule, nil
+}
+
+func (p *dbScheduleRepo) GetSchedules(ctx context.Context, customerXId string) ([]entity.TransferSchedule, error) {
+	var schedules []entity.TransferSchedule
+	err := p.dbConn.ModelContext(ctx, &schedules).
+		Where("customer_xid = ?", customerXId).
+		Order("created_at DESC").
+		Select()
+	if err != nil {
+		return nil, err
+	}
+
+	return schedules, nil
+}
+
+func (p *dbScheduleRepo) GetSchedule(ctx context.Context, customerXId, scheduleID string) (*entity.TransferSchedule, error) {
+	var schedule entity.TransferSchedule
+	err := p.dbConn.ModelContext(ctx, &schedule).
+		Where("id = ?", scheduleID).
+		Where("customer_xid = ?", customerXId).
+		Select()
+	if err == pg.ErrNoRows {
+		return nil, ErrScheduleNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &schedule, nil
+}
+
+// UpdateScheduleStatus pauses, resumes or cancels a schedule that is not finished yet
+func (p *dbScheduleRepo) UpdateScheduleStatus(ctx context.Context, param ParamUpdateScheduleStatus) (*entity.TransferSchedule, error) {
+	var schedule entity.TransferSchedule
+	updated := false
+	err := p.dbConn.RunInTransaction(ctx, func(tx *pg.Tx) error {
+		q := tx.Model(&schedule).
+			Set("status = ?", param.Status).
+			Set("attempts = 0").
+			Where("id = ?", param.ScheduleID).
+			Where("customer_xid = ?", param.CustomerXId).
+			Where("status IN (?, ?)", ScheduleStatusActive, ScheduleStatusPaused)
+		if !param.NextRunAt.IsZero() {
+			q = q.Set("next_run_at = ?", param.NextRunAt)
+		}
+		res, err := q.Returning("*").Update()
+		if err != nil || res.RowsAffected() == 0 {
+			return err
+		}
+
+		updated = true
+		return recordScheduleEvent(tx, EventScheduleStatusChanged, &schedule)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if !updated {
+		return p.finishedError(ctx, param.CustomerXId, param.ScheduleID)
+	}
+
+	return &schedule, nil
+}
+
+func (p *dbScheduleRepo) CancelSchedule(ctx context.Context, customerXId, scheduleID string) (*entity.TransferSchedule, error) {
+	return p.UpdateScheduleStatus(ctx, ParamUpdateScheduleStatus{
+		CustomerXId: customerXId,
+		ScheduleID:  scheduleID,
+		Status:      ScheduleStatusCancelled,
+	})
+}
+
+// finishedError tells a missing schedule apart from one that can no longer be changed
+func (p *dbScheduleRepo) finishedError(ctx context.Context, customerXId, scheduleID string) (*entity.TransferSchedule, error) {
+	_, err := p.GetSchedule(ctx, customerXId, scheduleID)
+	if err != nil {
+		return nil, err
+	}
+
+	return nil, ErrScheduleFinished
+}
+
+// GetDueScheduleIDs returns active schedules due on or before asOf, oldest first
+func (p *dbScheduleRepo) GetDueScheduleIDs(ctx context.Context, asOf time.Time, limit int) ([]string, error) {
+	var ids []string
+	err := p.dbConn.ModelContext(ctx, (*entity.TransferSchedule)(nil)).
+		Column("id").
+		Where("status = ?", ScheduleStatusActive).
+		Where("next_run_at <= ?", asOf).
+		Order("next_run_at").
+		Limit(limit).
+		Select(&ids)
+	if err != nil {
+		return nil, err
+	}
+
+	return ids, nil
+}
+
+// RunSchedule claims a due schedule and lets run execute it while the row stays locked, the
+// changes run makes on the schedule are saved when it returns nil. Schedules no longer due or
+// claimed by another worker are skipped and returned as nil.
+func (p *dbScheduleRepo) RunSchedule(ctx context.Context, scheduleID string, asOf time.Time, run ScheduleRunFunc) (*entity.TransferSchedule, error) {
+	var schedule entity.TransferSchedule
+	skipped := false
+	err := p.dbConn.RunInTransaction(ctx, func(tx *pg.Tx) error {
+		err := tx.Model(&schedule).
+			Where("id = ?", scheduleID).
+			Where("status = ?", ScheduleStatusActive).
+			Where("next_run_at <= ?", asOf).
+			For("UPDATE SKIP LOCKED").
+			Select()
+		if err == pg.ErrNoRows {
+			skipped = true
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		// an earlier attempt may have transferred before failing to save the schedule
+		transferred, err := tx.Model((*entity.History)(nil)).
+			Where("reference_id = ?", schedule.RunReferenceID).
+			Where("type = ?", HistoryTypeTransfer).
+			Exists()
+		if err != nil {
+			return err
+		}
+
+		err = run(&schedule, transferred)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.Model(&schedule).
+			Column("status", "next_run_at", "run_reference_id", "attempts", "last_run_at", "last_status", "last_error").
+			WherePK().
+			Update()
+		if err != nil {
+			return err
+		}
+
+		return recordScheduleEvent(tx, EventScheduleRan, &schedule)
+	})
+	if err != nil || skipped {
+		return nil, err
+	}
+
+	return &schedule, nil
+}
+
+func recordScheduleEvent(tx *pg.Tx, eventType string, schedule *entity.TransferSchedule) error {
+	return recordEvent(tx, eventType, AggregateSchedule, schedule.ID, schedule.CustomerXId, schedule)
+}
@@ -1,22 +1,145 @@
 package models
 
+import (
+	"errors"
+	"time"
+
+	"github.com/ahmadmirdas/julo-test/repository/database/models/entity"
+)
+
+var (
+	ErrWalletNotFound   = errors.New("wallet not found")
+	ErrWalletDisabled   = errors.New("wallet is disabled")
+	ErrInsufficientFund = errors.New("insufficient balance")
+	ErrWalletFrozen     = errors.New("wallet is frozen")
+	ErrWalletNotFrozen  = errors.New("wallet is not frozen")
+
+	ErrTransactionNotFound = errors.New("transaction not found")
+	ErrTransactionReversed = errors.New("transaction is already reversed")
+	ErrNotReversible       = errors.New("only deposits, withdrawals and adjustments can be reversed")
+)
+
 var (
 	WalletStatusEnabled  string = "enabled"
 	WalletStatusDisabled string = "disabled"
+	WalletStatusFrozen   string = "frozen"
+
+	HistoryTypeDeposit    string = "deposit"
+	HistoryTypeWithdraw   string = "withdraw"
+	HistoryTypeFee        string = "fee"
+	HistoryTypeFeeRevenue string = "fee_revenue"
+	HistoryTypeTransfer   string = "transfer"
+	HistoryTypeTransferIn string = "transfer_in"
+
+	HistoryTypeConversionOut string = "conversion_out"
+	HistoryTypeConversionIn  string = "conversion_in"
+
+	HistoryTypeLoanDisbursement string = "loan_disbursement"
+	HistoryTypeLoanRepayment    string = "loan_repayment"
+	HistoryTypeLateFee          string = "late_fee"
+
+	HistoryTypeAdjustmentCredit string = "adjustment_credit"
+	HistoryTypeAdjustmentDebit  string = "adjustment_debit"
+	HistoryTypeReversal         string = "reversal"
+
+	HistoryStatusSuccess  string = "success"
+	HistoryStatusAccrued  string = "accrued"
+	HistoryStatusReversed string = "reversed"
 )
 
 type ParamWalletDeposit struct {
 	WalletID    string
 	Balance     float64
 	Amount      float64
+	Currency    string
 	CustomerXId string
 	ReferenceID string
+	// Limit, when set, is checked again within the deposit transaction
+	Limit *LimitCheck
 }
 
 type ParamWalletWithdraw struct {
 	WalletID    string
 	Balance     float64
 	Amount      float64
+	Currency    string
 	CustomerXId string
 	ReferenceID string
+	// Fee is debited on top of Amount and credited to FeeAccount wallet owner
+	Fee        float64
+	FeeAccount string
+	// Limit, when set, is checked again within the withdraw transaction
+	Limit *LimitCheck
+}
+
+// ParamFreezeWallet freezes or unfreezes the main wallet of a customer in Currency. A frozen wallet is
+// disabled and cannot be enabled by the customer, unfreezing restores the state it was frozen in.
+// Audit, when set, builds the audit row written in the same transaction.
+type ParamFreezeWallet struct {
+	CustomerXId string
+	Currency    string
+	Frozen      bool
+	Audit       func(wallet *entity.Wallet) *entity.AdminAudit
+}
+
+// ParamAdjustBalance credits a positive Amount to the wallet or debits a negative one
+type ParamAdjustBalance struct {
+	WalletID    string
+	Currency    string
+	Amount      float64
+	ReferenceID string
+}
+
+// ParamReverseTransaction reverses the history line HistoryID, the reversal line carries the id of the
+// reversed line as its reference
+type ParamReverseTransaction struct {
+	HistoryID string
+}
+
+// ParamSearchWallets filters wallets and pockets, empty or zero filters match everything
+type ParamSearchWallets struct {
+	CustomerXId   string
+	Currency      string
+	Status        string
+	MinBalance    *float64
+	MaxBalance    *float64
+	CreatedAfter  time.Time
+	CreatedBefore time.Time
+	Limit         int
+	Offset        int
+}
+
+type ParamGetHistories struct {
+	WalletID string
+	Limit    int
+	Offset   int
+}
+
+type TransactionUsage struct {
+	Amount float64
+	Count  int
+}
+
+// LimitUsage is what the limits of a transaction are checked against, Balance is the total of the main
+// wallet and every pocket in the currency
+type LimitUsage struct {
+	Daily   TransactionUsage
+	Monthly TransactionUsage
+	Balance float64
+}
+
+// LimitCheck validates a transaction against the usage of its customer read within the transaction
+// posting it, once the main wallet is locked, so concurrent transactions cannot each pass the same limit
+type LimitCheck struct {
+	TxType       string
+	DailySince   time.Time
+	MonthlySince time.Time
+	Check        func(usage LimitUsage) error
+}
+
+type ParamUpdateKycLevel struct {
+	CustomerXId string
+	KycLevel    string
+	Actor       string
+	Reason      string
 }
@@ -1,21 +1,35 @@
 package models
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"sync"
 	"time"
 
 	"github.com/ahmadmirdas/julo-test/repository/database/models/entity"
+	"github.com/ahmadmirdas/julo-test/utils/metrics"
 	"github.com/go-pg/pg/v10"
+	"github.com/go-pg/pg/v10/orm"
 )
 
 type WalletDBRepo interface {
-	EnableWallet(customerXId string) (*entity.Wallet, error)
-	GetWallet(customerXId string) (*entity.Wallet, error)
-	WalletDeposit(param ParamWalletDeposit) (*entity.History, error)
-	WalletWithdraw(param ParamWalletWithdraw) (*entity.History, error)
-	UpdateStatusWallet(customerXId string, status bool) (*entity.Wallet, error)
+	EnableWallet(ctx context.Context, customerXId, currency string) (*entity.Wallet, error)
+	GetWallet(ctx context.Context, customerXId, currency string) (*entity.Wallet, error)
+	GetWallets(ctx context.Context, customerXId string) ([]entity.Wallet, error)
+	GetTotalBalance(ctx context.Context, customerXId, currency string) (float64, error)
+	WalletDeposit(ctx context.Context, param ParamWalletDeposit) (*entity.History, error)
+	WalletWithdraw(ctx context.Context, param ParamWalletWithdraw) (*entity.History, error)
+	UpdateStatusWallet(ctx context.Context, customerXId, currency string, status bool) (*entity.Wallet, error)
+	GetWalletLimit(ctx context.Context, walletID, txType string) (*entity.WalletLimit, error)
+	GetTransactionUsage(ctx context.Context, customerXId, currency, txType string, since time.Time) (*TransactionUsage, error)
+	UpdateKycLevel(ctx context.Context, param ParamUpdateKycLevel) ([]entity.Wallet, error)
+	GetKycHistory(ctx context.Context, customerXId string) ([]entity.KycHistory, error)
+	GetHistories(ctx context.Context, param ParamGetHistories) ([]entity.History, error)
+	FreezeWallet(ctx context.Context, param ParamFreezeWallet) (*entity.Wallet, error)
+	GetWalletByID(ctx context.Context, walletID string) (*entity.Wallet, error)
+	SearchWallets(ctx context.Context, param ParamSearchWallets) ([]entity.Wallet, error)
+	GetHistory(ctx context.Context, historyID string) (*entity.History, error)
 }
 
 type dbWalletRepo struct {
@@ -27,87 +41,239 @@ func NewDBWalletRepo(c *pg.DB) WalletDBRepo {
 	return &dbWalletRepo{dbConn: c}
 }
 
-func (p *dbWalletRepo) EnableWallet(customerXId string) (*entity.Wallet, error) {
+func (p *dbWalletRepo) EnableWallet(ctx context.Context, customerXId, currency string) (*entity.Wallet, error) {
 	if customerXId == "" {
 		return nil, errors.New("customerXId is empty")
 	}
 
-	resWallet, err := p.GetWallet(customerXId)
+	wallets, err := p.GetWallets(ctx, customerXId)
 	if err != nil {
 		return nil, err
 	}
 
-	if resWallet != nil {
-		if resWallet.IsEnabled {
-			return nil, fmt.Errorf("wallet is already enabled")
-		}
-	}
-
 	wallet := entity.Wallet{
 		OwnedBy:   customerXId,
 		IsEnabled: true,
 		EnabledAt: time.Now(),
+		Currency:  currency,
 	}
+	for _, w := range wallets {
+		if w.Currency == currency && !w.FrozenAt.IsZero() {
+			return nil, ErrWalletFrozen
+		}
+		if w.Currency == currency && w.IsEnabled {
+			return nil, fmt.Errorf("wallet is already enabled")
+		}
+		// a new currency wallet inherits the kyc level of the customer
+		wallet.KycLevel = w.KycLevel
+	}
+
 	p.mutex.Lock()
-	res, err := p.dbConn.Model(&wallet).
-		OnConflict("(owned_by) DO UPDATE").
-		Set("is_enabled = EXCLUDED.is_enabled").
-		Insert()
+	defer p.mutex.Unlock()
+	err = p.dbConn.RunInTransaction(ctx, func(tx *pg.Tx) error {
+		res, err := tx.Model(&wallet).
+			OnConflict("(owned_by, currency) WHERE parent_id IS NULL DO UPDATE").
+			Set("is_enabled = EXCLUDED.is_enabled").
+			Where("wallet.frozen_at IS NULL").
+			Returning("*").
+			Insert()
+		if err != nil {
+			return err
+		}
+		if res.RowsAffected() == 0 {
+			return fmt.Errorf("failed enabled wallet")
+		}
+
+		return recordWalletEvent(tx, EventWalletEnabled, &wallet)
+	})
 	if err != nil {
 		return nil, err
 	}
-	p.mutex.Unlock()
-	if res.RowsAffected() == 0 {
-		return nil, fmt.Errorf("failed enabled wallet")
-	}
+	metrics.WalletStatusChanges.WithLabelValues(WalletStatusEnabled).Inc()
 
 	return &wallet, nil
 }
 
-func (p *dbWalletRepo) GetWallet(customerXId string) (*entity.Wallet, error) {
+func (p *dbWalletRepo) GetWallet(ctx context.Context, customerXId, currency string) (*entity.Wallet, error) {
 	var wallet entity.Wallet
-	p.mutex.Lock()
-	err := p.dbConn.Model(&wallet).
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+	err := p.dbConn.ModelContext(ctx, &wallet).
 		Where("owned_by = ?", customerXId).
+		Where("currency = ?", currency).
+		Where("parent_id IS NULL").
 		Select()
 	if err != nil {
 		if err != pg.ErrNoRows {
 			return nil, err
 		}
 	}
-	p.mutex.Unlock()
 	return &wallet, nil
 }
 
-func (p *dbWalletRepo) WalletDeposit(param ParamWalletDeposit) (*entity.History, error) {
+// GetWallets returns every currency main wallet of a customer, pockets are excluded
+func (p *dbWalletRepo) GetWallets(ctx context.Context, customerXId string) ([]entity.Wallet, error) {
+	var wallets []entity.Wallet
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+	err := p.dbConn.ModelContext(ctx, &wallets).
+		Where("owned_by = ?", customerXId).
+		Where("parent_id IS NULL").
+		Order("currency").
+		Select()
+	if err != nil {
+		return nil, err
+	}
+	return wallets, nil
+}
+
+// GetTotalBalance sums the balance of the main wallet and every pocket of a customer in currency
+func (p *dbWalletRepo) GetTotalBalance(ctx context.Context, customerXId, currency string) (float64, error) {
+	return totalBalance(ctx, p.dbConn, customerXId, currency)
+}
+
+func totalBalance(ctx context.Context, db orm.DB, customerXId, currency string) (float64, error) {
+	var total float64
+	err := db.ModelContext(ctx, (*entity.Wallet)(nil)).
+		ColumnExpr("COALESCE(SUM(balance), 0)").
+		Where("owned_by = ?", customerXId).
+		Where("currency = ?", currency).
+		Select(&total)
+	if err != nil {
+		return 0, err
+	}
+
+	return total, nil
+}
+
+func (p *dbWalletRepo) WalletDeposit(ctx context.Context, param ParamWalletDeposit) (*entity.History, error) {
 	var result entity.History
 
-	wallet := entity.Wallet{}
-	res, err := p.dbConn.Model(&wallet).
-		Where("owned_by = ? ", param.CustomerXId).
-		Set("balance = ?", param.Balance+param.Amount).
-		Update()
+	history := entity.History{
+		WalletID:    param.WalletID,
+		Status:      HistoryStatusSuccess,
+		Amount:      param.Amount,
+		Currency:    param.Currency,
+		Type:        HistoryTypeDeposit,
+		ReferenceID: param.ReferenceID,
+	}
+
+	err := p.dbConn.RunInTransaction(ctx, func(tx *pg.Tx) error {
+		if err := checkLimit(tx, param.CustomerXId, param.Currency, param.Limit); err != nil {
+			return err
+		}
+
+		wallet := entity.Wallet{}
+		res, err := tx.Model(&wallet).
+			Where("id = ?", param.WalletID).
+			Where("currency = ?", param.Currency).
+			Set("balance = balance + ?", param.Amount).
+			Returning("*").
+			Update()
+		if err != nil {
+			return err
+		}
+		if res.RowsAffected() == 0 {
+			return fmt.Errorf("deposit failed - error update balance")
+		}
+
+		_, err = tx.Model(&history).Insert()
+		if err != nil {
+			return err
+		}
+
+		return recordTransactionEvent(tx, EventWalletDeposited, TransactionEventData{
+			TransactionID: history.ID,
+			WalletID:      wallet.ID,
+			CustomerXId:   wallet.OwnedBy,
+			Type:          history.Type,
+			Amount:        history.Amount,
+			Currency:      history.Currency,
+			ReferenceID:   history.ReferenceID,
+			Balance:       wallet.Balance,
+		})
+	})
 	if err != nil {
 		return nil, err
 	}
+	metrics.ObserveTransaction(HistoryTypeDeposit, param.Currency, param.Amount)
 
-	if res.RowsAffected() == 0 {
-		return nil, fmt.Errorf("deposit failed - error update balance")
+	err = p.dbConn.ModelContext(ctx, &result).Relation("Wallet").
+		Where("history.id = ?", history.ID).
+		Select()
+	if err != nil {
+		return nil, err
 	}
 
+	return &result, nil
+}
+
+func (p *dbWalletRepo) WalletWithdraw(ctx context.Context, param ParamWalletWithdraw) (*entity.History, error) {
+	var result entity.History
+
 	history := entity.History{
 		WalletID:    param.WalletID,
-		Status:      "success",
+		Status:      HistoryStatusSuccess,
+		Type:        HistoryTypeWithdraw,
 		Amount:      param.Amount,
-		Type:        "deposit",
+		Currency:    param.Currency,
 		ReferenceID: param.ReferenceID,
 	}
-	_, err = p.dbConn.Model(&history).Insert()
+	totalDebited := param.Amount + param.Fee
+
+	err := p.dbConn.RunInTransaction(ctx, func(tx *pg.Tx) error {
+		if err := checkLimit(tx, param.CustomerXId, param.Currency, param.Limit); err != nil {
+			return err
+		}
+
+		wallet := entity.Wallet{}
+		res, err := tx.Model(&wallet).
+			Where("id = ?", param.WalletID).
+			Where("currency = ?", param.Currency).
+			Where("balance >= ?", totalDebited).
+			Set("balance = balance - ?", totalDebited).
+			Returning("*").
+			Update()
+		if err != nil {
+			return err
+		}
+		if res.RowsAffected() == 0 {
+			return ErrInsufficientFund
+		}
+
+		_, err = tx.Model(&history).Insert()
+		if err != nil {
+			return err
+		}
+
+		if param.Fee > 0 {
+			err = postFee(tx, param.WalletID, param.FeeAccount, param.Currency, param.Fee, param.ReferenceID)
+			if err != nil {
+				return err
+			}
+		}
+
+		return recordTransactionEvent(tx, EventWalletWithdrawn, TransactionEventData{
+			TransactionID: history.ID,
+			WalletID:      wallet.ID,
+			CustomerXId:   wallet.OwnedBy,
+			Type:          history.Type,
+			Amount:        history.Amount,
+			Fee:           param.Fee,
+			Currency:      history.Currency,
+			ReferenceID:   history.ReferenceID,
+			Balance:       wallet.Balance,
+		})
+	})
 	if err != nil {
 		return nil, err
 	}
+	metrics.ObserveTransaction(HistoryTypeWithdraw, param.Currency, param.Amount)
+	if param.Fee > 0 {
+		metrics.ObserveTransaction(HistoryTypeFee, param.Currency, param.Fee)
+	}
 
-	err = p.dbConn.Model(&result).Relation("Wallet").
+	err = p.dbConn.ModelContext(ctx, &result).Relation("Wallet").
 		Where("history.id = ?", history.ID).
 		Select()
 	if err != nil {
@@ -117,70 +283,573 @@ func (p *dbWalletRepo) WalletDeposit(param ParamWalletDeposit) (*entity.History,
 	return &result, nil
 }
 
-func (p *dbWalletRepo) WalletWithdraw(param ParamWalletWithdraw) (*entity.History, error) {
-	var result entity.History
+// postFee records the fee debit on the customer wallet and credits it to the fee revenue wallet, the credit
+// is published as an event of the revenue wallet
+func postFee(tx *pg.Tx, walletID, feeAccount, currency string, fee float64, referenceID string) error {
+	if feeAccount == "" {
+		return errors.New("fee revenue account is not configured")
+	}
+
+	_, err := tx.Model(&entity.History{
+		WalletID:    walletID,
+		Status:      HistoryStatusSuccess,
+		Type:        HistoryTypeFee,
+		Amount:      fee,
+		Currency:    currency,
+		ReferenceID: referenceID,
+	}).Insert()
+	if err != nil {
+		return err
+	}
+
+	revenue := entity.Wallet{
+		OwnedBy:   feeAccount,
+		IsEnabled: true,
+		Balance:   fee,
+		Currency:  currency,
+		EnabledAt: time.Now(),
+	}
+	_, err = tx.Model(&revenue).
+		OnConflict("(owned_by, currency) WHERE parent_id IS NULL DO UPDATE").
+		Set("balance = wallet.balance + EXCLUDED.balance").
+		Returning("*").
+		Insert()
+	if err != nil {
+		return err
+	}
 
+	credit := entity.History{
+		WalletID:    revenue.ID,
+		Status:      HistoryStatusSuccess,
+		Type:        HistoryTypeFeeRevenue,
+		Amount:      fee,
+		Currency:    currency,
+		ReferenceID: referenceID,
+	}
+	_, err = tx.Model(&credit).Insert()
+	if err != nil {
+		return err
+	}
+
+	return recordTransactionEvent(tx, EventWalletFeeCollected, TransactionEventData{
+		TransactionID:       credit.ID,
+		WalletID:            revenue.ID,
+		CustomerXId:         revenue.OwnedBy,
+		Type:                credit.Type,
+		Amount:              credit.Amount,
+		Currency:            credit.Currency,
+		ReferenceID:         credit.ReferenceID,
+		Balance:             revenue.Balance,
+		CounterpartWalletID: walletID,
+	})
+}
+
+func (p *dbWalletRepo) UpdateStatusWallet(ctx context.Context, customerXId, currency string, status bool) (*entity.Wallet, error) {
 	wallet := entity.Wallet{}
 	p.mutex.Lock()
-	res, err := p.dbConn.Model(&wallet).
-		Where("owned_by = ? ", param.CustomerXId).
-		Set("balance = ?", param.Balance-param.Amount).
-		Update()
+	err := p.dbConn.RunInTransaction(ctx, func(tx *pg.Tx) error {
+		res, err := tx.Model(&wallet).
+			Where("owned_by = ?", customerXId).
+			Where("currency = ?", currency).
+			Where("parent_id IS NULL").
+			Set("is_enabled = ?", status).
+			Set("disabled_at = ?", time.Now()).
+			Returning("*").
+			Update()
+		if err != nil {
+			return err
+		}
+		if res.RowsAffected() == 0 {
+			return fmt.Errorf("failed update status wallet")
+		}
+
+		eventType := EventWalletDisabled
+		if status {
+			eventType = EventWalletEnabled
+		}
+		return recordWalletEvent(tx, eventType, &wallet)
+	})
+	p.mutex.Unlock()
 	if err != nil {
 		return nil, err
 	}
-	p.mutex.Unlock()
+	if status {
+		metrics.WalletStatusChanges.WithLabelValues(WalletStatusEnabled).Inc()
+	} else {
+		metrics.WalletStatusChanges.WithLabelValues(WalletStatusDisabled).Inc()
+	}
 
-	if res.RowsAffected() == 0 {
-		return nil, fmt.Errorf("withdraw failed - error update balance")
+	resWallet, err := p.GetWallet(ctx, customerXId, currency)
+	if err != nil {
+		return nil, err
+	}
+
+	return resWallet, nil
+}
+
+// GetWalletLimit returns the limit override of a wallet, an empty override when none is set
+func (p *dbWalletRepo) GetWalletLimit(ctx context.Context, walletID, txType string) (*entity.WalletLimit, error) {
+	walletLimit := entity.WalletLimit{WalletID: walletID, Type: txType}
+	err := p.dbConn.ModelContext(ctx, &walletLimit).WherePK().Select()
+	if err != nil && err != pg.ErrNoRows {
+		return nil, err
+	}
+
+	return &walletLimit, nil
+}
+
+// GetTransactionUsage sums the transactions of txType since a time across the main wallet and every
+// pocket of a customer in currency, so moving money into a pocket does not reset the usage
+func (p *dbWalletRepo) GetTransactionUsage(ctx context.Context, customerXId, currency, txType string, since time.Time) (*TransactionUsage, error) {
+	return transactionUsage(ctx, p.dbConn, customerXId, currency, txType, since)
+}
+
+func transactionUsage(ctx context.Context, db orm.DB, customerXId, currency, txType string, since time.Time) (*TransactionUsage, error) {
+	var usage TransactionUsage
+	err := db.ModelContext(ctx, (*entity.History)(nil)).
+		ColumnExpr("COALESCE(SUM(amount), 0)").
+		ColumnExpr("COUNT(*)").
+		Where("wallet_id IN (SELECT id FROM wallet WHERE owned_by = ? AND currency = ?)", customerXId, currency).
+		Where("type = ?", txType).
+		Where("status = ?", HistoryStatusSuccess).
+		Where("created_at >= ?", since).
+		Select(&usage.Amount, &usage.Count)
+	if err != nil {
+		return nil, err
+	}
+
+	return &usage, nil
+}
+
+// UpdateKycLevel changes the kyc level of every wallet of a customer and records it in the kyc audit trail
+func (p *dbWalletRepo) UpdateKycLevel(ctx context.Context, param ParamUpdateKycLevel) ([]entity.Wallet, error) {
+	var wallets []entity.Wallet
+	err := p.dbConn.RunInTransaction(ctx, func(tx *pg.Tx) error {
+		err := tx.Model(&wallets).
+			Where("owned_by = ?", param.CustomerXId).
+			For("UPDATE").
+			Select()
+		if err != nil {
+			return err
+		}
+		if len(wallets) == 0 {
+			return ErrWalletNotFound
+		}
+
+		for i := range wallets {
+			if wallets[i].KycLevel == param.KycLevel {
+				continue
+			}
+
+			_, err = tx.Model(&entity.KycHistory{
+				WalletID:  wallets[i].ID,
+				FromLevel: wallets[i].KycLevel,
+				ToLevel:   param.KycLevel,
+				Actor:     param.Actor,
+				Reason:    param.Reason,
+			}).Insert()
+			if err != nil {
+				return err
+			}
+
+			data := newWalletEventData(&wallets[i])
+			data.PreviousKycLevel = wallets[i].KycLevel
+			data.KycLevel = param.KycLevel
+			wallets[i].KycLevel = param.KycLevel
+			_, err = tx.Model(&wallets[i]).
+				Set("kyc_level = ?kyc_level").
+				WherePK().
+				Update()
+			if err != nil {
+				return err
+			}
+
+			err = recordEvent(tx, EventWalletKycChanged, AggregateWallet, wallets[i].ID, wallets[i].OwnedBy, data)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
 
+	return wallets, nil
+}
+
+func (p *dbWalletRepo) GetKycHistory(ctx context.Context, customerXId string) ([]entity.KycHistory, error) {
+	var histories []entity.KycHistory
+	err := p.dbConn.ModelContext(ctx, &histories).
+		Where("wallet_id IN (SELECT id FROM wallet WHERE owned_by = ?)", customerXId).
+		Order("created_at DESC").
+		Select()
+	if err != nil {
+		return nil, err
+	}
+
+	return histories, nil
+}
+
+// GetHistories returns a page of the transactions of a wallet or pocket, newest first
+func (p *dbWalletRepo) GetHistories(ctx context.Context, param ParamGetHistories) ([]entity.History, error) {
+	var histories []entity.History
+	err := p.dbConn.ModelContext(ctx, &histories).
+		Where("wallet_id = ?", param.WalletID).
+		Order("created_at DESC").
+		Limit(param.Limit).
+		Offset(param.Offset).
+		Select()
+	if err != nil {
+		return nil, err
+	}
+
+	return histories, nil
+}
+
+// FreezeWallet freezes or unfreezes the main wallet of a customer, pockets follow their main wallet.
+// Unfreezing restores the enabled state the wallet had when it was frozen.
+func (p *dbWalletRepo) FreezeWallet(ctx context.Context, param ParamFreezeWallet) (*entity.Wallet, error) {
+	wallet := &entity.Wallet{}
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	err := p.dbConn.RunInTransaction(ctx, func(tx *pg.Tx) error {
+		// disabled wallets are frozen as well, so lockWallet is not used
+		err := tx.Model(wallet).
+			Where("owned_by = ?", param.CustomerXId).
+			Where("currency = ?", param.Currency).
+			Where("parent_id IS NULL").
+			For("UPDATE").
+			Select()
+		if err == pg.ErrNoRows {
+			return ErrWalletNotFound
+		}
+		if err != nil {
+			return err
+		}
+		if param.Frozen && !wallet.FrozenAt.IsZero() {
+			return ErrWalletFrozen
+		}
+		if !param.Frozen && wallet.FrozenAt.IsZero() {
+			return ErrWalletNotFrozen
+		}
+
+		now := time.Now()
+		q := tx.Model(wallet).WherePK()
+		eventType := EventWalletFrozen
+		if param.Frozen {
+			wallet.FrozenAt = now
+			wallet.EnabledBeforeFreeze = wallet.IsEnabled
+			if wallet.IsEnabled {
+				wallet.IsEnabled = false
+				wallet.DisabledAt = now
+			}
+			q = q.Set("frozen_at = ?frozen_at").Set("enabled_before_freeze = ?enabled_before_freeze")
+		} else {
+			eventType = EventWalletUnfrozen
+			wallet.FrozenAt = time.Time{}
+			if wallet.EnabledBeforeFreeze {
+				wallet.IsEnabled = true
+				wallet.EnabledAt = now
+			}
+			wallet.EnabledBeforeFreeze = false
+			q = q.Set("frozen_at = NULL").Set("enabled_before_freeze = ?enabled_before_freeze")
+		}
+		q = q.Set("is_enabled = ?is_enabled").Set("enabled_at = ?enabled_at").Set("disabled_at = ?disabled_at")
+		if _, err := q.Update(); err != nil {
+			return err
+		}
+		if err := recordWalletEvent(tx, eventType, wallet); err != nil {
+			return err
+		}
+
+		if param.Audit == nil {
+			return nil
+		}
+		return recordAudit(tx, param.Audit(wallet))
+	})
+	if err != nil {
+		return nil, err
+	}
+	if param.Frozen && wallet.EnabledBeforeFreeze {
+		metrics.WalletStatusChanges.WithLabelValues(WalletStatusDisabled).Inc()
+	}
+	if !param.Frozen && wallet.IsEnabled {
+		metrics.WalletStatusChanges.WithLabelValues(WalletStatusEnabled).Inc()
+	}
+
+	return wallet, nil
+}
+
+// adjustBalance applies a manual correction within tx, a debit fails with ErrInsufficientFund rather
+// than taking the balance below zero. Corrections are only posted by approving an adjustment.
+func adjustBalance(tx *pg.Tx, param ParamAdjustBalance) (*entity.History, error) {
 	history := entity.History{
 		WalletID:    param.WalletID,
-		Status:      "success",
-		Type:        "withdraw",
+		Status:      HistoryStatusSuccess,
+		Type:        HistoryTypeAdjustmentCredit,
 		Amount:      param.Amount,
+		Currency:    param.Currency,
 		ReferenceID: param.ReferenceID,
 	}
-	p.mutex.Lock()
-	_, err = p.dbConn.Model(&history).Insert()
+	if param.Amount < 0 {
+		history.Type = HistoryTypeAdjustmentDebit
+		history.Amount = -param.Amount
+	}
+
+	wallet := entity.Wallet{}
+	q := tx.Model(&wallet).
+		Where("id = ?", param.WalletID).
+		Where("currency = ?", param.Currency).
+		Set("balance = balance + ?", param.Amount).
+		Returning("*")
+	if param.Amount < 0 {
+		q = q.Where("balance >= ?", history.Amount)
+	}
+	res, err := q.Update()
 	if err != nil {
 		return nil, err
 	}
-	p.mutex.Unlock()
+	if res.RowsAffected() == 0 {
+		if param.Amount < 0 {
+			return nil, ErrInsufficientFund
+		}
+		return nil, ErrWalletNotFound
+	}
 
-	p.mutex.RLock()
-	err = p.dbConn.Model(&result).Relation("Wallet").
-		Where("history.id = ?", history.ID).
+	if _, err := tx.Model(&history).Insert(); err != nil {
+		return nil, err
+	}
+
+	err = recordTransactionEvent(tx, EventWalletAdjusted, TransactionEventData{
+		TransactionID: history.ID,
+		WalletID:      wallet.ID,
+		CustomerXId:   wallet.OwnedBy,
+		Type:          history.Type,
+		Amount:        history.Amount,
+		Currency:      history.Currency,
+		ReferenceID:   history.ReferenceID,
+		Balance:       wallet.Balance,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &history, nil
+}
+
+// GetWalletByID returns a main wallet or pocket, ErrWalletNotFound when there is none
+func (p *dbWalletRepo) GetWalletByID(ctx context.Context, walletID string) (*entity.Wallet, error) {
+	var wallet entity.Wallet
+	err := p.dbConn.ModelContext(ctx, &wallet).
+		Where("id = ?", walletID).
 		Select()
+	if err == pg.ErrNoRows {
+		return nil, ErrWalletNotFound
+	}
 	if err != nil {
 		return nil, err
 	}
-	p.mutex.RUnlock()
 
-	return &result, nil
+	return &wallet, nil
+}
+
+// SearchWallets returns a page of main wallets and pockets matching every filter, newest first
+func (p *dbWalletRepo) SearchWallets(ctx context.Context, param ParamSearchWallets) ([]entity.Wallet, error) {
+	var wallets []entity.Wallet
+	q := p.dbConn.ModelContext(ctx, &wallets).
+		Order("created_at DESC", "id").
+		Limit(param.Limit).
+		Offset(param.Offset)
+	if param.CustomerXId != "" {
+		q = q.Where("owned_by = ?", param.CustomerXId)
+	}
+	if param.Currency != "" {
+		q = q.Where("currency = ?", param.Currency)
+	}
+	switch param.Status {
+	case WalletStatusEnabled:
+		q = q.Where("is_enabled")
+	case WalletStatusDisabled:
+		q = q.Where("NOT is_enabled").Where("frozen_at IS NULL")
+	case WalletStatusFrozen:
+		q = q.Where("frozen_at IS NOT NULL")
+	}
+	if param.MinBalance != nil {
+		q = q.Where("balance >= ?", *param.MinBalance)
+	}
+	if param.MaxBalance != nil {
+		q = q.Where("balance <= ?", *param.MaxBalance)
+	}
+	if !param.CreatedAfter.IsZero() {
+		q = q.Where("created_at >= ?", param.CreatedAfter)
+	}
+	if !param.CreatedBefore.IsZero() {
+		q = q.Where("created_at < ?", param.CreatedBefore)
+	}
+	if err := q.Select(); err != nil {
+		return nil, err
+	}
+
+	return wallets, nil
 }
 
-func (p *dbWalletRepo) UpdateStatusWallet(customerXId string, status bool) (*entity.Wallet, error) {
+// GetHistory returns a history line, ErrTransactionNotFound when there is none
+func (p *dbWalletRepo) GetHistory(ctx context.Context, historyID string) (*entity.History, error) {
+	var history entity.History
+	err := p.dbConn.ModelContext(ctx, &history).
+		Where("id = ?", historyID).
+		Select()
+	if err == pg.ErrNoRows {
+		return nil, ErrTransactionNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &history, nil
+}
+
+// ReversalDelta returns the balance change that reverses a deposit, withdrawal or adjustment,
+// ErrTransactionReversed or ErrNotReversible when it cannot be reversed
+func ReversalDelta(original *entity.History) (float64, error) {
+	if original.Status == HistoryStatusReversed {
+		return 0, ErrTransactionReversed
+	}
+	if original.Status != HistoryStatusSuccess {
+		return 0, ErrNotReversible
+	}
+	switch original.Type {
+	case HistoryTypeDeposit, HistoryTypeAdjustmentCredit:
+		return -original.Amount, nil
+	case HistoryTypeWithdraw, HistoryTypeAdjustmentDebit:
+		return original.Amount, nil
+	}
+	return 0, ErrNotReversible
+}
+
+// reverseTransaction posts the opposite of a deposit, withdrawal or adjustment on its wallet within tx
+// and marks it reversed, so it no longer counts toward limits. A withdrawal fee is not refunded.
+// Reversing a credit fails with ErrInsufficientFund when the balance no longer covers it. Reversals are
+// only posted by approving a reversal adjustment.
+func reverseTransaction(tx *pg.Tx, param ParamReverseTransaction) (*entity.History, error) {
+	var original entity.History
+	err := tx.Model(&original).
+		Where("id = ?", param.HistoryID).
+		For("UPDATE").
+		Select()
+	if err == pg.ErrNoRows {
+		return nil, ErrTransactionNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	delta, err := ReversalDelta(&original)
+	if err != nil {
+		return nil, err
+	}
+
 	wallet := entity.Wallet{}
-	p.mutex.Lock()
-	res, err := p.dbConn.Model(&wallet).
-		Where("owned_by = ?", customerXId).
-		Set("is_enabled = ?", status).
-		Set("disabled_at = ?", time.Now()).
-		Update()
+	q := tx.Model(&wallet).
+		Where("id = ?", original.WalletID).
+		Set("balance = balance + ?", delta).
+		Returning("*")
+	if delta < 0 {
+		q = q.Where("balance >= ?", -delta)
+	}
+	res, err := q.Update()
 	if err != nil {
 		return nil, err
 	}
-	p.mutex.Unlock()
 	if res.RowsAffected() == 0 {
-		return nil, fmt.Errorf("failed update status wallet")
+		return nil, ErrInsufficientFund
 	}
 
-	resWallet, err := p.GetWallet(customerXId)
+	_, err = tx.Model(&original).
+		Set("status = ?", HistoryStatusReversed).
+		WherePK().
+		Update()
 	if err != nil {
 		return nil, err
 	}
 
-	return resWallet, nil
+	reversal := entity.History{
+		WalletID:    original.WalletID,
+		Status:      HistoryStatusSuccess,
+		Type:        HistoryTypeReversal,
+		Amount:      original.Amount,
+		Currency:    original.Currency,
+		ReferenceID: original.ID,
+	}
+	if _, err := tx.Model(&reversal).Insert(); err != nil {
+		return nil, err
+	}
+
+	err = recordTransactionEvent(tx, EventWalletReversed, TransactionEventData{
+		TransactionID: reversal.ID,
+		WalletID:      wallet.ID,
+		CustomerXId:   wallet.OwnedBy,
+		Type:          reversal.Type,
+		Amount:        reversal.Amount,
+		Currency:      reversal.Currency,
+		ReferenceID:   reversal.ReferenceID,
+		Balance:       wallet.Balance,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &reversal, nil
+}
+
+// checkLimit locks the main wallet of the customer in currency, which serializes the transactions of the
+// main wallet and its pockets, then runs the limit check against the usage read within tx
+func checkLimit(tx *pg.Tx, customerXId, currency string, check *LimitCheck) error {
+	if check == nil {
+		return nil
+	}
+
+	ctx := tx.Context()
+	_, err := tx.ExecContext(ctx, "SELECT id FROM wallet WHERE owned_by = ? AND currency = ? AND parent_id IS NULL FOR UPDATE",
+		customerXId, currency)
+	if err != nil {
+		return err
+	}
+
+	daily, err := transactionUsage(ctx, tx, customerXId, currency, check.TxType, check.DailySince)
+	if err != nil {
+		return err
+	}
+	monthly, err := transactionUsage(ctx, tx, customerXId, currency, check.TxType, check.MonthlySince)
+	if err != nil {
+		return err
+	}
+	balance, err := totalBalance(ctx, tx, customerXId, currency)
+	if err != nil {
+		return err
+	}
+
+	return check.Check(LimitUsage{Daily: *daily, Monthly: *monthly, Balance: balance})
+}
+
+// lockWallet selects the enabled main wallet of the customer in currency for update
+func lockWallet(tx *pg.Tx, customerXId, currency string) (*entity.Wallet, error) {
+	var wallet entity.Wallet
+	err := tx.Model(&wallet).
+		Where("owned_by = ?", customerXId).
+		Where("currency = ?", currency).
+		Where("parent_id IS NULL").
+		For("UPDATE").
+		Select()
+	if err == pg.ErrNoRows {
+		return nil, ErrWalletNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !wallet.IsEnabled {
+		return nil, ErrWalletDisabled
+	}
+
+	return &wallet, nil
 }
@@ -0,0 +1,74 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+var (
+	WebhookStatusPending   string = "pending"
+	WebhookStatusSucceeded string = "succeeded"
+	WebhookStatusDead      string = "dead"
+)
+
+var (
+	ErrWebhookNotFound       = errors.New("webhook endpoint not found")
+	ErrDeliveryNotFound      = errors.New("webhook delivery not found")
+	ErrDeliveryNotReplayable = errors.New("only dead webhook deliveries can be replayed")
+	ErrCustomerNotLinked     = errors.New("customer is not linked to the partner")
+)
+
+type ParamCreateWebhook struct {
+	PartnerID   string
+	URL         string
+	Secret      string
+	EventTypes  []string
+	CustomerXId string
+	CreatedBy   string
+}
+
+// ParamLinkCustomer lets the endpoints of PartnerID receive the events of CustomerXId
+type ParamLinkCustomer struct {
+	PartnerID   string
+	CustomerXId string
+	CreatedBy   string
+}
+
+// ParamDispatchEvent creates a delivery of the event for every matching endpoint, each delivery
+// is handed to a JobType job that is attempted up to MaxAttempts times
+type ParamDispatchEvent struct {
+	EventID     string
+	EventType   string
+	CustomerXId string
+	Payload     json.RawMessage
+	JobType     string
+	MaxAttempts int
+}
+
+type ParamListDeliveries struct {
+	EndpointID string
+	Status     string
+	Limit      int
+	Offset     int
+}
+
+// ParamRecordAttempt stores the outcome of one delivery attempt and moves the delivery to Status
+type ParamRecordAttempt struct {
+	DeliveryID   string
+	ResponseCode int
+	Error        string
+	Duration     time.Duration
+	Status       string
+}
+
+type ParamReplayDelivery struct {
+	DeliveryID  string
+	JobType     string
+	MaxAttempts int
+}
+
+// WebhookJobPayload is the payload of the job delivering one webhook
+type WebhookJobPayload struct {
+	DeliveryID string `json:"delivery_id"`
+}
@@ -0,0 +1,324 @@
+package models
+
+import (
+	"context"
+	"time"
+
+	"github.com/ahmadmirdas/julo-test/repository/database/models/entity"
+	"github.com/ahmadmirdas/julo-test/utils/webhook"
+	"github.com/go-pg/pg/v10"
+)
+
+type WebhookDBRepo interface {
+	CreateEndpoint(ctx context.Context, param ParamCreateWebhook) (*entity.WebhookEndpoint, error)
+	GetEndpoints(ctx context.Context, partnerID string) ([]entity.WebhookEndpoint, error)
+	GetEndpoint(ctx context.Context, endpointID string) (*entity.WebhookEndpoint, error)
+	DisableEndpoint(ctx context.Context, endpointID string) (*entity.WebhookEndpoint, error)
+	LinkCustomer(ctx context.Context, param ParamLinkCustomer) (*entity.WebhookPartnerCustomer, error)
+	UnlinkCustomer(ctx context.Context, partnerID, customerXId string) error
+	DispatchEvent(ctx context.Context, param ParamDispatchEvent) (int, error)
+	GetDeliveries(ctx context.Context, param ParamListDeliveries) ([]entity.WebhookDelivery, error)
+	GetDelivery(ctx context.Context, deliveryID string) (*entity.WebhookDelivery, error)
+	RecordAttempt(ctx context.Context, param ParamRecordAttempt) error
+	ReplayDelivery(ctx context.Context, param ParamReplayDelivery) (*entity.WebhookDelivery, error)
+}
+
+type dbWebhookRepo struct {
+	dbConn *pg.DB
+}
+
+func NewDBWebhookRepo(c *pg.DB) WebhookDBRepo {
+	return &dbWebhookRepo{dbConn: c}
+}
+
+func (p *dbWebhookRepo) CreateEndpoint(ctx context.Context, param ParamCreateWebhook) (*entity.WebhookEndpoint, error) {
+	endpoint := entity.WebhookEndpoint{
+		PartnerID:   param.PartnerID,
+		URL:         param.URL,
+		Secret:      param.Secret,
+		EventTypes:  param.EventTypes,
+		CustomerXId: param.CustomerXId,
+		IsEnabled:   true,
+		CreatedBy:   param.CreatedBy,
+	}
+	if endpoint.EventTypes == nil {
+		endpoint.EventTypes = []string{}
+	}
+	err := p.dbConn.RunInTransaction(ctx, func(tx *pg.Tx) error {
+		if param.CustomerXId != "" {
+			linked, err := tx.Model((*entity.WebhookPartnerCustomer)(nil)).
+				Where("partner_id = ?", param.PartnerID).
+				Where("customer_xid = ?", param.CustomerXId).
+				Exists()
+			if err != nil {
+				return err
+			}
+			if !linked {
+				return ErrCustomerNotLinked
+			}
+		}
+
+		_, err := tx.Model(&endpoint).Returning("*").Insert()
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &endpoint, nil
+}
+
+// GetEndpoints returns the endpoints of a partner, every endpoint when partnerID is empty
+func (p *dbWebhookRepo) GetEndpoints(ctx context.Context, partnerID string) ([]entity.WebhookEndpoint, error) {
+	var endpoints []entity.WebhookEndpoint
+	q := p.dbConn.ModelContext(ctx, &endpoints).Order("created_at DESC")
+	if partnerID != "" {
+		q = q.Where("partner_id = ?", partnerID)
+	}
+	err := q.Select()
+	if err != nil {
+		return nil, err
+	}
+
+	return endpoints, nil
+}
+
+func (p *dbWebhookRepo) GetEndpoint(ctx context.Context, endpointID string) (*entity.WebhookEndpoint, error) {
+	endpoint := entity.WebhookEndpoint{ID: endpointID}
+	err := p.dbConn.ModelContext(ctx, &endpoint).WherePK().Select()
+	if err == pg.ErrNoRows {
+		return nil, ErrWebhookNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &endpoint, nil
+}
+
+// DisableEndpoint stops new deliveries to the endpoint, pending ones are dropped when attempted
+func (p *dbWebhookRepo) DisableEndpoint(ctx context.Context, endpointID string) (*entity.WebhookEndpoint, error) {
+	var endpoint entity.WebhookEndpoint
+	res, err := p.dbConn.ModelContext(ctx, &endpoint).
+		Set("is_enabled = false").
+		Set("disabled_at = ?", time.Now()).
+		Where("id = ?", endpointID).
+		Returning("*").
+		Update()
+	if err != nil {
+		return nil, err
+	}
+	if res.RowsAffected() == 0 {
+		return nil, ErrWebhookNotFound
+	}
+
+	return &endpoint, nil
+}
+
+// DispatchEvent delivers the event of a customer only to the partners the customer is linked to, to their endpoints
+// without customer and to the ones of that customer. An event without customer goes to every endpoint without customer.
+// Deliveries are unique per endpoint and event so an event relayed twice is delivered once.
+func (p *dbWebhookRepo) DispatchEvent(ctx context.Context, param ParamDispatchEvent) (int, error) {
+	var endpoints []entity.WebhookEndpoint
+	q := p.dbConn.ModelContext(ctx, &endpoints).Where("is_enabled")
+	if param.CustomerXId != "" {
+		q = q.Where("customer_xid IS NULL OR customer_xid = ?", param.CustomerXId).
+			Where("partner_id IN (SELECT partner_id FROM webhook_partner_customer WHERE customer_xid = ?)", param.CustomerXId)
+	} else {
+		q = q.Where("customer_xid IS NULL")
+	}
+	err := q.Select()
+	if err != nil {
+		return 0, err
+	}
+
+	dispatched := 0
+	err = p.dbConn.RunInTransaction(ctx, func(tx *pg.Tx) error {
+		for _, endpoint := range endpoints {
+			if !webhook.MatchEvent(endpoint.EventTypes, param.EventType) {
+				continue
+			}
+
+			delivery := entity.WebhookDelivery{
+				EndpointID: endpoint.ID,
+				EventID:    param.EventID,
+				EventType:  param.EventType,
+				Payload:    param.Payload,
+				Status:     WebhookStatusPending,
+			}
+			res, err := tx.Model(&delivery).
+				OnConflict("(endpoint_id, event_id) DO NOTHING").
+				Insert()
+			if err != nil {
+				return err
+			}
+			if res.RowsAffected() == 0 {
+				continue
+			}
+
+			_, err = enqueueJob(ctx, tx, ParamEnqueueJob{
+				Type:        param.JobType,
+				Payload:     WebhookJobPayload{DeliveryID: delivery.ID},
+				MaxAttempts: param.MaxAttempts,
+			})
+			if err != nil {
+				return err
+			}
+			dispatched++
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return dispatched, nil
+}
+
+// LinkCustomer lets a partner receive the events of a customer, linking twice returns the existing link
+func (p *dbWebhookRepo) LinkCustomer(ctx context.Context, param ParamLinkCustomer) (*entity.WebhookPartnerCustomer, error) {
+	link := entity.WebhookPartnerCustomer{
+		PartnerID:   param.PartnerID,
+		CustomerXId: param.CustomerXId,
+		CreatedBy:   param.CreatedBy,
+	}
+	_, err := p.dbConn.ModelContext(ctx, &link).
+		OnConflict("(partner_id, customer_xid) DO UPDATE").
+		Set("partner_id = EXCLUDED.partner_id").
+		Returning("*").
+		Insert()
+	if err != nil {
+		return nil, err
+	}
+
+	return &link, nil
+}
+
+// UnlinkCustomer stops the events of a customer from reaching the partner, including its endpoints of that customer
+func (p *dbWebhookRepo) UnlinkCustomer(ctx context.Context, partnerID, customerXId string) error {
+	res, err := p.dbConn.ModelContext(ctx, (*entity.WebhookPartnerCustomer)(nil)).
+		Where("partner_id = ?", partnerID).
+		Where("customer_xid = ?", customerXId).
+		Delete()
+	if err != nil {
+		return err
+	}
+	if res.RowsAffected() == 0 {
+		return ErrCustomerNotLinked
+	}
+
+	return nil
+}
+
+func (p *dbWebhookRepo) GetDeliveries(ctx context.Context, param ParamListDeliveries) ([]entity.WebhookDelivery, error) {
+	var deliveries []entity.WebhookDelivery
+	q := p.dbConn.ModelContext(ctx, &deliveries).
+		Where("endpoint_id = ?", param.EndpointID).
+		Order("created_at DESC").
+		Limit(param.Limit).
+		Offset(param.Offset)
+	if param.Status != "" {
+		q = q.Where("status = ?", param.Status)
+	}
+	err := q.Select()
+	if err != nil {
+		return nil, err
+	}
+
+	return deliveries, nil
+}
+
+// GetDelivery returns a delivery with its endpoint and every attempt made
+func (p *dbWebhookRepo) GetDelivery(ctx context.Context, deliveryID string) (*entity.WebhookDelivery, error) {
+	var delivery entity.WebhookDelivery
+	err := p.dbConn.ModelContext(ctx, &delivery).
+		Relation("Endpoint").
+		Relation("AttemptLogs", func(q *pg.Query) (*pg.Query, error) {
+			return q.Order("attempt"), nil
+		}).
+		Where("webhook_delivery.id = ?", deliveryID).
+		Select()
+	if err == pg.ErrNoRows {
+		return nil, ErrDeliveryNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &delivery, nil
+}
+
+func (p *dbWebhookRepo) RecordAttempt(ctx context.Context, param ParamRecordAttempt) error {
+	return p.dbConn.RunInTransaction(ctx, func(tx *pg.Tx) error {
+		var delivery entity.WebhookDelivery
+		q := tx.Model(&delivery).
+			Set("attempts = attempts + 1").
+			Set("status = ?", param.Status).
+			Set("last_error = ?", param.Error).
+			Where("id = ?", param.DeliveryID).
+			Returning("*")
+		if param.ResponseCode > 0 {
+			q = q.Set("last_response_code = ?", param.ResponseCode)
+		} else {
+			q = q.Set("last_response_code = NULL")
+		}
+		if param.Status == WebhookStatusSucceeded {
+			q = q.Set("delivered_at = ?", time.Now())
+		}
+		res, err := q.Update()
+		if err != nil {
+			return err
+		}
+		if res.RowsAffected() == 0 {
+			return ErrDeliveryNotFound
+		}
+
+		_, err = tx.Model(&entity.WebhookAttempt{
+			DeliveryID:   param.DeliveryID,
+			Attempt:      delivery.Attempts,
+			ResponseCode: param.ResponseCode,
+			Error:        param.Error,
+			DurationMs:   param.Duration.Milliseconds(),
+		}).Insert()
+		return err
+	})
+}
+
+// ReplayDelivery sends a dead delivery again, its earlier attempts are kept. A delivery still pending or
+// already succeeded is rejected so a replay never queues a second job for it.
+func (p *dbWebhookRepo) ReplayDelivery(ctx context.Context, param ParamReplayDelivery) (*entity.WebhookDelivery, error) {
+	var delivery entity.WebhookDelivery
+	err := p.dbConn.RunInTransaction(ctx, func(tx *pg.Tx) error {
+		res, err := tx.Model(&delivery).
+			Set("status = ?", WebhookStatusPending).
+			Where("id = ?", param.DeliveryID).
+			Where("status = ?", WebhookStatusDead).
+			Returning("*").
+			Update()
+		if err != nil {
+			return err
+		}
+		if res.RowsAffected() == 0 {
+			exists, err := tx.Model((*entity.WebhookDelivery)(nil)).Where("id = ?", param.DeliveryID).Exists()
+			if err != nil {
+				return err
+			}
+			if exists {
+				return ErrDeliveryNotReplayable
+			}
+			return ErrDeliveryNotFound
+		}
+
+		_, err = enqueueJob(ctx, tx, ParamEnqueueJob{
+			Type:        param.JobType,
+			Payload:     WebhookJobPayload{DeliveryID: delivery.ID},
+			MaxAttempts: param.MaxAttempts,
+		})
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &delivery, nil
+}
@@ -0,0 +1,105 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/ahmadmirdas/julo-test/repository/database/models"
+	"github.com/ahmadmirdas/julo-test/server/middleware"
+	"github.com/ahmadmirdas/julo-test/service"
+	"github.com/ahmadmirdas/julo-test/utils/activity"
+	"github.com/ahmadmirdas/julo-test/utils/log"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// skipAuthMethods are called without a customer token, like /api/v1/init
+var skipAuthMethods = []string{
+	"/wallet.v1.WalletService/Init",
+}
+
+// NewServer returns a gRPC server with the tracing, logging, error mapping and auth interceptors
+func NewServer(opts ...grpc.ServerOption) *grpc.Server {
+	opts = append(opts, grpc.ChainUnaryInterceptor(TracingInterceptor, LoggingInterceptor, ErrorInterceptor, AuthInterceptor))
+	return grpc.NewServer(opts...)
+}
+
+// LoggingInterceptor logs every call with its status code and duration
+func LoggingInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	start := time.Now()
+	res, err := handler(ctx, req)
+
+	logCtx := activity.NewRequestContext(ctx, "RPC."+info.FullMethod[strings.LastIndex(info.FullMethod, "/")+1:])
+	code := status.Code(err)
+	if code == codes.Internal || code == codes.Unknown {
+		log.WithContext(logCtx).Errorf("[RPC %s] %s in %v, error: %v", info.FullMethod, code, time.Since(start), err)
+	} else {
+		log.WithContext(logCtx).Infof("[RPC %s] %s in %v", info.FullMethod, code, time.Since(start))
+	}
+	return res, err
+}
+
+// ErrorInterceptor turns repository and service errors into gRPC status codes,
+// errors already carrying a status are passed as they are
+func ErrorInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	res, err := handler(ctx, req)
+	if err == nil {
+		return res, nil
+	}
+	if _, ok := status.FromError(err); ok {
+		return res, err
+	}
+
+	return res, status.Error(errorCode(err), err.Error())
+}
+
+// AuthInterceptor checks the customer token sent in the authorization metadata as "Token <jwt>"
+// and puts its claims in the context under the same key as AuthMiddleware
+func AuthInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	for _, method := range skipAuthMethods {
+		if info.FullMethod == method {
+			return handler(ctx, req)
+		}
+	}
+
+	md, _ := metadata.FromIncomingContext(ctx)
+	values := md.Get("authorization")
+	if len(values) == 0 || !strings.HasPrefix(values[0], "Token ") {
+		return nil, status.Error(codes.Unauthenticated, "invalid token")
+	}
+
+	claims, err := middleware.ParseToken(strings.TrimPrefix(values[0], "Token "))
+	if err != nil {
+		return nil, status.Error(codes.Unauthenticated, err.Error())
+	}
+	if custXId, _ := claims["customer_xid"].(string); custXId == "" {
+		return nil, status.Error(codes.Unauthenticated, "invalid token")
+	}
+
+	return handler(context.WithValue(ctx, middleware.Customer, claims), req)
+}
+
+func errorCode(err error) codes.Code {
+	switch {
+	case errors.Is(err, models.ErrWalletNotFound), errors.Is(err, models.ErrPocketNotFound):
+		return codes.NotFound
+	case errors.Is(err, models.ErrWalletDisabled), errors.Is(err, models.ErrWalletFrozen), errors.Is(err, models.ErrInsufficientFund):
+		return codes.FailedPrecondition
+	case service.IsKycError(err):
+		return codes.PermissionDenied
+	case service.IsRequestError(err):
+		return codes.FailedPrecondition
+	case service.IsLimitError(err):
+		return codes.InvalidArgument
+	case errors.Is(err, context.Canceled):
+		return codes.Canceled
+	case errors.Is(err, context.DeadlineExceeded):
+		return codes.DeadlineExceeded
+	default:
+		return codes.Internal
+	}
+}
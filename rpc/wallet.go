@@ -0,0 +1,230 @@
+package rpc
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/ahmadmirdas/julo-test/config"
+	"github.com/ahmadmirdas/julo-test/repository/database/models"
+	"github.com/ahmadmirdas/julo-test/repository/database/models/entity"
+	"github.com/ahmadmirdas/julo-test/rpc/walletpb"
+	"github.com/ahmadmirdas/julo-test/server/middleware"
+	"github.com/ahmadmirdas/julo-test/service"
+	"github.com/ahmadmirdas/julo-test/utils"
+	"github.com/ahmadmirdas/julo-test/utils/currency"
+	"github.com/ahmadmirdas/julo-test/utils/metrics"
+	"github.com/golang-jwt/jwt/v4"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// HistoryLimit is the page size of ListHistory when the request sets none
+const HistoryLimit = 10
+
+type walletServer struct {
+	walletpb.UnimplementedWalletServiceServer
+	walletRepo    models.WalletDBRepo
+	walletService service.WalletService
+}
+
+func NewWalletServer(walletRepo models.WalletDBRepo, walletService service.WalletService) walletpb.WalletServiceServer {
+	return &walletServer{
+		walletRepo:    walletRepo,
+		walletService: walletService,
+	}
+}
+
+func (s *walletServer) Init(ctx context.Context, req *walletpb.InitRequest) (*walletpb.InitResponse, error) {
+	if req.CustomerXid == "" {
+		return nil, status.Error(codes.InvalidArgument, "customer_xid is required")
+	}
+
+	token, err := middleware.GenerateToken(req.CustomerXid)
+	if err != nil {
+		return nil, err
+	}
+
+	return &walletpb.InitResponse{Token: token}, nil
+}
+
+func (s *walletServer) Enable(ctx context.Context, req *walletpb.EnableRequest) (*walletpb.Wallet, error) {
+	cur, err := requestCurrency(req.Currency)
+	if err != nil {
+		return nil, err
+	}
+
+	wallet, err := s.walletService.Enable(ctx, customerXId(ctx), cur.Code)
+	if err != nil {
+		return nil, err
+	}
+
+	return toWallet(wallet), nil
+}
+
+func (s *walletServer) Disable(ctx context.Context, req *walletpb.DisableRequest) (*walletpb.Wallet, error) {
+	cur, err := requestCurrency(req.Currency)
+	if err != nil {
+		return nil, err
+	}
+
+	wallet, err := s.walletRepo.UpdateStatusWallet(ctx, customerXId(ctx), cur.Code, false)
+	if err != nil {
+		return nil, err
+	}
+
+	return toWallet(wallet), nil
+}
+
+func (s *walletServer) GetBalance(ctx context.Context, req *walletpb.GetBalanceRequest) (*walletpb.Wallet, error) {
+	cur, err := requestCurrency(req.Currency)
+	if err != nil {
+		return nil, err
+	}
+
+	wallet, err := s.walletService.Wallet(ctx, customerXId(ctx), cur, "")
+	if err != nil {
+		return nil, err
+	}
+
+	return toWallet(wallet), nil
+}
+
+func (s *walletServer) Deposit(ctx context.Context, req *walletpb.DepositRequest) (*walletpb.Transaction, error) {
+	cur, err := requestCurrency(req.Currency)
+	if err != nil {
+		metrics.ObserveFailure(models.HistoryTypeDeposit, metrics.FailureInvalidRequest)
+		return nil, err
+	}
+
+	history, err := s.walletService.Deposit(ctx, service.ParamTransaction{
+		CustomerXId: customerXId(ctx),
+		Currency:    cur,
+		PocketID:    req.PocketId,
+		Amount:      req.Amount,
+		ReferenceID: req.ReferenceId,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return toTransaction(history, 0), nil
+}
+
+func (s *walletServer) Withdraw(ctx context.Context, req *walletpb.WithdrawRequest) (*walletpb.Transaction, error) {
+	cur, err := requestCurrency(req.Currency)
+	if err != nil {
+		metrics.ObserveFailure(models.HistoryTypeWithdraw, metrics.FailureInvalidRequest)
+		return nil, err
+	}
+
+	history, withdrawFee, err := s.walletService.Withdraw(ctx, service.ParamTransaction{
+		CustomerXId: customerXId(ctx),
+		Currency:    cur,
+		PocketID:    req.PocketId,
+		Amount:      req.Amount,
+		ReferenceID: req.ReferenceId,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return toTransaction(history, withdrawFee), nil
+}
+
+func (s *walletServer) ListHistory(ctx context.Context, req *walletpb.ListHistoryRequest) (*walletpb.ListHistoryResponse, error) {
+	cur, err := requestCurrency(req.Currency)
+	if err != nil {
+		return nil, err
+	}
+	if req.Limit < 0 || req.Offset < 0 {
+		return nil, status.Error(codes.InvalidArgument, "limit and offset must not be negative")
+	}
+	pageSize := int(req.Limit)
+	if pageSize == 0 {
+		pageSize = HistoryLimit
+	}
+
+	wallet, err := s.walletService.Wallet(ctx, customerXId(ctx), cur, req.PocketId)
+	if err != nil {
+		return nil, err
+	}
+
+	histories, err := s.walletRepo.GetHistories(ctx, models.ParamGetHistories{
+		WalletID: wallet.ID,
+		Limit:    pageSize,
+		Offset:   int(req.Offset),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	res := &walletpb.ListHistoryResponse{
+		Transactions: make([]*walletpb.Transaction, 0, len(histories)),
+	}
+	for i := range histories {
+		res.Transactions = append(res.Transactions, toTransaction(&histories[i], 0))
+	}
+	return res, nil
+}
+
+// requestCurrency returns the requested currency, the default currency when code is empty
+func requestCurrency(code string) (currency.Currency, error) {
+	cfg := config.Config.CurrencyCfg
+	code = strings.ToUpper(code)
+	if code == "" {
+		code = cfg.Default
+	}
+
+	cur, ok := currency.Get(code)
+	if !ok || !utils.Contains(cur.Code, cfg.Supported) {
+		return currency.Currency{}, status.Errorf(codes.InvalidArgument, "currency %s is not supported", code)
+	}
+
+	return cur, nil
+}
+
+// customerXId returns the customer of the token checked by the auth interceptor
+func customerXId(ctx context.Context) string {
+	claims, _ := ctx.Value(middleware.Customer).(jwt.MapClaims)
+	custXId, _ := claims["customer_xid"].(string)
+	return custXId
+}
+
+func toWallet(wallet *entity.Wallet) *walletpb.Wallet {
+	res := &walletpb.Wallet{
+		Id:       wallet.ID,
+		OwnedBy:  wallet.OwnedBy,
+		Status:   models.WalletStatusDisabled,
+		Balance:  wallet.Balance,
+		Currency: wallet.Currency,
+	}
+	if wallet.IsEnabled {
+		res.Status = models.WalletStatusEnabled
+	}
+	res.EnabledAt = timestamp(wallet.EnabledAt)
+	res.DisabledAt = timestamp(wallet.DisabledAt)
+	return res
+}
+
+func toTransaction(history *entity.History, fee float64) *walletpb.Transaction {
+	return &walletpb.Transaction{
+		Id:          history.ID,
+		WalletId:    history.WalletID,
+		Type:        history.Type,
+		Status:      history.Status,
+		Amount:      history.Amount,
+		Currency:    history.Currency,
+		ReferenceId: history.ReferenceID,
+		Fee:         fee,
+		CreatedAt:   timestamp(history.CreatedAt),
+	}
+}
+
+func timestamp(t time.Time) *timestamppb.Timestamp {
+	if t.IsZero() {
+		return nil
+	}
+	return timestamppb.New(t)
+}
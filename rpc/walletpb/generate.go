@@ -0,0 +1,5 @@
+// Package walletpb holds the protobuf messages and gRPC service of the wallet API,
+// regenerate it with protoc-gen-go v1.28.1 and protoc-gen-go-grpc v1.2.0 after editing wallet.proto
+package walletpb
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative wallet.proto
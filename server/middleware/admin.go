@@ -0,0 +1,84 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/ahmadmirdas/julo-test/config"
+	"github.com/ahmadmirdas/julo-test/utils/activity"
+	"github.com/ahmadmirdas/julo-test/utils/log"
+	"github.com/ahmadmirdas/julo-test/utils/tracing"
+)
+
+// Back-office roles, every role may do what the roles before it may
+const (
+	RoleViewer   string = "viewer"
+	RoleOperator string = "operator"
+	RoleApprover string = "approver"
+)
+
+var adminRoleRank = map[string]int{
+	RoleViewer:   1,
+	RoleOperator: 2,
+	RoleApprover: 3,
+}
+
+func IsValidAdminRole(role string) bool {
+	return adminRoleRank[role] > 0
+}
+
+// HasAdminRole reports whether role may do what required may
+func HasAdminRole(role, required string) bool {
+	return IsValidAdminRole(role) && adminRoleRank[role] >= adminRoleRank[required]
+}
+
+// StaffRole returns the highest role staff is registered with in admin.staff, empty when staff is
+// not registered. Names are matched case-insensitively as the config keys are.
+func StaffRole(staff string) string {
+	return config.Config.AdminCfg.Staff[strings.ToLower(staff)]
+}
+
+// AdminAuthMiddleware requires an admin token sent as "Authorization: Bearer <token>", its subject
+// becomes the actor of the request
+func AdminAuthMiddleware() func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			tokenString := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if tokenString == "" || tokenString == r.Header.Get("Authorization") {
+				internalErrorWrite(w, http.StatusUnauthorized, "admin token is required")
+				return
+			}
+
+			_, span := tracing.Start(r.Context(), "jwt.parse")
+			claims, err := ParseAdminToken(tokenString)
+			tracing.End(span, err)
+			if err != nil {
+				log.WithContext(r.Context()).Warnf("[Middleware AdminAuth] invalid admin token: %v", err)
+				internalErrorWrite(w, http.StatusUnauthorized, "invalid admin token")
+				return
+			}
+
+			ctx := activity.WithActorID(r.Context(), claims.Subject)
+			ctx = context.WithValue(ctx, AdminRole, claims.Role)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// RequireAdminRole serves next only to admins holding role or a role above it
+func RequireAdminRole(role string, next http.HandlerFunc) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !HasAdminRole(GetAdminRole(r.Context()), role) {
+			internalErrorWrite(w, http.StatusForbidden, "the "+role+" role is required")
+			return
+		}
+		next(w, r)
+	})
+}
+
+// GetAdminRole returns the role set by AdminAuthMiddleware
+func GetAdminRole(ctx context.Context) string {
+	role, _ := ctx.Value(AdminRole).(string)
+	return role
+}
@@ -0,0 +1,119 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ahmadmirdas/julo-test/config"
+)
+
+func TestHasAdminRole(t *testing.T) {
+	tests := []struct {
+		role     string
+		required string
+		want     bool
+	}{
+		{role: RoleViewer, required: RoleViewer, want: true},
+		{role: RoleViewer, required: RoleOperator, want: false},
+		{role: RoleViewer, required: RoleApprover, want: false},
+		{role: RoleOperator, required: RoleViewer, want: true},
+		{role: RoleOperator, required: RoleOperator, want: true},
+		{role: RoleOperator, required: RoleApprover, want: false},
+		{role: RoleApprover, required: RoleViewer, want: true},
+		{role: RoleApprover, required: RoleOperator, want: true},
+		{role: RoleApprover, required: RoleApprover, want: true},
+		{role: "", required: RoleViewer, want: false},
+		{role: "admin", required: RoleViewer, want: false},
+		{role: "Approver", required: RoleViewer, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.role+" as "+tt.required, func(t *testing.T) {
+			if got := HasAdminRole(tt.role, tt.required); got != tt.want {
+				t.Errorf("HasAdminRole(%q, %q) = %v, want %v", tt.role, tt.required, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRequireAdminRole(t *testing.T) {
+	tests := []struct {
+		name     string
+		role     string
+		required string
+		want     int
+	}{
+		{name: "same role", role: RoleOperator, required: RoleOperator, want: http.StatusOK},
+		{name: "higher role", role: RoleApprover, required: RoleViewer, want: http.StatusOK},
+		{name: "lower role", role: RoleOperator, required: RoleApprover, want: http.StatusForbidden},
+		{name: "no role", required: RoleViewer, want: http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := RequireAdminRole(tt.required, func(w http.ResponseWriter, r *http.Request) {})
+			r := httptest.NewRequest(http.MethodGet, "/admin/v1/wallets", nil)
+			if tt.role != "" {
+				r = r.WithContext(context.WithValue(r.Context(), AdminRole, tt.role))
+			}
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+			if w.Code != tt.want {
+				t.Errorf("status = %d, want %d", w.Code, tt.want)
+			}
+		})
+	}
+}
+
+func TestAdminToken(t *testing.T) {
+	config.Config.AdminCfg.Issuer = "julo-test-admin"
+	config.Config.AdminCfg.SignKey = "admin test"
+	config.Config.AdminCfg.Exp = 1
+	config.Config.AdminCfg.Staff = map[string]string{"alice": RoleOperator, "bob": RoleApprover}
+	t.Cleanup(func() { config.Config.AdminCfg.Staff = nil })
+
+	tests := []struct {
+		name    string
+		actor   string
+		role    string
+		wantErr bool
+	}{
+		{name: "registered role", actor: "alice", role: RoleOperator},
+		{name: "lower than registered", actor: "bob", role: RoleViewer},
+		{name: "names are case insensitive", actor: "Alice", role: RoleViewer},
+		{name: "above registered", actor: "alice", role: RoleApprover, wantErr: true},
+		{name: "unregistered", actor: "mallory", role: RoleViewer, wantErr: true},
+		{name: "unknown role", actor: "bob", role: "admin", wantErr: true},
+		{name: "no actor", role: RoleViewer, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			token, err := GenerateAdminToken(tt.actor, tt.role)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("GenerateAdminToken(%q, %q) error = %v, wantErr %v", tt.actor, tt.role, err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			claims, err := ParseAdminToken(token)
+			if err != nil {
+				t.Fatalf("ParseAdminToken() error = %v", err)
+			}
+			if claims.Role != tt.role || claims.Subject != strings.ToLower(tt.actor) {
+				t.Errorf("claims = %s as %s, want %s as %s", claims.Subject, claims.Role, strings.ToLower(tt.actor), tt.role)
+			}
+		})
+	}
+
+	t.Run("staff lowered below the token role", func(t *testing.T) {
+		token, err := GenerateAdminToken("bob", RoleApprover)
+		if err != nil {
+			t.Fatal(err)
+		}
+		config.Config.AdminCfg.Staff["bob"] = RoleViewer
+		if _, err := ParseAdminToken(token); err == nil {
+			t.Error("ParseAdminToken() accepted the approver token of a viewer")
+		}
+	})
+}
@@ -0,0 +1,44 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/ahmadmirdas/julo-test/config"
+	"github.com/ahmadmirdas/julo-test/utils/activity"
+)
+
+// ClientCertMiddleware requires a verified client certificate on the mutual TLS prefixes and
+// makes its subject the actor of the request
+func ClientCertMiddleware() func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			cfg := config.Config.TLSCfg
+			if !cfg.Enabled || !cfg.RequiresClientCert(r.URL.Path) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 {
+				internalErrorWrite(w, http.StatusUnauthorized, "client certificate is required")
+				return
+			}
+
+			actorID := cfg.Actor(r.TLS.VerifiedChains[0][0])
+			if actorID == "" {
+				internalErrorWrite(w, http.StatusForbidden, "client certificate has no subject")
+				return
+			}
+
+			r = r.WithContext(activity.WithActorID(r.Context(), actorID))
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// certActor returns the actor set by ClientCertMiddleware
+func certActor(r *http.Request) (string, bool) {
+	if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 {
+		return "", false
+	}
+	return activity.GetActorID(r.Context())
+}
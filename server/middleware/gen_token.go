@@ -1,6 +1,9 @@
 package middleware
 
 import (
+	"errors"
+	"fmt"
+	"strings"
 	"time"
 
 	"github.com/ahmadmirdas/julo-test/config"
@@ -36,3 +39,90 @@ func GenerateToken(customerXId string) (string, error) {
 
 	return signedToken, nil
 }
+
+// ParseToken verifies a customer token and returns its claims
+func ParseToken(tokenString string) (jwt.MapClaims, error) {
+	cfg := config.Config.JWTCfg
+	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
+		if method, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("signing method invalid")
+		} else if method != JWT_SIGNING_METHOD {
+			return nil, fmt.Errorf("signing method invalid")
+		}
+
+		return []byte(cfg.SignKey), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok || !token.Valid {
+		return nil, errors.New("invalid token")
+	}
+
+	return claims, nil
+}
+
+// AdminClaims identify a back-office staff member, the subject is the actor written to the audit log
+type AdminClaims struct {
+	jwt.RegisteredClaims
+	Role string `json:"role"`
+}
+
+// GenerateAdminToken signs a back-office token with the admin key, customer tokens are not accepted
+// on the admin routes and admin tokens are not accepted on the customer routes. The actor must be
+// registered in admin.staff with role or a role above it.
+func GenerateAdminToken(actor, role string) (string, error) {
+	if actor == "" {
+		return "", errors.New("actor is empty")
+	}
+	if !IsValidAdminRole(role) {
+		return "", fmt.Errorf("role must be one of %s, %s or %s", RoleViewer, RoleOperator, RoleApprover)
+	}
+	registered := StaffRole(actor)
+	if registered == "" {
+		return "", fmt.Errorf("%s is not registered in admin.staff", actor)
+	}
+	if !HasAdminRole(registered, role) {
+		return "", fmt.Errorf("%s is registered as %s and cannot hold the %s role", actor, registered, role)
+	}
+
+	cfg := config.Config.AdminCfg
+	claims := AdminClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    cfg.Issuer,
+			Subject:   strings.ToLower(actor),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Duration(cfg.Exp) * time.Hour)),
+		},
+		Role: role,
+	}
+
+	return jwt.NewWithClaims(JWT_SIGNING_METHOD, claims).SignedString([]byte(cfg.SignKey))
+}
+
+// ParseAdminToken verifies a back-office token and returns its claims, a token of staff since removed
+// from admin.staff or lowered below the role of the token is rejected
+func ParseAdminToken(tokenString string) (*AdminClaims, error) {
+	cfg := config.Config.AdminCfg
+	claims := &AdminClaims{}
+	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		if token.Method != JWT_SIGNING_METHOD {
+			return nil, fmt.Errorf("signing method invalid")
+		}
+
+		return []byte(cfg.SignKey), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if !token.Valid || !claims.VerifyIssuer(cfg.Issuer, true) || claims.Subject == "" || !IsValidAdminRole(claims.Role) {
+		return nil, errors.New("invalid token")
+	}
+	if !HasAdminRole(StaffRole(claims.Subject), claims.Role) {
+		return nil, fmt.Errorf("%s is no longer registered as %s", claims.Subject, claims.Role)
+	}
+
+	return claims, nil
+}
@@ -0,0 +1,56 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"net/http"
+
+	"github.com/ahmadmirdas/julo-test/config"
+	"github.com/ahmadmirdas/julo-test/utils/activity"
+	"github.com/ahmadmirdas/julo-test/utils/response"
+)
+
+const (
+	HeaderInternalToken = "X-Internal-Token"
+	HeaderActorID       = "X-Actor-ID"
+)
+
+// InternalAuthMiddleware protects internal APIs with a shared token and requires the calling actor,
+// callers authenticated by a client certificate need neither
+func InternalAuthMiddleware() func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if _, ok := certActor(r); ok {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			cfg := config.Config.InternalCfg
+			token := r.Header.Get(HeaderInternalToken)
+			if cfg.Token == "" || subtle.ConstantTimeCompare([]byte(token), []byte(cfg.Token)) != 1 {
+				internalErrorWrite(w, http.StatusUnauthorized, "invalid internal token")
+				return
+			}
+
+			actorID := r.Header.Get(HeaderActorID)
+			if actorID == "" {
+				internalErrorWrite(w, http.StatusBadRequest, HeaderActorID+" header is required")
+				return
+			}
+
+			r = r.WithContext(activity.WithActorID(r.Context(), actorID))
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func internalErrorWrite(w http.ResponseWriter, code int, message string) {
+	w.Header().Set("Content-type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(response.ResponseAPI{
+		Error_: &response.ApiError{
+			Code:    code,
+			Message: message,
+		},
+	})
+}
@@ -0,0 +1,31 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/ahmadmirdas/julo-test/utils/metrics"
+	"github.com/gorilla/mux"
+)
+
+// MetricsMiddleware records the count and latency of every request by route template. mux runs middlewares
+// on matched routes only, wrap the not found and method not allowed handlers of the router to record the
+// requests matching no route, under an empty route
+func MetricsMiddleware() func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			rw := metrics.NewResponseWriter(w)
+			next.ServeHTTP(rw, r)
+
+			var route string
+			if current := mux.CurrentRoute(r); current != nil {
+				route, _ = current.GetPathTemplate()
+			}
+			status := strconv.Itoa(rw.Status())
+			metrics.HTTPRequests.WithLabelValues(route, r.Method, status).Inc()
+			metrics.HTTPDuration.WithLabelValues(route, r.Method, status).Observe(time.Since(start).Seconds())
+		})
+	}
+}
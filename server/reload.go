@@ -0,0 +1,102 @@
+package server
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/ahmadmirdas/julo-test/config"
+	"github.com/ahmadmirdas/julo-test/utils/fee"
+	"github.com/ahmadmirdas/julo-test/utils/kyc"
+	"github.com/ahmadmirdas/julo-test/utils/limit"
+	"github.com/ahmadmirdas/julo-test/utils/metrics"
+	"github.com/ahmadmirdas/julo-test/utils/settings"
+	"github.com/fsnotify/fsnotify"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+)
+
+var (
+	ReloadTriggerFile   string = "file"
+	ReloadTriggerSignal string = "signal"
+)
+
+// reloadDebounce is how long the config file has to stay quiet before it is read, editors and
+// configmap updates write it in several steps
+const reloadDebounce = 500 * time.Millisecond
+
+// configReloader applies the reloadable settings to the running server, watch runs one reload at a time
+type configReloader struct {
+	settings *settings.Store
+}
+
+func newSettings(cfg config.Reloadable) *settings.Settings {
+	return &settings.Settings{
+		Fee:   fee.NewEngine(cfg.FeeRules),
+		Limit: limit.NewEngine(cfg.LimitTiers, cfg.LimitCurrencies),
+		Kyc:   kyc.NewPolicy(cfg.KycCapabilities),
+	}
+}
+
+// watch reloads when the config file is written and on SIGHUP until ctx is done
+func (c *configReloader) watch(ctx context.Context) {
+	changed := make(chan struct{}, 1)
+	w := viper.New()
+	w.SetConfigFile(viper.ConfigFileUsed())
+	if err := w.ReadInConfig(); err != nil {
+		logrus.Errorf("Watch config error, reload with SIGHUP only: %v", err)
+	} else {
+		w.OnConfigChange(func(fsnotify.Event) {
+			select {
+			case changed <- struct{}{}:
+			default:
+			}
+		})
+		w.WatchConfig()
+	}
+
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	debounce := time.NewTimer(reloadDebounce)
+	debounce.Stop()
+	defer debounce.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-changed:
+			debounce.Reset(reloadDebounce)
+		case <-debounce.C:
+			c.reload(ReloadTriggerFile)
+		case <-hup:
+			c.reload(ReloadTriggerSignal)
+		}
+	}
+}
+
+// reload applies the new settings only when the whole file is valid, the fee, limit and kyc settings
+// are swapped together
+func (c *configReloader) reload(trigger string) {
+	cfg, err := config.Reload()
+	if err != nil {
+		metrics.ConfigReloads.WithLabelValues(trigger, "rejected").Inc()
+		logrus.Errorf("Config reload from %s rejected, keeping the running values: %v", trigger, err)
+		return
+	}
+
+	if err := config.SetLogLevel(cfg.LogLevel); err != nil {
+		metrics.ConfigReloads.WithLabelValues(trigger, "rejected").Inc()
+		logrus.Errorf("Config reload from %s rejected, keeping the running values: %v", trigger, err)
+		return
+	}
+	c.settings.Swap(newSettings(cfg))
+
+	metrics.ConfigReloads.WithLabelValues(trigger, "applied").Inc()
+	metrics.ConfigReloadTime.Set(float64(time.Now().Unix()))
+	logrus.Infof("Config reloaded from %s: log level %q, %d fee rules, %d limit tiers, %d currency limit tiers, %d kyc levels",
+		trigger, cfg.LogLevel, len(cfg.FeeRules), len(cfg.LimitTiers), len(cfg.LimitCurrencies), len(cfg.KycCapabilities))
+}
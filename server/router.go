@@ -0,0 +1,119 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/ahmadmirdas/julo-test/api"
+	"github.com/ahmadmirdas/julo-test/handler"
+	"github.com/ahmadmirdas/julo-test/server/middleware"
+	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// routeHandlers are the handlers served by the http router
+type routeHandlers struct {
+	wallet   handler.HandlerWallet
+	stream   handler.HandlerStream
+	pocket   handler.HandlerPocket
+	fx       handler.HandlerFx
+	loan     handler.HandlerLoan
+	schedule handler.HandlerSchedule
+	kyc      handler.HandlerKyc
+	webhook  handler.HandlerWebhook
+	admin    handler.HandlerAdmin
+	health   handler.HandlerHealth
+}
+
+// newRouter registers every http route and middleware, the /api/v1 routes must match the OpenAPI
+// document which is checked by the tests
+func newRouter(h routeHandlers) *mux.Router {
+	r := mux.NewRouter()
+	apiV1 := r.PathPrefix("/api/v1").Subrouter()
+
+	apiV1.HandleFunc("/openapi.json", api.ServeSpec).Methods(http.MethodGet)
+	apiV1.HandleFunc("/init", h.wallet.InitAccountWallet).Methods(http.MethodPost)
+	apiV1.HandleFunc("/wallet", h.wallet.EnableWallet).Methods(http.MethodPost)
+	apiV1.HandleFunc("/wallet", h.wallet.ViewWalletBalance).Methods(http.MethodGet)
+	apiV1.HandleFunc("/wallet/deposits", h.wallet.DepositWallet).Methods(http.MethodPost)
+	apiV1.HandleFunc("/wallet/withdrawals", h.wallet.WithdrawWallet).Methods(http.MethodPost)
+	apiV1.HandleFunc("/wallet", h.wallet.DisableWallet).Methods(http.MethodPatch)
+	apiV1.HandleFunc("/wallet/stream", h.stream.StreamWallet).Methods(http.MethodGet)
+	apiV1.HandleFunc("/wallet/pockets", h.pocket.CreatePocket).Methods(http.MethodPost)
+	apiV1.HandleFunc("/wallet/pockets", h.pocket.ListPockets).Methods(http.MethodGet)
+	apiV1.HandleFunc("/wallet/pockets/transfers", h.pocket.TransferPocket).Methods(http.MethodPost)
+	apiV1.HandleFunc("/wallet/pockets/{pocket_id}", h.pocket.RenamePocket).Methods(http.MethodPatch)
+	apiV1.HandleFunc("/wallet/pockets/{pocket_id}", h.pocket.ClosePocket).Methods(http.MethodDelete)
+	apiV1.HandleFunc("/wallet/conversions/quotes", h.fx.CreateConversionQuote).Methods(http.MethodPost)
+	apiV1.HandleFunc("/wallet/conversions", h.fx.CreateConversion).Methods(http.MethodPost)
+	apiV1.HandleFunc("/wallet/loans", h.loan.ListLoans).Methods(http.MethodGet)
+	apiV1.HandleFunc("/wallet/schedules", h.schedule.CreateSchedule).Methods(http.MethodPost)
+	apiV1.HandleFunc("/wallet/schedules", h.schedule.ListSchedules).Methods(http.MethodGet)
+	apiV1.HandleFunc("/wallet/schedules/{schedule_id}", h.schedule.UpdateScheduleStatus).Methods(http.MethodPatch)
+	apiV1.HandleFunc("/wallet/schedules/{schedule_id}", h.schedule.CancelSchedule).Methods(http.MethodDelete)
+	apiV1.Use(middleware.AuthMiddleware())
+
+	internalV1 := r.PathPrefix("/internal/v1").Subrouter()
+	internalV1.HandleFunc("/wallets/{customer_xid}/kyc", h.kyc.ViewKycLevel).Methods(http.MethodGet)
+	internalV1.HandleFunc("/wallets/{customer_xid}/kyc", h.kyc.UpdateKycLevel).Methods(http.MethodPut)
+	internalV1.HandleFunc("/fx-rates", h.fx.ListFxRates).Methods(http.MethodGet)
+	internalV1.HandleFunc("/fx-rates", h.fx.UpdateFxRate).Methods(http.MethodPut)
+	internalV1.HandleFunc("/fx-rates/import", h.fx.ImportFxRates).Methods(http.MethodPost)
+	internalV1.HandleFunc("/loans", h.loan.DisburseLoan).Methods(http.MethodPost)
+	internalV1.HandleFunc("/loans/{loan_id}", h.loan.ViewLoan).Methods(http.MethodGet)
+	internalV1.HandleFunc("/webhooks", h.webhook.CreateWebhook).Methods(http.MethodPost)
+	internalV1.HandleFunc("/webhooks", h.webhook.ListWebhooks).Methods(http.MethodGet)
+	internalV1.HandleFunc("/webhooks/partners/{partner_id}/customers", h.webhook.LinkCustomer).Methods(http.MethodPost)
+	internalV1.HandleFunc("/webhooks/partners/{partner_id}/customers/{customer_xid}", h.webhook.UnlinkCustomer).Methods(http.MethodDelete)
+	internalV1.HandleFunc("/webhooks/deliveries/{delivery_id}", h.webhook.ViewDelivery).Methods(http.MethodGet)
+	internalV1.HandleFunc("/webhooks/deliveries/{delivery_id}/replay", h.webhook.ReplayDelivery).Methods(http.MethodPost)
+	internalV1.HandleFunc("/webhooks/{webhook_id}", h.webhook.DisableWebhook).Methods(http.MethodDelete)
+	internalV1.HandleFunc("/webhooks/{webhook_id}/deliveries", h.webhook.ListDeliveries).Methods(http.MethodGet)
+	internalV1.Use(middleware.InternalAuthMiddleware())
+
+	// back-office api, approver may do what operator may and operator what viewer may
+	adminV1 := r.PathPrefix("/admin/v1").Subrouter()
+	viewer := func(next http.HandlerFunc) http.Handler {
+		return middleware.RequireAdminRole(middleware.RoleViewer, next)
+	}
+	operator := func(next http.HandlerFunc) http.Handler {
+		return middleware.RequireAdminRole(middleware.RoleOperator, next)
+	}
+	approver := func(next http.HandlerFunc) http.Handler {
+		return middleware.RequireAdminRole(middleware.RoleApprover, next)
+	}
+	adminV1.Handle("/wallets", viewer(h.admin.SearchWallets)).Methods(http.MethodGet)
+	adminV1.Handle("/wallets/{wallet_id}", viewer(h.admin.ViewWallet)).Methods(http.MethodGet)
+	adminV1.Handle("/wallets/{wallet_id}/histories", viewer(h.admin.ListWalletHistories)).Methods(http.MethodGet)
+	adminV1.Handle("/wallets/{wallet_id}/freeze", operator(h.admin.FreezeWallet)).Methods(http.MethodPost)
+	adminV1.Handle("/wallets/{wallet_id}/freeze", operator(h.admin.UnfreezeWallet)).Methods(http.MethodDelete)
+	adminV1.Handle("/adjustments", viewer(h.admin.ListAdjustments)).Methods(http.MethodGet)
+	adminV1.Handle("/adjustments", operator(h.admin.RequestAdjustment)).Methods(http.MethodPost)
+	adminV1.Handle("/adjustments/{adjustment_id}", viewer(h.admin.ViewAdjustment)).Methods(http.MethodGet)
+	adminV1.Handle("/adjustments/{adjustment_id}/approve", approver(h.admin.ApproveAdjustment)).Methods(http.MethodPost)
+	adminV1.Handle("/adjustments/{adjustment_id}/reject", approver(h.admin.RejectAdjustment)).Methods(http.MethodPost)
+	adminV1.Handle("/transactions/{transaction_id}/reversal", operator(h.admin.RequestReversal)).Methods(http.MethodPost)
+	adminV1.Handle("/audit-logs", viewer(h.admin.ListAuditLogs)).Methods(http.MethodGet)
+	adminV1.Use(middleware.AdminAuthMiddleware())
+
+	// health routes sit outside the api subrouters so no auth applies
+	r.HandleFunc("/healthz", h.health.Liveness).Methods(http.MethodGet)
+	r.HandleFunc("/readyz", h.health.Readiness).Methods(http.MethodGet)
+	r.Use(mux.CORSMethodMiddleware(r))
+	r.Use(middleware.MetricsMiddleware())
+	r.Use(middleware.TracingMiddleware())
+	r.Use(middleware.ClientCertMiddleware())
+	// middlewares skip requests matching no route, the metrics of those are recorded by these handlers
+	r.NotFoundHandler = middleware.MetricsMiddleware()(http.NotFoundHandler())
+	r.MethodNotAllowedHandler = middleware.MetricsMiddleware()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}))
+
+	return r
+}
+
+// newMetricsHandler serves /metrics only, on server.metrics_address which is kept off the public listener
+func newMetricsHandler() http.Handler {
+	metricsMux := http.NewServeMux()
+	metricsMux.Handle("GET /metrics", promhttp.Handler())
+	return metricsMux
+}
@@ -0,0 +1,116 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/ahmadmirdas/julo-test/api"
+	"github.com/ahmadmirdas/julo-test/handler"
+	"github.com/ahmadmirdas/julo-test/utils/metrics"
+	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+// testRouter builds the router with handlers that are never called, walking it only reads the routes
+func testRouter() *mux.Router {
+	return newRouter(routeHandlers{
+		wallet:   handler.NewHandlerWallet(nil, nil),
+		stream:   handler.NewHandlerStream(nil, nil, nil, nil),
+		pocket:   handler.NewHandlerPocket(nil, nil),
+		fx:       handler.NewHandlerFx(nil, nil, nil),
+		loan:     handler.NewHandlerLoan(nil),
+		schedule: handler.NewHandlerSchedule(nil, nil),
+		kyc:      handler.NewHandlerKyc(nil),
+		webhook:  handler.NewHandlerWebhook(nil, "", 0),
+		admin:    handler.NewHandlerAdmin(nil),
+		health:   handler.NewHandlerHealth(nil),
+	})
+}
+
+// TestRoutesMatchOpenAPI requires every route under api.BasePath to be documented and every
+// documented operation to be served
+func TestRoutesMatchOpenAPI(t *testing.T) {
+	documented, err := api.Operations()
+	if err != nil {
+		t.Fatalf("read openapi document: %v", err)
+	}
+
+	served := make(map[string]bool)
+	err = testRouter().Walk(func(route *mux.Route, router *mux.Router, ancestors []*mux.Route) error {
+		path, err := route.GetPathTemplate()
+		if err != nil || !strings.HasPrefix(path, api.BasePath+"/") {
+			return nil
+		}
+		methods, err := route.GetMethods()
+		if err != nil {
+			return nil
+		}
+		for _, method := range methods {
+			served[method+" "+path] = true
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("walk routes: %v", err)
+	}
+
+	for _, op := range documented {
+		if !served[op] {
+			t.Errorf("%s is documented but not served", op)
+		}
+		delete(served, op)
+	}
+	var undocumented []string
+	for op := range served {
+		undocumented = append(undocumented, op)
+	}
+	sort.Strings(undocumented)
+	for _, op := range undocumented {
+		t.Errorf("%s is served but not documented", op)
+	}
+}
+
+// TestUnmatchedRequests checks requests matching no route are recorded under an empty route and that
+// /metrics is not served by the public router
+func TestUnmatchedRequests(t *testing.T) {
+	router := testRouter()
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		status int
+	}{
+		{name: "unknown path", method: http.MethodGet, path: "/unknown", status: http.StatusNotFound},
+		{name: "metrics", method: http.MethodGet, path: "/metrics", status: http.StatusNotFound},
+		{name: "unknown method", method: http.MethodPut, path: "/healthz", status: http.StatusMethodNotAllowed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			counter := metrics.HTTPRequests.WithLabelValues("", tt.method, strconv.Itoa(tt.status))
+			before := counterValue(t, counter)
+
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, httptest.NewRequest(tt.method, tt.path, nil))
+			if rec.Code != tt.status {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.status)
+			}
+			if got := counterValue(t, counter); got != before+1 {
+				t.Errorf("requests recorded under an empty route = %v, want %v", got, before+1)
+			}
+		})
+	}
+}
+
+func counterValue(t *testing.T, counter prometheus.Counter) float64 {
+	t.Helper()
+	var m dto.Metric
+	if err := counter.Write(&m); err != nil {
+		t.Fatalf("read counter: %v", err)
+	}
+	return m.GetCounter().GetValue()
+}
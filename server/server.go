@@ -2,91 +2,318 @@ package server
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"log"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
+	"syscall"
 	"time"
 
 	"github.com/ahmadmirdas/julo-test/config"
 	"github.com/ahmadmirdas/julo-test/config/database"
 	"github.com/ahmadmirdas/julo-test/handler"
+	"github.com/ahmadmirdas/julo-test/repository/database/migration"
 	"github.com/ahmadmirdas/julo-test/repository/database/models"
+	"github.com/ahmadmirdas/julo-test/rpc"
+	"github.com/ahmadmirdas/julo-test/rpc/walletpb"
 	"github.com/ahmadmirdas/julo-test/server/middleware"
-	"github.com/gorilla/mux"
+	"github.com/ahmadmirdas/julo-test/service"
+	"github.com/ahmadmirdas/julo-test/utils/metrics"
+	"github.com/ahmadmirdas/julo-test/utils/outbox"
+	"github.com/ahmadmirdas/julo-test/utils/penalty"
+	"github.com/ahmadmirdas/julo-test/utils/settings"
+	"github.com/ahmadmirdas/julo-test/utils/tlsconfig"
+	"github.com/ahmadmirdas/julo-test/utils/tracing"
+	"github.com/ahmadmirdas/julo-test/worker"
 	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
 )
 
-func RunServer() {
+// RunServer serves the http and gRPC apis until SIGINT or SIGTERM, the returned error
+// reports what could not be shut down cleanly
+func RunServer() error {
 	cfg := config.Config
-	cfgDb := cfg.PostgresCfg
-	paramCfgDB := database.ParamConn{
-		Username:    cfgDb.Username,
-		Password:    cfgDb.Password,
-		Host:        cfgDb.Host,
-		Port:        cfgDb.Port,
-		Database:    cfgDb.Database,
-		MaxConn:     cfgDb.MaxConn,
-		MinIdleConn: cfgDb.MinIdleConn,
-		MaxRetries:  cfgDb.MaxRetries,
+	paramCfgDB := database.ConfigParam()
+
+	shutdownTracing, err := tracing.Setup(cfg.TracingCfg)
+	if err != nil {
+		logrus.Fatalf("Tracing setup error: %v", err)
 	}
 
 	db := database.DbConn(paramCfgDB)
+	db.AddQueryHook(tracing.QueryHook{})
 
-	err := db.Ping(context.Background())
+	err = db.Ping(context.Background())
 	if err != nil {
 		logrus.Fatalf("Ping DB error: %v", err)
 		log.Fatalln(err)
 	}
 
+	if err := metrics.RegisterDB(db); err != nil {
+		logrus.Fatalf("Register DB metrics error: %v", err)
+	}
+
+	migrationVersion, err := migration.Latest()
+	if err != nil {
+		logrus.Fatalf("Read migrations error: %v", err)
+	}
+	if cfg.MigrationCfg.RequireLatest {
+		current, err := migration.CurrentVersion(context.Background(), db)
+		if err != nil {
+			logrus.Fatalf("Read migration version error: %v", err)
+		}
+		if current < migrationVersion {
+			logrus.Fatalf("Database is at migration %d, %d is required, run: julo-test migrate up", current, migrationVersion)
+		}
+	}
+	healthChecker := service.NewHealthChecker(db, service.ParamHealthChecker{
+		Timeout:          time.Duration(cfg.HealthCfg.Timeout) * time.Second,
+		MigrationVersion: migrationVersion,
+		MaxPoolUsage:     cfg.HealthCfg.MaxPoolUsage,
+	})
+	handlerHealth := handler.NewHandlerHealth(healthChecker)
+
 	walletRepo := models.NewDBWalletRepo(db)
-	handlerAPI := handler.NewHandlerWallet(walletRepo)
+	settingsStore := settings.NewStore(newSettings(cfg.Reloadable()))
+	pocketRepo := models.NewDBPocketRepo(db)
+	walletService := service.NewWalletService(walletRepo, pocketRepo, settingsStore)
+	transferService := service.NewTransferService(walletRepo, pocketRepo, settingsStore)
+	handlerAPI := handler.NewHandlerWallet(walletRepo, walletService)
+	handlerPocket := handler.NewHandlerPocket(pocketRepo, transferService)
+	handlerKyc := handler.NewHandlerKyc(walletRepo)
+	fxRepo := models.NewDBFxRepo(db)
+	handlerFx := handler.NewHandlerFx(fxRepo, walletRepo, settingsStore)
+	loanRepo := models.NewDBLoanRepo(db)
+	handlerLoan := handler.NewHandlerLoan(loanRepo)
+	scheduleRepo := models.NewDBScheduleRepo(db)
+	handlerSchedule := handler.NewHandlerSchedule(scheduleRepo, pocketRepo)
+
+	jobRepo := models.NewDBJobRepo(db)
+	jobQueue := worker.NewQueue(jobRepo, worker.QueueOptions{
+		Concurrency:       cfg.JobCfg.Concurrency,
+		PollInterval:      time.Duration(cfg.JobCfg.PollInterval) * time.Second,
+		VisibilityTimeout: time.Duration(cfg.JobCfg.VisibilityTimeout) * time.Second,
+		RetryBackoff:      time.Duration(cfg.JobCfg.RetryBackoff) * time.Second,
+		MaxBackoff:        time.Duration(cfg.JobCfg.MaxBackoff) * time.Second,
+	})
+	loanCollector := worker.NewLoanCollector(loanRepo, cfg.LoanCfg.BatchSize, cfg.LoanCfg.GraceDays,
+		time.Duration(cfg.LoanCfg.RetryInterval)*time.Second)
+	jobQueue.Register(worker.JobLoanCollect, loanCollector.Handle)
+	jobQueue.Every(worker.JobLoanCollect, time.Duration(cfg.LoanCfg.CollectInterval)*time.Second)
+	penaltyAccruer := worker.NewPenaltyAccruer(loanRepo, penalty.NewEngine(cfg.PenaltyCfg.Rules), cfg.LoanCfg.BatchSize, cfg.LoanCfg.GraceDays)
+	jobQueue.Register(worker.JobPenaltyAccrue, penaltyAccruer.Handle)
+	jobQueue.Every(worker.JobPenaltyAccrue, time.Duration(cfg.PenaltyCfg.AccrualInterval)*time.Second)
+	scheduleRunner := worker.NewScheduleRunner(scheduleRepo, transferService, cfg.ScheduleCfg.BatchSize, cfg.ScheduleCfg.MaxAttempts,
+		time.Duration(cfg.ScheduleCfg.RetryBackoff)*time.Second, time.Duration(cfg.ScheduleCfg.MaxBackoff)*time.Second)
+	jobQueue.Register(worker.JobScheduleRun, scheduleRunner.Handle)
+	jobQueue.Every(worker.JobScheduleRun, time.Duration(cfg.ScheduleCfg.PollInterval)*time.Second)
+	webhookRepo := models.NewDBWebhookRepo(db)
+	handlerWebhook := handler.NewHandlerWebhook(webhookRepo, worker.JobWebhookDeliver, cfg.WebhookCfg.MaxAttempts)
+	jobQueue.Register(worker.JobWebhookDeliver, worker.NewWebhookDeliverer(webhookRepo, time.Duration(cfg.WebhookCfg.Timeout)*time.Second).Handle)
+	jobQueue.Register(worker.JobPurge, worker.NewPurger(jobRepo, time.Duration(cfg.JobCfg.Retention)*time.Hour).Handle)
+	jobQueue.Every(worker.JobPurge, time.Hour)
+
+	sinks, err := outbox.NewSinks(cfg.OutboxCfg.Sinks)
+	if err != nil {
+		logrus.Fatalf("Outbox sinks error: %v", err)
+	}
+	sinks = append(sinks, worker.NewWebhookDispatcher(webhookRepo, cfg.WebhookCfg.MaxAttempts))
+	outboxRepo := models.NewDBOutboxRepo(db)
+	outboxRelay := worker.NewOutboxRelay(outboxRepo, sinks,
+		time.Duration(cfg.OutboxCfg.PollInterval)*time.Second, cfg.OutboxCfg.BatchSize, cfg.OutboxCfg.MaxAttempts)
 
-	// Declare a new router
-	r := mux.NewRouter()
-	apiV1 := r.PathPrefix("/api/v1").Subrouter()
+	adminService := service.NewAdminService(walletRepo, pocketRepo, models.NewDBAuditRepo(db), models.NewDBAdjustmentRepo(db),
+		service.AdminTokens{Customer: middleware.GenerateToken, Staff: middleware.GenerateAdminToken})
+	handlerAdmin := handler.NewHandlerAdmin(adminService)
 
-	apiV1.HandleFunc("/init", handlerAPI.InitAccountWallet).Methods(http.MethodPost)
-	apiV1.HandleFunc("/wallet", handlerAPI.EnableWallet).Methods(http.MethodPost)
-	apiV1.HandleFunc("/wallet", handlerAPI.ViewWalletBalance).Methods(http.MethodGet)
-	apiV1.HandleFunc("/wallet/deposits", handlerAPI.DepositWallet).Methods(http.MethodPost)
-	apiV1.HandleFunc("/wallet/withdrawals", handlerAPI.WithdrawWallet).Methods(http.MethodPost)
-	apiV1.HandleFunc("/wallet", handlerAPI.DisableWallet).Methods(http.MethodPatch)
-	r.Use(mux.CORSMethodMiddleware(r))
-	r.Use(middleware.AuthMiddleware())
+	eventHub := service.NewEventHub(db)
+	handlerStream := handler.NewHandlerStream(walletRepo, pocketRepo, outboxRepo, eventHub)
 
+	workerCtx, stopWorkers := context.WithCancel(context.Background())
+	defer stopWorkers()
+	jobQueue.Start(workerCtx)
+	go eventHub.Start(workerCtx)
+	reloader := &configReloader{settings: settingsStore}
+	go reloader.watch(workerCtx)
+	relayDone := make(chan struct{})
+	go func() {
+		outboxRelay.Start(workerCtx)
+		close(relayDone)
+	}()
+
+	r := newRouter(routeHandlers{
+		wallet:   handlerAPI,
+		stream:   handlerStream,
+		pocket:   handlerPocket,
+		fx:       handlerFx,
+		loan:     handlerLoan,
+		schedule: handlerSchedule,
+		kyc:      handlerKyc,
+		webhook:  handlerWebhook,
+		admin:    handlerAdmin,
+		health:   handlerHealth,
+	})
+
+	srvCfg := cfg.ServerCfg
 	srv := &http.Server{
-		Handler:      r,
-		Addr:         ":5000",
-		WriteTimeout: 15 * time.Second,
-		ReadTimeout:  15 * time.Second,
+		Handler:           r,
+		Addr:              srvCfg.Address,
+		ReadTimeout:       time.Duration(srvCfg.ReadTimeout) * time.Second,
+		ReadHeaderTimeout: time.Duration(srvCfg.ReadHeaderTimeout) * time.Second,
+		WriteTimeout:      time.Duration(srvCfg.WriteTimeout) * time.Second,
+		IdleTimeout:       time.Duration(srvCfg.IdleTimeout) * time.Second,
+		MaxHeaderBytes:    srvCfg.MaxHeaderBytes,
+	}
+	// streams never end by themselves, close them as soon as shutdown starts
+	srv.RegisterOnShutdown(eventHub.Close)
+	if cfg.TLSCfg.Enabled {
+		tlsLoader, err := tlsconfig.NewLoader(cfg.TLSCfg)
+		if err != nil {
+			logrus.Fatalf("TLS setup error: %v", err)
+		}
+		srv.TLSConfig = tlsLoader.ServerConfig()
+		go reloadTLSOnHangup(workerCtx, tlsLoader)
+	}
+
+	grpcSrv := rpc.NewServer()
+	walletpb.RegisterWalletServiceServer(grpcSrv, rpc.NewWalletServer(walletRepo, walletService))
+	grpcLis, err := net.Listen("tcp", ":"+cfg.GrpcCfg.Port)
+	if err != nil {
+		logrus.Fatalf("Listen gRPC error: %v", err)
 	}
+	log.Println("Starting gRPC on port " + cfg.GrpcCfg.Port)
+	serveErr := make(chan error, 3)
+	go func() {
+		if err := grpcSrv.Serve(grpcLis); err != nil {
+			serveErr <- fmt.Errorf("serve gRPC: %w", err)
+		}
+	}()
+
+	log.Println("Starting web on " + srvCfg.Address)
+	go func() {
+		var err error
+		if srv.TLSConfig != nil {
+			err = srv.ListenAndServeTLS("", "")
+		} else {
+			err = srv.ListenAndServe()
+		}
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			serveErr <- fmt.Errorf("serve http: %w", err)
+		}
+	}()
 
-	log.Println("Starting web on port 5000")
-	// Run our server in a goroutine so that it doesn't block.
+	metricsSrv := &http.Server{
+		Handler:           newMetricsHandler(),
+		Addr:              srvCfg.MetricsAddress,
+		ReadHeaderTimeout: time.Duration(srvCfg.ReadHeaderTimeout) * time.Second,
+	}
+	log.Println("Starting metrics on " + srvCfg.MetricsAddress)
 	go func() {
-		if err := srv.ListenAndServe(); err != nil {
-			log.Println(err)
+		if err := metricsSrv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			serveErr <- fmt.Errorf("serve metrics: %w", err)
 		}
 	}()
 
 	c := make(chan os.Signal, 1)
-	// We'll accept graceful shutdowns when quit via SIGINT (Ctrl+C)
-	// SIGKILL, SIGQUIT or SIGTERM (Ctrl+/) will not be caught.
-	signal.Notify(c, os.Interrupt)
+	// SIGTERM is sent by docker and orchestrators, SIGINT by Ctrl+C
+	signal.Notify(c, os.Interrupt, syscall.SIGTERM)
 
-	// Block until we receive our signal.
-	<-c
+	var errs []error
+	serving := true
+	select {
+	case sig := <-c:
+		log.Printf("received %s, shutting down", sig)
+	case err := <-serveErr:
+		log.Println(err)
+		errs = append(errs, err)
+		serving = false
+	}
+	// report not ready and keep serving until load balancers have seen it, a second signal cuts the delay short
+	healthChecker.Drain()
+	if delay := time.Duration(srvCfg.DrainDelay) * time.Second; serving && delay > 0 {
+		log.Printf("draining for %s", delay)
+		select {
+		case <-time.After(delay):
+		case sig := <-c:
+			log.Printf("received %s, skipping the drain delay", sig)
+		}
+	}
 
-	// Create a deadline to wait for.
-	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
+	grace := time.Duration(srvCfg.ShutdownGrace) * time.Second
+	if grace <= 0 {
+		grace = 30 * time.Second
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), grace)
 	defer cancel()
-	// Doesn't block if no connections, but will otherwise wait
-	// until the timeout deadline.
-	srv.Shutdown(ctx)
-	// Optionally, you could run srv.Shutdown in a goroutine and block on
-	// <-ctx.Done() if your application should wait for other services
-	// to finalize based on context cancellation.
-	log.Println("shutting down")
-	os.Exit(0)
+
+	// stop accepting requests and wait for the ones in flight
+	if err := srv.Shutdown(ctx); err != nil {
+		errs = append(errs, fmt.Errorf("shutdown http: %w", err))
+	}
+	if err := stopGrpc(ctx, grpcSrv); err != nil {
+		errs = append(errs, fmt.Errorf("shutdown gRPC: %w", err))
+	}
+	// stop claiming jobs and let the ones in flight finish
+	stopWorkers()
+	if err := jobQueue.Wait(ctx); err != nil {
+		errs = append(errs, fmt.Errorf("shutdown job queue: %w", err))
+	}
+	select {
+	case <-relayDone:
+	case <-ctx.Done():
+		errs = append(errs, fmt.Errorf("shutdown outbox relay: %w", ctx.Err()))
+	}
+	// scraped until the end so the shutdown itself is observed
+	if err := metricsSrv.Shutdown(ctx); err != nil {
+		errs = append(errs, fmt.Errorf("shutdown metrics: %w", err))
+	}
+	if err := shutdownTracing(ctx); err != nil {
+		errs = append(errs, fmt.Errorf("shutdown tracing: %w", err))
+	}
+	if err := db.Close(); err != nil {
+		errs = append(errs, fmt.Errorf("close db: %w", err))
+	}
+
+	log.Println("shut down")
+	return errors.Join(errs...)
+}
+
+// reloadTLSOnHangup reads the certificate files again on every SIGHUP until ctx is done
+func reloadTLSOnHangup(ctx context.Context, loader *tlsconfig.Loader) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hup:
+			if err := loader.Reload(); err != nil {
+				logrus.Errorf("Reload TLS error, keeping the previous certificate: %v", err)
+				continue
+			}
+			logrus.Info("TLS certificate reloaded")
+		}
+	}
+}
+
+// stopGrpc waits for the rpcs in flight until ctx is done, then cancels them
+func stopGrpc(ctx context.Context, grpcSrv *grpc.Server) error {
+	done := make(chan struct{})
+	go func() {
+		grpcSrv.GracefulStop()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		grpcSrv.Stop()
+		return ctx.Err()
+	}
 }
@@ -0,0 +1,686 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/ahmadmirdas/julo-test/repository/database/models"
+	"github.com/ahmadmirdas/julo-test/repository/database/models/entity"
+	"github.com/ahmadmirdas/julo-test/utils/activity"
+	"github.com/ahmadmirdas/julo-test/utils/log"
+	"github.com/google/uuid"
+)
+
+var (
+	ErrActorRequired  = errors.New("actor is required")
+	ErrReasonRequired = errors.New("reason is required")
+	ErrAmountRequired = errors.New("amount must not be zero")
+	ErrPocketFreeze   = errors.New("pockets follow their main wallet, freeze the main wallet instead")
+
+	ErrInvalidReference = errors.New("reference id must be a uuid")
+)
+
+var (
+	AdminActionFindWallet    string = "wallet.find"
+	AdminActionViewHistory   string = "wallet.history"
+	AdminActionFreeze        string = "wallet.freeze"
+	AdminActionUnfreeze      string = "wallet.unfreeze"
+	AdminActionExportHistory string = "wallet.export_history"
+	AdminActionMintToken     string = "token.mint"
+	AdminActionMintStaff     string = "staff_token.mint"
+	AdminActionSearchWallets string = "wallet.search"
+	AdminActionViewWallet    string = "wallet.view"
+	AdminActionRequestAdjust string = "adjustment.request"
+	AdminActionApproveAdjust string = "adjustment.approve"
+	AdminActionRejectAdjust  string = "adjustment.reject"
+	AdminActionRequestRevert string = "reversal.request"
+)
+
+// AdminWallet is a wallet or pocket as shown to the support staff
+type AdminWallet struct {
+	ID          string     `json:"id"`
+	CustomerXId string     `json:"customer_xid"`
+	ParentID    string     `json:"parent_id,omitempty"`
+	Name        string     `json:"name,omitempty"`
+	Currency    string     `json:"currency"`
+	Status      string     `json:"status"`
+	Balance     float64    `json:"balance"`
+	KycLevel    string     `json:"kyc_level"`
+	Segment     string     `json:"segment,omitempty"`
+	EnabledAt   *time.Time `json:"enabled_at,omitempty"`
+	DisabledAt  *time.Time `json:"disabled_at,omitempty"`
+	FrozenAt    *time.Time `json:"frozen_at,omitempty"`
+	CreatedAt   *time.Time `json:"created_at,omitempty"`
+}
+
+// AdminTransaction is a history row as shown to the support staff
+type AdminTransaction struct {
+	ID          string    `json:"id"`
+	WalletID    string    `json:"wallet_id"`
+	Type        string    `json:"type"`
+	Status      string    `json:"status"`
+	Amount      float64   `json:"amount"`
+	Currency    string    `json:"currency"`
+	ReferenceID string    `json:"reference_id"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// ParamAdminHistory reads the history of WalletID, or of the main wallet of a customer in Currency
+type ParamAdminHistory struct {
+	WalletID    string
+	CustomerXId string
+	Currency    string
+	Limit       int
+	Offset      int
+}
+
+// ParamAdminFreeze freezes or unfreezes the main wallet WalletID, or the main wallet of a customer in Currency
+type ParamAdminFreeze struct {
+	WalletID    string
+	CustomerXId string
+	Currency    string
+	Reason      string
+}
+
+// ParamAdminAdjust credits a positive Amount or debits a negative one on WalletID, or on the main wallet of
+// a customer in Currency, an empty ReferenceID is generated
+type ParamAdminAdjust struct {
+	WalletID    string
+	CustomerXId string
+	Currency    string
+	Amount      float64
+	Reason      string
+	ReferenceID string
+}
+
+// AdminService runs the support staff operations, every call is written to the audit log with the
+// actor of ctx whether it succeeds or not. Balance changes are only requested, another staff member
+// posts them by approving.
+type AdminService interface {
+	FindWallets(ctx context.Context, customerXId string) ([]AdminWallet, error)
+	GetHistory(ctx context.Context, param ParamAdminHistory) ([]AdminTransaction, error)
+	FreezeWallet(ctx context.Context, param ParamAdminFreeze) (*AdminWallet, error)
+	UnfreezeWallet(ctx context.Context, param ParamAdminFreeze) (*AdminWallet, error)
+	ExportHistory(ctx context.Context, param ParamAdminHistory, write func(AdminTransaction) error) (int, error)
+	MintToken(ctx context.Context, customerXId, reason string) (string, error)
+	GetAuditLogs(ctx context.Context, param models.ParamGetAuditLogs) ([]entity.AdminAudit, error)
+
+	SearchWallets(ctx context.Context, param models.ParamSearchWallets) ([]AdminWallet, error)
+	GetWallet(ctx context.Context, walletID string) (*AdminWallet, error)
+	RequestAdjustment(ctx context.Context, param ParamAdminAdjust) (*entity.AdminAdjustment, error)
+	ReviewAdjustment(ctx context.Context, id string, approve bool, note string) (*entity.AdminAdjustment, error)
+	GetAdjustment(ctx context.Context, id string) (*entity.AdminAdjustment, error)
+	GetAdjustments(ctx context.Context, param models.ParamGetAdjustments) ([]entity.AdminAdjustment, error)
+	RequestReversal(ctx context.Context, historyID, reason string) (*entity.AdminAdjustment, error)
+	MintStaffToken(ctx context.Context, role, reason string) (string, error)
+}
+
+type adminService struct {
+	walletRepo     models.WalletDBRepo
+	pocketRepo     models.PocketDBRepo
+	auditRepo      models.AuditDBRepo
+	adjustmentRepo models.AdjustmentDBRepo
+	tokens         AdminTokens
+}
+
+// AdminTokens signs customer api and back-office tokens, the generators of the server middleware so
+// minted tokens are accepted by it
+type AdminTokens struct {
+	Customer func(customerXId string) (string, error)
+	Staff    func(staff, role string) (string, error)
+}
+
+func NewAdminService(walletRepo models.WalletDBRepo, pocketRepo models.PocketDBRepo, auditRepo models.AuditDBRepo, adjustmentRepo models.AdjustmentDBRepo, tokens AdminTokens) AdminService {
+	return &adminService{
+		walletRepo:     walletRepo,
+		pocketRepo:     pocketRepo,
+		auditRepo:      auditRepo,
+		adjustmentRepo: adjustmentRepo,
+		tokens:         tokens,
+	}
+}
+
+// FindWallets returns every main wallet and pocket of a customer
+func (s *adminService) FindWallets(ctx context.Context, customerXId string) (res []AdminWallet, err error) {
+	audit := &entity.AdminAudit{Action: AdminActionFindWallet, CustomerXId: customerXId}
+	defer func() { s.audit(ctx, audit, err) }()
+	if err := s.checkActor(ctx); err != nil {
+		return nil, err
+	}
+
+	wallets, err := s.walletRepo.GetWallets(ctx, customerXId)
+	if err != nil {
+		return nil, err
+	}
+	if len(wallets) == 0 {
+		return nil, models.ErrWalletNotFound
+	}
+	pockets, err := s.pocketRepo.GetPockets(ctx, customerXId)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range wallets {
+		res = append(res, newAdminWallet(&wallets[i]))
+	}
+	for i := range pockets {
+		res = append(res, newAdminWallet(&pockets[i]))
+	}
+	return res, nil
+}
+
+// GetHistory returns a page of the transactions of a wallet or pocket, see ParamAdminHistory
+func (s *adminService) GetHistory(ctx context.Context, param ParamAdminHistory) (res []AdminTransaction, err error) {
+	audit := &entity.AdminAudit{Action: AdminActionViewHistory, CustomerXId: param.CustomerXId, WalletID: param.WalletID}
+	defer func() { s.audit(ctx, audit, err) }()
+	if err := s.checkActor(ctx); err != nil {
+		return nil, err
+	}
+
+	wallet, err := s.wallet(ctx, param.WalletID, param.CustomerXId, param.Currency)
+	if err != nil {
+		return nil, err
+	}
+	audit.WalletID, audit.CustomerXId = wallet.ID, wallet.OwnedBy
+
+	histories, err := s.walletRepo.GetHistories(ctx, models.ParamGetHistories{
+		WalletID: wallet.ID,
+		Limit:    param.Limit,
+		Offset:   param.Offset,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	res = make([]AdminTransaction, 0, len(histories))
+	for i := range histories {
+		res = append(res, newAdminTransaction(&histories[i]))
+	}
+	return res, nil
+}
+
+func (s *adminService) FreezeWallet(ctx context.Context, param ParamAdminFreeze) (*AdminWallet, error) {
+	return s.freeze(ctx, param, true)
+}
+
+func (s *adminService) UnfreezeWallet(ctx context.Context, param ParamAdminFreeze) (*AdminWallet, error) {
+	return s.freeze(ctx, param, false)
+}
+
+func (s *adminService) freeze(ctx context.Context, param ParamAdminFreeze, frozen bool) (res *AdminWallet, err error) {
+	audit := &entity.AdminAudit{Action: AdminActionUnfreeze, CustomerXId: param.CustomerXId, WalletID: param.WalletID, Reason: param.Reason}
+	if frozen {
+		audit.Action = AdminActionFreeze
+	}
+	audit.Detail = detail(map[string]interface{}{"currency": param.Currency})
+	defer func() { s.audit(ctx, audit, err) }()
+	if err := s.checkActor(ctx); err != nil {
+		return nil, err
+	}
+	if strings.TrimSpace(param.Reason) == "" {
+		return nil, ErrReasonRequired
+	}
+	if param.WalletID != "" {
+		wallet, err := s.walletRepo.GetWalletByID(ctx, param.WalletID)
+		if err != nil {
+			return nil, err
+		}
+		if wallet.ParentID != "" {
+			return nil, ErrPocketFreeze
+		}
+		param.CustomerXId, param.Currency = wallet.OwnedBy, wallet.Currency
+		audit.CustomerXId = wallet.OwnedBy
+		audit.Detail = detail(map[string]interface{}{"currency": param.Currency})
+	}
+
+	wallet, err := s.walletRepo.FreezeWallet(ctx, models.ParamFreezeWallet{
+		CustomerXId: param.CustomerXId,
+		Currency:    param.Currency,
+		Frozen:      frozen,
+		Audit: func(wallet *entity.Wallet) *entity.AdminAudit {
+			audit.WalletID, audit.CustomerXId = wallet.ID, wallet.OwnedBy
+			return s.succeeded(ctx, audit)
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	audit = nil
+
+	w := newAdminWallet(wallet)
+	return &w, nil
+}
+
+// RequestAdjustment stores a manual correction to be approved by another staff member before it is posted
+func (s *adminService) RequestAdjustment(ctx context.Context, param ParamAdminAdjust) (res *entity.AdminAdjustment, err error) {
+	param.ReferenceID = referenceID(param.ReferenceID)
+	audit := adjustAudit(AdminActionRequestAdjust, param)
+	defer func() { s.audit(ctx, audit, err) }()
+	wallet, err := s.checkAdjust(ctx, param)
+	if err != nil {
+		return nil, err
+	}
+	audit.WalletID, audit.CustomerXId = wallet.ID, wallet.OwnedBy
+
+	actor, _ := activity.GetActorID(ctx)
+	adjustment := &entity.AdminAdjustment{
+		WalletID:    wallet.ID,
+		CustomerXId: wallet.OwnedBy,
+		Currency:    wallet.Currency,
+		Amount:      param.Amount,
+		Reason:      param.Reason,
+		ReferenceID: param.ReferenceID,
+		RequestedBy: actor,
+	}
+	adjustment, err = s.adjustmentRepo.CreateAdjustment(ctx, models.ParamCreateAdjustment{
+		Adjustment: adjustment,
+		Audit: func(adjustment *entity.AdminAdjustment) *entity.AdminAudit {
+			audit.Detail = detail(map[string]interface{}{
+				"adjustment_id": adjustment.ID,
+				"amount":        adjustment.Amount,
+				"currency":      adjustment.Currency,
+				"reference_id":  adjustment.ReferenceID,
+			})
+			return s.succeeded(ctx, audit)
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	audit = nil
+
+	return adjustment, nil
+}
+
+// ReviewAdjustment approves and posts, or rejects, a pending adjustment or reversal requested by someone else
+func (s *adminService) ReviewAdjustment(ctx context.Context, id string, approve bool, note string) (res *entity.AdminAdjustment, err error) {
+	audit := &entity.AdminAudit{Action: AdminActionRejectAdjust, Reason: note}
+	if approve {
+		audit.Action = AdminActionApproveAdjust
+	}
+	audit.Detail = detail(map[string]interface{}{"adjustment_id": id})
+	defer func() { s.audit(ctx, audit, err) }()
+	if err := s.checkActor(ctx); err != nil {
+		return nil, err
+	}
+	if !approve && strings.TrimSpace(note) == "" {
+		return nil, ErrReasonRequired
+	}
+
+	actor, _ := activity.GetActorID(ctx)
+	adjustment, err := s.adjustmentRepo.ReviewAdjustment(ctx, models.ParamReviewAdjustment{
+		ID:       id,
+		Reviewer: actor,
+		Approve:  approve,
+		Note:     note,
+		Audit: func(adjustment *entity.AdminAdjustment) *entity.AdminAudit {
+			audit.WalletID, audit.CustomerXId = adjustment.WalletID, adjustment.CustomerXId
+			audit.Detail = detail(map[string]interface{}{
+				"adjustment_id": adjustment.ID,
+				"amount":        adjustment.Amount,
+				"currency":      adjustment.Currency,
+				"kind":          adjustment.Kind,
+				"requested_by":  adjustment.RequestedBy,
+				"history_id":    adjustment.HistoryID,
+			})
+			return s.succeeded(ctx, audit)
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	audit = nil
+
+	return adjustment, nil
+}
+
+// GetAdjustment reads an adjustment, reading adjustments is not audited
+func (s *adminService) GetAdjustment(ctx context.Context, id string) (*entity.AdminAdjustment, error) {
+	return s.adjustmentRepo.GetAdjustment(ctx, id)
+}
+
+func (s *adminService) GetAdjustments(ctx context.Context, param models.ParamGetAdjustments) ([]entity.AdminAdjustment, error) {
+	return s.adjustmentRepo.GetAdjustments(ctx, param)
+}
+
+// RequestReversal stores the reversal of a deposit, withdrawal or adjustment to be approved by another
+// staff member before it is posted
+func (s *adminService) RequestReversal(ctx context.Context, historyID, reason string) (res *entity.AdminAdjustment, err error) {
+	audit := &entity.AdminAudit{Action: AdminActionRequestRevert, Reason: reason}
+	audit.Detail = detail(map[string]interface{}{"transaction_id": historyID})
+	defer func() { s.audit(ctx, audit, err) }()
+	if err := s.checkActor(ctx); err != nil {
+		return nil, err
+	}
+	if strings.TrimSpace(reason) == "" {
+		return nil, ErrReasonRequired
+	}
+
+	original, err := s.walletRepo.GetHistory(ctx, historyID)
+	if err != nil {
+		return nil, err
+	}
+	delta, err := models.ReversalDelta(original)
+	if err != nil {
+		return nil, err
+	}
+	wallet, err := s.walletRepo.GetWalletByID(ctx, original.WalletID)
+	if err != nil {
+		return nil, err
+	}
+	audit.WalletID, audit.CustomerXId = wallet.ID, wallet.OwnedBy
+
+	actor, _ := activity.GetActorID(ctx)
+	adjustment := &entity.AdminAdjustment{
+		Kind:        models.AdjustmentKindReversal,
+		ReversalOf:  original.ID,
+		WalletID:    wallet.ID,
+		CustomerXId: wallet.OwnedBy,
+		Currency:    original.Currency,
+		Amount:      delta,
+		Reason:      reason,
+		ReferenceID: uuid.New().String(),
+		RequestedBy: actor,
+	}
+	adjustment, err = s.adjustmentRepo.CreateAdjustment(ctx, models.ParamCreateAdjustment{
+		Adjustment: adjustment,
+		Audit: func(adjustment *entity.AdminAdjustment) *entity.AdminAudit {
+			audit.Detail = detail(map[string]interface{}{
+				"transaction_id": historyID,
+				"adjustment_id":  adjustment.ID,
+				"amount":         adjustment.Amount,
+				"currency":       adjustment.Currency,
+			})
+			return s.succeeded(ctx, audit)
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	audit = nil
+
+	return adjustment, nil
+}
+
+// SearchWallets returns a page of main wallets and pockets matching every filter
+func (s *adminService) SearchWallets(ctx context.Context, param models.ParamSearchWallets) (res []AdminWallet, err error) {
+	audit := &entity.AdminAudit{Action: AdminActionSearchWallets, CustomerXId: param.CustomerXId}
+	audit.Detail = detail(map[string]interface{}{
+		"currency":       param.Currency,
+		"status":         param.Status,
+		"min_balance":    param.MinBalance,
+		"max_balance":    param.MaxBalance,
+		"created_after":  timePtr(param.CreatedAfter),
+		"created_before": timePtr(param.CreatedBefore),
+	})
+	defer func() { s.audit(ctx, audit, err) }()
+	if err := s.checkActor(ctx); err != nil {
+		return nil, err
+	}
+
+	wallets, err := s.walletRepo.SearchWallets(ctx, param)
+	if err != nil {
+		return nil, err
+	}
+
+	res = make([]AdminWallet, 0, len(wallets))
+	for i := range wallets {
+		res = append(res, newAdminWallet(&wallets[i]))
+	}
+	return res, nil
+}
+
+// GetWallet returns a main wallet or pocket
+func (s *adminService) GetWallet(ctx context.Context, walletID string) (res *AdminWallet, err error) {
+	audit := &entity.AdminAudit{Action: AdminActionViewWallet, WalletID: walletID}
+	defer func() { s.audit(ctx, audit, err) }()
+	if err := s.checkActor(ctx); err != nil {
+		return nil, err
+	}
+
+	wallet, err := s.walletRepo.GetWalletByID(ctx, walletID)
+	if err != nil {
+		return nil, err
+	}
+	audit.CustomerXId = wallet.OwnedBy
+
+	w := newAdminWallet(wallet)
+	return &w, nil
+}
+
+// ExportHistory writes every transaction of the main wallet of a customer in a currency, newest first,
+// and returns how many were written
+func (s *adminService) ExportHistory(ctx context.Context, param ParamAdminHistory, write func(AdminTransaction) error) (n int, err error) {
+	audit := &entity.AdminAudit{Action: AdminActionExportHistory, CustomerXId: param.CustomerXId, WalletID: param.WalletID}
+	defer func() {
+		audit.Detail = detail(map[string]interface{}{"currency": param.Currency, "exported": n})
+		s.audit(ctx, audit, err)
+	}()
+	if err := s.checkActor(ctx); err != nil {
+		return 0, err
+	}
+
+	wallet, err := s.wallet(ctx, param.WalletID, param.CustomerXId, param.Currency)
+	if err != nil {
+		return 0, err
+	}
+	audit.WalletID, audit.CustomerXId = wallet.ID, wallet.OwnedBy
+
+	pageSize := param.Limit
+	if pageSize <= 0 {
+		pageSize = 500
+	}
+	for offset := 0; ; offset += pageSize {
+		histories, err := s.walletRepo.GetHistories(ctx, models.ParamGetHistories{
+			WalletID: wallet.ID,
+			Limit:    pageSize,
+			Offset:   offset,
+		})
+		if err != nil {
+			return n, err
+		}
+		for i := range histories {
+			if err := write(newAdminTransaction(&histories[i])); err != nil {
+				return n, err
+			}
+			n++
+		}
+		if len(histories) < pageSize {
+			return n, nil
+		}
+	}
+}
+
+// MintToken returns a customer api token, meant for testing on behalf of a customer
+func (s *adminService) MintToken(ctx context.Context, customerXId, reason string) (token string, err error) {
+	audit := &entity.AdminAudit{Action: AdminActionMintToken, CustomerXId: customerXId, Reason: reason}
+	defer func() { s.audit(ctx, audit, err) }()
+	if err := s.checkActor(ctx); err != nil {
+		return "", err
+	}
+	if strings.TrimSpace(reason) == "" {
+		return "", ErrReasonRequired
+	}
+	if _, err := uuid.Parse(customerXId); err != nil {
+		return "", errors.New("customer xid must be a uuid")
+	}
+
+	return s.tokens.Customer(customerXId)
+}
+
+// MintStaffToken returns a back-office token of the actor with role, staff only mint their own tokens
+// so the requester and the approver of an adjustment are two registered people
+func (s *adminService) MintStaffToken(ctx context.Context, role, reason string) (token string, err error) {
+	actor, _ := activity.GetActorID(ctx)
+	audit := &entity.AdminAudit{Action: AdminActionMintStaff, Reason: reason}
+	audit.Detail = detail(map[string]interface{}{"staff": actor, "role": role})
+	defer func() { s.audit(ctx, audit, err) }()
+	if err := s.checkActor(ctx); err != nil {
+		return "", err
+	}
+	if strings.TrimSpace(reason) == "" {
+		return "", ErrReasonRequired
+	}
+
+	return s.tokens.Staff(actor, role)
+}
+
+// GetAuditLogs reads the audit log, reading it is not audited
+func (s *adminService) GetAuditLogs(ctx context.Context, param models.ParamGetAuditLogs) ([]entity.AdminAudit, error) {
+	return s.auditRepo.GetAuditLogs(ctx, param)
+}
+
+// wallet returns walletID when it is set, the main wallet of the customer in currency otherwise
+func (s *adminService) wallet(ctx context.Context, walletID, customerXId, currency string) (*entity.Wallet, error) {
+	if walletID != "" {
+		return s.walletRepo.GetWalletByID(ctx, walletID)
+	}
+	return s.mainWallet(ctx, customerXId, currency)
+}
+
+func (s *adminService) mainWallet(ctx context.Context, customerXId, currency string) (*entity.Wallet, error) {
+	wallet, err := s.walletRepo.GetWallet(ctx, customerXId, currency)
+	if err != nil {
+		return nil, err
+	}
+	if wallet.ID == "" {
+		return nil, models.ErrWalletNotFound
+	}
+	return wallet, nil
+}
+
+func (s *adminService) checkActor(ctx context.Context) error {
+	if actor, _ := activity.GetActorID(ctx); actor == "" {
+		return ErrActorRequired
+	}
+	return nil
+}
+
+// checkAdjust validates an adjustment and returns the wallet it applies to
+func (s *adminService) checkAdjust(ctx context.Context, param ParamAdminAdjust) (*entity.Wallet, error) {
+	if err := s.checkActor(ctx); err != nil {
+		return nil, err
+	}
+	if strings.TrimSpace(param.Reason) == "" {
+		return nil, ErrReasonRequired
+	}
+	if param.Amount == 0 {
+		return nil, ErrAmountRequired
+	}
+	if _, err := uuid.Parse(param.ReferenceID); err != nil {
+		return nil, ErrInvalidReference
+	}
+
+	return s.wallet(ctx, param.WalletID, param.CustomerXId, param.Currency)
+}
+
+// audit writes the outcome of an action, a failed write is logged as the action itself already happened.
+// A nil audit was written by the repository in the transaction of the action.
+func (s *adminService) audit(ctx context.Context, audit *entity.AdminAudit, err error) {
+	if audit == nil || !s.outcome(ctx, audit, err) {
+		return
+	}
+	if aerr := s.auditRepo.RecordAudit(ctx, audit); aerr != nil {
+		log.WithContext(ctx).Errorf("[Service Admin] error when record audit %s by %s on %s, error: %v", audit.Action, audit.Actor, audit.CustomerXId, aerr)
+	}
+}
+
+// succeeded completes audit as successful for the repository to write within the transaction of the
+// action, so a committed freeze or adjustment is never left without its audit row
+func (s *adminService) succeeded(ctx context.Context, audit *entity.AdminAudit) *entity.AdminAudit {
+	if !s.outcome(ctx, audit, nil) {
+		return nil
+	}
+	return audit
+}
+
+// outcome fills the actor and result of audit, false when there is no actor to attribute it to
+func (s *adminService) outcome(ctx context.Context, audit *entity.AdminAudit, err error) bool {
+	audit.Actor, _ = activity.GetActorID(ctx)
+	if audit.Actor == "" {
+		return false
+	}
+	if _, perr := uuid.Parse(audit.CustomerXId); perr != nil {
+		audit.CustomerXId = ""
+	}
+	if _, perr := uuid.Parse(audit.WalletID); perr != nil {
+		audit.WalletID = ""
+	}
+
+	audit.Result = models.AuditResultSuccess
+	audit.Error = ""
+	if err != nil {
+		audit.Result = models.AuditResultFailure
+		audit.Error = err.Error()
+	}
+	return true
+}
+
+func adjustAudit(action string, param ParamAdminAdjust) *entity.AdminAudit {
+	audit := &entity.AdminAudit{Action: action, CustomerXId: param.CustomerXId, WalletID: param.WalletID, Reason: param.Reason}
+	audit.Detail = detail(map[string]interface{}{
+		"currency":     param.Currency,
+		"amount":       param.Amount,
+		"reference_id": param.ReferenceID,
+	})
+	return audit
+}
+
+func referenceID(id string) string {
+	if id == "" {
+		return uuid.New().String()
+	}
+	return id
+}
+
+func detail(fields map[string]interface{}) json.RawMessage {
+	raw, _ := json.Marshal(fields)
+	return raw
+}
+
+func newAdminWallet(wallet *entity.Wallet) AdminWallet {
+	status := models.WalletStatusEnabled
+	if !wallet.FrozenAt.IsZero() {
+		status = models.WalletStatusFrozen
+	} else if !wallet.IsEnabled {
+		status = models.WalletStatusDisabled
+	}
+	return AdminWallet{
+		ID:          wallet.ID,
+		CustomerXId: wallet.OwnedBy,
+		ParentID:    wallet.ParentID,
+		Name:        wallet.Name,
+		Currency:    wallet.Currency,
+		Status:      status,
+		Balance:     wallet.Balance,
+		KycLevel:    wallet.KycLevel,
+		Segment:     wallet.Segment,
+		EnabledAt:   timePtr(wallet.EnabledAt),
+		DisabledAt:  timePtr(wallet.DisabledAt),
+		FrozenAt:    timePtr(wallet.FrozenAt),
+		CreatedAt:   timePtr(wallet.CreatedAt),
+	}
+}
+
+func newAdminTransaction(history *entity.History) AdminTransaction {
+	return AdminTransaction{
+		ID:          history.ID,
+		WalletID:    history.WalletID,
+		Type:        history.Type,
+		Status:      history.Status,
+		Amount:      history.Amount,
+		Currency:    history.Currency,
+		ReferenceID: history.ReferenceID,
+		CreatedAt:   history.CreatedAt,
+	}
+}
+
+func timePtr(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
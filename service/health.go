@@ -0,0 +1,146 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/ahmadmirdas/julo-test/repository/database/migration"
+	"github.com/go-pg/pg/v10"
+)
+
+var (
+	HealthStatusOK   string = "ok"
+	HealthStatusFail string = "fail"
+
+	HealthCheckDatabase  string = "database"
+	HealthCheckMigration string = "migration"
+	HealthCheckPool      string = "pool"
+	HealthCheckShutdown  string = "shutdown"
+)
+
+type HealthCheck struct {
+	Status     string      `json:"status"`
+	DurationMs int64       `json:"duration_ms"`
+	Error      string      `json:"error,omitempty"`
+	Detail     interface{} `json:"detail,omitempty"`
+}
+
+type HealthReport struct {
+	Status string                 `json:"status"`
+	Checks map[string]HealthCheck `json:"checks"`
+}
+
+type ParamHealthChecker struct {
+	// Timeout bounds every check
+	Timeout time.Duration
+	// MigrationVersion is the version the database must be migrated to
+	MigrationVersion int64
+	// MaxPoolUsage is the share of busy pool connections above which the instance is not ready
+	MaxPoolUsage float64
+}
+
+// HealthChecker reports whether the instance can serve traffic
+type HealthChecker struct {
+	db       *pg.DB
+	param    ParamHealthChecker
+	draining atomic.Bool
+}
+
+func NewHealthChecker(db *pg.DB, param ParamHealthChecker) *HealthChecker {
+	if param.Timeout <= 0 {
+		param.Timeout = 2 * time.Second
+	}
+	if param.MaxPoolUsage <= 0 {
+		param.MaxPoolUsage = 1
+	}
+	return &HealthChecker{db: db, param: param}
+}
+
+// Drain makes the instance report not ready, called when shutdown starts
+func (h *HealthChecker) Drain() {
+	h.draining.Store(true)
+}
+
+// Ready runs every check concurrently, the instance is ready when all of them pass
+func (h *HealthChecker) Ready(ctx context.Context) HealthReport {
+	report := HealthReport{Status: HealthStatusOK, Checks: make(map[string]HealthCheck)}
+	if h.draining.Load() {
+		report.Status = HealthStatusFail
+		report.Checks[HealthCheckShutdown] = HealthCheck{Status: HealthStatusFail, Error: "instance is shutting down"}
+		return report
+	}
+
+	checks := map[string]func(ctx context.Context) (interface{}, error){
+		HealthCheckDatabase:  h.checkDatabase,
+		HealthCheckMigration: h.checkMigration,
+		HealthCheckPool:      h.checkPool,
+	}
+
+	var (
+		mutex sync.Mutex
+		wg    sync.WaitGroup
+	)
+	for name, check := range checks {
+		wg.Add(1)
+		go func(name string, check func(ctx context.Context) (interface{}, error)) {
+			defer wg.Done()
+			checkCtx, cancel := context.WithTimeout(ctx, h.param.Timeout)
+			defer cancel()
+
+			start := time.Now()
+			detail, err := check(checkCtx)
+			res := HealthCheck{Status: HealthStatusOK, DurationMs: time.Since(start).Milliseconds(), Detail: detail}
+			if err != nil {
+				res.Status = HealthStatusFail
+				res.Error = err.Error()
+			}
+
+			mutex.Lock()
+			defer mutex.Unlock()
+			report.Checks[name] = res
+			if err != nil {
+				report.Status = HealthStatusFail
+			}
+		}(name, check)
+	}
+	wg.Wait()
+
+	return report
+}
+
+func (h *HealthChecker) checkDatabase(ctx context.Context) (interface{}, error) {
+	return nil, h.db.Ping(ctx)
+}
+
+func (h *HealthChecker) checkMigration(ctx context.Context) (interface{}, error) {
+	current, err := migration.CurrentVersion(ctx, h.db)
+	if err != nil {
+		return nil, err
+	}
+
+	detail := map[string]int64{"version": current, "expected": h.param.MigrationVersion}
+	if current < h.param.MigrationVersion {
+		return detail, fmt.Errorf("database is at migration %d, %d is expected", current, h.param.MigrationVersion)
+	}
+	return detail, nil
+}
+
+func (h *HealthChecker) checkPool(ctx context.Context) (interface{}, error) {
+	stats := h.db.PoolStats()
+	size := h.db.Options().PoolSize
+	busy := int(stats.TotalConns) - int(stats.IdleConns)
+
+	detail := map[string]interface{}{
+		"size":     size,
+		"busy":     busy,
+		"idle":     stats.IdleConns,
+		"timeouts": stats.Timeouts,
+	}
+	if size > 0 && float64(busy)/float64(size) >= h.param.MaxPoolUsage {
+		return detail, fmt.Errorf("connection pool is saturated, %d of %d connections busy", busy, size)
+	}
+	return detail, nil
+}
@@ -0,0 +1,88 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/ahmadmirdas/julo-test/repository/database/models"
+	"github.com/ahmadmirdas/julo-test/repository/database/models/entity"
+	"github.com/ahmadmirdas/julo-test/utils/limit"
+)
+
+// CheckLimit validates amount against the wallet limits. A pocket shares the limits of its main wallet:
+// the override and max balance of the main wallet apply, usage is summed over the main wallet and
+// every pocket in the currency and max balance is checked against their total balance. Limit
+// violations are returned as *limit.ErrLimitExceeded or limit.ErrInvalidAmount, any other error comes
+// from the repository. The returned check is passed to the repository, which runs it again against the
+// usage read within the transaction posting the amount.
+func CheckLimit(ctx context.Context, walletRepo models.WalletDBRepo, limitEngine *limit.Engine, wallet *entity.Wallet, txType string, amount float64, credit bool) (*models.LimitCheck, error) {
+	check, err := newLimitCheck(ctx, walletRepo, limitEngine, wallet, txType, amount, credit)
+	if err != nil {
+		return nil, err
+	}
+
+	daily, err := walletRepo.GetTransactionUsage(ctx, wallet.OwnedBy, wallet.Currency, txType, check.DailySince)
+	if err != nil {
+		return nil, err
+	}
+	monthly, err := walletRepo.GetTransactionUsage(ctx, wallet.OwnedBy, wallet.Currency, txType, check.MonthlySince)
+	if err != nil {
+		return nil, err
+	}
+	balance, err := walletRepo.GetTotalBalance(ctx, wallet.OwnedBy, wallet.Currency)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := check.Check(models.LimitUsage{Daily: *daily, Monthly: *monthly, Balance: balance}); err != nil {
+		return nil, err
+	}
+	return check, nil
+}
+
+// newLimitCheck resolves the limits of a transaction, see CheckLimit
+func newLimitCheck(ctx context.Context, walletRepo models.WalletDBRepo, limitEngine *limit.Engine, wallet *entity.Wallet, txType string, amount float64, credit bool) (*models.LimitCheck, error) {
+	if wallet.ParentID != "" {
+		parent, err := walletRepo.GetWalletByID(ctx, wallet.ParentID)
+		if err != nil {
+			return nil, err
+		}
+		wallet = parent
+	}
+
+	override, err := walletRepo.GetWalletLimit(ctx, wallet.ID, txType)
+	if err != nil {
+		return nil, err
+	}
+
+	limits := limitEngine.Limits(wallet.Currency, wallet.KycLevel, txType).Merge(limit.Limits{
+		TxLimit: limit.TxLimit{
+			MaxAmount:     override.MaxAmount,
+			DailyAmount:   override.DailyAmount,
+			DailyCount:    override.DailyCount,
+			MonthlyAmount: override.MonthlyAmount,
+			MonthlyCount:  override.MonthlyCount,
+		},
+		MaxBalance: wallet.MaxBalance,
+	})
+
+	now := time.Now()
+	return &models.LimitCheck{
+		TxType:       txType,
+		DailySince:   limit.StartOfDay(now),
+		MonthlySince: limit.StartOfMonth(now),
+		Check: func(usage models.LimitUsage) error {
+			return limits.Check(txType, amount, usage.Balance, credit,
+				limit.Usage{Amount: usage.Daily.Amount, Count: usage.Daily.Count},
+				limit.Usage{Amount: usage.Monthly.Amount, Count: usage.Monthly.Count},
+				now)
+		},
+	}, nil
+}
+
+// IsLimitError reports whether err is a limit violation rather than a repository failure
+func IsLimitError(err error) bool {
+	var exceeded *limit.ErrLimitExceeded
+	return errors.As(err, &exceeded) || errors.Is(err, limit.ErrInvalidAmount)
+}
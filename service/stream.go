@@ -0,0 +1,98 @@
+package service
+
+import (
+	"context"
+	"sync"
+
+	"github.com/ahmadmirdas/julo-test/utils/activity"
+	"github.com/ahmadmirdas/julo-test/utils/log"
+	"github.com/go-pg/pg/v10"
+)
+
+// OutboxEventChannel is the Postgres notification channel raised on every customer outbox event,
+// the payload is the customer xid
+const OutboxEventChannel string = "outbox_event"
+
+// EventHub listens to outbox notifications and wakes up the streams of the notified customer,
+// every replica runs its own hub so a stream is woken up whichever replica wrote the event
+type EventHub struct {
+	db          *pg.DB
+	mutex       sync.Mutex
+	subscribers map[string]map[chan struct{}]struct{}
+	closed      chan struct{}
+	closeOnce   sync.Once
+}
+
+func NewEventHub(db *pg.DB) *EventHub {
+	return &EventHub{
+		db:          db,
+		subscribers: make(map[string]map[chan struct{}]struct{}),
+		closed:      make(chan struct{}),
+	}
+}
+
+// Start listens until ctx is done, the listener reconnects by itself when the connection drops
+func (h *EventHub) Start(ctx context.Context) {
+	logCtx := activity.NewContext("Service.EventHub")
+	ln := h.db.Listen(ctx, OutboxEventChannel)
+	defer ln.Close()
+
+	ch := ln.Channel()
+	log.WithContext(logCtx).Infof("[Service EventHub] listening on %s", OutboxEventChannel)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case n, ok := <-ch:
+			if !ok {
+				return
+			}
+			h.notify(n.Payload)
+		}
+	}
+}
+
+// Subscribe returns a channel signalled when an event of the customer is written and
+// the func to release it. Signals are coalesced, the subscriber reads the events itself.
+func (h *EventHub) Subscribe(customerXId string) (<-chan struct{}, func()) {
+	wake := make(chan struct{}, 1)
+
+	h.mutex.Lock()
+	if h.subscribers[customerXId] == nil {
+		h.subscribers[customerXId] = make(map[chan struct{}]struct{})
+	}
+	h.subscribers[customerXId][wake] = struct{}{}
+	h.mutex.Unlock()
+
+	return wake, func() {
+		h.mutex.Lock()
+		defer h.mutex.Unlock()
+		delete(h.subscribers[customerXId], wake)
+		if len(h.subscribers[customerXId]) == 0 {
+			delete(h.subscribers, customerXId)
+		}
+	}
+}
+
+// Close ends every open stream, clients reconnect with their last event id to another replica
+func (h *EventHub) Close() {
+	h.closeOnce.Do(func() {
+		close(h.closed)
+	})
+}
+
+// Done is closed once the hub is closed
+func (h *EventHub) Done() <-chan struct{} {
+	return h.closed
+}
+
+func (h *EventHub) notify(customerXId string) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	for wake := range h.subscribers[customerXId] {
+		select {
+		case wake <- struct{}{}:
+		default:
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package service
+
+import (
+	"context"
+	"errors"
+
+	"github.com/ahmadmirdas/julo-test/config"
+	"github.com/ahmadmirdas/julo-test/repository/database/models"
+	"github.com/ahmadmirdas/julo-test/repository/database/models/entity"
+	"github.com/ahmadmirdas/julo-test/utils/currency"
+	"github.com/ahmadmirdas/julo-test/utils/settings"
+)
+
+var ErrTransferNotAllowed = errors.New("your kyc level cannot transfer")
+
+// ParamTransfer moves Amount between two wallets of a customer, an empty pocket id is the main wallet in Currency
+type ParamTransfer struct {
+	CustomerXId  string
+	Currency     string
+	FromPocketID string
+	ToPocketID   string
+	Amount       float64
+	ReferenceID  string
+}
+
+type TransferService interface {
+	Transfer(ctx context.Context, param ParamTransfer) (*entity.History, error)
+}
+
+type transferService struct {
+	walletRepo models.WalletDBRepo
+	pocketRepo models.PocketDBRepo
+	settings   *settings.Store
+}
+
+func NewTransferService(walletRepo models.WalletDBRepo, pocketRepo models.PocketDBRepo, settings *settings.Store) TransferService {
+	return &transferService{
+		walletRepo: walletRepo,
+		pocketRepo: pocketRepo,
+		settings:   settings,
+	}
+}
+
+// Transfer is the single path used by manual and scheduled transfers between own pockets, the fee of
+// the transfer rules is debited from the sender on top of the amount
+func (s *transferService) Transfer(ctx context.Context, param ParamTransfer) (*entity.History, error) {
+	main, err := s.walletRepo.GetWallet(ctx, param.CustomerXId, param.Currency)
+	if err != nil {
+		return nil, err
+	}
+	if main.ID == "" {
+		return nil, models.ErrWalletNotFound
+	}
+	if !main.IsEnabled {
+		return nil, models.ErrWalletDisabled
+	}
+
+	from, err := s.resolve(ctx, main, param.FromPocketID)
+	if err != nil {
+		return nil, err
+	}
+	to, err := s.resolve(ctx, main, param.ToPocketID)
+	if err != nil {
+		return nil, err
+	}
+
+	current := s.settings.Load()
+	if !current.Kyc.Allowed(from.KycLevel, models.HistoryTypeTransfer) {
+		return nil, ErrTransferNotAllowed
+	}
+	limitCheck, err := CheckLimit(ctx, s.walletRepo, current.Limit, from, models.HistoryTypeTransfer, param.Amount, false)
+	if err != nil {
+		return nil, err
+	}
+
+	cur, _ := currency.Get(main.Currency)
+	return s.pocketRepo.TransferPocket(ctx, models.ParamPocketTransfer{
+		CustomerXId:  param.CustomerXId,
+		FromWalletID: from.ID,
+		ToWalletID:   to.ID,
+		Amount:       param.Amount,
+		ReferenceID:  param.ReferenceID,
+		Fee:          current.Fee.Calculate(models.HistoryTypeTransfer, from.Segment, cur, param.Amount),
+		FeeAccount:   config.Config.FeeCfg.RevenueAccount,
+		Currency:     main.Currency,
+		Limit:        limitCheck,
+	})
+}
+
+func (s *transferService) resolve(ctx context.Context, main *entity.Wallet, pocketID string) (*entity.Wallet, error) {
+	if pocketID == "" {
+		return main, nil
+	}
+
+	pocket, err := s.pocketRepo.GetPocket(ctx, main.OwnedBy, pocketID)
+	if err != nil {
+		return nil, err
+	}
+	if pocket.Currency != main.Currency {
+		return nil, models.ErrPocketCurrencyMatch
+	}
+
+	return pocket, nil
+}
+
+// IsClientError reports whether err is caused by the request rather than by the service
+func IsClientError(err error) bool {
+	return IsLimitError(err) ||
+		IsKycError(err) ||
+		IsRequestError(err) ||
+		errors.Is(err, models.ErrWalletNotFound) ||
+		errors.Is(err, models.ErrWalletDisabled) ||
+		errors.Is(err, models.ErrWalletFrozen) ||
+		errors.Is(err, models.ErrInsufficientFund) ||
+		errors.Is(err, models.ErrPocketNotFound) ||
+		errors.Is(err, models.ErrPocketSameWallet) ||
+		errors.Is(err, models.ErrPocketCurrencyMatch)
+}
@@ -0,0 +1,101 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ahmadmirdas/julo-test/config"
+	"github.com/ahmadmirdas/julo-test/repository/database/models"
+	"github.com/ahmadmirdas/julo-test/repository/database/models/entity"
+	"github.com/ahmadmirdas/julo-test/utils/fee"
+	"github.com/ahmadmirdas/julo-test/utils/kyc"
+	"github.com/ahmadmirdas/julo-test/utils/limit"
+	"github.com/ahmadmirdas/julo-test/utils/settings"
+)
+
+// fakePocketRepo serves one open pocket and keeps the last transfer it was asked for
+type fakePocketRepo struct {
+	models.PocketDBRepo
+	pocket   entity.Wallet
+	transfer models.ParamPocketTransfer
+}
+
+func (f *fakePocketRepo) GetPocket(ctx context.Context, customerXId, pocketID string) (*entity.Wallet, error) {
+	if pocketID != f.pocket.ID {
+		return nil, models.ErrPocketNotFound
+	}
+	pocket := f.pocket
+	return &pocket, nil
+}
+
+func (f *fakePocketRepo) TransferPocket(ctx context.Context, param models.ParamPocketTransfer) (*entity.History, error) {
+	f.transfer = param
+	return &entity.History{WalletID: param.FromWalletID, Type: models.HistoryTypeTransfer, Amount: param.Amount}, nil
+}
+
+func TestTransferFee(t *testing.T) {
+	config.Config.FeeCfg.RevenueAccount = "fee-revenue"
+
+	main := entity.Wallet{
+		ID:        "c4d7d61f-b702-44a8-af97-5dbdafa96551",
+		OwnedBy:   "ea0212d3-abd6-406f-8c67-868e814a2436",
+		IsEnabled: true,
+		Balance:   100000,
+		Currency:  "IDR",
+		Segment:   "priority",
+		KycLevel:  kyc.LevelBasic,
+	}
+	pocket := main
+	pocket.ID = "5b0a6d6e-4c1f-4d57-9d7e-3f0b2a61c8a9"
+	pocket.ParentID = main.ID
+	pocket.Balance = 0
+
+	tests := []struct {
+		name  string
+		rules []fee.Rule
+		want  float64
+	}{
+		{
+			name:  "no transfer rule",
+			rules: []fee.Rule{{Type: models.HistoryTypeWithdraw, Segment: fee.AnySegment, Kind: fee.KindFlat, Flat: 2500}},
+			want:  0,
+		},
+		{
+			name:  "flat transfer rule",
+			rules: []fee.Rule{{Type: models.HistoryTypeTransfer, Segment: fee.AnySegment, Kind: fee.KindFlat, Flat: 1000}},
+			want:  1000,
+		},
+		{
+			name: "segment of the sender",
+			rules: []fee.Rule{
+				{Type: models.HistoryTypeTransfer, Segment: fee.AnySegment, Kind: fee.KindFlat, Flat: 1000},
+				{Type: models.HistoryTypeTransfer, Segment: "priority", Kind: fee.KindPercentage, Percentage: 1},
+			},
+			want: 200,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pocketRepo := &fakePocketRepo{pocket: pocket}
+			s := NewTransferService(&fakeWalletRepo{wallet: main}, pocketRepo, settings.NewStore(&settings.Settings{
+				Fee:   fee.NewEngine(tt.rules),
+				Limit: limit.NewEngine(nil, nil),
+				Kyc:   kyc.NewPolicy(map[string][]string{kyc.LevelBasic: {models.HistoryTypeTransfer}}),
+			}))
+
+			_, err := s.Transfer(context.Background(), ParamTransfer{
+				CustomerXId: main.OwnedBy,
+				Currency:    main.Currency,
+				ToPocketID:  pocket.ID,
+				Amount:      20000,
+				ReferenceID: "50535246-dcb2-4929-8cc9-004ea06f5241",
+			})
+			if err != nil {
+				t.Fatalf("Transfer() error = %v", err)
+			}
+			if got := pocketRepo.transfer; got.Fee != tt.want || got.FeeAccount != "fee-revenue" || got.Amount != 20000 {
+				t.Errorf("transfer of %v with fee %v to %q, want 20000 with fee %v to fee-revenue", got.Amount, got.Fee, got.FeeAccount, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,229 @@
+package service
+
+import (
+	"context"
+	"errors"
+
+	"github.com/ahmadmirdas/julo-test/config"
+	"github.com/ahmadmirdas/julo-test/repository/database/models"
+	"github.com/ahmadmirdas/julo-test/repository/database/models/entity"
+	"github.com/ahmadmirdas/julo-test/utils/currency"
+	"github.com/ahmadmirdas/julo-test/utils/metrics"
+	"github.com/ahmadmirdas/julo-test/utils/settings"
+)
+
+// ErrNoWallet is returned when the customer has no main wallet in the requested currency
+type ErrNoWallet struct {
+	Currency string
+}
+
+func (e *ErrNoWallet) Error() string {
+	return "you have no " + e.Currency + " wallet, mixed currency operations require an explicit conversion"
+}
+
+// ErrPocketUnavailable is returned when the requested pocket is closed or holds another currency
+type ErrPocketUnavailable struct {
+	Currency string
+}
+
+func (e *ErrPocketUnavailable) Error() string {
+	return "pocket is closed or its currency is not " + e.Currency
+}
+
+// ErrKycNotAllowed is returned when the kyc level of the wallet does not allow the transaction type
+type ErrKycNotAllowed struct {
+	Level  string
+	TxType string
+}
+
+func (e *ErrKycNotAllowed) Error() string {
+	return "your kyc level " + e.Level + " cannot " + e.TxType
+}
+
+// ErrAmountPrecision is returned when the amount has more decimals than its currency allows
+type ErrAmountPrecision struct {
+	Err error
+}
+
+func (e *ErrAmountPrecision) Error() string {
+	return e.Err.Error()
+}
+
+func (e *ErrAmountPrecision) Unwrap() error {
+	return e.Err
+}
+
+// ParamTransaction posts Amount to the main wallet of the customer in Currency, or to its pocket when PocketID is set
+type ParamTransaction struct {
+	CustomerXId string
+	Currency    currency.Currency
+	PocketID    string
+	Amount      float64
+	ReferenceID string
+}
+
+// WalletService is the single path of the wallet operations shared by the HTTP handlers and the gRPC server
+type WalletService interface {
+	Enable(ctx context.Context, customerXId, currency string) (*entity.Wallet, error)
+	Wallet(ctx context.Context, customerXId string, cur currency.Currency, pocketID string) (*entity.Wallet, error)
+	Deposit(ctx context.Context, param ParamTransaction) (*entity.History, error)
+	Withdraw(ctx context.Context, param ParamTransaction) (*entity.History, float64, error)
+}
+
+type walletService struct {
+	walletRepo models.WalletDBRepo
+	pocketRepo models.PocketDBRepo
+	settings   *settings.Store
+}
+
+func NewWalletService(walletRepo models.WalletDBRepo, pocketRepo models.PocketDBRepo, settings *settings.Store) WalletService {
+	return &walletService{
+		walletRepo: walletRepo,
+		pocketRepo: pocketRepo,
+		settings:   settings,
+	}
+}
+
+// Enable enables the wallet of the customer in currency, creating it on first use, a frozen wallet stays disabled
+func (s *walletService) Enable(ctx context.Context, customerXId, currency string) (*entity.Wallet, error) {
+	return s.walletRepo.EnableWallet(ctx, customerXId, currency)
+}
+
+// Wallet returns the enabled main wallet of the customer in cur, or its open pocket when pocketID is set
+func (s *walletService) Wallet(ctx context.Context, customerXId string, cur currency.Currency, pocketID string) (*entity.Wallet, error) {
+	wallet, err := s.walletRepo.GetWallet(ctx, customerXId, cur.Code)
+	if err != nil {
+		return nil, err
+	}
+	if wallet.ID == "" {
+		return nil, &ErrNoWallet{Currency: cur.Code}
+	}
+	if !wallet.IsEnabled {
+		return nil, models.ErrWalletDisabled
+	}
+	if pocketID == "" {
+		return wallet, nil
+	}
+
+	pocket, err := s.pocketRepo.GetPocket(ctx, customerXId, pocketID)
+	if err != nil {
+		return nil, err
+	}
+	if pocket.Currency != cur.Code || !pocket.IsEnabled {
+		return nil, &ErrPocketUnavailable{Currency: cur.Code}
+	}
+	return pocket, nil
+}
+
+// Deposit credits the wallet once its kyc level and limits allow it
+func (s *walletService) Deposit(ctx context.Context, param ParamTransaction) (history *entity.History, err error) {
+	defer func() { observeFailure(models.HistoryTypeDeposit, err) }()
+	wallet, limitCheck, _, err := s.prepare(ctx, models.HistoryTypeDeposit, param)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.walletRepo.WalletDeposit(ctx, models.ParamWalletDeposit{
+		WalletID:    wallet.ID,
+		Balance:     wallet.Balance,
+		CustomerXId: param.CustomerXId,
+		Amount:      param.Amount,
+		Currency:    wallet.Currency,
+		ReferenceID: param.ReferenceID,
+		Limit:       limitCheck,
+	})
+}
+
+// Withdraw debits the amount and its fee from the wallet once its kyc level and limits allow it,
+// the fee charged is returned with the transaction
+func (s *walletService) Withdraw(ctx context.Context, param ParamTransaction) (history *entity.History, withdrawFee float64, err error) {
+	defer func() { observeFailure(models.HistoryTypeWithdraw, err) }()
+	wallet, limitCheck, current, err := s.prepare(ctx, models.HistoryTypeWithdraw, param)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	withdrawFee = current.Fee.Calculate(models.HistoryTypeWithdraw, wallet.Segment, param.Currency, param.Amount)
+	history, err = s.walletRepo.WalletWithdraw(ctx, models.ParamWalletWithdraw{
+		WalletID:    wallet.ID,
+		Balance:     wallet.Balance,
+		CustomerXId: param.CustomerXId,
+		Amount:      param.Amount,
+		Currency:    wallet.Currency,
+		ReferenceID: param.ReferenceID,
+		Fee:         withdrawFee,
+		FeeAccount:  config.Config.FeeCfg.RevenueAccount,
+		Limit:       limitCheck,
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return history, withdrawFee, nil
+}
+
+// prepare resolves the wallet of a deposit or withdrawal and checks the amount, kyc level and limits
+// against one settings snapshot, which is returned for the fee
+func (s *walletService) prepare(ctx context.Context, txType string, param ParamTransaction) (*entity.Wallet, *models.LimitCheck, *settings.Settings, error) {
+	if err := param.Currency.Validate(param.Amount); err != nil {
+		return nil, nil, nil, &ErrAmountPrecision{Err: err}
+	}
+
+	wallet, err := s.Wallet(ctx, param.CustomerXId, param.Currency, param.PocketID)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	current := s.settings.Load()
+	if !current.Kyc.Allowed(wallet.KycLevel, txType) {
+		return nil, nil, nil, &ErrKycNotAllowed{Level: wallet.KycLevel, TxType: txType}
+	}
+	limitCheck, err := CheckLimit(ctx, s.walletRepo, current.Limit, wallet, txType, param.Amount, txType == models.HistoryTypeDeposit)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	return wallet, limitCheck, current, nil
+}
+
+// observeFailure counts a deposit or withdrawal that did not go through under the failure code of err
+func observeFailure(txType string, err error) {
+	if err == nil {
+		return
+	}
+	metrics.ObserveFailure(txType, failureCode(err))
+}
+
+// failureCode is the transaction_failures_total code of a wallet service error
+func failureCode(err error) string {
+	switch {
+	case IsRequestError(err):
+		return metrics.FailureInvalidRequest
+	case errors.Is(err, models.ErrWalletNotFound), errors.Is(err, models.ErrPocketNotFound):
+		return metrics.FailureWalletNotFound
+	case errors.Is(err, models.ErrWalletDisabled), errors.Is(err, models.ErrWalletFrozen):
+		return metrics.FailureWalletDisabled
+	case IsKycError(err):
+		return metrics.FailureKycNotAllowed
+	case IsLimitError(err):
+		return metrics.FailureLimitExceeded
+	case errors.Is(err, models.ErrInsufficientFund):
+		return metrics.FailureInsufficientFund
+	}
+	return metrics.FailureInternal
+}
+
+// IsKycError reports whether err is a transaction refused for the kyc level of the wallet
+func IsKycError(err error) bool {
+	var notAllowed *ErrKycNotAllowed
+	return errors.As(err, &notAllowed) || errors.Is(err, ErrTransferNotAllowed)
+}
+
+// IsRequestError reports whether err is a request the wallet cannot serve as asked, an amount with too many
+// decimals, a currency without wallet or an unavailable pocket
+func IsRequestError(err error) bool {
+	var precision *ErrAmountPrecision
+	var noWallet *ErrNoWallet
+	var pocket *ErrPocketUnavailable
+	return errors.As(err, &precision) || errors.As(err, &noWallet) || errors.As(err, &pocket)
+}
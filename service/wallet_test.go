@@ -0,0 +1,105 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/ahmadmirdas/julo-test/repository/database/models"
+	"github.com/ahmadmirdas/julo-test/repository/database/models/entity"
+	"github.com/ahmadmirdas/julo-test/utils/currency"
+	"github.com/ahmadmirdas/julo-test/utils/fee"
+	"github.com/ahmadmirdas/julo-test/utils/kyc"
+	"github.com/ahmadmirdas/julo-test/utils/limit"
+	"github.com/ahmadmirdas/julo-test/utils/metrics"
+	"github.com/ahmadmirdas/julo-test/utils/settings"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+// fakeWalletRepo serves one enabled wallet and debits it like the conditional update of WalletWithdraw,
+// methods the service under test does not call panic
+type fakeWalletRepo struct {
+	models.WalletDBRepo
+	wallet entity.Wallet
+}
+
+func (f *fakeWalletRepo) GetWallet(ctx context.Context, customerXId, currency string) (*entity.Wallet, error) {
+	wallet := f.wallet
+	return &wallet, nil
+}
+
+func (f *fakeWalletRepo) GetWalletLimit(ctx context.Context, walletID, txType string) (*entity.WalletLimit, error) {
+	return &entity.WalletLimit{WalletID: walletID, Type: txType}, nil
+}
+
+func (f *fakeWalletRepo) GetTransactionUsage(ctx context.Context, customerXId, currency, txType string, since time.Time) (*models.TransactionUsage, error) {
+	return &models.TransactionUsage{}, nil
+}
+
+func (f *fakeWalletRepo) GetTotalBalance(ctx context.Context, customerXId, currency string) (float64, error) {
+	return f.wallet.Balance, nil
+}
+
+func (f *fakeWalletRepo) WalletWithdraw(ctx context.Context, param models.ParamWalletWithdraw) (*entity.History, error) {
+	if param.Amount+param.Fee > f.wallet.Balance {
+		return nil, models.ErrInsufficientFund
+	}
+	return &entity.History{WalletID: param.WalletID, Type: models.HistoryTypeWithdraw, Amount: param.Amount}, nil
+}
+
+func TestFailureCode(t *testing.T) {
+	tests := []struct {
+		err  error
+		want string
+	}{
+		{err: &ErrAmountPrecision{Err: errors.New("too many decimals")}, want: metrics.FailureInvalidRequest},
+		{err: models.ErrWalletNotFound, want: metrics.FailureWalletNotFound},
+		{err: models.ErrWalletFrozen, want: metrics.FailureWalletDisabled},
+		{err: &ErrKycNotAllowed{Level: kyc.LevelBasic, TxType: models.HistoryTypeWithdraw}, want: metrics.FailureKycNotAllowed},
+		{err: models.ErrInsufficientFund, want: metrics.FailureInsufficientFund},
+		{err: fmt.Errorf("withdraw: %w", models.ErrInsufficientFund), want: metrics.FailureInsufficientFund},
+		{err: errors.New("connection refused"), want: metrics.FailureInternal},
+	}
+	for _, tt := range tests {
+		t.Run(tt.err.Error(), func(t *testing.T) {
+			if got := failureCode(tt.err); got != tt.want {
+				t.Errorf("failureCode(%v) = %s, want %s", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWithdrawInsufficientFund(t *testing.T) {
+	walletRepo := &fakeWalletRepo{wallet: entity.Wallet{
+		ID:        "c4d7d61f-b702-44a8-af97-5dbdafa96551",
+		OwnedBy:   "ea0212d3-abd6-406f-8c67-868e814a2436",
+		IsEnabled: true,
+		Balance:   10000,
+		Currency:  "IDR",
+		KycLevel:  kyc.LevelBasic,
+	}}
+	s := NewWalletService(walletRepo, nil, settings.NewStore(&settings.Settings{
+		Fee:   fee.NewEngine([]fee.Rule{{Type: models.HistoryTypeWithdraw, Segment: fee.AnySegment, Kind: fee.KindFlat, Flat: 2500}}),
+		Limit: limit.NewEngine(nil, nil),
+		Kyc:   kyc.NewPolicy(map[string][]string{kyc.LevelBasic: {models.HistoryTypeWithdraw}}),
+	}))
+	idr, _ := currency.Get("IDR")
+
+	failures := metrics.TransactionFailures.WithLabelValues(models.HistoryTypeWithdraw, metrics.FailureInsufficientFund)
+	before := testutil.ToFloat64(failures)
+	// the balance covers the amount but not its fee
+	_, _, err := s.Withdraw(context.Background(), ParamTransaction{
+		CustomerXId: walletRepo.wallet.OwnedBy,
+		Currency:    idr,
+		Amount:      9000,
+		ReferenceID: "50535246-dcb2-4929-8cc9-004ea06f5241",
+	})
+	if !errors.Is(err, models.ErrInsufficientFund) {
+		t.Fatalf("Withdraw() error = %v, want %v", err, models.ErrInsufficientFund)
+	}
+	if got := testutil.ToFloat64(failures) - before; got != 1 {
+		t.Errorf("%s failures counted %v times, want 1", metrics.FailureInsufficientFund, got)
+	}
+}
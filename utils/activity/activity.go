@@ -18,8 +18,13 @@ const (
 )
 
 func NewContext(action string) context.Context {
+	return NewRequestContext(context.Background(), action)
+}
+
+// NewRequestContext starts an activity under parent, keeping its deadline and trace span
+func NewRequestContext(parent context.Context, action string) context.Context {
 	activityId := uuid.New().String()
-	ctx := context.WithValue(context.Background(), ActivityID, activityId)
+	ctx := context.WithValue(parent, ActivityID, activityId)
 	return context.WithValue(ctx, Action, action)
 }
 
@@ -31,6 +36,14 @@ func GetAction(ctx context.Context) (string, bool) {
 	return getStringValueFromContext(ctx, Action)
 }
 
+func WithActorID(ctx context.Context, actorID string) context.Context {
+	return context.WithValue(ctx, ActorID, actorID)
+}
+
+func GetActorID(ctx context.Context) (string, bool) {
+	return getStringValueFromContext(ctx, ActorID)
+}
+
 func WithCakeID(ctx context.Context, cakeID int) context.Context {
 	return context.WithValue(ctx, CakeID, cakeID)
 }
@@ -44,6 +57,9 @@ func GetFields(ctx context.Context) map[string]interface{} {
 	if action, ok := GetAction(ctx); ok {
 		fields["action"] = action
 	}
+	if actorID, ok := GetActorID(ctx); ok {
+		fields["actor_id"] = actorID
+	}
 	return fields
 }
 
@@ -0,0 +1,59 @@
+package currency
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// Currency is an ISO 4217 currency with its number of minor unit digits
+type Currency struct {
+	Code       string
+	MinorUnits int
+}
+
+var currencies = map[string]Currency{
+	"AUD": {Code: "AUD", MinorUnits: 2},
+	"BHD": {Code: "BHD", MinorUnits: 3},
+	"CNY": {Code: "CNY", MinorUnits: 2},
+	"EUR": {Code: "EUR", MinorUnits: 2},
+	"GBP": {Code: "GBP", MinorUnits: 2},
+	"IDR": {Code: "IDR", MinorUnits: 2},
+	"JPY": {Code: "JPY", MinorUnits: 0},
+	"KRW": {Code: "KRW", MinorUnits: 0},
+	"KWD": {Code: "KWD", MinorUnits: 3},
+	"MYR": {Code: "MYR", MinorUnits: 2},
+	"PHP": {Code: "PHP", MinorUnits: 2},
+	"SGD": {Code: "SGD", MinorUnits: 2},
+	"THB": {Code: "THB", MinorUnits: 2},
+	"USD": {Code: "USD", MinorUnits: 2},
+	"VND": {Code: "VND", MinorUnits: 0},
+}
+
+// Get returns the currency of an ISO 4217 code, the code is case insensitive
+func Get(code string) (Currency, bool) {
+	c, ok := currencies[strings.ToUpper(code)]
+	return c, ok
+}
+
+// Round rounds amount half away from zero to the minor unit of the currency
+func (c Currency) Round(amount float64) float64 {
+	scale := math.Pow10(c.MinorUnits)
+	return math.Round(amount*scale) / scale
+}
+
+// Validate rejects amounts carrying more decimals than the currency minor unit
+func (c Currency) Validate(amount float64) error {
+	scale := math.Pow10(c.MinorUnits)
+	if math.Abs(amount*scale-math.Round(amount*scale)) > 1e-6 {
+		return fmt.Errorf("amount %v has more than %d decimals allowed for %s", amount, c.MinorUnits, c.Code)
+	}
+	return nil
+}
+
+// Floor rounds amount down to the minor unit of the currency
+func (c Currency) Floor(amount float64) float64 {
+	scale := math.Pow10(c.MinorUnits)
+	// the epsilon keeps exact values such as 0.29*100 from falling one minor unit short
+	return math.Floor(amount*scale+1e-9) / scale
+}
@@ -0,0 +1,125 @@
+package fee
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ahmadmirdas/julo-test/utils/currency"
+)
+
+const (
+	KindFlat       = "flat"
+	KindPercentage = "percentage"
+	KindTiered     = "tiered"
+
+	// AnySegment matches every customer segment when no specific rule is found
+	AnySegment = "*"
+)
+
+// Tier is a single bracket of a tiered rule, UpTo 0 means no upper bound
+type Tier struct {
+	UpTo       float64 `mapstructure:"up_to"`
+	Flat       float64 `mapstructure:"flat"`
+	Percentage float64 `mapstructure:"percentage"`
+}
+
+// Rule describes how fee is charged for a transaction type, customer segment and currency,
+// an empty Currency matches every currency
+type Rule struct {
+	Type       string  `mapstructure:"type"`
+	Segment    string  `mapstructure:"segment"`
+	Currency   string  `mapstructure:"currency"`
+	Kind       string  `mapstructure:"kind"`
+	Flat       float64 `mapstructure:"flat"`
+	Percentage float64 `mapstructure:"percentage"`
+	Tiers      []Tier  `mapstructure:"tiers"`
+	Min        float64 `mapstructure:"min"`
+	Max        float64 `mapstructure:"max"`
+}
+
+type Engine struct {
+	rules []Rule
+}
+
+func NewEngine(rules []Rule) *Engine {
+	return &Engine{rules: rules}
+}
+
+// Validate reports the first rule with an unknown kind or a negative amount
+func Validate(rules []Rule) error {
+	for i, rule := range rules {
+		if rule.Type == "" {
+			return fmt.Errorf("rule %d: type is required", i)
+		}
+		switch rule.Kind {
+		case KindFlat, KindPercentage:
+		case KindTiered:
+			if len(rule.Tiers) == 0 {
+				return fmt.Errorf("rule %d: tiered rule has no tiers", i)
+			}
+		default:
+			return fmt.Errorf("rule %d: unknown kind %q", i, rule.Kind)
+		}
+		if rule.Flat < 0 || rule.Percentage < 0 || rule.Min < 0 || rule.Max < 0 {
+			return fmt.Errorf("rule %d: amounts must not be negative", i)
+		}
+		if rule.Max > 0 && rule.Min > rule.Max {
+			return fmt.Errorf("rule %d: min is greater than max", i)
+		}
+	}
+	return nil
+}
+
+// Calculate returns the fee charged for amount rounded to the currency minor unit, zero when no rule matches
+func (e *Engine) Calculate(txType, segment string, cur currency.Currency, amount float64) float64 {
+	rule, ok := e.findRule(txType, segment, cur.Code)
+	if !ok || amount <= 0 {
+		return 0
+	}
+
+	var fee float64
+	switch rule.Kind {
+	case KindFlat:
+		fee = rule.Flat
+	case KindPercentage:
+		fee = amount * rule.Percentage / 100
+	case KindTiered:
+		for _, tier := range rule.Tiers {
+			if tier.UpTo == 0 || amount <= tier.UpTo {
+				fee = tier.Flat + amount*tier.Percentage/100
+				break
+			}
+		}
+	}
+
+	if rule.Min > 0 && fee < rule.Min {
+		fee = rule.Min
+	}
+	if rule.Max > 0 && fee > rule.Max {
+		fee = rule.Max
+	}
+
+	return cur.Round(fee)
+}
+
+func (e *Engine) findRule(txType, segment, currencyCode string) (Rule, bool) {
+	var fallback *Rule
+	for i, rule := range e.rules {
+		if rule.Type != txType {
+			continue
+		}
+		if rule.Currency != "" && !strings.EqualFold(rule.Currency, currencyCode) {
+			continue
+		}
+		if rule.Segment == segment {
+			return rule, true
+		}
+		if (rule.Segment == AnySegment || rule.Segment == "") && fallback == nil {
+			fallback = &e.rules[i]
+		}
+	}
+	if fallback != nil {
+		return *fallback, true
+	}
+	return Rule{}, false
+}
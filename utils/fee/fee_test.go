@@ -0,0 +1,76 @@
+package fee
+
+import (
+	"testing"
+
+	"github.com/ahmadmirdas/julo-test/utils/currency"
+)
+
+func TestCalculate(t *testing.T) {
+	idr, _ := currency.Get("IDR")
+	jpy, _ := currency.Get("JPY")
+	tiered := Rule{Type: "withdraw", Segment: AnySegment, Kind: KindTiered, Tiers: []Tier{
+		{UpTo: 100000, Flat: 1000},
+		{UpTo: 1000000, Flat: 500, Percentage: 0.5},
+		{Percentage: 0.25},
+	}}
+	engine := NewEngine([]Rule{
+		{Type: "withdraw", Segment: "premium", Kind: KindFlat, Flat: 0},
+		{Type: "withdraw", Segment: AnySegment, Currency: "JPY", Kind: KindPercentage, Percentage: 1.5},
+		tiered,
+		{Type: "transfer", Segment: AnySegment, Kind: KindPercentage, Percentage: 1, Min: 2500, Max: 10000},
+	})
+
+	tests := []struct {
+		name    string
+		txType  string
+		segment string
+		cur     currency.Currency
+		amount  float64
+		want    float64
+	}{
+		{name: "first tier", txType: "withdraw", cur: idr, amount: 50000, want: 1000},
+		{name: "upper bound is inclusive", txType: "withdraw", cur: idr, amount: 100000, want: 1000},
+		{name: "second tier", txType: "withdraw", cur: idr, amount: 200000, want: 1500},
+		{name: "unbounded tier", txType: "withdraw", cur: idr, amount: 2000000, want: 5000},
+		{name: "rounded down to the minor unit", txType: "transfer", cur: idr, amount: 333333.33, want: 3333.33},
+		{name: "rounded up to the minor unit", txType: "transfer", cur: idr, amount: 666666.67, want: 6666.67},
+		{name: "rounded half away from zero", txType: "withdraw", cur: jpy, amount: 1100, want: 17},
+		{name: "segment rule wins over any segment", txType: "withdraw", segment: "premium", cur: idr, amount: 50000, want: 0},
+		{name: "currency rule", txType: "withdraw", cur: jpy, amount: 1234, want: 19},
+		{name: "min", txType: "transfer", cur: idr, amount: 1000, want: 2500},
+		{name: "max", txType: "transfer", cur: idr, amount: 5000000, want: 10000},
+		{name: "no rule", txType: "deposit", cur: idr, amount: 50000, want: 0},
+		{name: "no amount", txType: "transfer", cur: idr, amount: 0, want: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := engine.Calculate(tt.txType, tt.segment, tt.cur, tt.amount); got != tt.want {
+				t.Errorf("Calculate(%s, %q, %s, %v) = %v, want %v", tt.txType, tt.segment, tt.cur.Code, tt.amount, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		rule    Rule
+		wantErr bool
+	}{
+		{name: "flat", rule: Rule{Type: "withdraw", Kind: KindFlat, Flat: 2500}},
+		{name: "tiered", rule: Rule{Type: "withdraw", Kind: KindTiered, Tiers: []Tier{{Flat: 1000}}}},
+		{name: "no type", rule: Rule{Kind: KindFlat}, wantErr: true},
+		{name: "unknown kind", rule: Rule{Type: "withdraw", Kind: "fixed"}, wantErr: true},
+		{name: "tiered without tiers", rule: Rule{Type: "withdraw", Kind: KindTiered}, wantErr: true},
+		{name: "negative amount", rule: Rule{Type: "withdraw", Kind: KindFlat, Flat: -1}, wantErr: true},
+		{name: "min above max", rule: Rule{Type: "withdraw", Kind: KindPercentage, Min: 10, Max: 5}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := Validate([]Rule{tt.rule}); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
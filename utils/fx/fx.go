@@ -0,0 +1,94 @@
+package fx
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/ahmadmirdas/julo-test/utils/currency"
+)
+
+const (
+	RoundingDown   = "down"
+	RoundingHalfUp = "half_up"
+)
+
+// Rate is the price of one Base unit in Quote
+type Rate struct {
+	Base  string
+	Quote string
+	Rate  float64
+}
+
+// Pricing holds the spread and rounding rules applied when quoting a conversion
+type Pricing struct {
+	SpreadPercentage float64            `mapstructure:"spread_percentage"`
+	PairSpreads      map[string]float64 `mapstructure:"pair_spreads"`
+	Rounding         string             `mapstructure:"rounding"`
+}
+
+// Spread returns the spread percentage of a pair, pair spreads are keyed by both codes such as usdidr
+func (p Pricing) Spread(from, to string) float64 {
+	if spread, ok := p.PairSpreads[strings.ToLower(from+to)]; ok {
+		return spread
+	}
+	return p.SpreadPercentage
+}
+
+// Convert applies the spread to the market rate and returns the customer rate and the converted amount
+func (p Pricing) Convert(marketRate, amount float64, from, to currency.Currency) (float64, float64) {
+	rate := marketRate * (1 - p.Spread(from.Code, to.Code)/100)
+	converted := amount * rate
+	if p.Rounding == RoundingHalfUp {
+		return rate, to.Round(converted)
+	}
+	return rate, to.Floor(converted)
+}
+
+// ParseRates reads rates from csv with base_currency,quote_currency,rate columns, a header row is optional
+func ParseRates(r io.Reader) ([]Rate, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = 3
+	reader.TrimLeadingSpace = true
+
+	var (
+		rates []Rate
+		errs  []string
+		line  int
+	)
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		line++
+		if err != nil {
+			return nil, err
+		}
+		if line == 1 && strings.EqualFold(record[0], "base_currency") {
+			continue
+		}
+
+		base, okBase := currency.Get(record[0])
+		quote, okQuote := currency.Get(record[1])
+		rate, errRate := strconv.ParseFloat(record[2], 64)
+		switch {
+		case !okBase || !okQuote:
+			errs = append(errs, fmt.Sprintf("line %d: unknown currency pair %s/%s", line, record[0], record[1]))
+		case base.Code == quote.Code:
+			errs = append(errs, fmt.Sprintf("line %d: base and quote currency are the same", line))
+		case errRate != nil || rate <= 0:
+			errs = append(errs, fmt.Sprintf("line %d: invalid rate %s", line, record[2]))
+		default:
+			rates = append(rates, Rate{Base: base.Code, Quote: quote.Code, Rate: rate})
+		}
+	}
+	if len(errs) > 0 {
+		return nil, errors.New(strings.Join(errs, "; "))
+	}
+
+	return rates, nil
+}
@@ -0,0 +1,90 @@
+package fx
+
+import (
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/ahmadmirdas/julo-test/utils/currency"
+)
+
+func TestConvert(t *testing.T) {
+	idr, _ := currency.Get("IDR")
+	usd, _ := currency.Get("USD")
+	jpy, _ := currency.Get("JPY")
+	// viper lowercases map keys, pair spreads are looked up in lower case
+	pairSpreads := map[string]float64{"usdjpy": 0.5}
+
+	tests := []struct {
+		name       string
+		pricing    Pricing
+		marketRate float64
+		amount     float64
+		from, to   currency.Currency
+		wantRate   float64
+		want       float64
+	}{
+		{name: "rounded down", pricing: Pricing{Rounding: RoundingDown}, marketRate: 0.0000625, amount: 100150, from: idr, to: usd, wantRate: 0.0000625, want: 6.25},
+		{name: "rounded half up", pricing: Pricing{Rounding: RoundingHalfUp}, marketRate: 0.0000625, amount: 100150, from: idr, to: usd, wantRate: 0.0000625, want: 6.26},
+		{name: "exact amount kept", pricing: Pricing{Rounding: RoundingDown}, marketRate: 16000, amount: 0.29, from: usd, to: idr, wantRate: 16000, want: 4640},
+		{name: "spread", pricing: Pricing{SpreadPercentage: 1, Rounding: RoundingDown}, marketRate: 16000, amount: 10, from: usd, to: idr, wantRate: 15840, want: 158400},
+		{name: "pair spread", pricing: Pricing{SpreadPercentage: 1, PairSpreads: pairSpreads, Rounding: RoundingDown}, marketRate: 150, amount: 10.01, from: usd, to: jpy, wantRate: 149.25, want: 1493},
+		{name: "pair spread rounded half up", pricing: Pricing{PairSpreads: pairSpreads, Rounding: RoundingHalfUp}, marketRate: 150, amount: 10.01, from: usd, to: jpy, wantRate: 149.25, want: 1494},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rate, got := tt.pricing.Convert(tt.marketRate, tt.amount, tt.from, tt.to)
+			if math.Abs(rate-tt.wantRate) > 1e-12 || got != tt.want {
+				t.Errorf("Convert(%v, %v) = %v, %v, want %v, %v", tt.marketRate, tt.amount, rate, got, tt.wantRate, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseRates(t *testing.T) {
+	tests := []struct {
+		name    string
+		csv     string
+		want    []Rate
+		wantErr string
+	}{
+		{
+			name: "with header",
+			csv:  "base_currency,quote_currency,rate\nUSD,IDR,16000\nidr, usd, 0.0000625\n",
+			want: []Rate{{Base: "USD", Quote: "IDR", Rate: 16000}, {Base: "IDR", Quote: "USD", Rate: 0.0000625}},
+		},
+		{
+			name: "without header",
+			csv:  "SGD,IDR,12000\n",
+			want: []Rate{{Base: "SGD", Quote: "IDR", Rate: 12000}},
+		},
+		{
+			name:    "every invalid line is reported",
+			csv:     "XXX,IDR,1\nUSD,USD,1\nUSD,IDR,-5\nUSD,IDR,abc\n",
+			wantErr: "line 1: unknown currency pair XXX/IDR; line 2: base and quote currency are the same; line 3: invalid rate -5; line 4: invalid rate abc",
+		},
+		{
+			name:    "wrong column count",
+			csv:     "USD,IDR\n",
+			wantErr: "wrong number of fields",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseRates(strings.NewReader(tt.csv))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("ParseRates() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseRates() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseRates() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,36 @@
+package kyc
+
+const (
+	LevelUnverified = "unverified"
+	LevelBasic      = "basic"
+	LevelFull       = "full"
+)
+
+var levels = []string{LevelUnverified, LevelBasic, LevelFull}
+
+func IsValidLevel(level string) bool {
+	for _, l := range levels {
+		if l == level {
+			return true
+		}
+	}
+	return false
+}
+
+// Policy decides which transaction types are allowed for each kyc level
+type Policy struct {
+	capabilities map[string][]string
+}
+
+func NewPolicy(capabilities map[string][]string) *Policy {
+	return &Policy{capabilities: capabilities}
+}
+
+func (p *Policy) Allowed(level, txType string) bool {
+	for _, capability := range p.capabilities[level] {
+		if capability == txType {
+			return true
+		}
+	}
+	return false
+}
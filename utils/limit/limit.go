@@ -0,0 +1,147 @@
+package limit
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// DefaultTier is used for wallets whose tier has no limits configured
+const DefaultTier = "default"
+
+// TxLimit holds the limits of a single transaction type, zero means unlimited
+type TxLimit struct {
+	MaxAmount     float64 `mapstructure:"max_amount"`
+	DailyAmount   float64 `mapstructure:"daily_amount"`
+	DailyCount    int     `mapstructure:"daily_count"`
+	MonthlyAmount float64 `mapstructure:"monthly_amount"`
+	MonthlyCount  int     `mapstructure:"monthly_count"`
+}
+
+// TierLimit holds every limit of a tier keyed by transaction type
+type TierLimit struct {
+	MaxBalance   float64            `mapstructure:"max_balance"`
+	Transactions map[string]TxLimit `mapstructure:"transactions"`
+}
+
+// Limits are the effective limits of one wallet for one transaction type
+type Limits struct {
+	TxLimit
+	MaxBalance float64
+}
+
+var ErrInvalidAmount = errors.New("amount must be greater than zero")
+
+// Usage is the cumulative amount and count of transactions within a period
+type Usage struct {
+	Amount float64
+	Count  int
+}
+
+type ErrLimitExceeded struct {
+	Limit   string
+	Max     float64
+	ResetAt time.Time
+}
+
+func (e *ErrLimitExceeded) Error() string {
+	if e.ResetAt.IsZero() {
+		return fmt.Sprintf("%s limit of %v exceeded", e.Limit, e.Max)
+	}
+	return fmt.Sprintf("%s limit of %v exceeded, resets at %s", e.Limit, e.Max, e.ResetAt.Format(time.RFC3339))
+}
+
+type Engine struct {
+	tiers         map[string]TierLimit
+	currencyTiers map[string]map[string]TierLimit
+}
+
+// NewEngine creates limit engine, currencyTiers keyed by currency code replace tiers for that currency
+func NewEngine(tiers map[string]TierLimit, currencyTiers map[string]map[string]TierLimit) *Engine {
+	return &Engine{tiers: tiers, currencyTiers: currencyTiers}
+}
+
+// Limits returns the configured limits of tier for txType, falling back to DefaultTier
+func (e *Engine) Limits(currencyCode, tier, txType string) Limits {
+	tiers, ok := e.currencyTiers[strings.ToLower(currencyCode)]
+	if !ok {
+		tiers = e.tiers
+	}
+
+	tierLimit, ok := tiers[tier]
+	if !ok {
+		tierLimit = tiers[DefaultTier]
+	}
+
+	return Limits{
+		TxLimit:    tierLimit.Transactions[txType],
+		MaxBalance: tierLimit.MaxBalance,
+	}
+}
+
+// Merge returns l with every non zero value of override applied
+func (l Limits) Merge(override Limits) Limits {
+	if override.MaxAmount > 0 {
+		l.MaxAmount = override.MaxAmount
+	}
+	if override.DailyAmount > 0 {
+		l.DailyAmount = override.DailyAmount
+	}
+	if override.DailyCount > 0 {
+		l.DailyCount = override.DailyCount
+	}
+	if override.MonthlyAmount > 0 {
+		l.MonthlyAmount = override.MonthlyAmount
+	}
+	if override.MonthlyCount > 0 {
+		l.MonthlyCount = override.MonthlyCount
+	}
+	if override.MaxBalance > 0 {
+		l.MaxBalance = override.MaxBalance
+	}
+	return l
+}
+
+// Check validates a transaction of amount against l, credit transactions are also checked against max balance
+func (l Limits) Check(txType string, amount, balance float64, credit bool, daily, monthly Usage, now time.Time) error {
+	if amount <= 0 {
+		return ErrInvalidAmount
+	}
+
+	if l.MaxAmount > 0 && amount > l.MaxAmount {
+		return &ErrLimitExceeded{Limit: txType + " single amount", Max: l.MaxAmount}
+	}
+
+	nextDay := StartOfDay(now).AddDate(0, 0, 1)
+	if l.DailyAmount > 0 && daily.Amount+amount > l.DailyAmount {
+		return &ErrLimitExceeded{Limit: "daily " + txType + " amount", Max: l.DailyAmount, ResetAt: nextDay}
+	}
+	if l.DailyCount > 0 && daily.Count+1 > l.DailyCount {
+		return &ErrLimitExceeded{Limit: "daily " + txType + " count", Max: float64(l.DailyCount), ResetAt: nextDay}
+	}
+
+	nextMonth := StartOfMonth(now).AddDate(0, 1, 0)
+	if l.MonthlyAmount > 0 && monthly.Amount+amount > l.MonthlyAmount {
+		return &ErrLimitExceeded{Limit: "monthly " + txType + " amount", Max: l.MonthlyAmount, ResetAt: nextMonth}
+	}
+	if l.MonthlyCount > 0 && monthly.Count+1 > l.MonthlyCount {
+		return &ErrLimitExceeded{Limit: "monthly " + txType + " count", Max: float64(l.MonthlyCount), ResetAt: nextMonth}
+	}
+
+	if credit && l.MaxBalance > 0 && balance+amount > l.MaxBalance {
+		return &ErrLimitExceeded{Limit: "max balance", Max: l.MaxBalance}
+	}
+
+	return nil
+}
+
+func StartOfDay(t time.Time) time.Time {
+	year, month, day := t.Date()
+	return time.Date(year, month, day, 0, 0, 0, 0, t.Location())
+}
+
+func StartOfMonth(t time.Time) time.Time {
+	year, month, _ := t.Date()
+	return time.Date(year, month, 1, 0, 0, 0, 0, t.Location())
+}
@@ -0,0 +1,99 @@
+package limit
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestLimits(t *testing.T) {
+	engine := NewEngine(map[string]TierLimit{
+		DefaultTier: {MaxBalance: 2000000, Transactions: map[string]TxLimit{"deposit": {MaxAmount: 500000}}},
+		"full":      {MaxBalance: 20000000, Transactions: map[string]TxLimit{"deposit": {MaxAmount: 5000000, DailyCount: 10}}},
+	}, map[string]map[string]TierLimit{
+		// viper lowercases map keys, currency codes are looked up in lower case
+		"usd": {DefaultTier: {MaxBalance: 2000, Transactions: map[string]TxLimit{"deposit": {MaxAmount: 500}}}},
+	})
+
+	tests := []struct {
+		name     string
+		currency string
+		tier     string
+		txType   string
+		want     Limits
+	}{
+		{name: "tier", currency: "IDR", tier: "full", txType: "deposit", want: Limits{TxLimit: TxLimit{MaxAmount: 5000000, DailyCount: 10}, MaxBalance: 20000000}},
+		{name: "unknown tier falls back to default", currency: "IDR", tier: "basic", txType: "deposit", want: Limits{TxLimit: TxLimit{MaxAmount: 500000}, MaxBalance: 2000000}},
+		{name: "currency tiers replace the tiers", currency: "USD", tier: "full", txType: "deposit", want: Limits{TxLimit: TxLimit{MaxAmount: 500}, MaxBalance: 2000}},
+		{name: "transaction type without limits", currency: "IDR", tier: "full", txType: "withdraw", want: Limits{MaxBalance: 20000000}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := engine.Limits(tt.currency, tt.tier, tt.txType); got != tt.want {
+				t.Errorf("Limits(%s, %s, %s) = %+v, want %+v", tt.currency, tt.tier, tt.txType, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMerge(t *testing.T) {
+	base := Limits{TxLimit: TxLimit{MaxAmount: 100, DailyAmount: 1000, DailyCount: 5, MonthlyAmount: 10000, MonthlyCount: 50}, MaxBalance: 5000}
+	got := base.Merge(Limits{TxLimit: TxLimit{MaxAmount: 200, MonthlyCount: 60}})
+	want := Limits{TxLimit: TxLimit{MaxAmount: 200, DailyAmount: 1000, DailyCount: 5, MonthlyAmount: 10000, MonthlyCount: 60}, MaxBalance: 5000}
+	if got != want {
+		t.Errorf("Merge() = %+v, want %+v, zero values of the override must be ignored", got, want)
+	}
+}
+
+func TestCheck(t *testing.T) {
+	now := time.Date(2026, time.October, 19, 15, 4, 5, 0, time.UTC)
+	nextDay := time.Date(2026, time.October, 20, 0, 0, 0, 0, time.UTC)
+	nextMonth := time.Date(2026, time.November, 1, 0, 0, 0, 0, time.UTC)
+	limits := Limits{TxLimit: TxLimit{MaxAmount: 1000, DailyAmount: 3000, DailyCount: 3, MonthlyAmount: 10000, MonthlyCount: 10}, MaxBalance: 5000}
+
+	tests := []struct {
+		name      string
+		amount    float64
+		balance   float64
+		credit    bool
+		daily     Usage
+		monthly   Usage
+		wantLimit string
+		resetAt   time.Time
+		wantErr   error
+	}{
+		{name: "within limits", amount: 1000, balance: 4000, credit: true, daily: Usage{Amount: 2000, Count: 2}, monthly: Usage{Amount: 9000, Count: 9}},
+		{name: "zero amount", amount: 0, wantErr: ErrInvalidAmount},
+		{name: "single amount", amount: 1000.01, wantLimit: "deposit single amount"},
+		{name: "daily amount", amount: 500, daily: Usage{Amount: 2600, Count: 1}, wantLimit: "daily deposit amount", resetAt: nextDay},
+		{name: "daily count", amount: 500, daily: Usage{Amount: 100, Count: 3}, wantLimit: "daily deposit count", resetAt: nextDay},
+		{name: "monthly amount", amount: 500, monthly: Usage{Amount: 9600, Count: 1}, wantLimit: "monthly deposit amount", resetAt: nextMonth},
+		{name: "monthly count", amount: 500, monthly: Usage{Amount: 100, Count: 10}, wantLimit: "monthly deposit count", resetAt: nextMonth},
+		{name: "max balance on credit", amount: 500, balance: 4600, credit: true, wantLimit: "max balance"},
+		{name: "max balance ignored on debit", amount: 500, balance: 4600},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := limits.Check("deposit", tt.amount, tt.balance, tt.credit, tt.daily, tt.monthly, now)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("Check() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if tt.wantLimit == "" {
+				if err != nil {
+					t.Fatalf("Check() error = %v, want none", err)
+				}
+				return
+			}
+			var exceeded *ErrLimitExceeded
+			if !errors.As(err, &exceeded) {
+				t.Fatalf("Check() error = %v, want the %s limit exceeded", err, tt.wantLimit)
+			}
+			if exceeded.Limit != tt.wantLimit || !exceeded.ResetAt.Equal(tt.resetAt) {
+				t.Errorf("exceeded %s resetting at %s, want %s resetting at %s", exceeded.Limit, exceeded.ResetAt, tt.wantLimit, tt.resetAt)
+			}
+		})
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 
 	"github.com/ahmadmirdas/julo-test/utils/activity"
+	"go.opentelemetry.io/otel/trace"
 
 	"github.com/sirupsen/logrus"
 )
@@ -12,7 +13,12 @@ const (
 	MAX_LOG_ENTRY_SIZE = 8 * 1024
 )
 
+// WithContext returns an entry with the activity fields of ctx and the ids of its trace span, if any
 func WithContext(ctx context.Context) *logrus.Entry {
 	fields := activity.GetFields(ctx)
+	if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+		fields["trace_id"] = sc.TraceID().String()
+		fields["span_id"] = sc.SpanID().String()
+	}
 	return logrus.WithFields(fields)
 }
@@ -0,0 +1,121 @@
+// Package metrics holds the Prometheus metrics of the service, served on /metrics of server.metrics_address
+package metrics
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var Namespace string = "julo_wallet"
+
+// Failure codes of transaction_failures_total, the same for the http api and the gRPC api
+const (
+	FailureInvalidRequest   = "invalid_request"
+	FailureWalletNotFound   = "wallet_not_found"
+	FailureWalletDisabled   = "wallet_disabled"
+	FailureKycNotAllowed    = "kyc_not_allowed"
+	FailureLimitExceeded    = "limit_exceeded"
+	FailureInsufficientFund = "insufficient_fund"
+	FailureInternal         = "internal"
+)
+
+var (
+	HTTPRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: Namespace,
+		Name:      "http_requests_total",
+		Help:      "HTTP requests by mux route template, method and status code.",
+	}, []string{"route", "method", "status"})
+
+	HTTPDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: Namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "HTTP request latency by mux route template, method and status code.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"route", "method", "status"})
+
+	Transactions = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: Namespace,
+		Name:      "transactions_total",
+		Help:      "Committed wallet transactions by type and currency.",
+	}, []string{"type", "currency"})
+
+	TransactionAmount = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: Namespace,
+		Name:      "transaction_amount_total",
+		Help:      "Sum of the committed wallet transaction amounts by type and currency.",
+	}, []string{"type", "currency"})
+
+	TransactionFailures = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: Namespace,
+		Name:      "transaction_failures_total",
+		Help:      "Rejected or failed wallet transactions by type and failure code.",
+	}, []string{"type", "code"})
+
+	WalletStatusChanges = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: Namespace,
+		Name:      "wallet_status_changes_total",
+		Help:      "Wallets enabled or disabled.",
+	}, []string{"status"})
+
+	ConfigReloads = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: Namespace,
+		Name:      "config_reloads_total",
+		Help:      "Configuration reloads by trigger and result, rejected reloads keep the previous values.",
+	}, []string{"trigger", "result"})
+
+	ConfigReloadTime = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: Namespace,
+		Name:      "config_last_reload_success_timestamp_seconds",
+		Help:      "Unix time of the last applied configuration reload.",
+	})
+)
+
+// ObserveTransaction counts a committed transaction and adds its amount
+func ObserveTransaction(txType, currency string, amount float64) {
+	Transactions.WithLabelValues(txType, currency).Inc()
+	TransactionAmount.WithLabelValues(txType, currency).Add(amount)
+}
+
+// ObserveFailure counts a rejected or failed transaction under one of the Failure codes
+func ObserveFailure(txType, code string) {
+	TransactionFailures.WithLabelValues(txType, code).Inc()
+}
+
+// ResponseWriter records the status code written by a handler
+type ResponseWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+func NewResponseWriter(w http.ResponseWriter) *ResponseWriter {
+	return &ResponseWriter{ResponseWriter: w}
+}
+
+func (w *ResponseWriter) WriteHeader(statusCode int) {
+	if w.status == 0 {
+		w.status = statusCode
+	}
+	w.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (w *ResponseWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// Status returns the written status code, 200 when the handler wrote nothing
+func (w *ResponseWriter) Status() int {
+	if w.status == 0 {
+		return http.StatusOK
+	}
+	return w.status
+}
+
+// Unwrap lets http.ResponseController reach the flusher and deadlines of the wrapped writer
+func (w *ResponseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
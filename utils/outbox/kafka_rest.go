@@ -0,0 +1,100 @@
+package outbox
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// KafkaRESTSink produces every event to topic through the Kafka REST proxy v2 API, served by the
+// Confluent REST proxy or by Redpanda out of the box, it does not speak the Kafka protocol itself.
+// Records are keyed by customer so events of one customer stay ordered within a partition.
+type KafkaRESTSink struct {
+	endpoint string
+	client   *http.Client
+}
+
+type kafkaRecords struct {
+	Records []kafkaRecord `json:"records"`
+}
+
+type kafkaRecord struct {
+	Key   string `json:"key,omitempty"`
+	Value Event  `json:"value"`
+}
+
+type kafkaResponse struct {
+	Offsets []struct {
+		Partition int    `json:"partition"`
+		ErrorCode *int   `json:"error_code"`
+		Error     string `json:"error"`
+	} `json:"offsets"`
+}
+
+func NewKafkaRESTSink(proxyURL, topic string, timeout time.Duration) (*KafkaRESTSink, error) {
+	if proxyURL == "" || topic == "" {
+		return nil, errors.New("url and topic are required")
+	}
+
+	return &KafkaRESTSink{
+		endpoint: strings.TrimRight(proxyURL, "/") + "/topics/" + url.PathEscape(topic),
+		client:   &http.Client{Timeout: timeout},
+	}, nil
+}
+
+func (s *KafkaRESTSink) Name() string {
+	return SinkKafkaREST
+}
+
+func (s *KafkaRESTSink) Publish(ctx context.Context, event Event) error {
+	key := event.CustomerXId
+	if key == "" {
+		key = event.AggregateID
+	}
+	body, err := json.Marshal(kafkaRecords{Records: []kafkaRecord{{Key: key, Value: event}}})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/vnd.kafka.json.v2+json")
+	req.Header.Set("Accept", "application/vnd.kafka.v2+json")
+
+	res, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		msg, _ := io.ReadAll(io.LimitReader(res.Body, 1024))
+		return fmt.Errorf("kafka proxy responded %s: %s", res.Status, msg)
+	}
+
+	var produced kafkaResponse
+	if err := json.NewDecoder(res.Body).Decode(&produced); err != nil {
+		return err
+	}
+	for _, offset := range produced.Offsets {
+		if offset.ErrorCode != nil || offset.Error != "" {
+			return fmt.Errorf("kafka produce failed: %s", offset.Error)
+		}
+	}
+
+	return nil
+}
+
+func (s *KafkaRESTSink) Close() error {
+	s.client.CloseIdleConnections()
+	return nil
+}
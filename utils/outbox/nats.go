@@ -0,0 +1,59 @@
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/nats-io/nats.go"
+)
+
+// NATSSink publishes every event on subject.<event type>, the publish is flushed so a broken
+// connection fails the delivery instead of dropping it
+type NATSSink struct {
+	conn    *nats.Conn
+	subject string
+	timeout time.Duration
+}
+
+func NewNATSSink(url, subject string, timeout time.Duration) (*NATSSink, error) {
+	if url == "" {
+		url = nats.DefaultURL
+	}
+	if subject == "" {
+		return nil, errors.New("subject is required")
+	}
+
+	conn, err := nats.Connect(url, nats.Name("julo-test outbox"), nats.Timeout(timeout), nats.MaxReconnects(-1))
+	if err != nil {
+		return nil, err
+	}
+
+	return &NATSSink{conn: conn, subject: subject, timeout: timeout}, nil
+}
+
+func (s *NATSSink) Name() string {
+	return SinkNATS
+}
+
+func (s *NATSSink) Publish(ctx context.Context, event Event) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	msg := nats.NewMsg(s.subject + "." + event.Type)
+	msg.Header.Set("Nats-Msg-Id", event.ID)
+	msg.Data = data
+	err = s.conn.PublishMsg(msg)
+	if err != nil {
+		return err
+	}
+
+	return s.conn.FlushTimeout(s.timeout)
+}
+
+func (s *NATSSink) Close() error {
+	return s.conn.Drain()
+}
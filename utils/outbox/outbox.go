@@ -0,0 +1,82 @@
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+const (
+	SinkHTTP      = "http"
+	SinkFile      = "file"
+	SinkNATS      = "nats"
+	SinkKafkaREST = "kafka-rest"
+)
+
+// Event is the envelope delivered to every sink, Version is the schema version of Data for Type
+type Event struct {
+	ID            string          `json:"id"`
+	Sequence      int64           `json:"sequence"`
+	Type          string          `json:"type"`
+	Version       int             `json:"version"`
+	AggregateType string          `json:"aggregate_type"`
+	AggregateID   string          `json:"aggregate_id"`
+	CustomerXId   string          `json:"customer_xid,omitempty"`
+	OccurredAt    time.Time       `json:"occurred_at"`
+	Data          json.RawMessage `json:"data"`
+}
+
+// Sink delivers events to a downstream system. Delivery is at least once, consumers dedupe on the event id.
+type Sink interface {
+	Name() string
+	Publish(ctx context.Context, event Event) error
+	Close() error
+}
+
+// SinkConfig configures one sink, fields not used by Type are ignored
+type SinkConfig struct {
+	Type    string `mapstructure:"type"`
+	URL     string `mapstructure:"url"`
+	Path    string `mapstructure:"path"`
+	Subject string `mapstructure:"subject"`
+	Topic   string `mapstructure:"topic"`
+	Timeout int    `mapstructure:"timeout"`
+}
+
+// NewSinks builds every configured sink, the ones already opened are closed when one fails
+func NewSinks(configs []SinkConfig) ([]Sink, error) {
+	sinks := make([]Sink, 0, len(configs))
+	for _, cfg := range configs {
+		sink, err := newSink(cfg)
+		if err != nil {
+			for _, s := range sinks {
+				s.Close()
+			}
+			return nil, fmt.Errorf("outbox sink %s: %v", cfg.Type, err)
+		}
+		sinks = append(sinks, sink)
+	}
+
+	return sinks, nil
+}
+
+func newSink(cfg SinkConfig) (Sink, error) {
+	timeout := time.Duration(cfg.Timeout) * time.Second
+	if timeout <= 0 {
+		timeout = 5 * time.Second
+	}
+
+	switch cfg.Type {
+	case SinkHTTP:
+		return NewHTTPSink(cfg.URL, timeout)
+	case SinkFile:
+		return NewFileSink(cfg.Path)
+	case SinkNATS:
+		return NewNATSSink(cfg.URL, cfg.Subject, timeout)
+	case SinkKafkaREST:
+		return NewKafkaRESTSink(cfg.URL, cfg.Topic, timeout)
+	}
+
+	return nil, fmt.Errorf("unknown sink type %q", cfg.Type)
+}
@@ -0,0 +1,192 @@
+//go:build integration
+
+package outbox
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nats-io/nats.go"
+)
+
+// The tests run against the brokers of docker-compose:
+//
+//	docker-compose --profile brokers up -d
+//	go test -tags integration ./utils/outbox
+//
+// OUTBOX_TEST_NATS_URL and OUTBOX_TEST_KAFKA_REST_URL point them elsewhere.
+
+func testEvent() Event {
+	return Event{
+		ID:            uuid.New().String(),
+		Sequence:      1,
+		Type:          "wallet.deposited",
+		Version:       1,
+		AggregateType: "wallet",
+		AggregateID:   uuid.New().String(),
+		CustomerXId:   uuid.New().String(),
+		OccurredAt:    time.Now().UTC().Truncate(time.Millisecond),
+		Data:          json.RawMessage(`{"amount":10000}`),
+	}
+}
+
+func envOr(key, fallback string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return fallback
+}
+
+func TestNATSSinkPublish(t *testing.T) {
+	url := envOr("OUTBOX_TEST_NATS_URL", nats.DefaultURL)
+	subject := "julo.test." + uuid.New().String()
+
+	conn, err := nats.Connect(url)
+	if err != nil {
+		t.Fatalf("connect %s: %v", url, err)
+	}
+	defer conn.Close()
+	sub, err := conn.SubscribeSync(subject + ".>")
+	if err != nil {
+		t.Fatalf("subscribe: %v", err)
+	}
+	if err := conn.Flush(); err != nil {
+		t.Fatalf("flush subscription: %v", err)
+	}
+
+	sink, err := NewNATSSink(url, subject, 5*time.Second)
+	if err != nil {
+		t.Fatalf("new sink: %v", err)
+	}
+	defer sink.Close()
+
+	event := testEvent()
+	if err := sink.Publish(context.Background(), event); err != nil {
+		t.Fatalf("publish: %v", err)
+	}
+
+	msg, err := sub.NextMsg(5 * time.Second)
+	if err != nil {
+		t.Fatalf("receive: %v", err)
+	}
+	if msg.Subject != subject+"."+event.Type {
+		t.Errorf("subject = %s, want %s", msg.Subject, subject+"."+event.Type)
+	}
+	if got := msg.Header.Get("Nats-Msg-Id"); got != event.ID {
+		t.Errorf("Nats-Msg-Id = %s, want %s", got, event.ID)
+	}
+	assertEvent(t, msg.Data, event)
+}
+
+func TestKafkaRESTSinkPublish(t *testing.T) {
+	proxy := envOr("OUTBOX_TEST_KAFKA_REST_URL", "http://localhost:8082")
+	topic := "julo-test-" + uuid.New().String()
+
+	sink, err := NewKafkaRESTSink(proxy, topic, 10*time.Second)
+	if err != nil {
+		t.Fatalf("new sink: %v", err)
+	}
+	defer sink.Close()
+
+	event := testEvent()
+	if err := sink.Publish(context.Background(), event); err != nil {
+		t.Fatalf("publish: %v", err)
+	}
+
+	records := consumeKafkaREST(t, proxy, topic)
+	if len(records) != 1 {
+		t.Fatalf("consumed %d records, want 1", len(records))
+	}
+	if records[0].Key != event.CustomerXId {
+		t.Errorf("key = %s, want the customer %s", records[0].Key, event.CustomerXId)
+	}
+	assertEvent(t, records[0].Value, event)
+}
+
+func TestKafkaRESTSinkUnknownProxy(t *testing.T) {
+	sink, err := NewKafkaRESTSink("http://127.0.0.1:1", "julo-test", time.Second)
+	if err != nil {
+		t.Fatalf("new sink: %v", err)
+	}
+	if err := sink.Publish(context.Background(), testEvent()); err == nil {
+		t.Fatal("publish to an unreachable proxy succeeded")
+	}
+}
+
+func assertEvent(t *testing.T, data []byte, want Event) {
+	t.Helper()
+	var got Event
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatalf("decode event %s: %v", data, err)
+	}
+	if got.ID != want.ID || got.Type != want.Type || got.CustomerXId != want.CustomerXId || !got.OccurredAt.Equal(want.OccurredAt) {
+		t.Errorf("event = %+v, want %+v", got, want)
+	}
+	if !bytes.Equal(got.Data, want.Data) {
+		t.Errorf("data = %s, want %s", got.Data, want.Data)
+	}
+}
+
+type kafkaRESTRecord struct {
+	Key   string          `json:"key"`
+	Value json.RawMessage `json:"value"`
+}
+
+// consumeKafkaREST reads topic from the beginning with a throwaway consumer of the REST proxy v2 API
+func consumeKafkaREST(t *testing.T, proxy, topic string) []kafkaRESTRecord {
+	t.Helper()
+	group := "julo-test-" + uuid.New().String()
+	client := &http.Client{Timeout: 10 * time.Second}
+	call := func(method, url string, body interface{}, out interface{}) {
+		t.Helper()
+		var payload bytes.Buffer
+		if body != nil {
+			json.NewEncoder(&payload).Encode(body)
+		}
+		req, _ := http.NewRequest(method, url, &payload)
+		req.Header.Set("Content-Type", "application/vnd.kafka.v2+json")
+		req.Header.Set("Accept", "application/vnd.kafka.json.v2+json")
+		res, err := client.Do(req)
+		if err != nil {
+			t.Fatalf("%s %s: %v", method, url, err)
+		}
+		defer res.Body.Close()
+		if res.StatusCode < 200 || res.StatusCode > 299 {
+			t.Fatalf("%s %s responded %s", method, url, res.Status)
+		}
+		if out != nil {
+			if err := json.NewDecoder(res.Body).Decode(out); err != nil {
+				t.Fatalf("decode %s: %v", url, err)
+			}
+		}
+	}
+
+	var consumer struct {
+		BaseURI string `json:"base_uri"`
+	}
+	call(http.MethodPost, proxy+"/consumers/"+group, map[string]string{
+		"format":            "json",
+		"auto.offset.reset": "earliest",
+	}, &consumer)
+	// the proxy advertises its own address, keep the host the test was pointed at
+	base := proxy + "/consumers/" + group + "/instances/" + path.Base(consumer.BaseURI)
+	defer call(http.MethodDelete, base, nil, nil)
+	call(http.MethodPost, base+"/subscription", map[string][]string{"topics": {topic}}, nil)
+
+	deadline := time.Now().Add(15 * time.Second)
+	for time.Now().Before(deadline) {
+		var records []kafkaRESTRecord
+		call(http.MethodGet, base+"/records?timeout=1000", nil, &records)
+		if len(records) > 0 {
+			return records
+		}
+	}
+	return nil
+}
@@ -0,0 +1,81 @@
+package penalty
+
+import (
+	"strings"
+
+	"github.com/ahmadmirdas/julo-test/utils/currency"
+)
+
+const (
+	KindFlat            = "flat"
+	KindDailyPercentage = "daily_percentage"
+)
+
+// Rule is the late fee of an overdue installment in a currency. A flat fee is charged once per installment,
+// a daily percentage is accrued every overdue day. MaxTotal caps the accrued penalty of one installment
+// and zero means no cap
+type Rule struct {
+	Currency   string  `mapstructure:"currency"`
+	Kind       string  `mapstructure:"kind"`
+	Flat       float64 `mapstructure:"flat"`
+	Percentage float64 `mapstructure:"percentage"`
+	MaxTotal   float64 `mapstructure:"max_total"`
+}
+
+type Engine struct {
+	rules []Rule
+}
+
+func NewEngine(rules []Rule) *Engine {
+	return &Engine{rules: rules}
+}
+
+// Rules returns the rule applied to each currency, the first rule of a currency wins as in Calculate
+func (e *Engine) Rules() []Rule {
+	rules := make([]Rule, 0, len(e.rules))
+	seen := make(map[string]bool)
+	for _, rule := range e.rules {
+		code := strings.ToUpper(rule.Currency)
+		if seen[code] {
+			continue
+		}
+		seen[code] = true
+		rule.Currency = code
+		rules = append(rules, rule)
+	}
+	return rules
+}
+
+// Calculate returns the penalty of one overdue day given the outstanding amount and the penalty accrued so far
+func (e *Engine) Calculate(cur currency.Currency, outstanding, accrued float64) float64 {
+	var rule *Rule
+	for i := range e.rules {
+		if strings.EqualFold(e.rules[i].Currency, cur.Code) {
+			rule = &e.rules[i]
+			break
+		}
+	}
+	if rule == nil || outstanding <= 0 {
+		return 0
+	}
+
+	var amount float64
+	switch rule.Kind {
+	case KindFlat:
+		if accrued > 0 {
+			return 0
+		}
+		amount = rule.Flat
+	case KindDailyPercentage:
+		amount = outstanding * rule.Percentage / 100
+	}
+
+	if rule.MaxTotal > 0 && accrued+amount > rule.MaxTotal {
+		amount = rule.MaxTotal - accrued
+	}
+	if amount < 0 {
+		return 0
+	}
+
+	return cur.Round(amount)
+}
@@ -0,0 +1,58 @@
+package penalty
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/ahmadmirdas/julo-test/utils/currency"
+)
+
+func TestCalculate(t *testing.T) {
+	idr, _ := currency.Get("IDR")
+	usd, _ := currency.Get("USD")
+	jpy, _ := currency.Get("JPY")
+	engine := NewEngine([]Rule{
+		{Currency: "idr", Kind: KindDailyPercentage, Percentage: 0.1, MaxTotal: 5000},
+		{Currency: "IDR", Kind: KindFlat, Flat: 99999},
+		{Currency: "USD", Kind: KindFlat, Flat: 5},
+	})
+
+	tests := []struct {
+		name        string
+		cur         currency.Currency
+		outstanding float64
+		accrued     float64
+		want        float64
+	}{
+		{name: "daily percentage", cur: idr, outstanding: 1000000, want: 1000},
+		{name: "daily percentage rounded to the minor unit", cur: idr, outstanding: 123456.78, want: 123.46},
+		{name: "capped by max total", cur: idr, outstanding: 1000000, accrued: 4500, want: 500},
+		{name: "nothing once the cap is reached", cur: idr, outstanding: 1000000, accrued: 5000, want: 0},
+		{name: "flat on the first overdue day", cur: usd, outstanding: 100, want: 5},
+		{name: "flat charged once", cur: usd, outstanding: 100, accrued: 5, want: 0},
+		{name: "paid installment", cur: idr, outstanding: 0, want: 0},
+		{name: "currency without rule", cur: jpy, outstanding: 100000, want: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := engine.Calculate(tt.cur, tt.outstanding, tt.accrued); got != tt.want {
+				t.Errorf("Calculate(%s, %v, %v) = %v, want %v", tt.cur.Code, tt.outstanding, tt.accrued, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRules(t *testing.T) {
+	engine := NewEngine([]Rule{
+		{Currency: "idr", Kind: KindDailyPercentage, Percentage: 0.1},
+		{Currency: "IDR", Kind: KindFlat, Flat: 99999},
+		{Currency: "USD", Kind: KindFlat, Flat: 5},
+	})
+	want := []Rule{
+		{Currency: "IDR", Kind: KindDailyPercentage, Percentage: 0.1},
+		{Currency: "USD", Kind: KindFlat, Flat: 5},
+	}
+	if got := engine.Rules(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Rules() = %+v, want %+v", got, want)
+	}
+}
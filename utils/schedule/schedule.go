@@ -0,0 +1,45 @@
+package schedule
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/robfig/cron/v3"
+)
+
+// Parse validates a standard five field cron expression, descriptors such as @monthly and a
+// CRON_TZ= prefix are accepted too
+func Parse(expr string) (cron.Schedule, error) {
+	s, err := cron.ParseStandard(expr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cron expression: %v", err)
+	}
+
+	return s, nil
+}
+
+// Next returns the first occurrence of expr strictly after t
+func Next(expr string, t time.Time) (time.Time, error) {
+	s, err := Parse(expr)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	return s.Next(t), nil
+}
+
+// Backoff doubles base for every failed attempt, capped at max when max is set
+func Backoff(base, max time.Duration, attempt int) time.Duration {
+	delay := base
+	for i := 1; i < attempt; i++ {
+		delay *= 2
+		if max > 0 && delay >= max {
+			return max
+		}
+	}
+	if max > 0 && delay > max {
+		return max
+	}
+
+	return delay
+}
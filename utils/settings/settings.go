@@ -0,0 +1,39 @@
+// Package settings holds the fee, limit and kyc settings transactions are checked against as one
+// snapshot, a config reload swaps the whole snapshot so a transaction never mixes two reloads
+package settings
+
+import (
+	"sync/atomic"
+
+	"github.com/ahmadmirdas/julo-test/utils/fee"
+	"github.com/ahmadmirdas/julo-test/utils/kyc"
+	"github.com/ahmadmirdas/julo-test/utils/limit"
+)
+
+// Settings is one generation of the reloadable settings, it is not modified once stored
+type Settings struct {
+	Fee   *fee.Engine
+	Limit *limit.Engine
+	Kyc   *kyc.Policy
+}
+
+// Store keeps the current settings, Swap replaces all of them at once
+type Store struct {
+	current atomic.Pointer[Settings]
+}
+
+func NewStore(settings *Settings) *Store {
+	s := &Store{}
+	s.current.Store(settings)
+	return s
+}
+
+// Load returns the current settings, load them once per transaction and check it against that snapshot
+func (s *Store) Load() *Settings {
+	return s.current.Load()
+}
+
+// Swap replaces the settings, transactions already running keep the snapshot they loaded
+func (s *Store) Swap(settings *Settings) {
+	s.current.Store(settings)
+}
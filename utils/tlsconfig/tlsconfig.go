@@ -0,0 +1,172 @@
+// Package tlsconfig builds the TLS configuration of the http server from files that can be reloaded
+package tlsconfig
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync/atomic"
+)
+
+var (
+	Version12 string = "1.2"
+	Version13 string = "1.3"
+)
+
+// Config enables TLS on the http server. ClientCAFile turns on mutual TLS for the ClientCertPrefixes,
+// the certificate subject common name is mapped to an actor through ClientActors or used as is. Viper
+// lowercases map keys so ClientActors is matched case insensitively.
+type Config struct {
+	Enabled            bool              `mapstructure:"enabled"`
+	CertFile           string            `mapstructure:"cert_file"`
+	KeyFile            string            `mapstructure:"key_file"`
+	MinVersion         string            `mapstructure:"min_version"`
+	CipherSuites       []string          `mapstructure:"cipher_suites"`
+	ClientCAFile       string            `mapstructure:"client_ca_file"`
+	ClientCertPrefixes []string          `mapstructure:"client_cert_prefixes"`
+	ClientActors       map[string]string `mapstructure:"client_actors"`
+}
+
+// Loader keeps the current TLS configuration, Reload swaps it without dropping open connections
+type Loader struct {
+	cfg     Config
+	current atomic.Pointer[tls.Config]
+}
+
+// NewLoader reads the certificate files once, the server keeps running on them until the next Reload
+func NewLoader(cfg Config) (*Loader, error) {
+	l := &Loader{cfg: cfg}
+	if err := l.Reload(); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// Reload reads the certificate, key and client CA files again, the previous configuration is kept on error
+func (l *Loader) Reload() error {
+	tlsCfg, err := build(l.cfg)
+	if err != nil {
+		return err
+	}
+	l.current.Store(tlsCfg)
+	return nil
+}
+
+// ServerConfig returns the configuration to serve with, every handshake picks up the last reloaded files
+func (l *Loader) ServerConfig() *tls.Config {
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			return l.current.Load(), nil
+		},
+	}
+}
+
+func build(cfg Config) (*tls.Config, error) {
+	if cfg.CertFile == "" || cfg.KeyFile == "" {
+		return nil, errors.New("tls cert_file and key_file are required")
+	}
+	cert, err := tls.LoadX509KeyPair(cfg.CertFile, cfg.KeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("load certificate: %w", err)
+	}
+
+	minVersion, err := parseVersion(cfg.MinVersion)
+	if err != nil {
+		return nil, err
+	}
+	ciphers, err := parseCipherSuites(cfg.CipherSuites)
+	if err != nil {
+		return nil, err
+	}
+
+	tlsCfg := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   minVersion,
+		CipherSuites: ciphers,
+		NextProtos:   []string{"h2", "http/1.1"},
+	}
+	if cfg.ClientCAFile != "" {
+		pem, err := os.ReadFile(cfg.ClientCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("read client ca: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificate found in %s", cfg.ClientCAFile)
+		}
+		tlsCfg.ClientCAs = pool
+		// the path is unknown during the handshake, the prefixes requiring a certificate are enforced by the middleware
+		tlsCfg.ClientAuth = tls.VerifyClientCertIfGiven
+	}
+
+	return tlsCfg, nil
+}
+
+func parseVersion(version string) (uint16, error) {
+	switch version {
+	case Version12, "":
+		return tls.VersionTLS12, nil
+	case Version13:
+		return tls.VersionTLS13, nil
+	default:
+		return 0, fmt.Errorf("unsupported tls min_version %q, use %s or %s", version, Version12, Version13)
+	}
+}
+
+// parseCipherSuites maps the suite names to their ids, only secure suites are accepted and
+// an empty list keeps the Go defaults. TLS 1.3 suites are not configurable.
+func parseCipherSuites(names []string) ([]uint16, error) {
+	if len(names) == 0 {
+		return nil, nil
+	}
+
+	known := make(map[string]uint16)
+	for _, suite := range tls.CipherSuites() {
+		known[suite.Name] = suite.ID
+	}
+
+	ids := make([]uint16, 0, len(names))
+	for _, name := range names {
+		id, ok := known[strings.TrimSpace(name)]
+		if !ok {
+			return nil, fmt.Errorf("unsupported tls cipher suite %q", name)
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// Check reports the invalid settings without reading the certificate files
+func (cfg Config) Check() error {
+	if _, err := parseVersion(cfg.MinVersion); err != nil {
+		return err
+	}
+	_, err := parseCipherSuites(cfg.CipherSuites)
+	return err
+}
+
+// Actor returns the actor of a verified client certificate
+func (cfg Config) Actor(cert *x509.Certificate) string {
+	subject := cert.Subject.CommonName
+	if actor, ok := cfg.ClientActors[strings.ToLower(subject)]; ok {
+		return actor
+	}
+	return subject
+}
+
+// RequiresClientCert reports whether path is served to verified client certificates only
+func (cfg Config) RequiresClientCert(path string) bool {
+	if cfg.ClientCAFile == "" {
+		return false
+	}
+	for _, prefix := range cfg.ClientCertPrefixes {
+		if strings.HasPrefix(path, prefix) {
+			return true
+		}
+	}
+	return false
+}
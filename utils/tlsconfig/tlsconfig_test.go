@@ -0,0 +1,177 @@
+package tlsconfig
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseCipherSuites(t *testing.T) {
+	tests := []struct {
+		name    string
+		names   []string
+		want    []uint16
+		wantErr bool
+	}{
+		{name: "go defaults", names: nil, want: nil},
+		{
+			name:  "secure suites in order",
+			names: []string{"TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384", " TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305_SHA256"},
+			want:  []uint16{tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384, tls.TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305_SHA256},
+		},
+		{name: "insecure suite", names: []string{"TLS_RSA_WITH_RC4_128_SHA"}, wantErr: true},
+		{name: "unknown suite", names: []string{"TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384", "TLS_NOPE"}, wantErr: true},
+		{name: "names are case sensitive", names: []string{"tls_ecdhe_rsa_with_aes_256_gcm_sha384"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseCipherSuites(tt.names)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseCipherSuites(%v) error = %v, wantErr %v", tt.names, err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseCipherSuites(%v) = %v, want %v", tt.names, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseVersion(t *testing.T) {
+	tests := []struct {
+		version string
+		want    uint16
+		wantErr bool
+	}{
+		{version: "", want: tls.VersionTLS12},
+		{version: Version12, want: tls.VersionTLS12},
+		{version: Version13, want: tls.VersionTLS13},
+		{version: "1.1", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.version, func(t *testing.T) {
+			got, err := parseVersion(tt.version)
+			if (err != nil) != tt.wantErr || got != tt.want {
+				t.Errorf("parseVersion(%q) = %v, %v, want %v, wantErr %v", tt.version, got, err, tt.want, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestClientCerts(t *testing.T) {
+	cfg := Config{
+		ClientCAFile:       "ca.crt",
+		ClientCertPrefixes: []string{"/internal/"},
+		ClientActors:       map[string]string{"billing.svc": "billing"},
+	}
+	tests := []struct {
+		name       string
+		cfg        Config
+		path       string
+		commonName string
+		required   bool
+		actor      string
+	}{
+		{name: "mapped actor", cfg: cfg, path: "/internal/v1/loans", commonName: "Billing.svc", required: true, actor: "billing"},
+		{name: "common name as actor", cfg: cfg, path: "/internal/v1/loans", commonName: "collector", required: true, actor: "collector"},
+		{name: "public path", cfg: cfg, path: "/api/v1/wallet", commonName: "collector", actor: "collector"},
+		{name: "without client ca", cfg: Config{ClientCertPrefixes: []string{"/internal/"}}, path: "/internal/v1/loans", commonName: "collector", actor: "collector"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.cfg.RequiresClientCert(tt.path); got != tt.required {
+				t.Errorf("RequiresClientCert(%s) = %v, want %v", tt.path, got, tt.required)
+			}
+			cert := &x509.Certificate{Subject: pkix.Name{CommonName: tt.commonName}}
+			if got := tt.cfg.Actor(cert); got != tt.actor {
+				t.Errorf("Actor(%s) = %s, want %s", tt.commonName, got, tt.actor)
+			}
+		})
+	}
+}
+
+func TestLoaderReload(t *testing.T) {
+	dir := t.TempDir()
+	cfg := Config{CertFile: filepath.Join(dir, "server.crt"), KeyFile: filepath.Join(dir, "server.key"), MinVersion: Version13}
+	writeCert(t, cfg, "first")
+
+	loader, err := NewLoader(cfg)
+	if err != nil {
+		t.Fatalf("NewLoader() error = %v", err)
+	}
+	if got := servedCommonName(t, loader); got != "first" {
+		t.Fatalf("serving %s, want first", got)
+	}
+
+	writeCert(t, cfg, "second")
+	if err := loader.Reload(); err != nil {
+		t.Fatalf("Reload() error = %v", err)
+	}
+	if got := servedCommonName(t, loader); got != "second" {
+		t.Errorf("serving %s after reload, want second", got)
+	}
+
+	if err := os.WriteFile(cfg.KeyFile, []byte("not a key"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := loader.Reload(); err == nil {
+		t.Fatal("Reload() of a broken key succeeded")
+	}
+	if got := servedCommonName(t, loader); got != "second" {
+		t.Errorf("serving %s after a failed reload, want the previous certificate", got)
+	}
+}
+
+func servedCommonName(t *testing.T, loader *Loader) string {
+	t.Helper()
+	tlsCfg, err := loader.ServerConfig().GetConfigForClient(&tls.ClientHelloInfo{})
+	if err != nil {
+		t.Fatalf("GetConfigForClient() error = %v", err)
+	}
+	if tlsCfg.MinVersion != tls.VersionTLS13 {
+		t.Errorf("min version = %x, want TLS 1.3", tlsCfg.MinVersion)
+	}
+	leaf, err := x509.ParseCertificate(tlsCfg.Certificates[0].Certificate[0])
+	if err != nil {
+		t.Fatalf("parse served certificate: %v", err)
+	}
+	return leaf.Subject.CommonName
+}
+
+// writeCert writes a self-signed certificate for commonName and its key to the files of cfg
+func writeCert(t *testing.T, cfg Config, commonName string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(cfg.CertFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(cfg.KeyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,61 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"errors"
+
+	"github.com/go-pg/pg/v10"
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.12.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// QueryHook creates a span for every go-pg query run under a traced context, queries outside
+// a trace are left alone. Statements are recorded unformatted so values do not leak into traces.
+type QueryHook struct{}
+
+func (QueryHook) BeforeQuery(ctx context.Context, event *pg.QueryEvent) (context.Context, error) {
+	if !trace.SpanContextFromContext(ctx).IsValid() {
+		return ctx, nil
+	}
+
+	ctx, _ = Start(ctx, "pg."+operation(event), trace.WithSpanKind(trace.SpanKindClient))
+	return ctx, nil
+}
+
+func (QueryHook) AfterQuery(ctx context.Context, event *pg.QueryEvent) error {
+	span := trace.SpanFromContext(ctx)
+	if !span.IsRecording() {
+		return nil
+	}
+
+	attrs := []attribute.KeyValue{semconv.DBSystemPostgreSQL, semconv.DBOperationKey.String(operation(event))}
+	if query, err := event.UnformattedQuery(); err == nil {
+		attrs = append(attrs, semconv.DBStatementKey.String(string(query)))
+	}
+	if event.Result != nil {
+		attrs = append(attrs, attribute.Int("db.rows_affected", event.Result.RowsAffected()))
+	}
+	span.SetAttributes(attrs...)
+
+	err := event.Err
+	if errors.Is(err, pg.ErrNoRows) {
+		err = nil
+	}
+	End(span, err)
+	return nil
+}
+
+// operation returns the first keyword of the query, e.g. SELECT
+func operation(event *pg.QueryEvent) string {
+	query, err := event.UnformattedQuery()
+	if err != nil {
+		return "QUERY"
+	}
+	query = bytes.TrimSpace(query)
+	if i := bytes.IndexAny(query, " \n\t("); i > 0 {
+		query = query[:i]
+	}
+	return string(bytes.ToUpper(query))
+}
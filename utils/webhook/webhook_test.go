@@ -0,0 +1,93 @@
+package webhook
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSign(t *testing.T) {
+	// the digest documented to partners, HMAC-SHA256 of "1760000000.<body>" keyed with the secret
+	want := "t=1760000000,v1=66e880d7175fffb43ce10c4e14db1cfb230c8804b5aafb116affbc9a836c7690"
+	if got := Sign("whsec_test", time.Unix(1760000000, 0), []byte(`{"id":"evt_1"}`)); got != want {
+		t.Errorf("Sign() = %s, want %s", got, want)
+	}
+}
+
+func TestVerify(t *testing.T) {
+	secret := "whsec_test"
+	body := []byte(`{"id":"evt_1"}`)
+	now := time.Now()
+	valid := Sign(secret, now, body)
+	ts := strconv.FormatInt(now.Unix(), 10)
+
+	tests := []struct {
+		name      string
+		secret    string
+		header    string
+		body      []byte
+		tolerance time.Duration
+		wantErr   string
+	}{
+		{name: "valid", secret: secret, header: valid, body: body, tolerance: time.Minute},
+		{name: "spaces after commas", secret: secret, header: strings.ReplaceAll(valid, ",", ", "), body: body, tolerance: time.Minute},
+		{name: "no tolerance accepts old signatures", secret: secret, header: Sign(secret, now.Add(-time.Hour), body), body: body},
+		{name: "too old", secret: secret, header: Sign(secret, now.Add(-time.Hour), body), body: body, tolerance: time.Minute, wantErr: "signature timestamp is too old"},
+		{name: "other secret", secret: "whsec_other", header: valid, body: body, tolerance: time.Minute, wantErr: "signature mismatch"},
+		{name: "tampered body", secret: secret, header: valid, body: []byte(`{"id":"evt_2"}`), tolerance: time.Minute, wantErr: "signature mismatch"},
+		{name: "tampered timestamp", secret: secret, header: strings.Replace(valid, "t="+ts, "t="+strconv.FormatInt(now.Unix()+1, 10), 1), body: body, tolerance: time.Minute, wantErr: "signature mismatch"},
+		{name: "no signature", secret: secret, header: "t=" + ts, body: body, wantErr: "malformed signature header"},
+		{name: "no timestamp", secret: secret, header: strings.TrimPrefix(valid, "t="+ts+","), body: body, wantErr: "malformed signature header"},
+		{name: "timestamp not a number", secret: secret, header: "t=yesterday,v1=abc", body: body, wantErr: "malformed signature timestamp"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Verify(tt.secret, tt.header, tt.body, tt.tolerance)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Verify() error = %v", err)
+				}
+				return
+			}
+			if err == nil || err.Error() != tt.wantErr {
+				t.Errorf("Verify() error = %v, want %s", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestMatchEvent(t *testing.T) {
+	tests := []struct {
+		name    string
+		filters []string
+		event   string
+		want    bool
+	}{
+		{name: "no filter", event: "wallet.deposited", want: true},
+		{name: "wildcard", filters: []string{"*"}, event: "wallet.deposited", want: true},
+		{name: "exact", filters: []string{"loan.disbursed", "wallet.deposited"}, event: "wallet.deposited", want: true},
+		{name: "namespace", filters: []string{"wallet.*"}, event: "wallet.withdrawn", want: true},
+		{name: "other namespace", filters: []string{"wallet.*"}, event: "loan.disbursed", want: false},
+		{name: "namespace prefix is not a namespace", filters: []string{"wallet.*"}, event: "wallets.deposited", want: false},
+		{name: "other event", filters: []string{"wallet.deposited"}, event: "wallet.withdrawn", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := MatchEvent(tt.filters, tt.event); got != tt.want {
+				t.Errorf("MatchEvent(%v, %s) = %v, want %v", tt.filters, tt.event, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewSecret(t *testing.T) {
+	first, err := NewSecret()
+	if err != nil {
+		t.Fatalf("NewSecret() error = %v", err)
+	}
+	second, _ := NewSecret()
+	if !strings.HasPrefix(first, "whsec_") || len(first) != len("whsec_")+64 || first == second {
+		t.Errorf("NewSecret() = %s then %s, want distinct whsec_ secrets of 32 random bytes", first, second)
+	}
+}
@@ -0,0 +1,80 @@
+package worker
+
+import (
+	"context"
+	"time"
+
+	"github.com/ahmadmirdas/julo-test/repository/database/models"
+	"github.com/ahmadmirdas/julo-test/repository/database/models/entity"
+	"github.com/ahmadmirdas/julo-test/utils/activity"
+	"github.com/ahmadmirdas/julo-test/utils/log"
+)
+
+// LoanCollector periodically auto-debits due loan installments from the borrower wallet
+type LoanCollector struct {
+	loanRepo      models.LoanDBRepo
+	batchSize     int
+	graceDays     int
+	retryInterval time.Duration
+}
+
+// NewLoanCollector returns a collector that retries an installment left unpaid after retryInterval
+func NewLoanCollector(loanRepo models.LoanDBRepo, batchSize, graceDays int, retryInterval time.Duration) *LoanCollector {
+	if batchSize <= 0 {
+		batchSize = 100
+	}
+	if retryInterval <= 0 {
+		retryInterval = time.Hour
+	}
+	return &LoanCollector{
+		loanRepo:      loanRepo,
+		batchSize:     batchSize,
+		graceDays:     graceDays,
+		retryInterval: retryInterval,
+	}
+}
+
+// Handle runs a collection as the periodic loan collect job
+func (c *LoanCollector) Handle(ctx context.Context, _ *entity.Job) error {
+	return c.Collect(ctx)
+}
+
+// Collect runs one pass over every installment due today or earlier
+func (c *LoanCollector) Collect(ctx context.Context) error {
+	actx := activity.NewContext("Worker.LoanCollector")
+	now := time.Now()
+	// due dates are stored as plain dates, compare them against the local calendar day
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	overdueAfter := today.AddDate(0, 0, -c.graceDays)
+	retryAt := now.Add(c.retryInterval)
+
+	ids, err := c.loanRepo.GetDueInstallmentIDs(ctx, today, c.batchSize)
+	if err != nil {
+		return err
+	}
+
+	for _, id := range ids {
+		if ctx.Err() != nil {
+			return nil
+		}
+
+		installment, err := c.loanRepo.CollectInstallment(ctx, models.ParamCollectInstallment{
+			InstallmentID: id,
+			OverdueAfter:  overdueAfter,
+			RetryAt:       retryAt,
+		})
+		if err != nil {
+			log.WithContext(actx).Errorf("[Worker LoanCollector] error when collect installment %s, error: %v", id, err)
+			if err := c.loanRepo.DeferInstallment(ctx, id, retryAt); err != nil {
+				log.WithContext(actx).Errorf("[Worker LoanCollector] error when defer installment %s, error: %v", id, err)
+			}
+			continue
+		}
+		if installment != nil {
+			log.WithContext(actx).Infof("[Worker LoanCollector] installment %s is %s, paid %v of %v",
+				installment.ID, installment.Status, installment.PaidAmount, installment.Amount)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,102 @@
+package worker
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/ahmadmirdas/julo-test/repository/database/models"
+	"github.com/ahmadmirdas/julo-test/repository/database/models/entity"
+	"github.com/ahmadmirdas/julo-test/utils/activity"
+	"github.com/ahmadmirdas/julo-test/utils/log"
+	"github.com/ahmadmirdas/julo-test/utils/outbox"
+)
+
+// OutboxRelay delivers the events written to the outbox to every configured sink
+type OutboxRelay struct {
+	outboxRepo  models.OutboxDBRepo
+	sinks       []outbox.Sink
+	interval    time.Duration
+	batchSize   int
+	maxAttempts int
+}
+
+func NewOutboxRelay(outboxRepo models.OutboxDBRepo, sinks []outbox.Sink, interval time.Duration, batchSize, maxAttempts int) *OutboxRelay {
+	if interval <= 0 {
+		interval = time.Second
+	}
+	if batchSize <= 0 {
+		batchSize = 100
+	}
+	if maxAttempts <= 0 {
+		maxAttempts = 20
+	}
+	return &OutboxRelay{
+		outboxRepo:  outboxRepo,
+		sinks:       sinks,
+		interval:    interval,
+		batchSize:   batchSize,
+		maxAttempts: maxAttempts,
+	}
+}
+
+// Start relays pending events until ctx is done, full batches are followed by the next one right away
+func (o *OutboxRelay) Start(ctx context.Context) {
+	actx := activity.NewContext("Worker.OutboxRelay")
+	defer o.close()
+
+	for {
+		n, err := o.Relay(ctx)
+		if err != nil {
+			log.WithContext(actx).Errorf("[Worker OutboxRelay] error when relay events, error: %v", err)
+		}
+		if err == nil && n == o.batchSize && ctx.Err() == nil {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(o.interval):
+		}
+	}
+}
+
+// Relay publishes one batch of pending events and returns how many were delivered
+func (o *OutboxRelay) Relay(ctx context.Context) (int, error) {
+	if len(o.sinks) == 0 {
+		return 0, nil
+	}
+
+	return o.outboxRepo.RelayEvents(ctx, models.ParamRelayEvents{
+		Limit:       o.batchSize,
+		MaxAttempts: o.maxAttempts,
+		Publish: func(events []entity.OutboxEvent) (int, error) {
+			for i := range events {
+				if err := o.publish(ctx, &events[i]); err != nil {
+					return i, err
+				}
+			}
+			return len(events), nil
+		},
+	})
+}
+
+func (o *OutboxRelay) publish(ctx context.Context, e *entity.OutboxEvent) error {
+	event := models.ToEvent(e)
+	for _, sink := range o.sinks {
+		if err := sink.Publish(ctx, event); err != nil {
+			return fmt.Errorf("%s sink: %v", sink.Name(), err)
+		}
+	}
+
+	return nil
+}
+
+func (o *OutboxRelay) close() {
+	for _, sink := range o.sinks {
+		if err := sink.Close(); err != nil {
+			log.WithContext(activity.NewContext("Worker.OutboxRelay")).Errorf("[Worker OutboxRelay] error when close %s sink, error: %v", sink.Name(), err)
+		}
+	}
+}
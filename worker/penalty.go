@@ -0,0 +1,79 @@
+package worker
+
+import (
+	"context"
+	"time"
+
+	"github.com/ahmadmirdas/julo-test/repository/database/models"
+	"github.com/ahmadmirdas/julo-test/repository/database/models/entity"
+	"github.com/ahmadmirdas/julo-test/utils/activity"
+	"github.com/ahmadmirdas/julo-test/utils/log"
+	"github.com/ahmadmirdas/julo-test/utils/penalty"
+)
+
+// PenaltyAccruer accrues one day of late fee on every overdue installment, running it more
+// than once a day is safe because accruals are unique per installment and date
+type PenaltyAccruer struct {
+	loanRepo  models.LoanDBRepo
+	engine    *penalty.Engine
+	batchSize int
+	graceDays int
+}
+
+func NewPenaltyAccruer(loanRepo models.LoanDBRepo, engine *penalty.Engine, batchSize, graceDays int) *PenaltyAccruer {
+	if batchSize <= 0 {
+		batchSize = 100
+	}
+	return &PenaltyAccruer{
+		loanRepo:  loanRepo,
+		engine:    engine,
+		batchSize: batchSize,
+		graceDays: graceDays,
+	}
+}
+
+// Handle runs an accrual as the periodic penalty accrue job
+func (a *PenaltyAccruer) Handle(ctx context.Context, _ *entity.Job) error {
+	return a.Accrue(ctx)
+}
+
+// Accrue applies today's late fee to every overdue installment not accrued yet today
+func (a *PenaltyAccruer) Accrue(ctx context.Context) error {
+	actx := activity.NewContext("Worker.PenaltyAccruer")
+	now := time.Now()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	overdueAfter := today.AddDate(0, 0, -a.graceDays)
+
+	for ctx.Err() == nil {
+		ids, err := a.loanRepo.GetOverdueInstallmentIDs(ctx, overdueAfter, today, a.engine.Rules(), a.batchSize)
+		if err != nil {
+			return err
+		}
+		if len(ids) == 0 {
+			return nil
+		}
+
+		accrued := 0
+		for _, id := range ids {
+			res, err := a.loanRepo.AccruePenalty(ctx, models.ParamAccruePenalty{
+				InstallmentID: id,
+				AccrualDate:   today,
+				Calculate:     a.engine.Calculate,
+			})
+			if err != nil {
+				log.WithContext(actx).Errorf("[Worker PenaltyAccruer] error when accrue installment %s, error: %v", id, err)
+				continue
+			}
+			if res != nil {
+				accrued++
+				log.WithContext(actx).Infof("[Worker PenaltyAccruer] installment %s accrued late fee %v", id, res.Amount)
+			}
+		}
+		// installments skipped because they are locked by another accruer would be returned again
+		if accrued == 0 || len(ids) < a.batchSize {
+			return nil
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,105 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/ahmadmirdas/julo-test/repository/database/models"
+	"github.com/ahmadmirdas/julo-test/repository/database/models/entity"
+	"github.com/ahmadmirdas/julo-test/utils/penalty"
+)
+
+// fakeLoanRepo serves overdue installments in batches and accrues them unless they are locked
+type fakeLoanRepo struct {
+	models.LoanDBRepo
+	overdue      []string
+	locked       map[string]bool
+	failing      map[string]bool
+	overdueAfter time.Time
+	accrualDates []time.Time
+	queries      int
+}
+
+func (f *fakeLoanRepo) GetOverdueInstallmentIDs(ctx context.Context, overdueAfter, accrualDate time.Time, rules []penalty.Rule, limit int) ([]string, error) {
+	f.queries++
+	f.overdueAfter = overdueAfter
+	if len(f.overdue) > limit {
+		return f.overdue[:limit], nil
+	}
+	return f.overdue, nil
+}
+
+func (f *fakeLoanRepo) AccruePenalty(ctx context.Context, param models.ParamAccruePenalty) (*entity.LoanPenalty, error) {
+	f.accrualDates = append(f.accrualDates, param.AccrualDate)
+	if f.failing[param.InstallmentID] {
+		return nil, errors.New("connection reset")
+	}
+	if f.locked[param.InstallmentID] {
+		return nil, nil
+	}
+	for i, id := range f.overdue {
+		if id == param.InstallmentID {
+			f.overdue = append(f.overdue[:i:i], f.overdue[i+1:]...)
+			break
+		}
+	}
+	return &entity.LoanPenalty{InstallmentID: param.InstallmentID}, nil
+}
+
+func TestPenaltyAccrue(t *testing.T) {
+	installments := func(n int) []string {
+		ids := make([]string, n)
+		for i := range ids {
+			ids[i] = fmt.Sprintf("installment-%d", i)
+		}
+		return ids
+	}
+
+	tests := []struct {
+		name        string
+		overdue     []string
+		locked      map[string]bool
+		failing     map[string]bool
+		wantQueries int
+		wantLeft    int
+	}{
+		{name: "nothing overdue", wantQueries: 1},
+		{name: "one partial batch", overdue: installments(2), wantQueries: 1},
+		{name: "full batches until a partial one", overdue: installments(7), wantQueries: 3},
+		{name: "full batches until an empty one", overdue: installments(6), wantQueries: 3},
+		{name: "stops when a full batch is locked by another accruer", overdue: installments(3),
+			locked: map[string]bool{"installment-0": true, "installment-1": true, "installment-2": true}, wantQueries: 1, wantLeft: 3},
+		{name: "a failed installment does not stop the batch", overdue: installments(2),
+			failing: map[string]bool{"installment-0": true}, wantQueries: 1, wantLeft: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &fakeLoanRepo{overdue: tt.overdue, locked: tt.locked, failing: tt.failing}
+			accruer := NewPenaltyAccruer(repo, penalty.NewEngine(nil), 3, 2)
+
+			if err := accruer.Accrue(context.Background()); err != nil {
+				t.Fatalf("Accrue() error = %v", err)
+			}
+			if repo.queries != tt.wantQueries {
+				t.Errorf("queried %d batches, want %d", repo.queries, tt.wantQueries)
+			}
+			if len(repo.overdue) != tt.wantLeft {
+				t.Errorf("%d installments left unaccrued, want %d", len(repo.overdue), tt.wantLeft)
+			}
+
+			now := time.Now()
+			today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+			if !repo.overdueAfter.Equal(today.AddDate(0, 0, -2)) {
+				t.Errorf("overdue after %s, want two grace days before %s", repo.overdueAfter, today)
+			}
+			for _, date := range repo.accrualDates {
+				if !date.Equal(today) {
+					t.Errorf("accrued on %s, want %s", date, today)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,258 @@
+package worker
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/ahmadmirdas/julo-test/repository/database/models"
+	"github.com/ahmadmirdas/julo-test/repository/database/models/entity"
+	"github.com/ahmadmirdas/julo-test/utils/activity"
+	"github.com/ahmadmirdas/julo-test/utils/log"
+	"github.com/ahmadmirdas/julo-test/utils/schedule"
+)
+
+const (
+	JobLoanCollect    string = "loan.collect"
+	JobPenaltyAccrue  string = "penalty.accrue"
+	JobScheduleRun    string = "schedule.run"
+	JobWebhookDeliver string = "webhook.deliver"
+	JobPurge          string = "jobs.purge"
+)
+
+// periodicMaxAttempts is low because the next period runs the job again anyway
+const periodicMaxAttempts = 3
+
+// JobHandler runs one job, a returned error retries the job with backoff. Handlers should return
+// early when ctx is done so the queue can drain on shutdown.
+type JobHandler func(ctx context.Context, job *entity.Job) error
+
+type periodicJob struct {
+	jobType  string
+	interval time.Duration
+}
+
+// QueueOptions tunes the job queue, zero values fall back to defaults
+type QueueOptions struct {
+	Concurrency       int
+	PollInterval      time.Duration
+	VisibilityTimeout time.Duration
+	RetryBackoff      time.Duration
+	MaxBackoff        time.Duration
+}
+
+// Queue runs jobs stored in Postgres with the handler registered for their type. Jobs are claimed
+// with SKIP LOCKED so any number of server instances can share the queue.
+type Queue struct {
+	jobRepo  models.JobDBRepo
+	opts     QueueOptions
+	handlers map[string]JobHandler
+	types    []string
+	periodic []periodicJob
+	wg       sync.WaitGroup
+}
+
+func NewQueue(jobRepo models.JobDBRepo, opts QueueOptions) *Queue {
+	if opts.Concurrency <= 0 {
+		opts.Concurrency = 4
+	}
+	if opts.PollInterval <= 0 {
+		opts.PollInterval = time.Second
+	}
+	if opts.VisibilityTimeout <= 0 {
+		opts.VisibilityTimeout = 5 * time.Minute
+	}
+	if opts.RetryBackoff <= 0 {
+		opts.RetryBackoff = 10 * time.Second
+	}
+	return &Queue{
+		jobRepo:  jobRepo,
+		opts:     opts,
+		handlers: map[string]JobHandler{},
+	}
+}
+
+// Register sets the handler of jobType, it must be called before Start
+func (q *Queue) Register(jobType string, handler JobHandler) {
+	if _, ok := q.handlers[jobType]; !ok {
+		q.types = append(q.types, jobType)
+	}
+	q.handlers[jobType] = handler
+}
+
+// Every enqueues jobType once per interval, instances enqueueing the same period share one job
+func (q *Queue) Every(jobType string, interval time.Duration) {
+	if interval <= 0 {
+		interval = time.Minute
+	}
+	q.periodic = append(q.periodic, periodicJob{jobType: jobType, interval: interval})
+}
+
+// Start launches the workers and the periodic enqueuers, they stop when ctx is done
+func (q *Queue) Start(ctx context.Context) {
+	for i := 0; i < q.opts.Concurrency; i++ {
+		q.wg.Add(1)
+		go q.work(ctx)
+	}
+	for _, p := range q.periodic {
+		q.wg.Add(1)
+		go q.enqueueEvery(ctx, p)
+	}
+}
+
+// Wait blocks until every worker stopped and the jobs in flight are finished, or ctx is done
+func (q *Queue) Wait(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		q.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("jobs still running: %w", ctx.Err())
+	}
+}
+
+func (q *Queue) work(ctx context.Context) {
+	defer q.wg.Done()
+	actx := activity.NewContext("Worker.Queue")
+
+	for ctx.Err() == nil {
+		q.bury(actx)
+		job, err := q.jobRepo.ClaimJob(actx, q.types, q.opts.VisibilityTimeout)
+		if err != nil {
+			log.WithContext(actx).Errorf("[Worker Queue] error when claim job, error: %v", err)
+		}
+		if job != nil {
+			q.run(ctx, job)
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+		case <-time.After(q.opts.PollInterval):
+		}
+	}
+}
+
+// bury marks dead the jobs whose lock expired on their last attempt, ClaimJob no longer claims them
+func (q *Queue) bury(actx context.Context) {
+	jobs, err := q.jobRepo.BuryExpiredJobs(actx, q.types)
+	if err != nil {
+		log.WithContext(actx).Errorf("[Worker Queue] error when bury expired jobs, error: %v", err)
+		return
+	}
+	for _, job := range jobs {
+		log.WithContext(actx).Errorf("[Worker Queue] job %s %s is dead after %d attempts, error: %s", job.Type, job.ID, job.Attempts, job.LastError)
+	}
+}
+
+// run executes a claimed job and keeps its lock alive until the handler returns
+func (q *Queue) run(ctx context.Context, job *entity.Job) {
+	actx := activity.NewContext("Worker.Queue")
+
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(q.opts.VisibilityTimeout / 2)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				if err := q.jobRepo.ExtendJob(actx, job.ID, job.Attempts, q.opts.VisibilityTimeout); err != nil {
+					log.WithContext(actx).Errorf("[Worker Queue] error when extend job %s, error: %v", job.ID, err)
+				}
+			}
+		}
+	}()
+
+	err := q.handle(ctx, job)
+	close(done)
+
+	if err == nil {
+		if err := q.jobRepo.CompleteJob(actx, job.ID, job.Attempts); err != nil {
+			log.WithContext(actx).Errorf("[Worker Queue] error when complete job %s, error: %v", job.ID, err)
+		}
+		return
+	}
+
+	failed, ferr := q.jobRepo.FailJob(actx, models.ParamFailJob{
+		JobID:    job.ID,
+		Attempts: job.Attempts,
+		Error:    err.Error(),
+		RetryAt:  time.Now().Add(schedule.Backoff(q.opts.RetryBackoff, q.opts.MaxBackoff, job.Attempts)),
+	})
+	if ferr != nil {
+		log.WithContext(actx).Errorf("[Worker Queue] error when fail job %s, error: %v", job.ID, ferr)
+		return
+	}
+	if failed != nil && failed.Status == models.JobStatusDead {
+		log.WithContext(actx).Errorf("[Worker Queue] job %s %s is dead after %d attempts, error: %v", job.Type, job.ID, job.Attempts, err)
+		return
+	}
+	log.WithContext(actx).Warnf("[Worker Queue] job %s %s attempt %d failed, error: %v", job.Type, job.ID, job.Attempts, err)
+}
+
+// handle turns a handler panic into a failed attempt
+func (q *Queue) handle(ctx context.Context, job *entity.Job) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("job panicked: %v", r)
+		}
+	}()
+
+	return q.handlers[job.Type](ctx, job)
+}
+
+func (q *Queue) enqueueEvery(ctx context.Context, p periodicJob) {
+	defer q.wg.Done()
+	actx := activity.NewContext("Worker.Queue")
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+
+	for {
+		period := time.Now().Truncate(p.interval)
+		_, err := q.jobRepo.EnqueueJob(actx, models.ParamEnqueueJob{
+			Type:        p.jobType,
+			RunAt:       period,
+			MaxAttempts: periodicMaxAttempts,
+			UniqueKey:   fmt.Sprintf("%s:%d", p.jobType, period.Unix()),
+		})
+		if err != nil {
+			log.WithContext(actx).Errorf("[Worker Queue] error when enqueue %s, error: %v", p.jobType, err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Purger deletes finished jobs older than the retention so the jobs table stays small
+type Purger struct {
+	jobRepo   models.JobDBRepo
+	retention time.Duration
+}
+
+func NewPurger(jobRepo models.JobDBRepo, retention time.Duration) *Purger {
+	if retention <= 0 {
+		retention = 7 * 24 * time.Hour
+	}
+	return &Purger{jobRepo: jobRepo, retention: retention}
+}
+
+func (p *Purger) Handle(ctx context.Context, _ *entity.Job) error {
+	n, err := p.jobRepo.PurgeJobs(ctx, time.Now().Add(-p.retention))
+	if err != nil {
+		return err
+	}
+	log.WithContext(activity.NewContext("Worker.Purger")).Infof("[Worker Purger] purged %d finished jobs", n)
+	return nil
+}
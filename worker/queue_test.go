@@ -0,0 +1,178 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ahmadmirdas/julo-test/repository/database/models"
+	"github.com/ahmadmirdas/julo-test/repository/database/models/entity"
+)
+
+// fakeJobRepo hands out the claimable jobs once and records what the queue did with them
+type fakeJobRepo struct {
+	models.JobDBRepo
+	mu        sync.Mutex
+	claimable []*entity.Job
+	expired   []entity.Job
+	buried    int
+	extended  []int
+	completed []int
+	failed    []models.ParamFailJob
+}
+
+func (f *fakeJobRepo) ClaimJob(ctx context.Context, types []string, visibility time.Duration) (*entity.Job, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if len(f.claimable) == 0 {
+		return nil, nil
+	}
+	job := f.claimable[0]
+	f.claimable = f.claimable[1:]
+	return job, nil
+}
+
+func (f *fakeJobRepo) BuryExpiredJobs(ctx context.Context, types []string) ([]entity.Job, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	jobs := f.expired
+	f.expired = nil
+	f.buried += len(jobs)
+	return jobs, nil
+}
+
+func (f *fakeJobRepo) ExtendJob(ctx context.Context, jobID string, attempts int, visibility time.Duration) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.extended = append(f.extended, attempts)
+	return nil
+}
+
+func (f *fakeJobRepo) CompleteJob(ctx context.Context, jobID string, attempts int) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.completed = append(f.completed, attempts)
+	return nil
+}
+
+func (f *fakeJobRepo) FailJob(ctx context.Context, param models.ParamFailJob) (*entity.Job, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.failed = append(f.failed, param)
+	return &entity.Job{ID: param.JobID, Status: models.JobStatusPending}, nil
+}
+
+func TestQueueRun(t *testing.T) {
+	tests := []struct {
+		name      string
+		handler   JobHandler
+		completed bool
+		failure   string
+		extended  bool
+	}{
+		{
+			name:      "completes",
+			handler:   func(ctx context.Context, job *entity.Job) error { return nil },
+			completed: true,
+		},
+		{
+			name:    "fails",
+			handler: func(ctx context.Context, job *entity.Job) error { return errors.New("upstream down") },
+			failure: "upstream down",
+		},
+		{
+			name:    "panics",
+			handler: func(ctx context.Context, job *entity.Job) error { panic("boom") },
+			failure: "job panicked: boom",
+		},
+		{
+			name: "extends a long attempt",
+			handler: func(ctx context.Context, job *entity.Job) error {
+				time.Sleep(50 * time.Millisecond)
+				return nil
+			},
+			completed: true,
+			extended:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &fakeJobRepo{}
+			q := NewQueue(repo, QueueOptions{VisibilityTimeout: 20 * time.Millisecond, RetryBackoff: time.Minute})
+			q.Register("test", tt.handler)
+
+			start := time.Now()
+			q.run(context.Background(), &entity.Job{ID: "job", Type: "test", Attempts: 2})
+			// the lock extender may still be returning
+			repo.mu.Lock()
+			defer repo.mu.Unlock()
+
+			if tt.completed != (len(repo.completed) == 1) {
+				t.Errorf("completed = %v, want %v", repo.completed, tt.completed)
+			}
+			for _, attempts := range append(repo.completed, repo.extended...) {
+				if attempts != 2 {
+					t.Errorf("attempt %d was acted on, want attempt 2", attempts)
+				}
+			}
+			if tt.extended != (len(repo.extended) > 0) {
+				t.Errorf("extended %d times, want extended = %v", len(repo.extended), tt.extended)
+			}
+			if tt.failure == "" {
+				if len(repo.failed) != 0 {
+					t.Errorf("failed = %+v, want none", repo.failed)
+				}
+				return
+			}
+			if len(repo.failed) != 1 {
+				t.Fatalf("failed %d times, want once", len(repo.failed))
+			}
+			failed := repo.failed[0]
+			if failed.Error != tt.failure || failed.Attempts != 2 {
+				t.Errorf("failed = %+v, want error %q on attempt 2", failed, tt.failure)
+			}
+			// the second attempt backs off twice the base
+			if retryIn := failed.RetryAt.Sub(start); retryIn < 2*time.Minute || retryIn > 2*time.Minute+time.Second {
+				t.Errorf("retry in %v, want 2m", retryIn)
+			}
+		})
+	}
+}
+
+// TestQueueBuriesExpiredJobs checks a worker marks the jobs whose lock expired on their last attempt
+// dead before it claims the next job
+func TestQueueBuriesExpiredJobs(t *testing.T) {
+	ran := make(chan struct{})
+	repo := &fakeJobRepo{
+		claimable: []*entity.Job{{ID: "next", Type: "test", Attempts: 1}},
+		expired:   []entity.Job{{ID: "expired", Type: "test", Attempts: 3, MaxAttempts: 3, Status: models.JobStatusDead}},
+	}
+	q := NewQueue(repo, QueueOptions{Concurrency: 1, PollInterval: time.Millisecond})
+	q.Register("test", func(ctx context.Context, job *entity.Job) error {
+		close(ran)
+		return nil
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	q.Start(ctx)
+	select {
+	case <-ran:
+	case <-time.After(time.Second):
+		t.Fatal("the claimable job did not run")
+	}
+	cancel()
+	if err := q.Wait(context.Background()); err != nil {
+		t.Fatalf("wait: %v", err)
+	}
+
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+	if repo.buried != 1 {
+		t.Errorf("buried %d jobs, want 1", repo.buried)
+	}
+	if len(repo.completed) != 1 {
+		t.Errorf("completed %d jobs, want 1", len(repo.completed))
+	}
+}
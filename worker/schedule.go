@@ -0,0 +1,137 @@
+package worker
+
+import (
+	"context"
+	"time"
+
+	"github.com/ahmadmirdas/julo-test/repository/database/models"
+	"github.com/ahmadmirdas/julo-test/repository/database/models/entity"
+	"github.com/ahmadmirdas/julo-test/service"
+	"github.com/ahmadmirdas/julo-test/utils/activity"
+	"github.com/ahmadmirdas/julo-test/utils/log"
+	"github.com/ahmadmirdas/julo-test/utils/schedule"
+	"github.com/google/uuid"
+)
+
+// ScheduleRunner executes due transfer schedules through the same transfer service as manual transfers
+type ScheduleRunner struct {
+	scheduleRepo    models.ScheduleDBRepo
+	transferService service.TransferService
+	batchSize       int
+	maxAttempts     int
+	retryBackoff    time.Duration
+	maxBackoff      time.Duration
+}
+
+func NewScheduleRunner(scheduleRepo models.ScheduleDBRepo, transferService service.TransferService, batchSize, maxAttempts int, retryBackoff, maxBackoff time.Duration) *ScheduleRunner {
+	if batchSize <= 0 {
+		batchSize = 100
+	}
+	if maxAttempts <= 0 {
+		maxAttempts = 5
+	}
+	if retryBackoff <= 0 {
+		retryBackoff = time.Minute
+	}
+	return &ScheduleRunner{
+		scheduleRepo:    scheduleRepo,
+		transferService: transferService,
+		batchSize:       batchSize,
+		maxAttempts:     maxAttempts,
+		retryBackoff:    retryBackoff,
+		maxBackoff:      maxBackoff,
+	}
+}
+
+// Handle runs the due schedules as the periodic schedule run job
+func (s *ScheduleRunner) Handle(ctx context.Context, _ *entity.Job) error {
+	return s.Run(ctx)
+}
+
+// Run executes every schedule due now, batch by batch
+func (s *ScheduleRunner) Run(ctx context.Context) error {
+	actx := activity.NewContext("Worker.ScheduleRunner")
+
+	for ctx.Err() == nil {
+		now := time.Now()
+		ids, err := s.scheduleRepo.GetDueScheduleIDs(ctx, now, s.batchSize)
+		if err != nil {
+			return err
+		}
+		if len(ids) == 0 {
+			return nil
+		}
+
+		ran := 0
+		for _, id := range ids {
+			res, err := s.scheduleRepo.RunSchedule(ctx, id, now, func(sc *entity.TransferSchedule, transferred bool) error {
+				return s.execute(ctx, sc, transferred, now)
+			})
+			if err != nil {
+				log.WithContext(actx).Errorf("[Worker ScheduleRunner] error when run schedule %s, error: %v", id, err)
+				continue
+			}
+			if res != nil {
+				ran++
+				log.WithContext(actx).Infof("[Worker ScheduleRunner] schedule %s run %s, next run at %v", id, res.LastStatus, res.NextRunAt)
+			}
+		}
+		// schedules locked by another runner would be returned again
+		if ran == 0 || len(ids) < s.batchSize {
+			return nil
+		}
+	}
+
+	return nil
+}
+
+// execute transfers the pending occurrence of sc and moves it to its next run. Transient failures
+// are retried with backoff, rejected transfers and exhausted retries skip the occurrence.
+func (s *ScheduleRunner) execute(ctx context.Context, sc *entity.TransferSchedule, transferred bool, now time.Time) error {
+	sc.LastRunAt = now
+	sc.LastError = ""
+	if !transferred {
+		_, err := s.transferService.Transfer(ctx, service.ParamTransfer{
+			CustomerXId:  sc.CustomerXId,
+			Currency:     sc.Currency,
+			FromPocketID: sc.FromPocketID,
+			ToPocketID:   sc.ToPocketID,
+			Amount:       sc.Amount,
+			ReferenceID:  sc.RunReferenceID,
+		})
+		if err != nil {
+			sc.LastError = err.Error()
+			sc.Attempts++
+			if !service.IsClientError(err) && sc.Attempts < s.maxAttempts {
+				sc.LastStatus = models.ScheduleRunRetrying
+				sc.NextRunAt = now.Add(schedule.Backoff(s.retryBackoff, s.maxBackoff, sc.Attempts))
+				return nil
+			}
+			sc.LastStatus = models.ScheduleRunFailed
+			return s.advance(sc, now)
+		}
+	}
+
+	sc.LastStatus = models.ScheduleRunSuccess
+	return s.advance(sc, now)
+}
+
+// advance finishes a one-off schedule or moves a recurring one to its next occurrence after now
+func (s *ScheduleRunner) advance(sc *entity.TransferSchedule, now time.Time) error {
+	sc.Attempts = 0
+	if sc.CronExpr == "" {
+		sc.Status = models.ScheduleStatusCompleted
+		if sc.LastStatus == models.ScheduleRunFailed {
+			sc.Status = models.ScheduleStatusFailed
+		}
+		return nil
+	}
+
+	next, err := schedule.Next(sc.CronExpr, now)
+	if err != nil {
+		return err
+	}
+	sc.NextRunAt = next
+	sc.RunReferenceID = uuid.New().String()
+	return nil
+}
@@ -0,0 +1,142 @@
+package worker
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/ahmadmirdas/julo-test/repository/database/models"
+	"github.com/ahmadmirdas/julo-test/repository/database/models/entity"
+	"github.com/ahmadmirdas/julo-test/utils/outbox"
+	"github.com/ahmadmirdas/julo-test/utils/webhook"
+)
+
+// WebhookDispatcher is the outbox sink turning every relayed event into webhook deliveries
+type WebhookDispatcher struct {
+	webhookRepo models.WebhookDBRepo
+	maxAttempts int
+}
+
+func NewWebhookDispatcher(webhookRepo models.WebhookDBRepo, maxAttempts int) *WebhookDispatcher {
+	if maxAttempts <= 0 {
+		maxAttempts = 10
+	}
+	return &WebhookDispatcher{webhookRepo: webhookRepo, maxAttempts: maxAttempts}
+}
+
+func (d *WebhookDispatcher) Name() string {
+	return "webhook"
+}
+
+func (d *WebhookDispatcher) Publish(ctx context.Context, event outbox.Event) error {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	_, err = d.webhookRepo.DispatchEvent(ctx, models.ParamDispatchEvent{
+		EventID:     event.ID,
+		EventType:   event.Type,
+		CustomerXId: event.CustomerXId,
+		Payload:     payload,
+		JobType:     JobWebhookDeliver,
+		MaxAttempts: d.maxAttempts,
+	})
+	return err
+}
+
+func (d *WebhookDispatcher) Close() error {
+	return nil
+}
+
+// WebhookDeliverer posts one signed delivery per job, failed attempts are retried by the job queue
+// with backoff and the delivery is dead once the job runs out of attempts
+type WebhookDeliverer struct {
+	webhookRepo models.WebhookDBRepo
+	client      *http.Client
+}
+
+func NewWebhookDeliverer(webhookRepo models.WebhookDBRepo, timeout time.Duration) *WebhookDeliverer {
+	if timeout <= 0 {
+		timeout = 10 * time.Second
+	}
+	return &WebhookDeliverer{
+		webhookRepo: webhookRepo,
+		client:      &http.Client{Timeout: timeout},
+	}
+}
+
+func (d *WebhookDeliverer) Handle(ctx context.Context, job *entity.Job) error {
+	var payload models.WebhookJobPayload
+	if err := json.Unmarshal(job.Payload, &payload); err != nil {
+		return err
+	}
+
+	delivery, err := d.webhookRepo.GetDelivery(ctx, payload.DeliveryID)
+	if err != nil {
+		return err
+	}
+	if delivery.Status != models.WebhookStatusPending {
+		return nil
+	}
+	if !delivery.Endpoint.IsEnabled {
+		return d.webhookRepo.RecordAttempt(ctx, models.ParamRecordAttempt{
+			DeliveryID: delivery.ID,
+			Error:      "endpoint is disabled",
+			Status:     models.WebhookStatusDead,
+		})
+	}
+
+	started := time.Now()
+	code, sendErr := d.send(ctx, delivery)
+	attempt := models.ParamRecordAttempt{
+		DeliveryID:   delivery.ID,
+		ResponseCode: code,
+		Duration:     time.Since(started),
+		Status:       models.WebhookStatusSucceeded,
+	}
+	if sendErr != nil {
+		attempt.Error = sendErr.Error()
+		attempt.Status = models.WebhookStatusPending
+		if job.Attempts >= job.MaxAttempts {
+			attempt.Status = models.WebhookStatusDead
+		}
+	}
+	err = d.webhookRepo.RecordAttempt(ctx, attempt)
+	if err != nil {
+		return err
+	}
+
+	return sendErr
+}
+
+func (d *WebhookDeliverer) send(ctx context.Context, delivery *entity.WebhookDelivery) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, delivery.Endpoint.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, err
+	}
+	now := time.Now()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "julo-test-webhook/1")
+	req.Header.Set(webhook.HeaderID, delivery.ID)
+	req.Header.Set(webhook.HeaderEvent, delivery.EventType)
+	req.Header.Set(webhook.HeaderTimestamp, fmt.Sprint(now.Unix()))
+	req.Header.Set(webhook.HeaderSignature, webhook.Sign(delivery.Endpoint.Secret, now, delivery.Payload))
+
+	res, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer res.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(res.Body, 64<<10))
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return res.StatusCode, fmt.Errorf("endpoint responded %s", res.Status)
+	}
+
+	return res.StatusCode, nil
+}